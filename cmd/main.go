@@ -1,11 +1,16 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/rioam2/witigo/pkg/codegen"
+	"github.com/rioam2/witigo/pkg/wit"
 )
 
 func main() {
@@ -17,21 +22,170 @@ func main() {
 	switch os.Args[1] {
 
 	case "generate":
+		input, outDir, opts := parseBindingsArgs("generate", os.Args[2:], "")
+		generate(input, outDir, opts)
+
+	case "check":
+		input, outDir, opts := parseBindingsArgs("check", os.Args[2:], "Exits with status 2 and prints the differences if the bindings in outDir are not up to date.\n")
+		check(input, outDir, opts)
+
+	case "wit":
 		if len(os.Args) < 3 {
-			fmt.Printf("Usage: %s generate <input> <outDir>\n", os.Args[0])
+			fmt.Printf("Usage: %s wit <input>\n", os.Args[0])
 			os.Exit(1)
 		}
-		generate(os.Args[2], os.Args[3])
+		printWit(os.Args[2])
+
+	case "diff":
+		flags := flag.NewFlagSet("diff", flag.ExitOnError)
+		world := flags.String("world", "", "world to compare (default: the only world of the package)")
+		asJson := flags.Bool("json", false, "print the changes as JSON")
+		flags.Usage = func() {
+			fmt.Printf("Usage: %s diff [-world <name>] [-json] <old> <new>\n", os.Args[0])
+			fmt.Printf("Exits with status 2 if the new version breaks hosts of the old one.\n")
+			flags.PrintDefaults()
+		}
+		flags.Parse(os.Args[2:])
+		if flags.NArg() < 2 {
+			flags.Usage()
+			os.Exit(1)
+		}
+		diff(flags.Arg(0), flags.Arg(1), *world, *asJson)
 
 	default:
 		fmt.Printf("Unknown command: %s\n", os.Args[1])
-		fmt.Printf("Available commands: generate\n")
+		fmt.Printf("Available commands: generate, check, wit, diff\n")
+		os.Exit(1)
+
+	}
+}
+
+// parseBindingsArgs parses the flags and arguments of a command generating bindings, like generate
+// or check, and returns its input, output directory and options, from a configuration file that
+// the flags and arguments override.
+func parseBindingsArgs(command string, args []string, help string) (string, string, codegen.GenerateOptions) {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	var opts codegen.GenerateOptions
+	configPath := flags.String("config", "", "configuration file, which flags and arguments override (default: witigo.yaml, witigo.yml or witigo.json when no arguments are given)")
+	flags.Var((*stringList)(&opts.Worlds), "world", "world to generate bindings for, may be repeated (default: the only world of the package)")
+	flags.StringVar(&opts.TypesPackage, "types-package", "", "import path of the types package shared by multiple worlds (default: derived from the import path of outDir)")
+	flags.StringVar(&opts.ImportPath, "import-path", "", "import path of outDir (default: derived from go.mod)")
+	flags.StringVar(&opts.PackageName, "package", "", "Go package name of the bindings of a single world (default: derived from the component name)")
+	flags.BoolVar(&opts.SplitFiles, "split", false, "split the bindings into instance.go, types.go and a file per interface")
+	flags.BoolVar(&opts.Wit, "wit", false, "write the WIT source of the definition next to the bindings")
+	flags.BoolVar(&opts.Idiomatic, "idiomatic", false, "name types after their WIT name alone, like Customer rather than CustomerRecord")
+	flags.BoolVar(&opts.SealedVariants, "sealed-variants", false, "generate variants as sealed interfaces with a type per case")
+	flags.BoolVar(&opts.UnwrapResults, "unwrap-results", false, "return results of exported functions as (T, error)")
+	flags.BoolVar(&opts.Mock, "mock", false, "declare a Mock implementing Component, for tests without the component")
+	flags.BoolVar(&opts.Guest, "guest", false, "generate bindings for a component written in Go, which implements the exports and calls the imports")
+	flags.Var((*nameOverrides)(&opts.Names), "name", "Go name of a type or exported function, as `<wit-name>=<GoName>`, may be repeated")
+	flags.Usage = func() {
+		fmt.Printf("Usage: %s %s [-config <file>] [-world <name>]... [-types-package <path>] [-import-path <path>] [-package <name>] [-split] [-wit] [-idiomatic] [-sealed-variants] [-unwrap-results] [-mock] [-guest] [-name <wit-name>=<GoName>]... [<input> <outDir>]\n", os.Args[0], command)
+		fmt.Print(help)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	input, outDir := flags.Arg(0), flags.Arg(1)
+	if *configPath == "" && flags.NArg() == 0 {
+		if found, err := codegen.FindConfig("."); err == nil {
+			*configPath = found
+		}
+	}
+	if *configPath != "" {
+		config, err := codegen.LoadConfig(*configPath)
+		if err != nil {
+			fmt.Printf("Error loading configuration: %v\n", err)
+			os.Exit(1)
+		}
+		opts = overrideOptions(config.Options(), opts, flags)
+		if flags.NArg() == 0 {
+			input, outDir = config.Input, config.Output
+		}
+	}
+	if input == "" || outDir == "" || flags.NArg() == 1 {
+		flags.Usage()
 		os.Exit(1)
+	}
+	return input, outDir, opts
+}
+
+// stringList is a flag that may be given several times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// nameOverrides is a flag that may be given several times, as `<wit-name>=<GoName>`.
+type nameOverrides map[string]string
+
+func (o *nameOverrides) String() string {
+	var overrides []string
+	for name, override := range *o {
+		overrides = append(overrides, name+"="+override)
+	}
+	sort.Strings(overrides)
+	return strings.Join(overrides, ",")
+}
 
+func (o *nameOverrides) Set(value string) error {
+	name, override, ok := strings.Cut(value, "=")
+	if !ok || name == "" || override == "" {
+		return fmt.Errorf("expected <wit-name>=<GoName>, got %q", value)
+	}
+	if *o == nil {
+		*o = map[string]string{}
 	}
+	(*o)[name] = override
+	return nil
 }
 
-func generate(inputFile, outDir string) {
+// overrideOptions returns the options of a configuration file, overridden by the flags set.
+func overrideOptions(opts codegen.GenerateOptions, flagOpts codegen.GenerateOptions, flags *flag.FlagSet) codegen.GenerateOptions {
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "world":
+			opts.Worlds = flagOpts.Worlds
+		case "types-package":
+			opts.TypesPackage = flagOpts.TypesPackage
+		case "import-path":
+			opts.ImportPath = flagOpts.ImportPath
+		case "package":
+			opts.PackageName = flagOpts.PackageName
+		case "split":
+			opts.SplitFiles = flagOpts.SplitFiles
+		case "wit":
+			opts.Wit = flagOpts.Wit
+		case "idiomatic":
+			opts.Idiomatic = flagOpts.Idiomatic
+		case "sealed-variants":
+			opts.SealedVariants = flagOpts.SealedVariants
+		case "unwrap-results":
+			opts.UnwrapResults = flagOpts.UnwrapResults
+		case "mock":
+			opts.Mock = flagOpts.Mock
+		case "guest":
+			opts.Guest = flagOpts.Guest
+		case "name":
+			names := map[string]string{}
+			for name, override := range opts.Names {
+				names[name] = override
+			}
+			for name, override := range flagOpts.Names {
+				names[name] = override
+			}
+			opts.Names = names
+		}
+	})
+	return opts
+}
+
+func generate(inputFile, outDir string, opts codegen.GenerateOptions) {
 	inputFile, err := filepath.Abs(inputFile)
 	if err != nil {
 		fmt.Printf("Error resolving input file path: %v\n", err)
@@ -55,9 +209,70 @@ func generate(inputFile, outDir string) {
 		}
 	}
 
-	err = codegen.GenerateFromFile(inputFile, outDir)
+	err = codegen.GenerateFromFileWithOptions(inputFile, outDir, opts)
 	if err != nil {
 		fmt.Printf("Error generating code: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// check prints the differences between the bindings in outDir and the bindings generated anew,
+// and exits with status 2 if there are any.
+func check(input, outDir string, opts codegen.GenerateOptions) {
+	diffs, err := codegen.CheckFromFileWithOptions(input, outDir, opts)
+	if err != nil {
+		fmt.Printf("Error checking bindings: %v\n", err)
+		os.Exit(1)
+	}
+	if len(diffs) == 0 {
+		fmt.Printf("Bindings in %s are up to date\n", outDir)
+		return
+	}
+	for _, d := range diffs {
+		fmt.Print(d.Diff)
+	}
+	fmt.Printf("Bindings in %s are out of date in %d file(s), regenerate them with `%s generate`\n", outDir, len(diffs), filepath.Base(os.Args[0]))
+	os.Exit(2)
+}
+
+// printWit prints the WIT source of a component or of WIT sources.
+func printWit(input string) {
+	witDefinition, err := codegen.LoadDefinition(input)
+	if err != nil {
+		fmt.Printf("Error loading WIT definition: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(witDefinition.Wit())
+}
+
+// diff prints the changes between two versions of a world, and exits with status 2 if any of them
+// is breaking.
+func diff(oldInput, newInput, world string, asJson bool) {
+	load := func(input string) wit.WitWorldDefinition {
+		witDefinition, err := codegen.LoadDefinition(input)
+		if err != nil {
+			fmt.Printf("Error loading WIT definition: %v\n", err)
+			os.Exit(1)
+		}
+		witWorld, err := witDefinition.World(world)
+		if err != nil {
+			fmt.Printf("Error selecting world of %s: %v\n", input, err)
+			os.Exit(1)
+		}
+		return witWorld
+	}
+	comparison := wit.Compare(load(oldInput), load(newInput))
+	if asJson {
+		output, err := json.MarshalIndent(comparison, "", "  ")
+		if err != nil {
+			fmt.Printf("Error encoding changes: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(output))
+	} else {
+		fmt.Print(comparison.String())
+	}
+	if comparison.Breaking() {
+		os.Exit(2)
+	}
+}
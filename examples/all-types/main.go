@@ -36,7 +36,7 @@ func main() {
 	}
 	fmt.Printf("Result of NestedRecordFunc: %+v\n", nestedRecordFuncResult)
 
-	simpleRecordFuncResult, err := instance.SimpleRecordFunc(all_types_example_component.SimpleRecordRecord{Id: 42})
+	simpleRecordFuncResult, err := instance.SimpleRecordFunc(all_types_example_component.SimpleRecord{Id: 42})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error calling SimpleRecordFunc: %v\n", err)
 		os.Exit(1)
@@ -159,11 +159,11 @@ func TestComplexVariant(t *testing.T) {
 			name: "pair transform",
 			input: all_types_example_component.ComplexUnionVariant{
 				Type: all_types_example_component.ComplexUnionVariantTypePair,
-				Pair: all_types_example_component.SmallRecordRecord{X: 5, Y: 10},
+				Pair: all_types_example_component.SmallRecord{X: 5, Y: 10},
 			},
 			expected: all_types_example_component.ComplexUnionVariant{
 				Type: all_types_example_component.ComplexUnionVariantTypePair,
-				Pair: all_types_example_component.SmallRecordRecord{X: 4, Y: 11},
+				Pair: all_types_example_component.SmallRecord{X: 4, Y: 11},
 			},
 		},
 	}
@@ -48,4 +48,20 @@ type AbiOptions struct {
 	Memory         RuntimeMemory
 	Call           RuntimeCall
 	Context        context.Context
+	// Hooks, if set, is notified of traced calls and allocations.
+	Hooks Hooks
+	// Converters, if set, converts the Go types that WIT types are mapped to.
+	Converters *Converters
+}
+
+// SizeOf returns the size in bytes of the given value type as defined in the Canonical ABI, with
+// Go types converted by the options laid out like the WIT types they are converted to.
+func (opts AbiOptions) SizeOf(value any) uint64 {
+	return sizeOf(opts.Converters, value)
+}
+
+// AlignmentOf returns the alignment in bytes of the given value type as defined in the Canonical
+// ABI, with Go types converted by the options laid out like the WIT types they are converted to.
+func (opts AbiOptions) AlignmentOf(value any) uint64 {
+	return alignmentOf(opts.Converters, value)
 }
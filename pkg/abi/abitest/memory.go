@@ -0,0 +1,76 @@
+package abitest
+
+import (
+	"encoding/binary"
+)
+
+// PageSize is the size in bytes of a WebAssembly linear memory page.
+const PageSize = 65536
+
+// Memory is an abi.RuntimeMemory backed by a plain byte slice. Like WebAssembly linear memory,
+// it has a fixed size that only changes when it is explicitly grown, and every access outside of
+// that size fails instead of silently extending the slice.
+type Memory struct {
+	bytes []byte
+}
+
+// NewMemory creates a zero-filled memory with the given number of pages.
+func NewMemory(pages uint64) *Memory {
+	return &Memory{bytes: make([]byte, pages*PageSize)}
+}
+
+// NewMemoryFromBytes creates a memory whose contents (and size) are a copy of the given bytes.
+func NewMemoryFromBytes(b []byte) *Memory {
+	return &Memory{bytes: append([]byte(nil), b...)}
+}
+
+// Bytes returns the backing slice of the memory. Mutating it mutates the memory.
+func (m *Memory) Bytes() []byte {
+	return m.bytes
+}
+
+// Grow extends the memory by the given number of pages and returns the previous size in pages.
+func (m *Memory) Grow(pages uint64) uint64 {
+	previous := uint64(len(m.bytes)) / PageSize
+	m.bytes = append(m.bytes, make([]byte, pages*PageSize)...)
+	return previous
+}
+
+func (m *Memory) Size() uint64 {
+	return uint64(len(m.bytes))
+}
+
+func (m *Memory) Read(offset, byteCount uint64) ([]byte, bool) {
+	if !m.inBounds(offset, byteCount) {
+		return nil, false
+	}
+	return m.bytes[offset : offset+byteCount], true
+}
+
+func (m *Memory) ReadUint32Le(offset uint64) (uint32, bool) {
+	if !m.inBounds(offset, 4) {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(m.bytes[offset:]), true
+}
+
+func (m *Memory) Write(offset uint64, v []byte) bool {
+	if !m.inBounds(offset, uint64(len(v))) {
+		return false
+	}
+	copy(m.bytes[offset:], v)
+	return true
+}
+
+func (m *Memory) WriteUint32Le(offset uint64, v uint32) bool {
+	if !m.inBounds(offset, 4) {
+		return false
+	}
+	binary.LittleEndian.PutUint32(m.bytes[offset:], v)
+	return true
+}
+
+func (m *Memory) inBounds(offset, byteCount uint64) bool {
+	end := offset + byteCount
+	return end >= offset && end <= uint64(len(m.bytes))
+}
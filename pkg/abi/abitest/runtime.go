@@ -0,0 +1,165 @@
+// Package abitest provides an in-memory fake of a WebAssembly runtime for testing code built on
+// pkg/abi. It supplies a byte-slice backed RuntimeMemory and a RuntimeCall that implements
+// cabi_realloc with a simple bump allocator, so that lifting and lowering can be exercised with
+// plain `go test` and without compiling or instantiating a component.
+package abitest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rioam2/witigo/pkg/abi"
+)
+
+// HeapBase is the address of the first allocation made by a Runtime. Addresses below it are
+// never handed out so that a zero pointer is never a valid allocation.
+const HeapBase = 0x1000
+
+// Func is the signature of a fake exported function registered on a Runtime.
+type Func func(ctx context.Context, params ...uint64) ([]uint64, error)
+
+// Allocation describes a block of memory handed out by cabi_realloc.
+type Allocation struct {
+	Ptr       uint64
+	Size      uint64
+	Alignment uint64
+}
+
+// Runtime is a fake component instance. Its Call method implements abi.RuntimeCall: calls to
+// cabi_realloc are served by an allocator that tracks every live allocation, calls to
+// cabi_post_* functions without a registered export are no-ops, and all other calls are
+// dispatched to functions registered with Export.
+type Runtime struct {
+	Memory *Memory
+
+	exports     map[string]Func
+	allocations map[uint64]Allocation
+	heapTop     uint64
+	allocs      int
+	frees       int
+}
+
+var _ abi.RuntimeMemory = &Memory{}
+
+// NewRuntime creates a fake runtime with a single page of memory.
+func NewRuntime() *Runtime {
+	return &Runtime{
+		Memory:      NewMemory(1),
+		exports:     map[string]Func{},
+		allocations: map[uint64]Allocation{},
+		heapTop:     HeapBase,
+	}
+}
+
+// AbiOptions returns UTF-8 AbiOptions wired to the runtime's memory and call function.
+func (r *Runtime) AbiOptions() abi.AbiOptions {
+	return abi.AbiOptions{
+		StringEncoding: abi.StringEncodingUTF8,
+		Memory:         r.Memory,
+		Call:           r.Call,
+		Context:        context.Background(),
+	}
+}
+
+// Export registers a fake exported function under the given name, replacing any previous one.
+func (r *Runtime) Export(name string, fn Func) {
+	r.exports[name] = fn
+}
+
+// Call invokes the named function. It has the signature of abi.RuntimeCall.
+func (r *Runtime) Call(ctx context.Context, name string, params ...uint64) ([]uint64, error) {
+	if fn, ok := r.exports[name]; ok {
+		return fn(ctx, params...)
+	}
+	if name == "cabi_realloc" {
+		if len(params) != 4 {
+			return nil, fmt.Errorf("cabi_realloc expects 4 parameters, got %d", len(params))
+		}
+		ptr, err := r.Realloc(params[0], params[1], params[2], params[3])
+		if err != nil {
+			return nil, err
+		}
+		return []uint64{ptr}, nil
+	}
+	if strings.HasPrefix(name, "cabi_post_") {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("function %s not found in module", name)
+}
+
+// Realloc implements the semantics of cabi_realloc. A zero oldPtr allocates a new block, a zero
+// newSize frees oldPtr, and anything else moves the contents of oldPtr into a new block.
+// Freed memory is never reused, which keeps use-after-free bugs visible as stale data.
+func (r *Runtime) Realloc(oldPtr, oldSize, alignment, newSize uint64) (uint64, error) {
+	if oldPtr != 0 {
+		old, ok := r.allocations[oldPtr]
+		if !ok {
+			return 0, fmt.Errorf("cabi_realloc of unallocated pointer %d", oldPtr)
+		}
+		delete(r.allocations, oldPtr)
+		r.frees++
+		if newSize == 0 {
+			return 0, nil
+		}
+		if oldSize > old.Size {
+			oldSize = old.Size
+		}
+	}
+	if newSize == 0 && alignment == 0 {
+		return 0, nil
+	}
+	if alignment == 0 {
+		alignment = 1
+	}
+
+	// Zero-sized blocks still consume a byte so that every live allocation has a unique address.
+	ptr := abi.AlignTo(r.heapTop, alignment)
+	end := ptr + max(newSize, 1)
+	if end > r.Memory.Size() {
+		r.Memory.Grow((end - r.Memory.Size() + PageSize - 1) / PageSize)
+	}
+	r.heapTop = end
+	r.allocations[ptr] = Allocation{Ptr: ptr, Size: newSize, Alignment: alignment}
+	r.allocs++
+
+	if oldPtr != 0 {
+		copySize := min(oldSize, newSize)
+		copy(r.Memory.bytes[ptr:ptr+copySize], r.Memory.bytes[oldPtr:oldPtr+copySize])
+	}
+	return ptr, nil
+}
+
+// Allocations returns the allocations that have not been freed yet, ordered by address.
+func (r *Runtime) Allocations() []Allocation {
+	live := make([]Allocation, 0, len(r.allocations))
+	for _, a := range r.allocations {
+		live = append(live, a)
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i].Ptr < live[j].Ptr })
+	return live
+}
+
+// AllocCount returns the total number of allocations made, including ones since freed.
+func (r *Runtime) AllocCount() int {
+	return r.allocs
+}
+
+// FreeCount returns the total number of allocations freed.
+func (r *Runtime) FreeCount() int {
+	return r.frees
+}
+
+// CheckLeaks returns an error describing every allocation that has not been freed.
+func (r *Runtime) CheckLeaks() error {
+	live := r.Allocations()
+	if len(live) == 0 {
+		return nil
+	}
+	leaks := make([]string, len(live))
+	for i, a := range live {
+		leaks[i] = fmt.Sprintf("%d bytes at %d", a.Size, a.Ptr)
+	}
+	return fmt.Errorf("%d allocation(s) not freed: %s", len(live), strings.Join(leaks, ", "))
+}
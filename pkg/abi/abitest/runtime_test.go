@@ -0,0 +1,106 @@
+package abitest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rioam2/witigo/pkg/abi"
+	"github.com/rioam2/witigo/pkg/abi/abitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type PointRecord struct {
+	X    int16
+	Y    uint64
+	Name string
+	Tags []string
+}
+
+func TestRuntimeRealloc(t *testing.T) {
+	r := abitest.NewRuntime()
+
+	ptr, err := r.Realloc(0, 0, 8, 3)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(abitest.HeapBase), ptr)
+
+	next, err := r.Realloc(0, 0, 8, 8)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), next%8, "allocation must respect alignment")
+	assert.Greater(t, next, ptr)
+
+	require.True(t, r.Memory.Write(ptr, []byte{1, 2, 3}))
+	moved, err := r.Realloc(ptr, 3, 1, 5)
+	require.NoError(t, err)
+	data, ok := r.Memory.Read(moved, 3)
+	require.True(t, ok)
+	assert.Equal(t, []byte{1, 2, 3}, data, "realloc must preserve contents")
+
+	_, err = r.Realloc(ptr, 0, 0, 0)
+	assert.Error(t, err, "freeing a moved pointer is a double free")
+
+	assert.Len(t, r.Allocations(), 2)
+	assert.Equal(t, 3, r.AllocCount())
+	assert.Equal(t, 1, r.FreeCount())
+}
+
+func TestRuntimeGrowsMemory(t *testing.T) {
+	r := abitest.NewRuntime()
+	ptr, err := r.Realloc(0, 0, 1, 3*abitest.PageSize)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, r.Memory.Size(), ptr+3*abitest.PageSize)
+	assert.Equal(t, uint64(0), r.Memory.Size()%abitest.PageSize)
+}
+
+func TestMemoryBounds(t *testing.T) {
+	m := abitest.NewMemoryFromBytes([]byte{1, 2, 3, 4})
+	_, ok := m.Read(2, 4)
+	assert.False(t, ok)
+	assert.False(t, m.Write(3, []byte{0, 0}))
+	assert.False(t, m.WriteUint32Le(1, 0))
+	_, ok = m.Read(^uint64(0), 2)
+	assert.False(t, ok, "overflowing offsets must not wrap around")
+
+	v, ok := m.ReadUint32Le(0)
+	require.True(t, ok)
+	assert.Equal(t, uint32(0x04030201), v)
+}
+
+func TestRuntimeExports(t *testing.T) {
+	r := abitest.NewRuntime()
+	r.Export("add", func(ctx context.Context, params ...uint64) ([]uint64, error) {
+		return []uint64{params[0] + params[1]}, nil
+	})
+	opts := r.AbiOptions()
+
+	ret, postReturn, err := abi.Call(opts, "add", 2, 3)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), ret)
+	assert.NoError(t, postReturn(), "post-return functions default to no-ops")
+
+	_, _, err = abi.Call(opts, "missing")
+	assert.ErrorContains(t, err, "function missing not found")
+}
+
+func TestRoundTripWithoutLeaks(t *testing.T) {
+	r := abitest.NewRuntime()
+	opts := r.AbiOptions()
+	original := PointRecord{X: -3, Y: 1 << 40, Name: "origin", Tags: []string{"a", "", "ccc"}}
+
+	ptr, free, err := abi.Write(opts, original, nil)
+	require.NoError(t, err)
+
+	var decoded PointRecord
+	require.NoError(t, abi.Read(opts, ptr, &decoded))
+	assert.Equal(t, original, decoded)
+
+	require.NoError(t, free())
+	assert.NoError(t, r.CheckLeaks())
+}
+
+func TestCheckLeaks(t *testing.T) {
+	r := abitest.NewRuntime()
+	_, _, err := abi.Write(r.AbiOptions(), "leaked", nil)
+	require.NoError(t, err)
+	assert.ErrorContains(t, r.CheckLeaks(), "2 allocation(s) not freed")
+}
@@ -0,0 +1,129 @@
+package abi
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Converter converts values of a Go type, like time.Time, to and from values of the Go type
+// representing a WIT type, like a record of seconds and nanoseconds.
+type Converter struct {
+	goType  reflect.Type
+	witType reflect.Type
+	toWit   reflect.Value
+	fromWit reflect.Value
+}
+
+// NewConverter returns the converter of values of the Go type G to and from values of the Go type
+// W representing a WIT type. Zero values of G are also converted to lay out the parameters of
+// variants and results holding them, so toWit must accept the zero value of G.
+//
+// W must not hold values of G, which would be converted again while writing the converted value.
+func NewConverter[G, W any](toWit func(G) (W, error), fromWit func(W) (G, error)) *Converter {
+	goType := reflect.TypeFor[G]()
+	witType := reflect.TypeFor[W]()
+	if toWit == nil || fromWit == nil {
+		panic(fmt.Sprintf("converter of %s must convert both ways", goType))
+	}
+	if holdsType(witType, goType, map[reflect.Type]bool{}) {
+		panic(fmt.Sprintf("converter of %s converts to %s, which holds it", goType, witType))
+	}
+	return &Converter{
+		goType:  goType,
+		witType: witType,
+		toWit:   reflect.ValueOf(toWit),
+		fromWit: reflect.ValueOf(fromWit),
+	}
+}
+
+// holdsType reports whether values of t are or hold values of target, in fields and elements.
+func holdsType(t reflect.Type, target reflect.Type, seen map[reflect.Type]bool) bool {
+	if t == target {
+		return true
+	}
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return holdsType(t.Elem(), target, seen)
+	case reflect.Map:
+		return holdsType(t.Key(), target, seen) || holdsType(t.Elem(), target, seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if holdsType(t.Field(i).Type, target, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Converters holds the converters of the Go types that WIT types are mapped to, by Go type. Values
+// of these Go types are read and written as the WIT types they are converted to, wherever they
+// appear, by the AbiOptions holding the converters. Generated bindings declare the converters of
+// the type mappings they were generated with, so that bindings mapping the same Go type to
+// different WIT types can be used in the same program.
+type Converters struct {
+	byGoType map[reflect.Type]*Converter
+}
+
+// NewConverters returns the converters of distinct Go types.
+func NewConverters(converters ...*Converter) *Converters {
+	c := &Converters{byGoType: map[reflect.Type]*Converter{}}
+	for _, converter := range converters {
+		if other, ok := c.byGoType[converter.goType]; ok {
+			panic(fmt.Sprintf("%s is converted to both %s and %s", converter.goType, other.witType, converter.witType))
+		}
+		c.byGoType[converter.goType] = converter
+	}
+	return c
+}
+
+// of returns the converter of the type of rv, if any. Nil Converters convert no type.
+func (c *Converters) of(rv reflect.Value) (*Converter, bool) {
+	if c == nil || !rv.IsValid() {
+		return nil, false
+	}
+	converter, ok := c.byGoType[rv.Type()]
+	return converter, ok
+}
+
+// call calls a conversion function with value and returns its result.
+func (c *Converter) call(fn reflect.Value, value reflect.Value, from, to reflect.Type) (reflect.Value, error) {
+	out := fn.Call([]reflect.Value{value})
+	if err, _ := out[1].Interface().(error); err != nil {
+		return reflect.Value{}, fmt.Errorf("failed to convert %s to %s: %w", from, to, err)
+	}
+	return out[0], nil
+}
+
+// wit returns the value of the WIT type converted from rv, a value of the Go type.
+func (c *Converter) wit(rv reflect.Value) (any, error) {
+	w, err := c.call(c.toWit, rv, c.goType, c.witType)
+	if err != nil {
+		return nil, err
+	}
+	return fieldValue(w), nil
+}
+
+// zero returns a pointer to the zero value of the WIT type, to compute its size and alignment.
+func (c *Converter) zero() any {
+	return reflect.New(c.witType).Interface()
+}
+
+// read reads a value of the WIT type from memory at the specified pointer, and converts it into rv,
+// an addressable value of the Go type.
+func (c *Converter) read(opts AbiOptions, ptr uint64, rv reflect.Value) error {
+	w := reflect.New(c.witType)
+	if err := Read(opts, ptr, w.Interface()); err != nil {
+		return err
+	}
+	g, err := c.call(c.fromWit, w.Elem(), c.witType, c.goType)
+	if err != nil {
+		return err
+	}
+	rv.Set(g)
+	return nil
+}
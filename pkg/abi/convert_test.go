@@ -0,0 +1,158 @@
+package abi_test
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rioam2/witigo/pkg/abi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Synthetic WIT types and the Go types they are mapped to, mirroring generated code with type
+// mappings.
+type TimestampRecord struct {
+	Seconds uint64
+	Nanos   uint32
+}
+
+type PairTuple struct {
+	Elem0 string
+	Elem1 string
+}
+
+// Generated tuples declare their kind, which their names do not tell.
+func (PairTuple) WitKind() abi.Kind { return abi.KindRecord }
+
+type Headers map[string]string
+
+type ConvertedRecord struct {
+	At      time.Time
+	Headers Headers
+	Maybe   abi.Option[time.Time]
+	Many    []time.Time
+}
+
+func timestampToWit(t time.Time) (TimestampRecord, error) {
+	if t.IsZero() {
+		return TimestampRecord{}, nil
+	}
+	if t.Unix() < 0 {
+		return TimestampRecord{}, errors.New("time before the epoch")
+	}
+	return TimestampRecord{Seconds: uint64(t.Unix()), Nanos: uint32(t.Nanosecond())}, nil
+}
+
+func timestampFromWit(r TimestampRecord) (time.Time, error) {
+	return time.Unix(int64(r.Seconds), int64(r.Nanos)).UTC(), nil
+}
+
+func headersToWit(h Headers) ([]PairTuple, error) {
+	pairs := []PairTuple{}
+	for k, v := range h {
+		pairs = append(pairs, PairTuple{k, v})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Elem0 < pairs[j].Elem0 })
+	return pairs, nil
+}
+
+func headersFromWit(pairs []PairTuple) (Headers, error) {
+	h := Headers{}
+	for _, pair := range pairs {
+		h[pair.Elem0] = pair.Elem1
+	}
+	return h, nil
+}
+
+// converters are the converters of the synthetic type mappings.
+var converters = abi.NewConverters(
+	abi.NewConverter(timestampToWit, timestampFromWit),
+	abi.NewConverter(headersToWit, headersFromWit),
+)
+
+func TestConverterRoundTrip(t *testing.T) {
+	opts := createAbiOptionsFromMemoryMap(nil)
+	opts.Converters = converters
+	at := time.Unix(1700000000, 5).UTC()
+	original := ConvertedRecord{
+		At:      at,
+		Headers: Headers{"accept": "text/plain", "host": "example.com"},
+		Maybe:   abi.Some(at.Add(time.Second)),
+		Many:    []time.Time{at, at.Add(time.Hour)},
+	}
+	ptr, free, err := abi.Write(opts, original, nil)
+	require.NoError(t, err)
+	defer free()
+	var decoded ConvertedRecord
+	require.NoError(t, abi.Read(opts, ptr, &decoded))
+	assert.Equal(t, original, decoded)
+
+	// Mapped types are laid out like the WIT types they are converted to.
+	assert.Equal(t, abi.SizeOf(TimestampRecord{}), opts.SizeOf(at))
+	assert.Equal(t, abi.AlignmentOf(TimestampRecord{}), opts.AlignmentOf(at))
+	params, freeParams, err := abi.WriteParameters(opts, at)
+	require.NoError(t, err)
+	defer freeParams()
+	assert.Equal(t, []uint64{1700000000, 5}, params)
+}
+
+func TestConverterErrors(t *testing.T) {
+	opts := createAbiOptionsFromMemoryMap(nil)
+	opts.Converters = converters
+	before := time.Unix(-1, 0)
+	_, _, err := abi.Write(opts, ConvertedRecord{At: before}, nil)
+	assert.ErrorContains(t, err, "failed to convert time.Time to abi_test.TimestampRecord: time before the epoch")
+	_, _, err = abi.WriteParameters(opts, before)
+	assert.ErrorContains(t, err, "time before the epoch")
+
+	// Converting a type to a type holding it would convert the converted value again.
+	assert.PanicsWithValue(t, "converter of string converts to string, which holds it", func() {
+		abi.NewConverter(func(s string) (string, error) { return s, nil }, func(s string) (string, error) { return s, nil })
+	})
+	type LabelRecord struct {
+		Names []string
+	}
+	assert.PanicsWithValue(t, "converter of string converts to abi_test.LabelRecord, which holds it", func() {
+		abi.NewConverter(
+			func(s string) (LabelRecord, error) { return LabelRecord{[]string{s}}, nil },
+			func(r LabelRecord) (string, error) { return r.Names[0], nil },
+		)
+	})
+	assert.PanicsWithValue(t, "time.Time is converted to both abi_test.TimestampRecord and abi_test.TimestampRecord", func() {
+		abi.NewConverters(abi.NewConverter(timestampToWit, timestampFromWit), abi.NewConverter(timestampToWit, timestampFromWit))
+	})
+}
+
+func TestConvertersAreScoped(t *testing.T) {
+	// Another bindings package maps time.Time to its own WIT type, with its own converters.
+	type OtherTimestampRecord struct{ Millis uint64 }
+	others := abi.NewConverters(abi.NewConverter(
+		func(t time.Time) (OtherTimestampRecord, error) {
+			return OtherTimestampRecord{uint64(t.UnixMilli())}, nil
+		},
+		func(r OtherTimestampRecord) (time.Time, error) { return time.UnixMilli(int64(r.Millis)).UTC(), nil },
+	))
+
+	opts := createAbiOptionsFromMemoryMap(nil)
+	at := time.Unix(1700000000, 5000000).UTC()
+	for converters, expected := range map[*abi.Converters][]uint64{
+		converters: {1700000000, 5000000},
+		others:     {1700000000005},
+	} {
+		opts.Converters = converters
+		params, freeParams, err := abi.WriteParameters(opts, at)
+		require.NoError(t, err)
+		assert.Equal(t, expected, params)
+		require.NoError(t, freeParams())
+		var decoded time.Time
+		require.NoError(t, abi.ReadParameters(opts, params, &decoded))
+		assert.Equal(t, at, decoded)
+	}
+
+	// Options without converters convert no type.
+	opts.Converters = nil
+	_, _, err := abi.WriteParameters(opts, Headers{"host": "example.com"})
+	assert.Error(t, err)
+}
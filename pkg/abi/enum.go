@@ -6,10 +6,54 @@ import (
 	"reflect"
 )
 
+// validEnum is implemented by enums that know their cases, like generated enums, which are rejected
+// when their discriminant does not correspond to a case.
+type validEnum interface {
+	IsValid() bool
+}
+
+// checkEnum returns a DiscriminantError if rv, an enum implementing validEnum, is out of range.
+// Other enums are accepted as is.
+func checkEnum(rv reflect.Value) error {
+	e, ok := rv.Interface().(validEnum)
+	if !ok || e.IsValid() {
+		return nil
+	}
+	// Cases are numbered from 0, so the first invalid discriminant is the number of cases.
+	cases := 0
+	probe := reflect.New(rv.Type()).Elem()
+	for {
+		setIntegerValue(probe, uint64(cases))
+		if !probe.Interface().(validEnum).IsValid() {
+			break
+		}
+		cases++
+	}
+	return &DiscriminantError{Kind: "enum", Value: integerValue(rv), Cases: cases}
+}
+
+// EnumString returns the name of the case of an enum value, or the name of its type followed by
+// its discriminant, like `ColorEnum(7)`, if it is out of range. cases are the names of the cases.
+func EnumString(value any, cases ...string) string {
+	rv := reflect.ValueOf(value)
+	if !isIntegerType(rv) {
+		return fmt.Sprintf("%T(?)", value)
+	}
+	if discriminant := integerValue(rv); discriminant < uint64(len(cases)) {
+		return cases[discriminant]
+	}
+	if rv.CanInt() {
+		return fmt.Sprintf("%s(%d)", rv.Type().Name(), rv.Int())
+	}
+	return fmt.Sprintf("%s(%d)", rv.Type().Name(), rv.Uint())
+}
+
 // ReadEnum reads an enum value from linear memory at the specified pointer into the result.
-// Enums are represented in generated code as named integer types with the suffix `Enum` whose
-// underlying type is the smallest unsigned integer capable of holding all cases (u8/u16/u32/u64).
-// We treat them identically to their underlying integer representation while validating the type.
+// Enums are represented in generated code as named integer types, declaring KindEnum or named with
+// the suffix `Enum`, whose underlying type is the smallest unsigned integer capable of holding all
+// cases (u8/u16/u32/u64).
+// We treat them identically to their underlying integer representation while validating the type,
+// and the discriminant if the enum has an `IsValid() bool` method, as generated enums do.
 func ReadEnum(opts AbiOptions, ptr uint64, result any) error {
 	rv := reflect.ValueOf(result)
 	if rv.Kind() != reflect.Pointer || rv.IsNil() {
@@ -22,6 +66,10 @@ func ReadEnum(opts AbiOptions, ptr uint64, result any) error {
 	if err := ReadInt(opts, ptr, result); err != nil {
 		return fmt.Errorf("failed to read enum as integer: %w", err)
 	}
+	if err := checkEnum(rv); err != nil {
+		rv.SetZero()
+		return err
+	}
 	return nil
 }
 
@@ -37,6 +85,9 @@ func WriteEnum(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free Ab
 	if !isEnumType(rv) {
 		return 0, AbiFreeCallbackNoop, fmt.Errorf("value must be an enum, got %s", rv.Type().Name())
 	}
+	if err := checkEnum(rv); err != nil {
+		return 0, AbiFreeCallbackNoop, err
+	}
 	return WriteInt(opts, value, ptrHint)
 }
 
@@ -52,5 +103,8 @@ func WriteParameterEnum(opts AbiOptions, value any) (params []Parameter, free Ab
 	if !isEnumType(rv) {
 		return nil, AbiFreeCallbackNoop, fmt.Errorf("value must be an enum, got %s", rv.Type().Name())
 	}
+	if err := checkEnum(rv); err != nil {
+		return nil, AbiFreeCallbackNoop, err
+	}
 	return WriteParameterInt(opts, value)
 }
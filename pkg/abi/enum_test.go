@@ -124,3 +124,43 @@ func TestWriteParameterEnumErrors(t *testing.T) {
 		assert.Contains(t, err.Error(), "value must be an enum")
 	})
 }
+
+// Synthetic enum that knows its cases, like generated enums.
+type BoundedEnum uint8
+
+func (v BoundedEnum) IsValid() bool { return v < 3 }
+
+func (v BoundedEnum) String() string { return abi.EnumString(v, "a", "b", "c") }
+
+func TestEnumBounds(t *testing.T) {
+	opts := createAbiOptionsFromMemoryMap(map[uint64][]byte{
+		0x00: {0x02, 0x03},
+	})
+
+	var v BoundedEnum
+	require.NoError(t, abi.Read(opts, 0x00, &v))
+	assert.Equal(t, BoundedEnum(2), v)
+
+	err := abi.Read(opts, 0x01, &v)
+	var discriminantErr *abi.DiscriminantError
+	require.ErrorAs(t, err, &discriminantErr)
+	assert.Equal(t, abi.DiscriminantError{Kind: "enum", Value: 3, Cases: 3}, *discriminantErr)
+	assert.Equal(t, BoundedEnum(0), v)
+
+	invalid := BoundedEnum(7)
+	_, _, err = abi.Write(opts, &invalid, nil)
+	assert.ErrorIs(t, err, abi.ErrInvalidDiscriminant)
+	_, _, err = abi.WriteParameter(opts, &invalid)
+	assert.EqualError(t, err, "enum discriminant 7 out of range [0,3)")
+
+	// Enums that do not know their cases are passed through.
+	unbounded := SampleEnum(7)
+	_, _, err = abi.Write(opts, &unbounded, nil)
+	assert.NoError(t, err)
+}
+
+func TestEnumString(t *testing.T) {
+	assert.Equal(t, "b", BoundedEnum(1).String())
+	assert.Equal(t, "BoundedEnum(7)", BoundedEnum(7).String())
+	assert.Equal(t, "b", abi.FormatValue(BoundedEnum(1)))
+}
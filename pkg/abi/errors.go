@@ -0,0 +1,127 @@
+package abi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrOutOfBounds is matched by errors caused by an access outside of linear memory.
+	ErrOutOfBounds = errors.New("out of bounds memory access")
+	// ErrInvalidDiscriminant is matched by errors caused by a variant, option or enum
+	// discriminant that does not correspond to a case of the type.
+	ErrInvalidDiscriminant = errors.New("invalid discriminant")
+	// ErrInvalidUTF8 is matched by errors caused by lifting a string that is not valid UTF-8.
+	ErrInvalidUTF8 = errors.New("invalid UTF-8")
+	// ErrTrap is matched by errors caused by the guest trapping during a call.
+	ErrTrap = errors.New("wasm trap")
+)
+
+// MemoryError is returned when a value cannot be read from or written to linear memory.
+type MemoryError struct {
+	// Op is either "read" or "write".
+	Op string
+	// What describes the accessed value, for example "list length".
+	What string
+	Ptr  uint64
+	Size uint64
+}
+
+func (e *MemoryError) Error() string {
+	return fmt.Sprintf("failed to %s %s at %d", e.Op, e.What, e.Ptr)
+}
+
+func (e *MemoryError) Is(target error) bool {
+	return target == ErrOutOfBounds
+}
+
+// readError returns a MemoryError for a failed read of size bytes at ptr.
+func readError(what string, ptr uint64, size uint64) error {
+	return &MemoryError{Op: "read", What: what, Ptr: ptr, Size: size}
+}
+
+// writeError returns a MemoryError for a failed write of size bytes at ptr.
+func writeError(what string, ptr uint64, size uint64) error {
+	return &MemoryError{Op: "write", What: what, Ptr: ptr, Size: size}
+}
+
+// DiscriminantError is returned when a discriminant does not correspond to a case of its type.
+type DiscriminantError struct {
+	// Kind is the kind of the type, for example "variant" or "option".
+	Kind  string
+	Value uint64
+	Cases int
+}
+
+func (e *DiscriminantError) Error() string {
+	return fmt.Sprintf("%s discriminant %d out of range [0,%d)", e.Kind, e.Value, e.Cases)
+}
+
+func (e *DiscriminantError) Is(target error) bool {
+	return target == ErrInvalidDiscriminant
+}
+
+// TrapFrame is a frame of the wasm stack trace of a trap.
+type TrapFrame struct {
+	// Function is the signature of the function, for example "$calc.add(i32,i32) i32".
+	Function string
+	// Sources are the source locations of the frame, available when the module contains DWARF
+	// debug information.
+	Sources []string
+}
+
+// TrapError is returned when the guest traps during a call, for example by executing an
+// unreachable instruction or accessing memory out of bounds.
+type TrapError struct {
+	// Function is the name of the called export.
+	Function string
+	// Message is the error reported by the runtime, without the stack trace.
+	Message string
+	// Frames is the wasm stack trace, innermost frame first.
+	Frames []TrapFrame
+	// Err is the error reported by the runtime.
+	Err error
+}
+
+func (e *TrapError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TrapError) Is(target error) bool {
+	return target == ErrTrap
+}
+
+func (e *TrapError) Unwrap() error {
+	return e.Err
+}
+
+const wasmStackTraceHeader = "\nwasm stack trace:\n"
+
+// newTrapError parses the wasm stack trace that wazero appends to the errors of failed calls.
+// Errors without a stack trace, such as a guest exiting through WASI, are returned unchanged.
+func newTrapError(function string, err error) error {
+	msg := err.Error()
+	idx := strings.Index(msg, wasmStackTraceHeader)
+	if idx < 0 {
+		return err
+	}
+	trap := &TrapError{Function: function, Message: msg[:idx], Err: err}
+	trace := msg[idx+len(wasmStackTraceHeader):]
+	// A Go stack trace follows a blank line when the runtime recovered from a Go panic.
+	if end := strings.Index(trace, "\n\n"); end >= 0 {
+		trace = trace[:end]
+	}
+	for _, line := range strings.Split(trace, "\n") {
+		switch {
+		case strings.HasPrefix(line, "\t..."):
+			// wazero truncates long stack traces.
+		case strings.HasPrefix(line, "\t\t") && len(trap.Frames) > 0:
+			frame := &trap.Frames[len(trap.Frames)-1]
+			frame.Sources = append(frame.Sources, strings.TrimPrefix(line, "\t\t"))
+		case strings.HasPrefix(line, "\t"):
+			trap.Frames = append(trap.Frames, TrapFrame{Function: strings.TrimPrefix(line, "\t")})
+		}
+	}
+	return trap
+}
@@ -0,0 +1,96 @@
+package abi_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rioam2/witigo/pkg/abi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutOfBoundsError(t *testing.T) {
+	opts := createAbiOptionsFromMemoryMap(map[uint64][]byte{0: {0, 0, 0, 0}})
+
+	var result uint32
+	err := abi.Read(opts, 0x1000, &result)
+	require.ErrorIs(t, err, abi.ErrOutOfBounds)
+	var memErr *abi.MemoryError
+	require.True(t, errors.As(err, &memErr))
+	assert.Equal(t, "read", memErr.Op)
+	assert.Equal(t, uint64(0x1000), memErr.Ptr)
+	assert.Equal(t, uint64(4), memErr.Size)
+	assert.EqualError(t, err, "failed to read 4 bytes at 4096")
+
+	var list []uint32
+	err = abi.Read(opts, 0x1000, &list)
+	assert.ErrorIs(t, err, abi.ErrOutOfBounds)
+	assert.NotErrorIs(t, err, abi.ErrInvalidDiscriminant)
+}
+
+func TestInvalidDiscriminantError(t *testing.T) {
+	opts := createAbiOptionsFromMemoryMap(map[uint64][]byte{0: {5, 0, 0, 0, 0, 0, 0, 0}})
+
+	var variant SampleVariant
+	err := abi.Read(opts, 0, &variant)
+	require.ErrorIs(t, err, abi.ErrInvalidDiscriminant)
+	var discErr *abi.DiscriminantError
+	require.True(t, errors.As(err, &discErr))
+	assert.Equal(t, "variant", discErr.Kind)
+	assert.Equal(t, uint64(5), discErr.Value)
+	assert.Equal(t, 3, discErr.Cases)
+
+	var option abi.Option[uint32]
+	err = abi.Read(opts, 0, &option)
+	require.ErrorIs(t, err, abi.ErrInvalidDiscriminant)
+	assert.EqualError(t, err, "option discriminant 5 out of range [0,2)")
+}
+
+func TestInvalidUTF8Error(t *testing.T) {
+	opts := createAbiOptionsFromMemoryMap(map[uint64][]byte{
+		0:   {100, 0, 0, 0},
+		4:   {2, 0, 0, 0},
+		100: {0xc3, 0x28},
+	})
+
+	var result string
+	err := abi.Read(opts, 0, &result)
+	assert.ErrorIs(t, err, abi.ErrInvalidUTF8)
+}
+
+func TestTrapError(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("trap", func(t *testing.T) {
+		call := instantiateMetered(t, abi.Limits{})
+
+		// Growing beyond 4 GiB fails, upon which grow executes unreachable.
+		_, err := call(ctx, "grow", 0x10000)
+		require.ErrorIs(t, err, abi.ErrTrap)
+		var trapErr *abi.TrapError
+		require.True(t, errors.As(err, &trapErr))
+		assert.Equal(t, "grow", trapErr.Function)
+		assert.Equal(t, "wasm error: unreachable", trapErr.Message)
+		require.Len(t, trapErr.Frames, 1)
+		assert.Contains(t, trapErr.Frames[0].Function, "(i32) i32")
+		assert.Contains(t, err.Error(), "wasm stack trace")
+	})
+
+	t.Run("budget exceeded", func(t *testing.T) {
+		call := instantiateMetered(t, abi.Limits{Fuel: 10})
+
+		_, err := call(ctx, "spin", 100)
+		assert.ErrorIs(t, err, abi.ErrBudgetExceeded)
+		var trapErr *abi.TrapError
+		require.True(t, errors.As(err, &trapErr))
+		assert.Len(t, trapErr.Frames, 2)
+	})
+
+	t.Run("missing export is not a trap", func(t *testing.T) {
+		call := instantiateMetered(t, abi.Limits{})
+
+		_, err := call(ctx, "missing")
+		assert.NotErrorIs(t, err, abi.ErrTrap)
+	})
+}
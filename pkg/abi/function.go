@@ -24,8 +24,12 @@ func Call(opts AbiOptions, name string, params ...uint64) (ret uint64, postRetur
 	return ret, postReturn, err
 }
 
-// abiRealloc reallocates memory at the specified pointer with the given size and alignment.
+// abiRealloc reallocates memory at the specified pointer with the given size and alignment. Every
+// allocation goes through it, so it reports them to opts.Hooks, unlike frees.
 func abiRealloc(opts AbiOptions, oldPtr uint64, oldSize uint64, alignment uint64, newSize uint64) (ptr uint64, free AbiFreeCallback, err error) {
+	if opts.Hooks != nil && newSize > 0 {
+		opts.Hooks.OnAlloc(opts.Context, newSize, alignment)
+	}
 	return Call(opts, "cabi_realloc", oldPtr, oldSize, alignment, newSize)
 }
 
@@ -35,6 +39,12 @@ func abiFree(opts AbiOptions, ptr uint64) error {
 	return err
 }
 
+// Malloc allocates memory of the specified size and alignment in the linear memory of the instance
+// using its cabi_realloc export. The returned callback frees the allocation.
+func Malloc(opts AbiOptions, size uint64, alignment uint64) (ptr uint64, free AbiFreeCallback, err error) {
+	return abiMalloc(opts, size, alignment)
+}
+
 // abiMalloc allocates memory of the specified size and alignment.
 func abiMalloc(opts AbiOptions, size uint64, alignment uint64) (ptr uint64, free AbiFreeCallback, err error) {
 	ptr, _, err = abiRealloc(opts, 0, 0, alignment, size)
@@ -0,0 +1,181 @@
+package abi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-cz/textcase"
+)
+
+// Hooks observes the calls made into a component instance. Arguments and results are the lifted
+// Go values of a call, which can be rendered with FormatValue.
+type Hooks interface {
+	// OnCall is invoked before the exported function name is called with args.
+	OnCall(ctx context.Context, name string, args []any)
+	// OnReturn is invoked after the exported function name returned. result is nil for
+	// functions without a result.
+	OnReturn(ctx context.Context, name string, result any, err error, duration time.Duration)
+	// OnAlloc is invoked before memory is allocated in the instance with cabi_realloc.
+	OnAlloc(ctx context.Context, size uint64, alignment uint64)
+}
+
+// TraceCall notifies opts.Hooks of a call to the exported function name with the given lifted
+// arguments. The returned function must be called with the lifted result once the call returned.
+func TraceCall(opts AbiOptions, name string, args ...any) (done func(result any, err error)) {
+	if opts.Hooks == nil {
+		return func(any, error) {}
+	}
+	opts.Hooks.OnCall(opts.Context, name, args)
+	start := time.Now()
+	return func(result any, err error) {
+		opts.Hooks.OnReturn(opts.Context, name, result, err, time.Since(start))
+	}
+}
+
+// SlogHooks are Hooks that log calls to a slog.Logger. Calls, returns and allocations are logged
+// at debug level, and calls that return an error at error level.
+type SlogHooks struct {
+	Logger *slog.Logger
+}
+
+var _ Hooks = SlogHooks{}
+
+// NewSlogHooks creates Hooks logging to logger, or to slog.Default() if logger is nil.
+func NewSlogHooks(logger *slog.Logger) SlogHooks {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return SlogHooks{Logger: logger}
+}
+
+func (h SlogHooks) OnCall(ctx context.Context, name string, args []any) {
+	if !h.Logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	formatted := make([]string, len(args))
+	for i, arg := range args {
+		formatted[i] = FormatValue(arg)
+	}
+	h.Logger.DebugContext(ctx, "component call", "function", name, "args", "("+strings.Join(formatted, ", ")+")")
+}
+
+func (h SlogHooks) OnReturn(ctx context.Context, name string, result any, err error, duration time.Duration) {
+	if err != nil {
+		h.Logger.ErrorContext(ctx, "component call failed", "function", name, "error", err, "duration", duration)
+		return
+	}
+	if !h.Logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	h.Logger.DebugContext(ctx, "component return", "function", name, "result", FormatValue(result), "duration", duration)
+}
+
+func (h SlogHooks) OnAlloc(ctx context.Context, size uint64, alignment uint64) {
+	h.Logger.DebugContext(ctx, "component alloc", "size", size, "alignment", alignment)
+}
+
+// FormatValue renders a lifted value in a WIT-like text form, for example
+// `{name: "a", tags: ["b"], shape: circle(1.5), parent: none}`. Values implementing fmt.Stringer
+// are rendered with their String method.
+func FormatValue(value any) string {
+	if value == nil {
+		return "_"
+	}
+	return formatValue(reflect.ValueOf(value))
+}
+
+func formatValue(rv reflect.Value) string {
+	if rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return "_"
+		}
+		return formatValue(rv.Elem())
+	}
+	if rv.CanInterface() {
+		if s, ok := rv.Interface().(fmt.Stringer); ok {
+			return s.String()
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if isEnumType(rv) {
+			return fmt.Sprintf("%s(%d)", rv.Type().Name(), rv.Int())
+		}
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if isEnumType(rv) {
+			return fmt.Sprintf("%s(%d)", rv.Type().Name(), rv.Uint())
+		}
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 64)
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool())
+	case reflect.String:
+		return strconv.Quote(rv.String())
+	case reflect.Slice, reflect.Array:
+		elems := make([]string, rv.Len())
+		for i := range elems {
+			elems[i] = formatValue(rv.Index(i))
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case reflect.Struct:
+		return formatStruct(rv)
+	default:
+		return fmt.Sprintf("%v", rv)
+	}
+}
+
+func formatStruct(rv reflect.Value) string {
+	switch {
+	case isSealedVariantType(rv):
+		v, _ := sealedVariantOf(rv)
+		caseName := textcase.KebabCase(strings.TrimPrefix(rv.Type().Name(), v.iface.Name()))
+		if rv.NumField() == 0 {
+			return caseName
+		}
+		return caseName + "(" + formatValue(rv.Field(0)) + ")"
+	case isStructResultType(rv):
+		if rv.Field(0).Bool() {
+			return "err(" + formatValue(rv.Field(2)) + ")"
+		}
+		return "ok(" + formatValue(rv.Field(1)) + ")"
+	case rv.NumField() == 0:
+		return "()"
+	case isStructVariantType(rv):
+		caseIndex := 0
+		if rv.Field(0).CanUint() {
+			caseIndex = int(rv.Field(0).Uint())
+		} else {
+			caseIndex = int(rv.Field(0).Int())
+		}
+		if caseIndex < 0 || caseIndex >= rv.NumField()-1 {
+			return fmt.Sprintf("%s(invalid case %d)", rv.Type().Name(), caseIndex)
+		}
+		caseName := textcase.KebabCase(rv.Type().Field(caseIndex + 1).Name)
+		payload := rv.Field(caseIndex + 1)
+		if isAnonymousEmptyStruct(payload) {
+			return caseName
+		}
+		return caseName + "(" + formatValue(payload) + ")"
+	case isStructOptionType(rv) && rv.FieldByName("IsSome").IsValid():
+		if !rv.FieldByName("IsSome").Bool() {
+			return "none"
+		}
+		return "some(" + formatValue(rv.FieldByName("Value")) + ")"
+	default:
+		fields := make([]string, rv.NumField())
+		for i := range fields {
+			fields[i] = textcase.KebabCase(rv.Type().Field(i).Name) + ": " + formatValue(rv.Field(i))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+}
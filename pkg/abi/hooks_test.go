@@ -0,0 +1,117 @@
+package abi_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/rioam2/witigo/pkg/abi"
+	"github.com/rioam2/witigo/pkg/abi/abitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type HookPointRecord struct {
+	X           int16
+	DisplayName string
+	Tags        []string
+	Parent      abi.Option[uint32]
+}
+
+type recordingHooks struct {
+	events []string
+}
+
+func (h *recordingHooks) OnCall(ctx context.Context, name string, args []any) {
+	h.events = append(h.events, "call "+name)
+}
+
+func (h *recordingHooks) OnReturn(ctx context.Context, name string, result any, err error, duration time.Duration) {
+	h.events = append(h.events, "return "+name+" "+abi.FormatValue(result))
+}
+
+func (h *recordingHooks) OnAlloc(ctx context.Context, size uint64, alignment uint64) {
+	h.events = append(h.events, fmt.Sprintf("alloc %d %d", size, alignment))
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    any
+		expected string
+	}{
+		{name: "nil", value: nil, expected: "_"},
+		{name: "integer", value: uint32(42), expected: "42"},
+		{name: "float", value: float32(1.5), expected: "1.5"},
+		{name: "string", value: "a \"b\"", expected: `"a \"b\""`},
+		{name: "list", value: []int8{1, -2}, expected: "[1, -2]"},
+		{name: "enum", value: SampleEnumBeta, expected: "SampleEnum(3)"},
+		{name: "option none", value: abi.Option[int]{}, expected: "none"},
+		{name: "option some", value: abi.Option[string]{IsSome: true, Value: "x"}, expected: `some("x")`},
+		{name: "variant without payload", value: SampleVariant{Type: SampleVariantTypeA}, expected: "a"},
+		{name: "variant with payload", value: SampleVariant{Type: SampleVariantTypeC, C: "c"}, expected: `c("c")`},
+		{name: "variant with invalid case", value: SampleVariant{Type: 7}, expected: "SampleVariant(invalid case 7)"},
+		{
+			name:     "record",
+			value:    &HookPointRecord{X: -1, DisplayName: "p", Tags: []string{"t"}, Parent: abi.Option[uint32]{IsSome: true, Value: 2}},
+			expected: `{x: -1, display-name: "p", tags: ["t"], parent: some(2)}`,
+		},
+		{name: "stringer", value: time.Second, expected: "1s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, abi.FormatValue(tt.value))
+		})
+	}
+}
+
+func TestTraceCall(t *testing.T) {
+	rt := abitest.NewRuntime()
+	hooks := &recordingHooks{}
+	opts := rt.AbiOptions()
+	opts.Hooks = hooks
+
+	done := abi.TraceCall(opts, "greet", "world")
+	_, free, err := abi.Malloc(opts, 16, 4)
+	require.NoError(t, err)
+	require.NoError(t, free())
+	_, freeParams, err := abi.WriteParameters(opts, "world")
+	require.NoError(t, err)
+	require.NoError(t, freeParams())
+	done("hello, world", nil)
+
+	// Frees are not reported.
+	assert.Equal(t, []string{"call greet", "alloc 16 4", "alloc 5 1", `return greet "hello, world"`}, hooks.events)
+}
+
+func TestTraceCallWithoutHooks(t *testing.T) {
+	done := abi.TraceCall(abi.AbiOptions{}, "greet", "world")
+	done(nil, nil)
+}
+
+func TestSlogHooks(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	opts := abi.AbiOptions{Context: context.Background(), Hooks: abi.NewSlogHooks(logger)}
+
+	abi.TraceCall(opts, "add", uint32(1), uint32(2))(uint32(3), nil)
+	abi.TraceCall(opts, "fail")(nil, errors.New("trap"))
+
+	assert.Equal(t, `level=DEBUG msg="component call" function=add args="(1, 2)"
+level=DEBUG msg="component return" function=add result=3
+level=DEBUG msg="component call" function=fail args=()
+level=ERROR msg="component call failed" function=fail error=trap
+`, buf.String())
+}
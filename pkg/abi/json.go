@@ -0,0 +1,340 @@
+package abi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Values of the WIT types that have no natural JSON representation are encoded as follows:
+//
+//   - an enum as the name of its case, like "navy-blue";
+//   - flags as the list of the names of the flags set, like ["read","write"];
+//   - an option as null when it holds no value, or as its value;
+//   - a result as {"ok":value} or {"err":value};
+//   - a variant as {"case":payload}, or as "case" for a case without payload.
+//
+// Names are the WIT names of the cases and flags, which generated code passes to the functions
+// below. Payloads that are absent, like those of `result<_, E>`, are encoded as null.
+
+// errCaseMismatch is returned by UnmarshalCaseJSON when the JSON value is another case.
+var errCaseMismatch = errors.New("case mismatch")
+
+// jsonNull is the JSON encoding of absent values.
+var jsonNull = []byte("null")
+
+// MarshalJSON encodes the Option as null when it holds no value, or as its value.
+func (o Option[T]) MarshalJSON() ([]byte, error) {
+	return MarshalOptionJSON(o.IsSome, o.Value)
+}
+
+// UnmarshalJSON decodes the Option from null or from its value.
+func (o *Option[T]) UnmarshalJSON(data []byte) error {
+	return UnmarshalOptionJSON(data, &o.IsSome, &o.Value)
+}
+
+// MarshalJSON encodes the Result as {"ok":value} or {"err":value}.
+func (r Result[T, E]) MarshalJSON() ([]byte, error) {
+	return MarshalResultJSON(r.IsErr, r.Ok, r.Err)
+}
+
+// UnmarshalJSON decodes the Result from {"ok":value} or {"err":value}.
+func (r *Result[T, E]) UnmarshalJSON(data []byte) error {
+	return UnmarshalResultJSON(data, &r.IsErr, &r.Ok, &r.Err)
+}
+
+// MarshalResultJSON encodes a result as {"ok":ok}, or as {"err":err} when isErr is set.
+func MarshalResultJSON(isErr bool, ok any, err any) ([]byte, error) {
+	if isErr {
+		return MarshalCaseJSON("err", err)
+	}
+	return MarshalCaseJSON("ok", ok)
+}
+
+// UnmarshalResultJSON decodes a result from {"ok":value} or {"err":value} into the payloads that ok
+// and err point to, and sets isErr accordingly. The other payload is reset to its zero value.
+func UnmarshalResultJSON(data []byte, isErr *bool, ok any, err any) error {
+	okValue, errValue := reflect.ValueOf(ok), reflect.ValueOf(err)
+	if okValue.Kind() != reflect.Pointer || okValue.IsNil() || errValue.Kind() != reflect.Pointer || errValue.IsNil() {
+		return errors.New("must pass non-nil pointer payloads")
+	}
+	name, payload, splitErr := splitCaseJSON(data)
+	if splitErr != nil {
+		return fmt.Errorf("result: %w", splitErr)
+	}
+	if name != "ok" && name != "err" {
+		return fmt.Errorf("result: unknown case %q, expected \"ok\" or \"err\"", name)
+	}
+	okValue.Elem().SetZero()
+	errValue.Elem().SetZero()
+	*isErr = name == "err"
+	if *isErr {
+		return unmarshalPayload(payload, errValue.Elem())
+	}
+	return unmarshalPayload(payload, okValue.Elem())
+}
+
+// MarshalOptionJSON encodes an option as null when isSome is unset, or as its value.
+func MarshalOptionJSON(isSome bool, value any) ([]byte, error) {
+	if !isSome {
+		return jsonNull, nil
+	}
+	return json.Marshal(value)
+}
+
+// UnmarshalOptionJSON decodes an option from null or from its value, which value points to.
+func UnmarshalOptionJSON(data []byte, isSome *bool, value any) error {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("must pass a non-nil pointer value")
+	}
+	rv.Elem().SetZero()
+	*isSome = !bytes.Equal(bytes.TrimSpace(data), jsonNull)
+	if !*isSome {
+		return nil
+	}
+	return unmarshalPayload(data, rv.Elem())
+}
+
+// MarshalEnumText encodes an enum value as the name of its case.
+func MarshalEnumText(value any, cases ...string) ([]byte, error) {
+	rv := reflect.ValueOf(value)
+	if !isIntegerType(rv) {
+		return nil, fmt.Errorf("value must be an enum, got %T", value)
+	}
+	discriminant := integerValue(rv)
+	if discriminant >= uint64(len(cases)) {
+		return nil, &DiscriminantError{Kind: "enum", Value: discriminant, Cases: len(cases)}
+	}
+	return []byte(cases[discriminant]), nil
+}
+
+// UnmarshalEnumText decodes an enum value, which result points to, from the name of its case.
+func UnmarshalEnumText(text []byte, result any, cases ...string) error {
+	rv := reflect.ValueOf(result)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || !isIntegerType(rv.Elem()) {
+		return fmt.Errorf("result must be an enum pointer, got %T", result)
+	}
+	for i, c := range cases {
+		if c == string(text) {
+			setIntegerValue(rv.Elem(), uint64(i))
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown case %q of %s", text, rv.Elem().Type())
+}
+
+// MarshalFlagsJSON encodes flags, an integer whose bit i holds flag i, as the list of the names of
+// the flags set.
+func MarshalFlagsJSON(value any, flags ...string) ([]byte, error) {
+	rv := reflect.ValueOf(value)
+	if !isIntegerType(rv) {
+		return nil, fmt.Errorf("value must be flags, got %T", value)
+	}
+	bits := integerValue(rv)
+	names := []string{}
+	for i, name := range flags {
+		if bits&(1<<i) != 0 {
+			names = append(names, name)
+			bits &^= 1 << i
+		}
+	}
+	if bits != 0 {
+		return nil, fmt.Errorf("flags %s set bits %#x beyond its %d flags", rv.Type(), bits, len(flags))
+	}
+	return json.Marshal(names)
+}
+
+// UnmarshalFlagsJSON decodes flags, which result points to, from the list of the names of the
+// flags set.
+func UnmarshalFlagsJSON(data []byte, result any, flags ...string) error {
+	rv := reflect.ValueOf(result)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || !isIntegerType(rv.Elem()) {
+		return fmt.Errorf("result must be a flags pointer, got %T", result)
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	bits := uint64(0)
+	for _, name := range names {
+		i := 0
+		for i < len(flags) && flags[i] != name {
+			i++
+		}
+		if i == len(flags) {
+			return fmt.Errorf("unknown flag %q of %s", name, rv.Elem().Type())
+		}
+		bits |= 1 << i
+	}
+	setIntegerValue(rv.Elem(), bits)
+	return nil
+}
+
+// MarshalVariantJSON encodes a variant struct as {"case":payload}, or as "case" for a case without
+// payload. cases are the names of the cases, in order.
+func MarshalVariantJSON(value any, cases ...string) ([]byte, error) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	if !isStructVariantType(rv) || rv.NumField() != len(cases)+1 {
+		return nil, fmt.Errorf("value must be a variant with %d cases, got %T", len(cases), value)
+	}
+	discriminant := integerValue(rv.Field(0))
+	if discriminant >= uint64(len(cases)) {
+		return nil, &DiscriminantError{Kind: "variant", Value: discriminant, Cases: len(cases)}
+	}
+	payload := rv.Field(1 + int(discriminant))
+	if isAnonymousEmptyStruct(payload) {
+		return json.Marshal(cases[discriminant])
+	}
+	return MarshalCaseJSON(cases[discriminant], payload.Interface())
+}
+
+// UnmarshalVariantJSON decodes a variant struct, which result points to, from {"case":payload} or
+// from "case". cases are the names of the cases, in order.
+func UnmarshalVariantJSON(data []byte, result any, cases ...string) error {
+	rv := reflect.ValueOf(result)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("must pass a non-nil pointer result")
+	}
+	rv = rv.Elem()
+	if !isStructVariantType(rv) || rv.NumField() != len(cases)+1 {
+		return fmt.Errorf("result must be a variant with %d cases, got %s", len(cases), rv.Type())
+	}
+	name, payload, err := splitCaseJSON(data)
+	if err != nil {
+		return fmt.Errorf("variant %s: %w", rv.Type(), err)
+	}
+	for i, c := range cases {
+		if c != name {
+			continue
+		}
+		rv.SetZero()
+		setIntegerValue(rv.Field(0), uint64(i))
+		return unmarshalPayload(payload, rv.Field(1+i))
+	}
+	return fmt.Errorf("unknown case %q of %s", name, rv.Type())
+}
+
+// MarshalCaseJSON encodes a case of a variant as {"name":payload}, or as "name" if payload is nil.
+// Cases of variants declared as sealed interfaces are encoded with it.
+func MarshalCaseJSON(name string, payload any) ([]byte, error) {
+	if payload == nil {
+		return json.Marshal(name)
+	}
+	if isAnonymousEmptyStruct(reflect.ValueOf(payload)) {
+		payload = nil
+	}
+	return json.Marshal(map[string]any{name: payload})
+}
+
+// UnmarshalCaseJSON decodes a case of a variant from {"name":payload}, into the value payload points
+// to, or from "name" if payload is nil. Cases of variants declared as sealed interfaces are decoded
+// with it.
+func UnmarshalCaseJSON(data []byte, name string, payload any) error {
+	got, raw, err := splitCaseJSON(data)
+	if err != nil {
+		return err
+	}
+	if got != name {
+		return fmt.Errorf("%w: expected case %q, got %q", errCaseMismatch, name, got)
+	}
+	if payload == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(payload)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("must pass a non-nil pointer payload")
+	}
+	return unmarshalPayload(raw, rv.Elem())
+}
+
+// UnmarshalSealedVariantJSON decodes a variant declared as a sealed interface, which result points
+// to, into the registered case that the JSON value holds. Since encoding/json cannot decode into
+// an interface, structs holding such variants are decoded with it by their own UnmarshalJSON.
+func UnmarshalSealedVariantJSON(data []byte, result any) error {
+	rv := reflect.ValueOf(result)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("must pass a non-nil pointer result")
+	}
+	rv = rv.Elem()
+	v, ok := sealedVariantOf(rv)
+	if !ok || rv.Kind() != reflect.Interface {
+		return fmt.Errorf("result must be a registered variant interface pointer, got %s", rv.Type())
+	}
+	for _, t := range v.cases {
+		c := reflect.New(t)
+		err := json.Unmarshal(data, c.Interface())
+		if errors.Is(err, errCaseMismatch) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		rv.Set(c.Elem())
+		return nil
+	}
+	name, _, _ := splitCaseJSON(data)
+	return fmt.Errorf("unknown case %q of %s", name, rv.Type())
+}
+
+// splitCaseJSON returns the name and payload of a case encoded as {"name":payload} or as "name",
+// whose payload is null.
+func splitCaseJSON(data []byte) (name string, payload json.RawMessage, err error) {
+	if err := json.Unmarshal(data, &name); err == nil {
+		return name, jsonNull, nil
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil || len(object) != 1 {
+		return "", nil, fmt.Errorf("expected a case name or an object with a single case, got %s", data)
+	}
+	for name, payload := range object {
+		return name, payload, nil
+	}
+	panic("unreachable")
+}
+
+// unmarshalPayload decodes data into rv, an addressable payload. Absent payloads are decoded from
+// null, and variants declared as sealed interfaces with UnmarshalSealedVariantJSON.
+func unmarshalPayload(data []byte, rv reflect.Value) error {
+	if isAnonymousEmptyStruct(rv) {
+		if !bytes.Equal(bytes.TrimSpace(data), jsonNull) {
+			return fmt.Errorf("expected null for an absent payload, got %s", data)
+		}
+		return nil
+	}
+	if isSealedVariantType(rv) && rv.Kind() == reflect.Interface {
+		return UnmarshalSealedVariantJSON(data, rv.Addr().Interface())
+	}
+	return json.Unmarshal(data, rv.Addr().Interface())
+}
+
+func isIntegerType(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// integerValue returns the value of rv, an integer, as an unsigned integer.
+func integerValue(rv reflect.Value) uint64 {
+	if rv.CanInt() {
+		return uint64(rv.Int())
+	}
+	return rv.Uint()
+}
+
+// setIntegerValue sets rv, an integer, to value.
+func setIntegerValue(rv reflect.Value, value uint64) {
+	if rv.CanInt() {
+		rv.SetInt(int64(value))
+		return
+	}
+	rv.SetUint(value)
+}
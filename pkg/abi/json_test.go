@@ -0,0 +1,183 @@
+package abi_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/rioam2/witigo/pkg/abi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Synthetic types mirroring the JSON methods of generated code.
+type JSONColor uint8
+
+func (c JSONColor) MarshalText() ([]byte, error) {
+	return abi.MarshalEnumText(c, "red", "navy-blue")
+}
+
+func (c *JSONColor) UnmarshalText(text []byte) error {
+	return abi.UnmarshalEnumText(text, c, "red", "navy-blue")
+}
+
+type JSONPermissions uint8
+
+func (p JSONPermissions) MarshalJSON() ([]byte, error) {
+	return abi.MarshalFlagsJSON(p, "read", "write", "exec")
+}
+
+func (p *JSONPermissions) UnmarshalJSON(data []byte) error {
+	return abi.UnmarshalFlagsJSON(data, p, "read", "write", "exec")
+}
+
+type JSONShapeVariant struct {
+	Type   uint8
+	Dot    struct{}
+	Circle float64
+	Label  abi.Option[string]
+}
+
+func (v JSONShapeVariant) MarshalJSON() ([]byte, error) {
+	return abi.MarshalVariantJSON(v, "dot", "circle", "label")
+}
+
+func (v *JSONShapeVariant) UnmarshalJSON(data []byte) error {
+	return abi.UnmarshalVariantJSON(data, v, "dot", "circle", "label")
+}
+
+type JSONSealed interface {
+	isJSONSealed()
+}
+
+type JSONSealedDot struct{}
+type JSONSealedCircle struct{ Value float64 }
+
+func (JSONSealedDot) isJSONSealed()    {}
+func (JSONSealedCircle) isJSONSealed() {}
+
+func (c JSONSealedDot) MarshalJSON() ([]byte, error) {
+	return abi.MarshalCaseJSON("dot", nil)
+}
+
+func (c *JSONSealedDot) UnmarshalJSON(data []byte) error {
+	return abi.UnmarshalCaseJSON(data, "dot", nil)
+}
+
+func (c JSONSealedCircle) MarshalJSON() ([]byte, error) {
+	return abi.MarshalCaseJSON("circle", c.Value)
+}
+
+func (c *JSONSealedCircle) UnmarshalJSON(data []byte) error {
+	return abi.UnmarshalCaseJSON(data, "circle", &c.Value)
+}
+
+func init() {
+	abi.RegisterVariant[JSONSealed](JSONSealedDot{}, JSONSealedCircle{})
+}
+
+type JSONRecord struct {
+	Color  JSONColor
+	Perms  JSONPermissions
+	Shape  JSONShapeVariant
+	Maybe  abi.Option[uint32]
+	Result abi.Result[uint32, string]
+	Unit   abi.Result[struct{}, string]
+	Colors map[JSONColor]bool
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	original := JSONRecord{
+		Color:  1,
+		Perms:  0b101,
+		Shape:  JSONShapeVariant{Type: 2, Label: abi.Some("hello")},
+		Maybe:  abi.None[uint32](),
+		Result: abi.Result[uint32, string]{IsErr: true, Err: "failed"},
+		Colors: map[JSONColor]bool{0: true},
+	}
+	data, err := json.Marshal(original)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"Color": "navy-blue",
+		"Perms": ["read", "exec"],
+		"Shape": {"label": "hello"},
+		"Maybe": null,
+		"Result": {"err": "failed"},
+		"Unit": {"ok": null},
+		"Colors": {"red": true}
+	}`, string(data))
+
+	var decoded JSONRecord
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, original, decoded)
+}
+
+func TestJSONVariant(t *testing.T) {
+	for _, tc := range []struct {
+		value JSONShapeVariant
+		json  string
+	}{
+		{JSONShapeVariant{}, `"dot"`},
+		{JSONShapeVariant{Type: 1, Circle: 1.5}, `{"circle":1.5}`},
+		{JSONShapeVariant{Type: 2}, `{"label":null}`},
+	} {
+		data, err := json.Marshal(tc.value)
+		require.NoError(t, err)
+		assert.Equal(t, tc.json, string(data))
+
+		decoded := JSONShapeVariant{Type: 1, Circle: 2}
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, tc.value, decoded)
+	}
+
+	var decoded JSONShapeVariant
+	require.NoError(t, json.Unmarshal([]byte(`{"dot":null}`), &decoded))
+	assert.Equal(t, JSONShapeVariant{}, decoded)
+}
+
+func TestJSONSealedVariant(t *testing.T) {
+	for _, tc := range []struct {
+		value JSONSealed
+		json  string
+	}{
+		{JSONSealedDot{}, `"dot"`},
+		{JSONSealedCircle{Value: 1.5}, `{"circle":1.5}`},
+	} {
+		data, err := json.Marshal(tc.value)
+		require.NoError(t, err)
+		assert.Equal(t, tc.json, string(data))
+
+		var decoded JSONSealed
+		require.NoError(t, abi.UnmarshalSealedVariantJSON(data, &decoded))
+		assert.Equal(t, tc.value, decoded)
+	}
+
+	var decoded abi.Option[JSONSealed]
+	require.NoError(t, json.Unmarshal([]byte(`{"circle":2}`), &decoded))
+	assert.Equal(t, abi.Some[JSONSealed](JSONSealedCircle{Value: 2}), decoded)
+
+	var sealed JSONSealed
+	err := abi.UnmarshalSealedVariantJSON([]byte(`{"square":2}`), &sealed)
+	assert.EqualError(t, err, `unknown case "square" of abi_test.JSONSealed`)
+	err = abi.UnmarshalSealedVariantJSON([]byte(`{"circle":"big"}`), &sealed)
+	assert.Error(t, err)
+}
+
+func TestJSONErrors(t *testing.T) {
+	var color JSONColor
+	assert.EqualError(t, json.Unmarshal([]byte(`"green"`), &color), `unknown case "green" of abi_test.JSONColor`)
+	_, err := json.Marshal(JSONColor(2))
+	assert.ErrorIs(t, err, abi.ErrInvalidDiscriminant)
+
+	var perms JSONPermissions
+	assert.EqualError(t, json.Unmarshal([]byte(`["read","sudo"]`), &perms), `unknown flag "sudo" of abi_test.JSONPermissions`)
+	_, err = json.Marshal(JSONPermissions(0b1000))
+	assert.ErrorContains(t, err, "flags abi_test.JSONPermissions set bits 0x8 beyond its 3 flags")
+
+	var shape JSONShapeVariant
+	assert.EqualError(t, json.Unmarshal([]byte(`{"square":1}`), &shape), `unknown case "square" of abi_test.JSONShapeVariant`)
+	assert.Error(t, json.Unmarshal([]byte(`{"dot":null,"circle":1}`), &shape))
+	assert.Error(t, json.Unmarshal([]byte(`{"dot":1}`), &shape))
+
+	var result abi.Result[uint32, string]
+	assert.EqualError(t, json.Unmarshal([]byte(`{"error":"x"}`), &result), `result: unknown case "error", expected "ok" or "err"`)
+}
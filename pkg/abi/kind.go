@@ -0,0 +1,34 @@
+package abi
+
+import "reflect"
+
+// Kind is the kind of WIT type represented by a Go type, for the kinds that cannot be told from
+// the kind of the Go type alone.
+type Kind uint8
+
+const (
+	// KindUnknown is the kind of types that do not declare one.
+	KindUnknown Kind = iota
+	KindRecord
+	KindVariant
+	KindEnum
+	KindOption
+	KindResult
+)
+
+// Kinded is implemented by Go types that declare the kind of WIT type they represent, so that their
+// names are free. Types that do not implement it are told apart by their name: a `Record`, `Enum`
+// or `Variant` suffix, or an `Option` prefix.
+type Kinded interface {
+	WitKind() Kind
+}
+
+var kindedType = reflect.TypeFor[Kinded]()
+
+// kindOf returns the kind declared by the type of rv, or KindUnknown if it declares none.
+func kindOf(rv reflect.Value) Kind {
+	if !rv.IsValid() || !rv.Type().Implements(kindedType) {
+		return KindUnknown
+	}
+	return reflect.Zero(rv.Type()).Interface().(Kinded).WitKind()
+}
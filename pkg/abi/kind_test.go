@@ -0,0 +1,74 @@
+package abi_test
+
+import (
+	"testing"
+
+	"github.com/rioam2/witigo/pkg/abi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Synthetic types declaring their kind, as generated in idiomatic mode, whose names carry no suffix.
+type Shade uint8
+
+func (Shade) WitKind() abi.Kind { return abi.KindEnum }
+
+type Pixel struct {
+	X     uint16
+	Shade Shade
+	Label abi.Option[string]
+}
+
+func (Pixel) WitKind() abi.Kind { return abi.KindRecord }
+
+type ShapeType uint8
+
+type Shape struct {
+	Type   ShapeType
+	Circle float64
+	Dot    Pixel
+}
+
+func (Shape) WitKind() abi.Kind { return abi.KindVariant }
+
+// ConfusingRecord is named like a record, but declares being a variant.
+type ConfusingRecord struct {
+	Type ShapeType
+	A    uint32
+}
+
+func (ConfusingRecord) WitKind() abi.Kind { return abi.KindVariant }
+
+func TestKindedRoundTrip(t *testing.T) {
+	opts := createAbiOptionsFromMemoryMap(nil)
+	original := Shape{Type: 1, Dot: Pixel{X: 7, Shade: 2, Label: abi.Some("dot")}}
+	ptr, free, err := abi.Write(opts, original, nil)
+	require.NoError(t, err)
+	defer free()
+	var decoded Shape
+	require.NoError(t, abi.Read(opts, ptr, &decoded))
+	assert.Equal(t, original, decoded)
+
+	params, freeParams, err := abi.WriteParameters(opts, original.Dot)
+	require.NoError(t, err)
+	defer freeParams()
+	assert.Len(t, params, 5) // x, shade, label discriminant, label pointer and length
+}
+
+func TestKindedOverridesName(t *testing.T) {
+	opts := createAbiOptionsFromMemoryMap(nil)
+	params, free, err := abi.WriteParameters(opts, ConfusingRecord{Type: 0, A: 9})
+	require.NoError(t, err)
+	defer free()
+	assert.Equal(t, []uint64{0, 9}, params)
+}
+
+func TestOptionConstructors(t *testing.T) {
+	value, ok := abi.Some(3).Get()
+	assert.True(t, ok)
+	assert.Equal(t, 3, value)
+
+	value, ok = abi.None[int]().Get()
+	assert.False(t, ok)
+	assert.Equal(t, 0, value)
+}
@@ -0,0 +1,230 @@
+package abi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// ErrBudgetExceeded is matched by errors.Is for every error caused by a call exceeding Limits.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Budget resources reported by BudgetError.
+const (
+	BudgetMemory  = "memory"
+	BudgetFuel    = "fuel"
+	BudgetTimeout = "timeout"
+)
+
+// BudgetError is returned when a call exceeds one of its Limits. The guest was interrupted at an
+// arbitrary point, so its state must be considered corrupt and the instance should be recycled.
+type BudgetError struct {
+	// Resource is one of BudgetMemory, BudgetFuel or BudgetTimeout.
+	Resource string
+	// Limit is the configured limit, in pages, function calls or nanoseconds respectively.
+	Limit uint64
+	// Err is the error reported by the runtime, if any.
+	Err error
+}
+
+func (e *BudgetError) Error() string {
+	var limit string
+	switch e.Resource {
+	case BudgetMemory:
+		limit = fmt.Sprintf("%d pages", e.Limit)
+	case BudgetFuel:
+		limit = fmt.Sprintf("%d function calls", e.Limit)
+	case BudgetTimeout:
+		limit = time.Duration(e.Limit).String()
+	default:
+		limit = fmt.Sprint(e.Limit)
+	}
+	return fmt.Sprintf("%s %s: limit of %s", e.Resource, ErrBudgetExceeded, limit)
+}
+
+func (e *BudgetError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+func (e *BudgetError) Unwrap() error {
+	return e.Err
+}
+
+// Limits bounds the resources used by a component instance. Zero values disable a limit.
+type Limits struct {
+	// MaxMemoryPages is the maximum number of 64 KiB pages the linear memory may grow to.
+	MaxMemoryPages uint32
+	// Fuel is the maximum number of guest function calls a single exported call may make,
+	// including nested calls. Loops that do not call functions are bounded by Timeout instead.
+	Fuel uint64
+	// Timeout is the maximum wall-clock duration of a single exported call.
+	Timeout time.Duration
+}
+
+// IsZero reports whether no limit is configured.
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
+
+// Meter enforces Limits on a wazero module. The context returned by Context must be used to
+// compile and instantiate the module, and calls must go through the RuntimeCall returned by
+// Call. A Meter tracks a single module instance at a time.
+type Meter struct {
+	limits         Limits
+	memoryExceeded atomic.Bool
+	// memoryOversized is set when the initial size of the memory already exceeds the limit. wazero
+	// cannot fail the allocation of the initial memory, so calls are refused instead.
+	memoryOversized atomic.Bool
+}
+
+// NewMeter creates a Meter enforcing the given limits.
+func NewMeter(limits Limits) *Meter {
+	return &Meter{limits: limits}
+}
+
+// Limits returns the limits enforced by the meter.
+func (m *Meter) Limits() Limits {
+	return m.limits
+}
+
+// RuntimeConfig returns c configured to interrupt calls whose context is done, which is required
+// to enforce Timeout on guests that loop without calling functions.
+func (m *Meter) RuntimeConfig(c wazero.RuntimeConfig) wazero.RuntimeConfig {
+	if m.limits.Timeout > 0 {
+		return c.WithCloseOnContextDone(true)
+	}
+	return c
+}
+
+// Context returns ctx configured to meter modules compiled and instantiated with it.
+func (m *Meter) Context(ctx context.Context) context.Context {
+	if m.limits.MaxMemoryPages > 0 {
+		ctx = experimental.WithMemoryAllocator(ctx, experimental.MemoryAllocatorFunc(m.allocateMemory))
+	}
+	if m.limits.Fuel > 0 {
+		ctx = experimental.WithFunctionListenerFactory(ctx, fuelListenerFactory{})
+	}
+	return ctx
+}
+
+// Call wraps call so that every invocation is subject to the limits of the meter. Errors caused
+// by exceeding a limit are reported as *BudgetError.
+func (m *Meter) Call(call RuntimeCall) RuntimeCall {
+	if m.limits.IsZero() {
+		return call
+	}
+	return func(ctx context.Context, name string, params ...uint64) ([]uint64, error) {
+		callCtx := ctx
+		if m.limits.Timeout > 0 {
+			var cancel context.CancelFunc
+			callCtx, cancel = context.WithTimeout(ctx, m.limits.Timeout)
+			defer cancel()
+		}
+		var fuel *fuelTank
+		if m.limits.Fuel > 0 {
+			fuel = &fuelTank{remaining: m.limits.Fuel}
+			callCtx = context.WithValue(callCtx, fuelKey{}, fuel)
+		}
+		if m.memoryOversized.Load() {
+			err := fmt.Errorf("initial memory of the module exceeds %d pages", m.limits.MaxMemoryPages)
+			return nil, &BudgetError{Resource: BudgetMemory, Limit: uint64(m.limits.MaxMemoryPages), Err: err}
+		}
+		m.memoryExceeded.Store(false)
+
+		results, err := call(callCtx, name, params...)
+		if err == nil {
+			return results, nil
+		}
+		switch {
+		case fuel != nil && fuel.exhausted:
+			return nil, &BudgetError{Resource: BudgetFuel, Limit: m.limits.Fuel, Err: err}
+		case m.memoryExceeded.Load():
+			return nil, &BudgetError{Resource: BudgetMemory, Limit: uint64(m.limits.MaxMemoryPages), Err: err}
+		case m.limits.Timeout > 0 && errors.Is(callCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil:
+			return nil, &BudgetError{Resource: BudgetTimeout, Limit: uint64(m.limits.Timeout), Err: err}
+		default:
+			return nil, err
+		}
+	}
+}
+
+// allocateMemory backs linear memories with a buffer that refuses to grow beyond MaxMemoryPages.
+// Refused growth is recorded so that the resulting trap can be reported as a BudgetError.
+func (m *Meter) allocateMemory(capacity, _ uint64) experimental.LinearMemory {
+	limit := uint64(m.limits.MaxMemoryPages) * 65536
+	m.memoryOversized.Store(false)
+	return &meteredMemory{meter: m, limit: limit, capacity: min(capacity, limit)}
+}
+
+type meteredMemory struct {
+	meter    *Meter
+	limit    uint64
+	capacity uint64
+	buf      []byte
+}
+
+func (mem *meteredMemory) Reallocate(size uint64) []byte {
+	if mem.buf == nil {
+		// The initial allocation must not fail.
+		if size > mem.limit {
+			mem.meter.memoryOversized.Store(true)
+		}
+		mem.buf = make([]byte, size, max(size, mem.capacity))
+		return mem.buf
+	}
+	if size > mem.limit {
+		mem.meter.memoryExceeded.Store(true)
+		return nil
+	}
+	if size <= uint64(cap(mem.buf)) {
+		mem.buf = mem.buf[:size]
+		return mem.buf
+	}
+	buf := make([]byte, size)
+	copy(buf, mem.buf)
+	mem.buf = buf
+	return mem.buf
+}
+
+func (mem *meteredMemory) Free() {
+	mem.buf = nil
+}
+
+type fuelKey struct{}
+
+// fuelTank holds the remaining fuel of a call. It is only accessed from the goroutine executing
+// the call.
+type fuelTank struct {
+	remaining uint64
+	exhausted bool
+}
+
+func fuelFromContext(ctx context.Context) *fuelTank {
+	fuel, _ := ctx.Value(fuelKey{}).(*fuelTank)
+	return fuel
+}
+
+// fuelListenerFactory consumes one unit of fuel for every guest function call and aborts the
+// call once the tank of its context is empty.
+type fuelListenerFactory struct{}
+
+func (fuelListenerFactory) NewFunctionListener(api.FunctionDefinition) experimental.FunctionListener {
+	return experimental.FunctionListenerFunc(func(ctx context.Context, _ api.Module, def api.FunctionDefinition, _ []uint64, _ experimental.StackIterator) {
+		fuel := fuelFromContext(ctx)
+		if fuel == nil {
+			return
+		}
+		if fuel.remaining == 0 {
+			fuel.exhausted = true
+			// wazero recovers panics raised by listeners and returns them as errors of the call.
+			panic(fmt.Errorf("out of fuel calling %s: %w", def.DebugName(), ErrBudgetExceeded))
+		}
+		fuel.remaining--
+	})
+}
@@ -0,0 +1,88 @@
+package abi_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rioam2/witigo/pkg/abi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tetratelabs/wazero"
+)
+
+// instantiateMetered instantiates testdata/limits.wasm (built from testdata/limits.wat) with the
+// given limits and returns a metered call function.
+func instantiateMetered(t *testing.T, limits abi.Limits) abi.RuntimeCall {
+	t.Helper()
+	ctx := context.Background()
+	wasm, err := os.ReadFile("testdata/limits.wasm")
+	require.NoError(t, err)
+
+	meter := abi.NewMeter(limits)
+	r := wazero.NewRuntimeWithConfig(ctx, meter.RuntimeConfig(wazero.NewRuntimeConfig()))
+	t.Cleanup(func() { r.Close(ctx) })
+	cm, err := r.CompileModule(meter.Context(ctx), wasm)
+	require.NoError(t, err)
+	module, err := r.InstantiateModule(meter.Context(ctx), cm, wazero.NewModuleConfig().WithName(""))
+	require.NoError(t, err)
+	return meter.Call(abi.GetRuntimeCallFromWazero(module))
+}
+
+func TestLimits(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("fuel", func(t *testing.T) {
+		call := instantiateMetered(t, abi.Limits{Fuel: 100})
+
+		// spin itself consumes one unit of fuel.
+		_, err := call(ctx, "spin", 99)
+		require.NoError(t, err)
+
+		_, err = call(ctx, "spin", 100)
+		require.ErrorIs(t, err, abi.ErrBudgetExceeded)
+		var budgetErr *abi.BudgetError
+		require.True(t, errors.As(err, &budgetErr))
+		assert.Equal(t, abi.BudgetFuel, budgetErr.Resource)
+		assert.Equal(t, uint64(100), budgetErr.Limit)
+
+		// Fuel is refilled for every call.
+		_, err = call(ctx, "spin", 99)
+		assert.NoError(t, err)
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		call := instantiateMetered(t, abi.Limits{MaxMemoryPages: 4})
+
+		results, err := call(ctx, "grow", 3)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{4}, results)
+
+		_, err = call(ctx, "grow", 1)
+		require.ErrorIs(t, err, abi.ErrBudgetExceeded)
+		var budgetErr *abi.BudgetError
+		require.True(t, errors.As(err, &budgetErr))
+		assert.Equal(t, abi.BudgetMemory, budgetErr.Resource)
+		assert.Contains(t, budgetErr.Error(), "memory budget exceeded: limit of 4 pages")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		call := instantiateMetered(t, abi.Limits{Timeout: 50 * time.Millisecond})
+
+		_, err := call(ctx, "forever")
+		require.ErrorIs(t, err, abi.ErrBudgetExceeded)
+		var budgetErr *abi.BudgetError
+		require.True(t, errors.As(err, &budgetErr))
+		assert.Equal(t, abi.BudgetTimeout, budgetErr.Resource)
+		assert.Contains(t, budgetErr.Error(), "timeout budget exceeded: limit of 50ms")
+	})
+
+	t.Run("other errors are not budget errors", func(t *testing.T) {
+		call := instantiateMetered(t, abi.Limits{Fuel: 100, MaxMemoryPages: 4})
+		_, err := call(ctx, "missing")
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, abi.ErrBudgetExceeded)
+	})
+}
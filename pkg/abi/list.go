@@ -26,29 +26,34 @@ func ReadList(opts AbiOptions, ptr uint64, result any) error {
 	}
 
 	// Extract ABI properties of intrinsic type
-	alignment := AlignmentOf(result)
+	alignment := opts.AlignmentOf(result)
 	ptr = AlignTo(ptr, alignment)
 
 	// Extract the list data pointer from memory
 	listDataPtr, ok := opts.Memory.ReadUint32Le(ptr)
 	if !ok {
-		return fmt.Errorf("failed to read list data pointer at %d", ptr)
+		return readError("list data pointer", ptr, 4)
 	}
 
 	// Extract the list length from memory
 	listLength, ok := opts.Memory.ReadUint32Le(ptr + 4)
 	if !ok {
-		return fmt.Errorf("failed to read list length at %d", ptr+4)
+		return readError("list length", ptr+4, 4)
 	}
+	return readListData(opts, uint64(listDataPtr), uint64(listLength), rv)
+}
 
+// readListData reads the listLength elements of a list at listDataPtr into rv, a settable slice
+// value.
+func readListData(opts AbiOptions, listDataPtr uint64, listLength uint64, rv reflect.Value) error {
 	// Create a new slice of the appropriate type
 	elemType := rv.Type().Elem()
-	elemSize := SizeOf(reflect.Zero(elemType).Interface())
+	elemSize := opts.SizeOf(reflect.New(elemType).Interface())
 	newSlice := reflect.MakeSlice(rv.Type(), int(listLength), int(listLength))
 
 	// Read each element from memory and populate the new slice
-	for i := range uint64(listLength) {
-		elemPtr := uint64(listDataPtr) + i*elemSize
+	for i := range listLength {
+		elemPtr := listDataPtr + i*elemSize
 		elemVal := reflect.New(elemType).Interface()
 		err := Read(opts, elemPtr, elemVal)
 		if err != nil {
@@ -79,8 +84,8 @@ func WriteList(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free Ab
 	}
 
 	// Extract ABI properties of intrinsic type
-	size := SizeOf(value)
-	alignment := AlignmentOf(value)
+	size := opts.SizeOf(value)
+	alignment := opts.AlignmentOf(value)
 
 	// Allocate memory if ptrHint is not provided or is zero
 	if ptrHint != nil && *ptrHint != 0 {
@@ -104,11 +109,11 @@ func WriteList(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free Ab
 
 	// Write list header (data pointer and length)
 	if !opts.Memory.WriteUint32Le(ptr, uint32(listDataPtr)) {
-		return ptr, free, fmt.Errorf("failed to write list data pointer at %d", ptr)
+		return ptr, free, writeError("list data pointer", ptr, 4)
 	}
 
 	if !opts.Memory.WriteUint32Le(ptr+4, uint32(listLength)) {
-		return ptr, free, fmt.Errorf("failed to write list length at %d", ptr+4)
+		return ptr, free, writeError("list length", ptr+4, 4)
 	}
 
 	return ptr, free, nil
@@ -132,8 +137,8 @@ func WriteParameterList(opts AbiOptions, value any) (params []Parameter, free Ab
 	// Allocate memory for the list data
 	listLength := uint64(rv.Len())
 	elemType := rv.Type().Elem()
-	elemSize := SizeOf(reflect.Zero(elemType).Interface())
-	elemAlignment := AlignmentOf(reflect.Zero(elemType).Interface())
+	elemSize := opts.SizeOf(reflect.New(elemType).Interface())
+	elemAlignment := opts.AlignmentOf(reflect.New(elemType).Interface())
 	listDataPtr, listDataFree, err := abiMalloc(opts, elemSize*listLength, elemAlignment)
 	if err != nil {
 		return params, free, fmt.Errorf("failed to allocate memory for list data: %w", err)
@@ -143,7 +148,7 @@ func WriteParameterList(opts AbiOptions, value any) (params []Parameter, free Ab
 	// Write each element to memory
 	for i := range listLength {
 		elemPtr := listDataPtr + i*elemSize
-		_, elemFree, err := Write(opts, rv.Index(int(i)).Interface(), &elemPtr)
+		_, elemFree, err := Write(opts, fieldValue(rv.Index(int(i))), &elemPtr)
 		freeCallbacks = append(freeCallbacks, elemFree)
 
 		if err != nil {
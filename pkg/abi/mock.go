@@ -0,0 +1,47 @@
+package abi
+
+import (
+	"errors"
+	"slices"
+	"sync"
+)
+
+// ErrNotMocked is matched by the errors returned by the methods of generated mocks whose function
+// is not set.
+var ErrNotMocked = errors.New("not mocked")
+
+// MockCall is a call recorded by a generated mock.
+type MockCall struct {
+	// Method is the name of the method called, like `Add`.
+	Method string
+	// Args are the arguments of the call, in order.
+	Args []any
+}
+
+// MockRecorder records the calls made to a generated mock, which embeds it. It is safe for
+// concurrent use.
+type MockRecorder struct {
+	mu    sync.Mutex
+	calls []MockCall
+}
+
+// Record records a call of method with args.
+func (r *MockRecorder) Record(method string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, MockCall{Method: method, Args: args})
+}
+
+// Calls returns the calls recorded so far, in order. When methods are given, only the calls of
+// those methods are returned.
+func (r *MockRecorder) Calls(methods ...string) []MockCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	calls := []MockCall{}
+	for _, call := range r.calls {
+		if len(methods) == 0 || slices.Contains(methods, call.Method) {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
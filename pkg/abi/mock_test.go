@@ -0,0 +1,30 @@
+package abi_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/rioam2/witigo/pkg/abi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMockRecorder(t *testing.T) {
+	var r abi.MockRecorder
+	assert.Empty(t, r.Calls())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Record("Add", int32(i), int32(1))
+		}()
+	}
+	wg.Wait()
+	r.Record("Close")
+
+	assert.Len(t, r.Calls(), 11)
+	assert.Len(t, r.Calls("Add"), 10)
+	assert.Equal(t, []abi.MockCall{{Method: "Close"}}, r.Calls("Close"))
+	assert.Empty(t, r.Calls("Subtract"))
+}
@@ -6,11 +6,31 @@ import (
 	"reflect"
 )
 
+// Option is an optional value of type T, which is None unless IsSome is set.
 type Option[T any] struct {
 	IsSome bool
 	Value  T
 }
 
+// Some returns an Option holding value.
+func Some[T any](value T) Option[T] {
+	return Option[T]{IsSome: true, Value: value}
+}
+
+// None returns an Option holding no value.
+func None[T any]() Option[T] {
+	return Option[T]{}
+}
+
+// Get returns the value of the Option, and whether it holds one.
+func (o Option[T]) Get() (T, bool) {
+	return o.Value, o.IsSome
+}
+
+func (Option[T]) WitKind() Kind {
+	return KindOption
+}
+
 func ReadOption(opts AbiOptions, ptr uint64, result any) error {
 	// Validate input and retrieve element type of result
 	rv := reflect.ValueOf(result)
@@ -21,15 +41,19 @@ func ReadOption(opts AbiOptions, ptr uint64, result any) error {
 
 	// Check if the result is an Option type
 	structName := rv.Type().Name()
-	if rv.Kind() != reflect.Struct || len(structName) < 6 || structName[:6] != "Option" {
+	if !isStructOptionType(rv) {
 		return fmt.Errorf("expected Option type, got %s", structName)
 	}
 
 	// Read the discriminant
-	var isSome bool
-	if err := Read(opts, ptr, &isSome); err != nil {
+	var discriminant uint8
+	if err := Read(opts, ptr, &discriminant); err != nil {
 		return err
 	}
+	if discriminant > 1 {
+		return &DiscriminantError{Kind: "option", Value: uint64(discriminant), Cases: 2}
+	}
+	isSome := discriminant == 1
 	rv.Field(0).SetBool(isSome)
 
 	// If the discriminant indicates None, set the value to nil
@@ -38,7 +62,7 @@ func ReadOption(opts AbiOptions, ptr uint64, result any) error {
 	}
 
 	fieldRv := rv.Field(1)
-	valueAlignment := AlignmentOf(fieldRv.Interface())
+	valueAlignment := opts.AlignmentOf(fieldValue(fieldRv))
 	valuePtr := AlignTo(ptr+1, valueAlignment)
 
 	// Read the value into the second field of the Option struct
@@ -62,13 +86,13 @@ func WriteOption(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free
 
 	// Check if the result is an Option type
 	structName := rv.Type().Name()
-	if rv.Kind() != reflect.Struct || len(structName) < 6 || structName[:6] != "Option" {
+	if !isStructOptionType(rv) {
 		return ptr, free, fmt.Errorf("expected Option type, got %s", structName)
 	}
 
 	// Extract ABI properties of intrinsic type
-	size := SizeOf(value)
-	alignment := AlignmentOf(value)
+	size := opts.SizeOf(value)
+	alignment := opts.AlignmentOf(value)
 
 	// Allocate memory if ptrHint is not provided or is zero
 	if ptrHint != nil && *ptrHint != 0 {
@@ -87,12 +111,12 @@ func WriteOption(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free
 	if discriminant {
 		discriminantBytes[0] = 1
 	}
-	valueInterface := rv.Field(1).Interface()
+	valueInterface := fieldValue(rv.Field(1))
 	valuePtr := AlignTo(ptr+1, alignment)
 
 	// Write string descriptor to linear memory
 	if ok := opts.Memory.Write(ptr, discriminantBytes); !ok {
-		return ptr, free, fmt.Errorf("failed to write string data pointer at %d", ptr)
+		return ptr, free, writeError("option discriminant", ptr, 1)
 	}
 
 	_, valueFree, err := Write(opts, valueInterface, &valuePtr)
@@ -121,7 +145,7 @@ func WriteParameterOption(opts AbiOptions, value any) (params []Parameter, free
 
 	// Check if the result is an Option type
 	structName := rv.Type().Name()
-	if rv.Kind() != reflect.Struct || len(structName) < 6 || structName[:6] != "Option" {
+	if !isStructOptionType(rv) {
 		return params, free, fmt.Errorf("expected Option type, got %s", structName)
 	}
 
@@ -130,7 +154,7 @@ func WriteParameterOption(opts AbiOptions, value any) (params []Parameter, free
 	if discriminant {
 		discriminantUint = 1
 	}
-	valueInterface := rv.Field(1).Interface()
+	valueInterface := fieldValue(rv.Field(1))
 	valueParams, valueFree, err := WriteParameter(opts, valueInterface)
 	freeCallbacks = append(freeCallbacks, valueFree)
 	if err != nil {
@@ -3,6 +3,7 @@ package abi
 import (
 	"errors"
 	"fmt"
+	"math"
 	"reflect"
 )
 
@@ -11,6 +12,10 @@ import (
 // Reference: https://docs.wasmtime.dev/api/wasmtime_environ/component/constant.MAX_FLAT_PARAMS.html
 const MAX_FLAT_PARAMS = 16
 
+// MAX_FLAT_RESULTS is the canonical ABI-defined constant for the maximum number of “flat” results of a wasm function.
+// Over this number the results are returned through linear memory and the function returns a pointer to them.
+const MAX_FLAT_RESULTS = 1
+
 type Parameter struct {
 	Value     uint64
 	Size      uint64
@@ -27,6 +32,13 @@ func WriteParameter(opts AbiOptions, value any) (params []Parameter, free AbiFre
 	if !rv.IsValid() {
 		return nil, free, errors.New("must pass a valid value")
 	}
+	if c, ok := opts.Converters.of(rv); ok {
+		w, err := c.wit(rv)
+		if err != nil {
+			return nil, AbiFreeCallbackNoop, err
+		}
+		return WriteParameter(opts, w)
+	}
 
 	// Write based on the kind of the value
 	switch rv.Kind() {
@@ -44,9 +56,15 @@ func WriteParameter(opts AbiOptions, value any) (params []Parameter, free AbiFre
 		return WriteParameterString(opts, value)
 	case reflect.Slice:
 		return WriteParameterList(opts, value)
+	case reflect.Interface:
+		return WriteParameterSealedVariant(opts, value)
 	case reflect.Struct:
 		structName := rv.Type().Name()
-		if isStructVariantType(rv) {
+		if isSealedVariantType(rv) {
+			return WriteParameterSealedVariant(opts, value)
+		} else if isStructResultType(rv) {
+			return WriteParameterResult(opts, value)
+		} else if isStructVariantType(rv) {
 			return WriteParameterVariant(opts, value)
 		} else if isStructRecordType(rv) {
 			return WriteParameterRecord(opts, value)
@@ -94,7 +112,7 @@ func WriteIndirectParameters(opts AbiOptions, params ...Parameter) (ptr uint64,
 			bytes[i] = byte(param.Value >> (8 * i))
 		}
 		if !opts.Memory.Write(paramPtr, bytes) {
-			return ptr, free, fmt.Errorf("failed to write %d bytes at parameter pointer %d", param.Size, paramPtr)
+			return ptr, free, writeError(fmt.Sprintf("%d bytes of parameter", param.Size), paramPtr, param.Size)
 		}
 
 		offset += param.Size
@@ -134,3 +152,232 @@ func WriteParameters(opts AbiOptions, values ...any) (flatParams []uint64, free
 		return flatParams, free, nil
 	}
 }
+
+// ReadParameters reads values from the flat parameters of an ABI call into results, which point to
+// the values in the order of the parameters. It is the inverse of WriteParameters for parameters
+// passed as flat values, and is used to lift the parameters of exports within a guest.
+func ReadParameters(opts AbiOptions, flatParams []uint64, results ...any) error {
+	for i, result := range results {
+		n, err := ReadParameter(opts, flatParams, result)
+		if err != nil {
+			return fmt.Errorf("failed to read parameter %d: %w", i, err)
+		}
+		flatParams = flatParams[n:]
+	}
+	if len(flatParams) > 0 {
+		return fmt.Errorf("%d flat parameters left after reading %d parameters", len(flatParams), len(results))
+	}
+	return nil
+}
+
+// ReadParameter reads a value from the leading flat parameters of an ABI call into the result, and
+// returns the number of flat parameters it was read from. It is the inverse of WriteParameter.
+func ReadParameter(opts AbiOptions, flatParams []uint64, result any) (n int, err error) {
+	// Validate input and retrieve element type of result
+	rv := reflect.ValueOf(result)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return 0, errors.New("must pass a non-nil pointer result")
+	}
+	rv = rv.Elem()
+	if !rv.CanSet() {
+		return 0, errors.New("result must be a settable pointer")
+	}
+	n = flatParameterCount(opts.Converters, rv)
+	if len(flatParams) < n {
+		return 0, fmt.Errorf("%s needs %d flat parameters, got %d", rv.Type(), n, len(flatParams))
+	}
+	if c, ok := opts.Converters.of(rv); ok {
+		w := reflect.New(c.witType)
+		if _, err := ReadParameter(opts, flatParams, w.Interface()); err != nil {
+			return 0, err
+		}
+		g, err := c.call(c.fromWit, w.Elem(), c.witType, c.goType)
+		if err != nil {
+			return 0, err
+		}
+		rv.Set(g)
+		return n, nil
+	}
+
+	// Read based on the kind of the result
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// Setting the value truncates it to the size of the result, which sign-extends the values of
+		// signed integers passed in the low bits of the flat parameter.
+		rv.SetInt(int64(flatParams[0]))
+		if isEnumType(rv) {
+			return n, checkParameterEnum(rv)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		rv.SetUint(flatParams[0])
+		if isEnumType(rv) {
+			return n, checkParameterEnum(rv)
+		}
+	case reflect.Bool:
+		rv.SetBool(flatParams[0] != 0)
+	case reflect.Float32:
+		rv.SetFloat(float64(math.Float32frombits(uint32(flatParams[0]))))
+	case reflect.Float64:
+		rv.SetFloat(math.Float64frombits(flatParams[0]))
+	case reflect.String:
+		return n, readStringData(opts, flatParams[0], flatParams[1], rv)
+	case reflect.Slice:
+		return n, readListData(opts, flatParams[0], flatParams[1], rv)
+	case reflect.Interface:
+		return n, readParameterSealedVariant(opts, flatParams, rv)
+	case reflect.Struct:
+		structName := rv.Type().Name()
+		if rv.NumField() == 0 {
+			return n, nil
+		}
+		if isStructResultType(rv) {
+			return n, readParameterResult(opts, flatParams, rv)
+		} else if isStructVariantType(rv) {
+			return n, readParameterVariant(opts, flatParams, rv)
+		} else if isStructRecordType(rv) {
+			for i := 0; i < rv.NumField(); i++ {
+				fieldN, err := ReadParameter(opts, flatParams, rv.Field(i).Addr().Interface())
+				if err != nil {
+					return 0, fmt.Errorf("failed to read field %d: %w", i, err)
+				}
+				flatParams = flatParams[fieldN:]
+			}
+		} else if isStructOptionType(rv) {
+			if flatParams[0] > 1 {
+				return 0, &DiscriminantError{Kind: "option", Value: flatParams[0], Cases: 2}
+			}
+			rv.Field(0).SetBool(flatParams[0] == 1)
+			if flatParams[0] == 1 {
+				if _, err := ReadParameter(opts, flatParams[1:], rv.Field(1).Addr().Interface()); err != nil {
+					return 0, err
+				}
+			}
+		} else {
+			return 0, fmt.Errorf("reading struct %s is not implemented", structName)
+		}
+	default:
+		return 0, fmt.Errorf("unsupported kind: %s", rv.Kind())
+	}
+	return n, nil
+}
+
+// readParameterResult reads a result from its discriminant followed by the joined flat parameters
+// of its payloads.
+func readParameterResult(opts AbiOptions, flatParams []uint64, rv reflect.Value) error {
+	if flatParams[0] > 1 {
+		return &DiscriminantError{Kind: "result", Value: flatParams[0], Cases: 2}
+	}
+	rv.Field(0).SetBool(flatParams[0] == 1)
+	payload := rv.Field(1 + int(flatParams[0]))
+	if isAnonymousEmptyStruct(payload) {
+		return nil
+	}
+	if _, err := ReadParameter(opts, flatParams[1:], payload.Addr().Interface()); err != nil {
+		return fmt.Errorf("failed to read result payload parameters: %w", err)
+	}
+	return nil
+}
+
+// readParameterVariant reads a variant struct from its discriminant followed by the joined flat
+// parameters of its cases.
+func readParameterVariant(opts AbiOptions, flatParams []uint64, rv reflect.Value) error {
+	numCases := rv.NumField() - 1
+	if flatParams[0] >= uint64(numCases) {
+		return &DiscriminantError{Kind: "variant", Value: flatParams[0], Cases: numCases}
+	}
+	setIntegerValue(rv.Field(0), flatParams[0])
+	activeField := rv.Field(int(flatParams[0]) + 1)
+	if isAnonymousEmptyStruct(activeField) {
+		return nil
+	}
+	if _, err := ReadParameter(opts, flatParams[1:], activeField.Addr().Interface()); err != nil {
+		return fmt.Errorf("failed to read variant payload parameters: %w", err)
+	}
+	return nil
+}
+
+// readParameterSealedVariant reads a variant declared as a sealed interface from its discriminant
+// followed by the joined flat parameters of its cases.
+func readParameterSealedVariant(opts AbiOptions, flatParams []uint64, rv reflect.Value) error {
+	v, ok := sealedVariantOf(rv)
+	if !ok {
+		return fmt.Errorf("result must be a registered variant interface pointer, got %s", rv.Type())
+	}
+	if flatParams[0] >= uint64(len(v.cases)) {
+		return &DiscriminantError{Kind: "variant", Value: flatParams[0], Cases: len(v.cases)}
+	}
+	c := reflect.New(v.cases[flatParams[0]]).Elem()
+	if c.NumField() > 0 {
+		if _, err := ReadParameter(opts, flatParams[1:], c.Field(0).Addr().Interface()); err != nil {
+			return fmt.Errorf("failed to read variant payload parameters: %w", err)
+		}
+	}
+	rv.Set(c)
+	return nil
+}
+
+// flatParameterCount returns the number of flat parameters that values of the type of rv are
+// passed as, like WriteParameter writes them with the converters convs.
+func flatParameterCount(convs *Converters, rv reflect.Value) int {
+	if c, ok := convs.of(rv); ok {
+		return flatParameterCount(convs, reflect.New(c.witType).Elem())
+	}
+	// joinedCount returns the number of flat parameters of a discriminant followed by the joined
+	// flat parameters of payloads of the given types, which are nil for cases without payload.
+	joinedCount := func(payloads ...reflect.Type) int {
+		n := 0
+		for _, t := range payloads {
+			if t != nil {
+				n = max(n, flatParameterCount(convs, reflect.New(t).Elem()))
+			}
+		}
+		return 1 + n
+	}
+
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice:
+		return 2
+	case reflect.Interface:
+		v, ok := sealedVariantOf(rv)
+		if !ok {
+			return 1
+		}
+		payloads := make([]reflect.Type, len(v.cases))
+		for i := range v.cases {
+			payloads[i] = v.payloadType(i)
+		}
+		return joinedCount(payloads...)
+	case reflect.Struct:
+		if rv.NumField() == 0 {
+			return 0
+		}
+		switch {
+		case isStructResultType(rv), isStructVariantType(rv):
+			// The first field holds the discriminant, and the others the payloads.
+			var payloads []reflect.Type
+			for i := 1; i < rv.NumField(); i++ {
+				payloads = append(payloads, rv.Field(i).Type())
+			}
+			return joinedCount(payloads...)
+		case isStructOptionType(rv):
+			return joinedCount(rv.Field(1).Type())
+		}
+		n := 0
+		for i := 0; i < rv.NumField(); i++ {
+			n += flatParameterCount(convs, rv.Field(i))
+		}
+		return n
+	default:
+		return 1
+	}
+}
+
+// checkParameterEnum checks the discriminant of an enum read from a flat parameter into rv, leaving
+// the zero value if it is invalid, like ReadEnum.
+func checkParameterEnum(rv reflect.Value) error {
+	if err := checkEnum(rv); err != nil {
+		rv.SetZero()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package abi_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/rioam2/witigo/pkg/abi"
+	"github.com/rioam2/witigo/pkg/abi/abitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadParametersRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{name: "negative s8", value: int8(-5)},
+		{name: "negative s32", value: int32(math.MinInt32)},
+		{name: "u64", value: uint64(math.MaxUint64)},
+		{name: "bool", value: true},
+		{name: "f32", value: float32(-1.5)},
+		{name: "f64", value: math.Pi},
+		{name: "string", value: "hello"},
+		{name: "list", value: []uint16{1, 2, 3}},
+		{name: "record", value: HookPointRecord{X: -1, DisplayName: "p", Tags: []string{"t"}, Parent: abi.Option[uint32]{IsSome: true, Value: 2}}},
+		{name: "none", value: abi.Option[string]{}},
+		{name: "enum", value: BoundedEnum(2)},
+		{name: "variant", value: SampleVariant{Type: SampleVariantTypeC, C: "c"}},
+		{name: "sealed variant", value: SealedHolder{Id: 1, Shape: SealedB{Value: 7}, Many: []Sealed{SealedA{}}}},
+		{name: "ok", value: abi.Result[uint8, string]{Ok: 3}},
+		{name: "err", value: abi.Result[uint8, string]{IsErr: true, Err: "failed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := abitest.NewRuntime().AbiOptions()
+			flatParams, free, err := abi.WriteParameters(opts, tt.value, uint32(42))
+			require.NoError(t, err)
+			defer free()
+
+			decoded := reflect.New(reflect.TypeOf(tt.value))
+			var trailing uint32
+			require.NoError(t, abi.ReadParameters(opts, flatParams, decoded.Interface(), &trailing))
+			assert.Equal(t, tt.value, decoded.Elem().Interface())
+			assert.Equal(t, uint32(42), trailing)
+		})
+	}
+}
+
+func TestReadParametersErrors(t *testing.T) {
+	opts := abitest.NewRuntime().AbiOptions()
+
+	var s string
+	err := abi.ReadParameters(opts, []uint64{0}, &s)
+	assert.ErrorContains(t, err, "needs 2 flat parameters, got 1")
+
+	var u uint32
+	err = abi.ReadParameters(opts, []uint64{1, 2}, &u)
+	assert.ErrorContains(t, err, "1 flat parameters left")
+
+	var option abi.Option[uint32]
+	err = abi.ReadParameters(opts, []uint64{2, 0}, &option)
+	var discriminantErr *abi.DiscriminantError
+	require.True(t, errors.As(err, &discriminantErr))
+	assert.Equal(t, "option", discriminantErr.Kind)
+
+	enum := BoundedEnum(1)
+	err = abi.ReadParameters(opts, []uint64{3}, &enum)
+	require.True(t, errors.As(err, &discriminantErr))
+	assert.Equal(t, "enum", discriminantErr.Kind)
+	assert.Equal(t, BoundedEnum(0), enum)
+}
@@ -24,14 +24,14 @@ func ReadInt(opts AbiOptions, ptr uint64, result any) error {
 	}
 
 	// Extract ABI properties of intrinsic type
-	size := SizeOf(result)
-	alignment := AlignmentOf(result)
+	size := opts.SizeOf(result)
+	alignment := opts.AlignmentOf(result)
 	ptr = AlignTo(ptr, alignment)
 
 	// Read the bytes from memory
 	bytes, ok := opts.Memory.Read(ptr, size)
 	if !ok {
-		return fmt.Errorf("failed to read %d bytes at pointer %d", size, ptr)
+		return readError(fmt.Sprintf("%d bytes", size), ptr, size)
 	}
 
 	// Convert bytes to the appropriate type
@@ -73,8 +73,8 @@ func WriteInt(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free Abi
 	}
 
 	// Extract ABI properties of intrinsic type
-	size := SizeOf(value)
-	alignment := AlignmentOf(value)
+	size := opts.SizeOf(value)
+	alignment := opts.AlignmentOf(value)
 
 	// Allocate memory if ptrHint is not provided or is zero
 	if ptrHint != nil && *ptrHint != 0 {
@@ -104,7 +104,7 @@ func WriteInt(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free Abi
 
 	// Write bytes to memory
 	if !opts.Memory.Write(ptr, bytes) {
-		return ptr, free, fmt.Errorf("failed to write %d bytes at int/uint pointer %d", size, ptr)
+		return ptr, free, writeError(fmt.Sprintf("%d bytes of int/uint", size), ptr, size)
 	}
 
 	return ptr, free, nil
@@ -133,14 +133,14 @@ func WriteParameterInt(opts AbiOptions, value any) (params []Parameter, free Abi
 	if rv.CanUint() {
 		params = append(params, Parameter{
 			Value:     rv.Uint(),
-			Size:      SizeOf(value),
-			Alignment: AlignmentOf(value),
+			Size:      opts.SizeOf(value),
+			Alignment: opts.AlignmentOf(value),
 		})
 	} else if rv.CanInt() {
 		params = append(params, Parameter{
 			Value:     uint64(rv.Int()),
-			Size:      SizeOf(value),
-			Alignment: AlignmentOf(value),
+			Size:      opts.SizeOf(value),
+			Alignment: opts.AlignmentOf(value),
 		})
 	}
 	return params, free, nil
@@ -162,7 +162,7 @@ func ReadBool(opts AbiOptions, ptr uint64, result any) error {
 	// Read a single byte from memory
 	bytes, ok := opts.Memory.Read(ptr, 1)
 	if !ok {
-		return fmt.Errorf("failed to read boolean at pointer %d", ptr)
+		return readError("boolean", ptr, 1)
 	}
 
 	// Set the boolean value based on the byte read
@@ -192,8 +192,8 @@ func WriteBool(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free Ab
 	}
 
 	// Extract ABI properties of intrinsic type
-	size := SizeOf(value)
-	alignment := AlignmentOf(value)
+	size := opts.SizeOf(value)
+	alignment := opts.AlignmentOf(value)
 
 	// Allocate memory if ptrHint is not provided or is zero
 	if ptrHint != nil && *ptrHint != 0 {
@@ -215,7 +215,7 @@ func WriteBool(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free Ab
 
 	// Write bytes to memory
 	if !opts.Memory.Write(ptr, bytes) {
-		return ptr, free, fmt.Errorf("failed to write %d bytes at boolean pointer %d", size, ptr)
+		return ptr, free, writeError(fmt.Sprintf("%d bytes of boolean", size), ptr, size)
 	}
 
 	return ptr, free, nil
@@ -245,14 +245,14 @@ func WriteParameterBool(opts AbiOptions, value any) (params []Parameter, free Ab
 	if rv.Bool() {
 		params = append(params, Parameter{
 			Value:     1,
-			Size:      SizeOf(value),
-			Alignment: AlignmentOf(value),
+			Size:      opts.SizeOf(value),
+			Alignment: opts.AlignmentOf(value),
 		})
 	} else {
 		params = append(params, Parameter{
 			Value:     0,
-			Size:      SizeOf(value),
-			Alignment: AlignmentOf(value),
+			Size:      opts.SizeOf(value),
+			Alignment: opts.AlignmentOf(value),
 		})
 	}
 	return params, free, nil
@@ -275,14 +275,14 @@ func ReadFloat(opts AbiOptions, ptr uint64, result any) error {
 	}
 
 	// Extract ABI properties of intrinsic type
-	size := SizeOf(result)
-	alignment := AlignmentOf(result)
+	size := opts.SizeOf(result)
+	alignment := opts.AlignmentOf(result)
 	ptr = AlignTo(ptr, alignment)
 
 	// Read the floatBytes from memory
 	floatBytes, ok := opts.Memory.Read(ptr, size)
 	if !ok {
-		return fmt.Errorf("failed to read %d bytes at pointer %d", size, ptr)
+		return readError(fmt.Sprintf("%d bytes", size), ptr, size)
 	}
 
 	// Handle NaN values for float types
@@ -339,8 +339,8 @@ func WriteFloat(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free A
 	}
 
 	// Extract ABI properties of intrinsic type
-	size := SizeOf(value)
-	alignment := AlignmentOf(value)
+	size := opts.SizeOf(value)
+	alignment := opts.AlignmentOf(value)
 
 	// Allocate memory if ptrHint is not provided or is zero
 	if ptrHint != nil && *ptrHint != 0 {
@@ -364,7 +364,7 @@ func WriteFloat(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free A
 
 	// Write bytes to memory
 	if !opts.Memory.Write(ptr, floatBytes) {
-		return ptr, free, fmt.Errorf("failed to write %d bytes at float pointer %d", size, ptr)
+		return ptr, free, writeError(fmt.Sprintf("%d bytes of float", size), ptr, size)
 	}
 
 	return ptr, free, nil
@@ -395,14 +395,14 @@ func WriteParameterFloat(opts AbiOptions, value any) (params []Parameter, free A
 	if rv.Kind() == reflect.Float32 {
 		params = append(params, Parameter{
 			Value:     uint64(math.Float32bits(float32(rv.Float()))),
-			Size:      SizeOf(value),
-			Alignment: AlignmentOf(value),
+			Size:      opts.SizeOf(value),
+			Alignment: opts.AlignmentOf(value),
 		})
 	} else if rv.Kind() == reflect.Float64 {
 		params = append(params, Parameter{
 			Value:     uint64(math.Float64bits(rv.Float())),
-			Size:      SizeOf(value),
-			Alignment: AlignmentOf(value),
+			Size:      opts.SizeOf(value),
+			Alignment: opts.AlignmentOf(value),
 		})
 	}
 
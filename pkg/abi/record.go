@@ -40,7 +40,7 @@ func ReadRecord(opts AbiOptions, ptr uint64, result any) error {
 		}
 	}
 
-	alignment := AlignmentOf(result)
+	alignment := opts.AlignmentOf(result)
 	ptr = AlignTo(ptr, alignment)
 
 	for i := 0; i < rv.NumField(); i++ {
@@ -51,8 +51,8 @@ func ReadRecord(opts AbiOptions, ptr uint64, result any) error {
 		fieldType := field.Type()
 		fieldVal := reflect.New(fieldType).Interface()
 
-		fieldSize := SizeOf(field.Interface())
-		fieldAlignment := AlignmentOf(field.Interface())
+		fieldSize := opts.SizeOf(fieldValue(field))
+		fieldAlignment := opts.AlignmentOf(fieldValue(field))
 		fieldPtr := AlignTo(ptr, fieldAlignment)
 
 		err := Read(opts, fieldPtr, fieldVal)
@@ -89,8 +89,8 @@ func WriteRecord(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free
 	}
 
 	// Allocate memory if ptrHint is not provided or is zero
-	size := SizeOf(value)
-	alignment := AlignmentOf(value)
+	size := opts.SizeOf(value)
+	alignment := opts.AlignmentOf(value)
 	if ptrHint != nil && *ptrHint != 0 {
 		ptr = AlignTo(*ptrHint, alignment)
 	} else {
@@ -106,11 +106,11 @@ func WriteRecord(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free
 	fieldPtr := ptr
 	for i := 0; i < rv.NumField(); i++ {
 		field := rv.Field(i)
-		fieldSize := SizeOf(field.Interface())
-		fieldAlignment := AlignmentOf(field.Interface())
+		fieldSize := opts.SizeOf(fieldValue(field))
+		fieldAlignment := opts.AlignmentOf(fieldValue(field))
 		fieldPtr = AlignTo(fieldPtr, fieldAlignment)
 
-		_, fieldFree, err := Write(opts, field.Interface(), &fieldPtr)
+		_, fieldFree, err := Write(opts, fieldValue(field), &fieldPtr)
 		freeCallbacks = append(freeCallbacks, fieldFree)
 
 		if err != nil {
@@ -145,7 +145,7 @@ func WriteParameterRecord(opts AbiOptions, value any) (params []Parameter, free
 
 	for i := 0; i < rv.NumField(); i++ {
 		field := rv.Field(i)
-		fieldArgs, fieldFree, err := WriteParameter(opts, field.Interface())
+		fieldArgs, fieldFree, err := WriteParameter(opts, fieldValue(field))
 		freeCallbacks = append(freeCallbacks, fieldFree)
 		if err != nil {
 			return nil, free, fmt.Errorf("failed to write field %d: %w", i, err)
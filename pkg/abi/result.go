@@ -0,0 +1,171 @@
+package abi
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Result is the value of a WIT `result<T, E>`, which holds Ok unless IsErr is set, in which case
+// it holds Err. Absent payloads are represented by struct{}.
+type Result[T, E any] struct {
+	IsErr bool
+	Ok    T
+	Err   E
+}
+
+func (Result[T, E]) WitKind() Kind {
+	return KindResult
+}
+
+// ResultError is the error returned by bindings that unwrap a `result<T, E>` into `(T, error)`,
+// when the result holds an error. It holds the error payload, which is accessed with errors.As.
+type ResultError[E any] struct {
+	Value E
+}
+
+func (e *ResultError[E]) Error() string {
+	if s, ok := any(e.Value).(string); ok {
+		return s
+	}
+	return "error result: " + FormatValue(e.Value)
+}
+
+func isStructResultType(rv reflect.Value) bool {
+	return rv.Kind() == reflect.Struct && kindOf(rv) == KindResult
+}
+
+// resultPayloadAlignment returns the alignment of the payloads of a result, which is also the
+// offset of the payload.
+func resultPayloadAlignment(convs *Converters, rv reflect.Value) uint64 {
+	return max(alignmentOf(convs, fieldValue(rv.Field(1))), alignmentOf(convs, fieldValue(rv.Field(2))))
+}
+
+// ReadResult reads a result from memory at the specified pointer into the result.
+func ReadResult(opts AbiOptions, ptr uint64, result any) error {
+	rv := reflect.ValueOf(result)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("must pass a non-nil pointer result")
+	}
+	rv = rv.Elem()
+	if !isStructResultType(rv) {
+		return fmt.Errorf("expected Result type, got %s", rv.Type())
+	}
+
+	ptr = AlignTo(ptr, opts.AlignmentOf(result))
+	var discriminant uint8
+	if err := Read(opts, ptr, &discriminant); err != nil {
+		return err
+	}
+	if discriminant > 1 {
+		return &DiscriminantError{Kind: "result", Value: uint64(discriminant), Cases: 2}
+	}
+	rv.Field(0).SetBool(discriminant == 1)
+
+	payload := rv.Field(1 + int(discriminant))
+	if isAnonymousEmptyStruct(payload) {
+		return nil
+	}
+	return Read(opts, AlignTo(ptr+1, resultPayloadAlignment(opts.Converters, rv)), payload.Addr().Interface())
+}
+
+// WriteResult writes a result to linear memory and returns the pointer & free callback.
+func WriteResult(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free AbiFreeCallback, err error) {
+	ptr = 0
+	freeCallbacks := []AbiFreeCallback{}
+	free = wrapFreeCallbacks(&freeCallbacks)
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	if !isStructResultType(rv) {
+		return ptr, free, fmt.Errorf("expected Result type, got %T", value)
+	}
+
+	size := opts.SizeOf(value)
+	alignment := opts.AlignmentOf(value)
+	if ptrHint != nil && *ptrHint != 0 {
+		ptr = AlignTo(*ptrHint, alignment)
+	} else {
+		var freeResult AbiFreeCallback
+		ptr, freeResult, err = abiMalloc(opts, size, alignment)
+		if err != nil {
+			return ptr, free, err
+		}
+		freeCallbacks = append(freeCallbacks, freeResult)
+	}
+
+	discriminant := 0
+	if rv.Field(0).Bool() {
+		discriminant = 1
+	}
+	if !opts.Memory.Write(ptr, []byte{byte(discriminant)}) {
+		return ptr, free, writeError("result discriminant", ptr, 1)
+	}
+	payload := rv.Field(1 + discriminant)
+	if isAnonymousEmptyStruct(payload) {
+		return ptr, free, nil
+	}
+	payloadPtr := AlignTo(ptr+1, resultPayloadAlignment(opts.Converters, rv))
+	_, payloadFree, err := Write(opts, fieldValue(payload), &payloadPtr)
+	freeCallbacks = append(freeCallbacks, payloadFree)
+	if err != nil {
+		return ptr, free, fmt.Errorf("failed to write result payload: %w", err)
+	}
+	return ptr, free, nil
+}
+
+// WriteParameterResult flattens the discriminant plus the payload parameters of a result.
+func WriteParameterResult(opts AbiOptions, value any) (params []Parameter, free AbiFreeCallback, err error) {
+	params = []Parameter{}
+	freeCallbacks := []AbiFreeCallback{}
+	free = wrapFreeCallbacks(&freeCallbacks)
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	if !isStructResultType(rv) {
+		return params, free, fmt.Errorf("expected Result type, got %T", value)
+	}
+
+	discriminant := 0
+	if rv.Field(0).Bool() {
+		discriminant = 1
+	}
+	params = append(params, Parameter{Value: uint64(discriminant), Size: 1, Alignment: 1})
+
+	// Join the flattened payloads of both cases, slot by slot.
+	var slots []Parameter
+	for _, payload := range []reflect.Value{rv.Field(1), rv.Field(2)} {
+		if isAnonymousEmptyStruct(payload) {
+			continue
+		}
+		payloadParams, payloadFree, err := WriteParameter(opts, reflect.New(payload.Type()).Interface())
+		freeCallbacks = append(freeCallbacks, payloadFree)
+		if err != nil {
+			return params, free, err
+		}
+		for slotIndex, param := range payloadParams {
+			if slotIndex >= len(slots) {
+				slots = append(slots, Parameter{Size: param.Size, Alignment: param.Alignment})
+				continue
+			}
+			slots[slotIndex].Size = max(slots[slotIndex].Size, param.Size)
+			slots[slotIndex].Alignment = max(slots[slotIndex].Alignment, param.Alignment)
+		}
+	}
+
+	if payload := rv.Field(1 + discriminant); !isAnonymousEmptyStruct(payload) {
+		payloadParams, payloadFree, err := WriteParameter(opts, fieldValue(payload))
+		freeCallbacks = append(freeCallbacks, payloadFree)
+		if err != nil {
+			return params, free, fmt.Errorf("failed to write result payload parameters: %w", err)
+		}
+		for slotIndex, param := range payloadParams {
+			slots[slotIndex].Value = param.Value
+		}
+	}
+	return append(params, slots...), free, nil
+}
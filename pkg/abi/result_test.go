@@ -0,0 +1,73 @@
+package abi_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rioam2/witigo/pkg/abi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultRoundTrip(t *testing.T) {
+	opts := createAbiOptionsFromMemoryMap(nil)
+	for _, original := range []abi.Result[uint64, string]{
+		{Ok: 42},
+		{IsErr: true, Err: "not found"},
+	} {
+		ptr, free, err := abi.Write(opts, original, nil)
+		require.NoError(t, err)
+		defer free()
+		var decoded abi.Result[uint64, string]
+		require.NoError(t, abi.Read(opts, ptr, &decoded))
+		assert.Equal(t, original, decoded)
+	}
+}
+
+func TestResultLayout(t *testing.T) {
+	assert.Equal(t, uint64(16), abi.SizeOf(abi.Result[uint64, string]{}))
+	assert.Equal(t, uint64(8), abi.AlignmentOf(abi.Result[uint64, string]{}))
+	assert.Equal(t, uint64(8), abi.SizeOf(abi.Result[struct{}, uint32]{}))
+	assert.Equal(t, uint64(1), abi.SizeOf(abi.Result[struct{}, struct{}]{}))
+
+	// The payload of an error is read after the discriminant, aligned like the largest payload.
+	opts := createAbiOptionsFromMemoryMap(map[uint64][]byte{
+		0x00: {0x01, 0x00, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00},
+	})
+	var decoded abi.Result[struct{}, uint32]
+	require.NoError(t, abi.Read(opts, 0x00, &decoded))
+	assert.Equal(t, abi.Result[struct{}, uint32]{IsErr: true, Err: 7}, decoded)
+}
+
+func TestWriteParameterResult(t *testing.T) {
+	opts := createAbiOptionsFromMemoryMap(nil)
+	params, free, err := abi.WriteParameters(opts, abi.Result[uint32, struct{}]{Ok: 9})
+	require.NoError(t, err)
+	defer free()
+	assert.Equal(t, []uint64{0, 9}, params)
+
+	params, free, err = abi.WriteParameters(opts, abi.Result[uint32, struct{}]{IsErr: true})
+	require.NoError(t, err)
+	defer free()
+	assert.Equal(t, []uint64{1, 0}, params)
+}
+
+func TestResultInvalidDiscriminant(t *testing.T) {
+	opts := createAbiOptionsFromMemoryMap(map[uint64][]byte{0x00: {0x02}})
+	var decoded abi.Result[uint32, uint32]
+	err := abi.Read(opts, 0x00, &decoded)
+	assert.ErrorIs(t, err, abi.ErrInvalidDiscriminant)
+}
+
+func TestResultError(t *testing.T) {
+	var err error = &abi.ResultError[string]{Value: "not found"}
+	assert.EqualError(t, err, "not found")
+
+	err = errors.Join(errors.New("call failed"), &abi.ResultError[uint32]{Value: 3})
+	var resultErr *abi.ResultError[uint32]
+	require.True(t, errors.As(err, &resultErr))
+	assert.Equal(t, uint32(3), resultErr.Value)
+	assert.EqualError(t, resultErr, "error result: 3")
+
+	assert.Equal(t, "err(\"x\")", abi.FormatValue(abi.Result[uint8, string]{IsErr: true, Err: "x"}))
+}
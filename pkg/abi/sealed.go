@@ -0,0 +1,280 @@
+package abi
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// sealedVariant holds the cases of a variant declared as a sealed interface, in the order of the
+// cases of the WIT variant.
+type sealedVariant struct {
+	iface reflect.Type
+	cases []reflect.Type
+}
+
+var (
+	sealedVariantsMu sync.RWMutex
+	// sealedVariants holds the registered variants by interface type and by case type.
+	sealedVariants = map[reflect.Type]*sealedVariant{}
+)
+
+// RegisterVariant registers the cases of a variant declared as the sealed interface V, in the order
+// of the cases of the WIT variant. Each case is a struct with no field, for a case without payload,
+// or with a single field holding the payload. Values of V are then read and written like variants,
+// and a nil V is written as the first case, like the zero value of a variant struct.
+func RegisterVariant[V any](cases ...V) {
+	iface := reflect.TypeFor[V]()
+	if iface.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("variant %s must be an interface", iface))
+	}
+	if len(cases) == 0 {
+		panic(fmt.Sprintf("variant %s must have cases", iface))
+	}
+	v := &sealedVariant{iface: iface}
+	for _, c := range cases {
+		t := reflect.TypeOf(c)
+		if t == nil || t.Kind() != reflect.Struct || t.NumField() > 1 {
+			panic(fmt.Sprintf("case %v of variant %s must be a struct with at most one field", t, iface))
+		}
+		v.cases = append(v.cases, t)
+	}
+
+	sealedVariantsMu.Lock()
+	defer sealedVariantsMu.Unlock()
+	sealedVariants[iface] = v
+	for _, t := range v.cases {
+		sealedVariants[t] = v
+	}
+}
+
+// sealedVariantOf returns the registered variant of rv, which is either the interface of the
+// variant or one of its cases.
+func sealedVariantOf(rv reflect.Value) (*sealedVariant, bool) {
+	if !rv.IsValid() || (rv.Kind() != reflect.Interface && rv.Kind() != reflect.Struct) {
+		return nil, false
+	}
+	sealedVariantsMu.RLock()
+	defer sealedVariantsMu.RUnlock()
+	v, ok := sealedVariants[rv.Type()]
+	return v, ok
+}
+
+func isSealedVariantType(rv reflect.Value) bool {
+	_, ok := sealedVariantOf(rv)
+	return ok
+}
+
+// payloadType returns the type of the payload of a case, or nil if it has none.
+func (v *sealedVariant) payloadType(caseIndex int) reflect.Type {
+	if v.cases[caseIndex].NumField() == 0 {
+		return nil
+	}
+	return v.cases[caseIndex].Field(0).Type
+}
+
+// active returns the case index and payload of rv, which is either the interface of the variant or
+// one of its cases. The payload is invalid for cases without payload.
+func (v *sealedVariant) active(rv reflect.Value) (caseIndex int, payload reflect.Value) {
+	if rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			rv = reflect.New(v.cases[0]).Elem()
+		} else {
+			rv = rv.Elem()
+		}
+	}
+	for i, t := range v.cases {
+		if t == rv.Type() {
+			caseIndex = i
+		}
+	}
+	if rv.NumField() == 0 {
+		return caseIndex, reflect.Value{}
+	}
+	return caseIndex, rv.Field(0)
+}
+
+// layout returns the size of the discriminant, the offset of the payload, and the size and
+// alignment of the variant, whose payloads of Go types that convs converts are laid out like the
+// WIT types they are converted to.
+func (v *sealedVariant) layout(convs *Converters) (discriminantSize, payloadOffset, size, alignment uint64) {
+	switch {
+	case len(v.cases) <= 1<<8:
+		discriminantSize = 1
+	case len(v.cases) <= 1<<16:
+		discriminantSize = 2
+	default:
+		discriminantSize = 4
+	}
+	payloadSize := uint64(0)
+	payloadAlignment := uint64(1)
+	for i := range v.cases {
+		t := v.payloadType(i)
+		if t == nil {
+			continue
+		}
+		zero := reflect.New(t).Interface()
+		payloadSize = max(payloadSize, sizeOf(convs, zero))
+		payloadAlignment = max(payloadAlignment, alignmentOf(convs, zero))
+	}
+	alignment = max(discriminantSize, payloadAlignment)
+	payloadOffset = AlignTo(discriminantSize, payloadAlignment)
+	return discriminantSize, payloadOffset, AlignTo(payloadOffset+payloadSize, alignment), alignment
+}
+
+// ReadSealedVariant reads a variant from memory at the specified pointer into the result, which
+// points to a variant interface registered with RegisterVariant.
+func ReadSealedVariant(opts AbiOptions, ptr uint64, result any) error {
+	rv := reflect.ValueOf(result)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("must pass a non-nil pointer result")
+	}
+	rv = rv.Elem()
+	v, ok := sealedVariantOf(rv)
+	if !ok || rv.Kind() != reflect.Interface {
+		return fmt.Errorf("result must be a registered variant interface pointer, got %s", rv.Type())
+	}
+
+	discriminantSize, payloadOffset, _, alignment := v.layout(opts.Converters)
+	ptr = AlignTo(ptr, alignment)
+	bytes, ok := opts.Memory.Read(ptr, discriminantSize)
+	if !ok {
+		return readError("variant discriminant", ptr, discriminantSize)
+	}
+	discriminant := uint64(0)
+	for i, b := range bytes {
+		discriminant |= uint64(b) << (8 * i)
+	}
+	if discriminant >= uint64(len(v.cases)) {
+		return &DiscriminantError{Kind: "variant", Value: discriminant, Cases: len(v.cases)}
+	}
+
+	c := reflect.New(v.cases[discriminant]).Elem()
+	if c.NumField() > 0 {
+		if err := Read(opts, ptr+payloadOffset, c.Field(0).Addr().Interface()); err != nil {
+			return err
+		}
+	}
+	rv.Set(c)
+	return nil
+}
+
+// WriteSealedVariant writes a variant value, given as a variant interface registered with
+// RegisterVariant or as one of its cases, to linear memory and returns the pointer & free callback.
+func WriteSealedVariant(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free AbiFreeCallback, err error) {
+	ptr = 0
+	freeCallbacks := []AbiFreeCallback{}
+	free = wrapFreeCallbacks(&freeCallbacks)
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	v, ok := sealedVariantOf(rv)
+	if !ok {
+		return ptr, free, fmt.Errorf("value must be a registered variant, got %T", value)
+	}
+
+	discriminantSize, payloadOffset, size, alignment := v.layout(opts.Converters)
+	if ptrHint != nil && *ptrHint != 0 {
+		ptr = AlignTo(*ptrHint, alignment)
+	} else {
+		var freeVar AbiFreeCallback
+		ptr, freeVar, err = abiMalloc(opts, size, alignment)
+		if err != nil {
+			return ptr, free, err
+		}
+		freeCallbacks = append(freeCallbacks, freeVar)
+	}
+
+	caseIndex, payload := v.active(rv)
+	bytes := make([]byte, discriminantSize)
+	for i := range bytes {
+		bytes[i] = byte(caseIndex >> (8 * i))
+	}
+	if !opts.Memory.Write(ptr, bytes) {
+		return ptr, free, writeError("variant discriminant", ptr, discriminantSize)
+	}
+	if !payload.IsValid() {
+		return ptr, free, nil
+	}
+	payloadPtr := ptr + payloadOffset
+	_, payloadFree, err := Write(opts, fieldValue(payload), &payloadPtr)
+	freeCallbacks = append(freeCallbacks, payloadFree)
+	if err != nil {
+		return ptr, free, fmt.Errorf("failed to write variant payload: %w", err)
+	}
+	return ptr, free, nil
+}
+
+// WriteParameterSealedVariant flattens the discriminant plus the active case payload parameters of
+// a variant given as a variant interface registered with RegisterVariant or as one of its cases.
+func WriteParameterSealedVariant(opts AbiOptions, value any) (params []Parameter, free AbiFreeCallback, err error) {
+	params = []Parameter{}
+	freeCallbacks := []AbiFreeCallback{}
+	free = wrapFreeCallbacks(&freeCallbacks)
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	v, ok := sealedVariantOf(rv)
+	if !ok {
+		return params, free, fmt.Errorf("value must be a registered variant, got %T", value)
+	}
+
+	discriminantSize, _, _, _ := v.layout(opts.Converters)
+	caseIndex, payload := v.active(rv)
+	params = append(params, Parameter{
+		Value:     uint64(caseIndex),
+		Size:      discriminantSize,
+		Alignment: discriminantSize,
+	})
+
+	// Join the flattened payloads of all cases, slot by slot.
+	var slots []Parameter
+	for i := range v.cases {
+		t := v.payloadType(i)
+		if t == nil {
+			continue
+		}
+		caseParams, caseFree, err := WriteParameter(opts, reflect.New(t).Interface())
+		freeCallbacks = append(freeCallbacks, caseFree)
+		if err != nil {
+			return params, free, err
+		}
+		for slotIndex, param := range caseParams {
+			if slotIndex >= len(slots) {
+				slots = append(slots, Parameter{Size: param.Size, Alignment: param.Alignment})
+				continue
+			}
+			slots[slotIndex].Size = max(slots[slotIndex].Size, param.Size)
+			slots[slotIndex].Alignment = max(slots[slotIndex].Alignment, param.Alignment)
+		}
+	}
+
+	if payload.IsValid() {
+		payloadParams, payloadFree, err := WriteParameter(opts, fieldValue(payload))
+		freeCallbacks = append(freeCallbacks, payloadFree)
+		if err != nil {
+			return params, free, fmt.Errorf("failed to write variant payload parameters: %w", err)
+		}
+		for slotIndex, param := range payloadParams {
+			slots[slotIndex].Value = param.Value
+		}
+	}
+	return append(params, slots...), free, nil
+}
+
+// fieldValue returns the value of a field, an element or a payload to pass to the ABI functions.
+// Values of interface type are passed by pointer, so that the functions see the variant interface
+// rather than the case it holds, which is nil for the zero value.
+func fieldValue(rv reflect.Value) any {
+	if rv.Kind() == reflect.Interface {
+		ptr := reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+		return ptr.Interface()
+	}
+	return rv.Interface()
+}
@@ -0,0 +1,93 @@
+package abi_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rioam2/witigo/pkg/abi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Synthetic variant declared as a sealed interface, mirroring SampleVariant.
+type Sealed interface {
+	isSealed()
+}
+
+type SealedA struct{}
+type SealedB struct{ Value uint32 }
+type SealedC struct{ Value string }
+
+func (SealedA) isSealed() {}
+func (SealedB) isSealed() {}
+func (SealedC) isSealed() {}
+
+type SealedHolder struct {
+	Id    uint8
+	Shape Sealed
+	Many  []Sealed
+}
+
+func (SealedHolder) WitKind() abi.Kind { return abi.KindRecord }
+
+func init() {
+	abi.RegisterVariant[Sealed](SealedA{}, SealedB{}, SealedC{})
+}
+
+func TestSealedVariantRoundTrip(t *testing.T) {
+	opts := createAbiOptionsFromMemoryMap(nil)
+	for _, original := range []Sealed{SealedA{}, SealedB{Value: 42}, SealedC{Value: "hello"}} {
+		ptr, free, err := abi.Write(opts, &original, nil)
+		require.NoError(t, err)
+		defer free()
+		var decoded Sealed
+		require.NoError(t, abi.Read(opts, ptr, &decoded))
+		assert.Equal(t, original, decoded)
+	}
+}
+
+func TestSealedVariantInRecord(t *testing.T) {
+	opts := createAbiOptionsFromMemoryMap(nil)
+	original := SealedHolder{Id: 1, Shape: SealedC{Value: "x"}, Many: []Sealed{SealedB{Value: 7}, SealedA{}}}
+	ptr, free, err := abi.Write(opts, original, nil)
+	require.NoError(t, err)
+	defer free()
+	var decoded SealedHolder
+	require.NoError(t, abi.Read(opts, ptr, &decoded))
+	assert.Equal(t, original, decoded)
+
+	// A nil variant is written as its first case.
+	ptr, free, err = abi.Write(opts, SealedHolder{Id: 2}, nil)
+	require.NoError(t, err)
+	defer free()
+	require.NoError(t, abi.Read(opts, ptr, &decoded))
+	assert.Equal(t, SealedHolder{Id: 2, Shape: SealedA{}, Many: []Sealed{}}, decoded)
+}
+
+func TestSealedVariantLayout(t *testing.T) {
+	assert.Equal(t, abi.SizeOf(SampleVariant{}), abi.SizeOf(new(Sealed)))
+	assert.Equal(t, abi.AlignmentOf(SampleVariant{}), abi.AlignmentOf(new(Sealed)))
+
+	opts := createAbiOptionsFromMemoryMap(nil)
+	sealed, free, err := abi.WriteParameters(opts, SealedB{Value: 0xDEADBEEF})
+	require.NoError(t, err)
+	defer free()
+	structural, free, err := abi.WriteParameters(opts, SampleVariant{Type: SampleVariantTypeB, B: 0xDEADBEEF})
+	require.NoError(t, err)
+	defer free()
+	assert.Equal(t, structural, sealed)
+}
+
+func TestSealedVariantInvalidDiscriminant(t *testing.T) {
+	opts := createAbiOptionsFromMemoryMap(map[uint64][]byte{0x00: {0x03}})
+	var decoded Sealed
+	err := abi.Read(opts, 0x00, &decoded)
+	var discriminantErr *abi.DiscriminantError
+	require.True(t, errors.As(err, &discriminantErr))
+	assert.Equal(t, 3, int(discriminantErr.Value))
+}
+
+func TestSealedVariantFormat(t *testing.T) {
+	assert.Equal(t, "b(42)", abi.FormatValue(SealedB{Value: 42}))
+	assert.Equal(t, "{id: 1, shape: a, many: []}", abi.FormatValue(SealedHolder{Id: 1, Shape: SealedA{}}))
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"unicode/utf8"
 
 	"golang.org/x/text/encoding/unicode"
 )
@@ -28,44 +29,53 @@ func ReadString(opts AbiOptions, ptr uint64, result any) error {
 	}
 
 	// Extract ABI properties of intrinsic type
-	alignment := AlignmentOf(result)
+	alignment := opts.AlignmentOf(result)
 	ptr = AlignTo(ptr, alignment)
-	strEncoding := opts.StringEncoding
-	strAlignment := strEncoding.Alignment()
-	taggedCodeUnitSize := strEncoding.CodeUnitSize()
 
 	// Read location of string data
 	strPtr, ok := opts.Memory.ReadUint32Le(ptr)
 	if !ok {
-		return fmt.Errorf("failed to read string pointer at %d", ptr)
+		return readError("string pointer", ptr, 4)
 	}
 
 	// Read the number of tagged code units in the string
 	taggedCodeUnits, ok := opts.Memory.ReadUint32Le(ptr + 4)
 	if !ok {
-		return fmt.Errorf("failed to read tagged code units at %d", ptr+4)
+		return readError("tagged code units", ptr+4, 4)
 	}
+	return readStringData(opts, uint64(strPtr), uint64(taggedCodeUnits), rv)
+}
+
+// readStringData reads the data of a string of taggedCodeUnits code units at strPtr into rv, a
+// settable string value.
+func readStringData(opts AbiOptions, strPtr uint64, taggedCodeUnits uint64, rv reflect.Value) error {
+	strEncoding := opts.StringEncoding
+	strAlignment := strEncoding.Alignment()
+	taggedCodeUnitSize := strEncoding.CodeUnitSize()
 
 	// Validate alignment of string data pointer
-	if uint64(strPtr) != AlignTo(uint64(strPtr), uint64(strAlignment)) {
+	if strPtr != AlignTo(strPtr, strAlignment) {
 		return fmt.Errorf("string pointer %d is not aligned to %d bytes", strPtr, strAlignment)
 	}
 
 	// Validate that the string pointer is within bounds
-	strByteLength := uint64(taggedCodeUnits) * taggedCodeUnitSize
-	if uint64(strPtr)+strByteLength > opts.Memory.Size() {
-		return fmt.Errorf("string pointer %d with length %d exceeds memory size %d", strPtr, strByteLength, opts.Memory.Size())
+	strByteLength := taggedCodeUnits * taggedCodeUnitSize
+	if strPtr+strByteLength > opts.Memory.Size() {
+		return readError(fmt.Sprintf("%d bytes of string data", strByteLength), strPtr, strByteLength)
 	}
 
 	// Read the string data from memory
-	strData, ok := opts.Memory.Read(uint64(strPtr), strByteLength)
+	strData, ok := opts.Memory.Read(strPtr, strByteLength)
 	if !ok {
-		return fmt.Errorf("failed to read string data at %d with length %d", strPtr, strByteLength)
+		return readError(fmt.Sprintf("%d bytes of string data", strByteLength), strPtr, strByteLength)
 	}
 
 	// Convert the string data based on the encoding
 	switch strEncoding {
 	case StringEncodingUTF8:
+		if !utf8.Valid(strData) {
+			return fmt.Errorf("string data at %d: %w", strPtr, ErrInvalidUTF8)
+		}
 		rv.SetString(string(strData))
 		return nil
 	case StringEncodingUTF16:
@@ -102,8 +112,8 @@ func WriteString(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free
 	}
 
 	// Extract ABI properties of intrinsic type
-	size := SizeOf(value)
-	alignment := AlignmentOf(value)
+	size := opts.SizeOf(value)
+	alignment := opts.AlignmentOf(value)
 
 	// Allocate memory if ptrHint is not provided or is zero
 	if ptrHint != nil && *ptrHint != 0 {
@@ -127,10 +137,10 @@ func WriteString(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free
 
 	// Write string descriptor to linear memory
 	if ok := opts.Memory.WriteUint32Le(ptr, uint32(strDataPtr)); !ok {
-		return ptr, free, fmt.Errorf("failed to write string data pointer at %d", ptr)
+		return ptr, free, writeError("string data pointer", ptr, 4)
 	}
 	if ok := opts.Memory.WriteUint32Le(ptr+4, uint32(strDataLen)); !ok {
-		return ptr, free, fmt.Errorf("failed to write string length at %d", ptr+4)
+		return ptr, free, writeError("string length", ptr+4, 4)
 	}
 
 	return ptr, free, nil
@@ -192,7 +202,7 @@ func WriteParameterString(opts AbiOptions, value any) (params []Parameter, free
 
 	// Write the string data to memory
 	if !opts.Memory.Write(strDataPtr, strData) {
-		return params, free, fmt.Errorf("failed to write string data at %d", strDataPtr)
+		return params, free, writeError(fmt.Sprintf("%d bytes of string data", strByteLength), strDataPtr, strByteLength)
 	}
 
 	params = append(params, Parameter{
@@ -0,0 +1,30 @@
+;; Core module used by limits_test.go. Rebuild limits.wasm with:
+;;
+;;   wasm-tools parse limits.wat -o limits.wasm
+(module
+  (memory (export "memory") 1)
+
+  (func $tick)
+
+  ;; spin calls $tick n times.
+  (func (export "spin") (param $n i32)
+    (block $done
+      (loop $again
+        (br_if $done (i32.eqz (local.get $n)))
+        (call $tick)
+        (local.set $n (i32.sub (local.get $n) (i32.const 1)))
+        (br $again))))
+
+  ;; forever loops without calling any function.
+  (func (export "forever")
+    (loop $again
+      (br $again)))
+
+  ;; grow grows the memory by the given number of pages and traps if that fails.
+  (func (export "grow") (param $pages i32) (result i32)
+    (local $old i32)
+    (local.set $old (memory.grow (local.get $pages)))
+    (if (i32.eq (local.get $old) (i32.const -1))
+      (then unreachable))
+    (memory.size))
+)
@@ -25,6 +25,9 @@ func Read(opts AbiOptions, ptr uint64, result any) error {
 		return errors.New("must pass a non-nil pointer result")
 	}
 	rv = rv.Elem()
+	if c, ok := opts.Converters.of(rv); ok {
+		return c.read(opts, ptr, rv)
+	}
 
 	// Read based on the kind of the result
 	switch rv.Kind() {
@@ -42,12 +45,16 @@ func Read(opts AbiOptions, ptr uint64, result any) error {
 		return ReadString(opts, ptr, result)
 	case reflect.Slice:
 		return ReadList(opts, ptr, result)
+	case reflect.Interface:
+		return ReadSealedVariant(opts, ptr, result)
 	case reflect.Struct:
 		structName := rv.Type().Name()
 		if rv.NumField() == 0 {
 			return nil
 		}
-		if isStructVariantType(rv) {
+		if isStructResultType(rv) {
+			return ReadResult(opts, ptr, result)
+		} else if isStructVariantType(rv) {
 			return ReadVariant(opts, ptr, result)
 		} else if isStructRecordType(rv) {
 			return ReadRecord(opts, ptr, result)
@@ -71,6 +78,13 @@ func Write(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free AbiFre
 	if !rv.IsValid() {
 		return ptr, free, errors.New("must pass a valid value")
 	}
+	if c, ok := opts.Converters.of(rv); ok {
+		w, err := c.wit(rv)
+		if err != nil {
+			return 0, AbiFreeCallbackNoop, err
+		}
+		return Write(opts, w, ptrHint)
+	}
 
 	// Write based on the kind of the value
 	switch rv.Kind() {
@@ -88,12 +102,18 @@ func Write(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free AbiFre
 		return WriteString(opts, value, ptrHint)
 	case reflect.Slice:
 		return WriteList(opts, value, ptrHint)
+	case reflect.Interface:
+		return WriteSealedVariant(opts, value, ptrHint)
 	case reflect.Struct:
 		structName := rv.Type().Name()
 		if isAnonymousEmptyStruct(rv) { // empty struct{} case payload
 			return 0, AbiFreeCallbackNoop, nil
 		}
-		if isStructVariantType(rv) {
+		if isSealedVariantType(rv) {
+			return WriteSealedVariant(opts, value, ptrHint)
+		} else if isStructResultType(rv) {
+			return WriteResult(opts, value, ptrHint)
+		} else if isStructVariantType(rv) {
 			return WriteVariant(opts, value, ptrHint)
 		} else if isStructRecordType(rv) {
 			return WriteRecord(opts, value, ptrHint)
@@ -115,12 +135,22 @@ func AlignTo(ptr uint64, alignment uint64) uint64 {
 	return uint64(math.Ceil(float64(ptr)/float64(alignment)) * float64(alignment))
 }
 
-// SizeOf returns the size in bytes of the given value type as defined in the Canonical ABI.
+// SizeOf returns the size in bytes of the given value type as defined in the Canonical ABI. Values
+// of Go types converted to WIT types are measured with AbiOptions.SizeOf.
 func SizeOf(value any) uint64 {
+	return sizeOf(nil, value)
+}
+
+// sizeOf returns the size in bytes of the given value type, whose Go types that convs converts are
+// laid out like the WIT types they are converted to.
+func sizeOf(convs *Converters, value any) uint64 {
 	rv := reflect.ValueOf(value)
 	if rv.Kind() == reflect.Ptr {
 		rv = rv.Elem()
 	}
+	if c, ok := convs.of(rv); ok {
+		return sizeOf(convs, c.zero())
+	}
 	switch rv.Kind() {
 	case reflect.Bool, reflect.Int8, reflect.Uint8:
 		return 1
@@ -130,36 +160,43 @@ func SizeOf(value any) uint64 {
 		return 4
 	case reflect.Int64, reflect.Uint64, reflect.Float64, reflect.String, reflect.Slice:
 		return 8
-	case reflect.Struct:
+	case reflect.Interface, reflect.Struct:
 		structName := rv.Type().Name()
 		if isAnonymousEmptyStruct(rv) {
 			return 0
 		}
-		if isStructVariantType(rv) {
+		if v, ok := sealedVariantOf(rv); ok {
+			_, _, size, _ := v.layout(convs)
+			return size
+		} else if isStructResultType(rv) {
+			payloadAlignment := resultPayloadAlignment(convs, rv)
+			payloadSize := max(sizeOf(convs, fieldValue(rv.Field(1))), sizeOf(convs, fieldValue(rv.Field(2))))
+			return AlignTo(AlignTo(1, payloadAlignment)+payloadSize, payloadAlignment)
+		} else if isStructVariantType(rv) {
 			// Variant size = size(discriminant) + max(size(case_i)) aligned to max variant alignment.
 			if rv.NumField() == 0 {
 				panic(panicVariantMissingDiscriminant)
 			}
-			discriminantSize := SizeOf(rv.Field(0).Interface())
+			discriminantSize := sizeOf(convs, rv.Field(0).Interface())
 			maxCaseSize := uint64(0)
 			for i := 1; i < rv.NumField(); i++ {
 				field := rv.Field(i)
-				fieldSize := SizeOf(field.Interface())
+				fieldSize := sizeOf(convs, fieldValue(field))
 				if fieldSize > maxCaseSize {
 					maxCaseSize = fieldSize
 				}
 			}
-			return AlignTo(discriminantSize+maxCaseSize, maxVariantAlignment(rv))
+			return AlignTo(discriminantSize+maxCaseSize, maxVariantAlignment(convs, rv))
 		} else if isStructRecordType(rv) {
 			size := uint64(0)
 			for i := 0; i < rv.NumField(); i++ {
 				field := rv.Field(i)
-				fieldSize := SizeOf(field.Interface())
-				fieldAlignment := AlignmentOf(field.Interface())
+				fieldSize := sizeOf(convs, fieldValue(field))
+				fieldAlignment := alignmentOf(convs, fieldValue(field))
 				size = AlignTo(size, fieldAlignment)
 				size += fieldSize
 			}
-			recordAlignment := AlignmentOf(value)
+			recordAlignment := alignmentOf(convs, value)
 			return AlignTo(size, recordAlignment)
 		} else if isStructOptionType(rv) {
 			numFields := rv.NumField()
@@ -168,8 +205,8 @@ func SizeOf(value any) uint64 {
 			}
 			discriminantRv := rv.Field(0)
 			valueRv := rv.Field(1)
-			valueAlignment := AlignmentOf(valueRv.Interface())
-			totalSize := SizeOf(discriminantRv.Interface()) + SizeOf(valueRv.Interface())
+			valueAlignment := alignmentOf(convs, fieldValue(valueRv))
+			totalSize := sizeOf(convs, discriminantRv.Interface()) + sizeOf(convs, fieldValue(valueRv))
 			return AlignTo(totalSize, valueAlignment)
 		} else {
 			panic(fmt.Errorf("size of struct %s is not implemented", structName))
@@ -180,11 +217,21 @@ func SizeOf(value any) uint64 {
 }
 
 // AlignmentOf returns the alignment in bytes of the given value type as defined in the Canonical ABI.
+// Values of Go types converted to WIT types are measured with AbiOptions.AlignmentOf.
 func AlignmentOf(value any) uint64 {
+	return alignmentOf(nil, value)
+}
+
+// alignmentOf returns the alignment in bytes of the given value type, whose Go types that convs
+// converts are laid out like the WIT types they are converted to.
+func alignmentOf(convs *Converters, value any) uint64 {
 	rv := reflect.ValueOf(value)
 	if rv.Kind() == reflect.Ptr {
 		rv = rv.Elem()
 	}
+	if c, ok := convs.of(rv); ok {
+		return alignmentOf(convs, c.zero())
+	}
 	switch rv.Kind() {
 	case reflect.Bool, reflect.Int8, reflect.Uint8:
 		return 1
@@ -194,21 +241,26 @@ func AlignmentOf(value any) uint64 {
 		return 4
 	case reflect.Int64, reflect.Uint64, reflect.Float64:
 		return 8
-	case reflect.Struct:
+	case reflect.Interface, reflect.Struct:
 		structName := rv.Type().Name()
 		if isAnonymousEmptyStruct(rv) {
 			return 1
 		}
-		if isStructVariantType(rv) {
+		if v, ok := sealedVariantOf(rv); ok {
+			_, _, _, alignment := v.layout(convs)
+			return alignment
+		} else if isStructResultType(rv) {
+			return resultPayloadAlignment(convs, rv)
+		} else if isStructVariantType(rv) {
 			if rv.NumField() == 0 {
 				panic(panicVariantMissingDiscriminant)
 			}
-			return maxVariantAlignment(rv)
+			return maxVariantAlignment(convs, rv)
 		} else if isStructRecordType(rv) {
 			alignment := uint64(1)
 			for i := 0; i < rv.NumField(); i++ {
 				field := rv.Field(i)
-				fieldAlignment := AlignmentOf(field.Interface())
+				fieldAlignment := alignmentOf(convs, fieldValue(field))
 				if fieldAlignment > alignment {
 					alignment = fieldAlignment
 				}
@@ -220,7 +272,7 @@ func AlignmentOf(value any) uint64 {
 				panic(fmt.Errorf("Option type must contain only discriminant and value fields"))
 			}
 			valueRv := rv.Field(1)
-			alignment := AlignmentOf(valueRv.Interface())
+			alignment := alignmentOf(convs, fieldValue(valueRv))
 			return alignment
 		} else {
 			panic(fmt.Errorf("alignment of struct %s is not implemented", structName))
@@ -245,6 +297,9 @@ func isStructRecordType(rv reflect.Value) bool {
 	if rv.Kind() != reflect.Struct {
 		return false
 	}
+	if kind := kindOf(rv); kind != KindUnknown {
+		return kind == KindRecord
+	}
 	structName := rv.Type().Name()
 	return len(structName) >= 6 && structName[len(structName)-6:] == "Record"
 }
@@ -253,8 +308,11 @@ func isStructVariantType(rv reflect.Value) bool {
 	if rv.Kind() != reflect.Struct {
 		return false
 	}
-	name := rv.Type().Name()
-	if len(name) < 7 || name[len(name)-7:] != "Variant" { // suffix Variant
+	if kind := kindOf(rv); kind != KindUnknown {
+		if kind != KindVariant {
+			return false
+		}
+	} else if name := rv.Type().Name(); len(name) < 7 || name[len(name)-7:] != "Variant" { // suffix Variant
 		return false
 	}
 	// Minimal structural check: first field named Type
@@ -268,19 +326,19 @@ func isStructVariantType(rv reflect.Value) bool {
 // all (non-empty) case payload fields of a variant struct. The caller MUST pass
 // a reflect.Value satisfying isStructVariantType. Panics if the variant is
 // malformed (e.g. zero fields).
-func maxVariantAlignment(rv reflect.Value) uint64 {
+func maxVariantAlignment(convs *Converters, rv reflect.Value) uint64 {
 	if rv.NumField() == 0 {
 		panic("Variant struct must contain at least a discriminant field")
 	}
 	// Start with discriminant alignment
-	maxAlign := AlignmentOf(rv.Field(0).Interface())
+	maxAlign := alignmentOf(convs, rv.Field(0).Interface())
 	for i := 1; i < rv.NumField(); i++ {
 		f := rv.Field(i)
 		// Skip empty struct case payloads – they contribute nothing
 		if f.Kind() == reflect.Struct && f.Type().NumField() == 0 {
 			continue
 		}
-		a := AlignmentOf(f.Interface())
+		a := alignmentOf(convs, fieldValue(f))
 		if a > maxAlign {
 			maxAlign = a
 		}
@@ -300,17 +358,20 @@ func isStructOptionType(rv reflect.Value) bool {
 	if rv.Kind() != reflect.Struct {
 		return false
 	}
+	if kind := kindOf(rv); kind != KindUnknown {
+		return kind == KindOption
+	}
 	structName := rv.Type().Name()
 	return len(structName) >= 6 && structName[:6] == "Option"
 }
 
-// isEnumType returns true if the reflected value is a named integer type whose
-// Go typename ends with the canonical "Enum" suffix produced by the code
-// generator (see generateEnumTypedefFromType). Enums are represented as the
-// smallest unsigned integer type capable of holding the discriminant as per
-// the Canonical ABI; here we simply treat them as integers for load/store and
-// parameter flattening but add this predicate so that future specialized logic
-// (e.g. bounds checking) can hook in without changing call sites.
+// isEnumType returns true if the reflected value is a named integer type that
+// declares KindEnum, or whose Go typename ends with the canonical "Enum" suffix
+// produced by the code generator (see generateEnumTypedefFromType). Enums are
+// represented as the smallest unsigned integer type capable of holding the
+// discriminant as per the Canonical ABI; here we treat them as integers for
+// load/store and parameter flattening, after checking the bounds of enums that
+// implement IsValid (see checkEnum).
 func isEnumType(rv reflect.Value) bool {
 	if rv.Kind() == reflect.Ptr {
 		rv = rv.Elem()
@@ -333,6 +394,10 @@ func isEnumType(rv reflect.Value) bool {
 		return false
 	}
 
+	if kind := kindOf(rv); kind != KindUnknown {
+		return kind == KindEnum
+	}
+
 	const enumSuffix = "Enum"
 	const enumSuffixLen = len(enumSuffix)
 	name := t.Name()
@@ -22,8 +22,8 @@ func ReadVariant(opts AbiOptions, ptr uint64, result any) error {
 
 	// Read discriminant into first field
 	discriminantField := rv.Field(0)
-	discriminantSize := SizeOf(discriminantField.Interface())
-	discriminantAlign := AlignmentOf(discriminantField.Interface())
+	discriminantSize := opts.SizeOf(discriminantField.Interface())
+	discriminantAlign := opts.AlignmentOf(discriminantField.Interface())
 	discriminantPtr := AlignTo(ptr, discriminantAlign)
 
 	// allocate a temporary variable to read into then set (so we invoke int logic)
@@ -45,7 +45,7 @@ func ReadVariant(opts AbiOptions, ptr uint64, result any) error {
 	caseIndex := int(discrVal)
 	numCases := rv.NumField() - 1
 	if caseIndex < 0 || caseIndex >= numCases { // invalid discriminant
-		return fmt.Errorf("variant discriminant %d out of range [0,%d)", caseIndex, numCases)
+		return &DiscriminantError{Kind: "variant", Value: uint64(caseIndex), Cases: numCases}
 	}
 
 	// Active case field is offset +1 from Type field
@@ -56,7 +56,7 @@ func ReadVariant(opts AbiOptions, ptr uint64, result any) error {
 		return nil
 	}
 
-	valuePtr := AlignTo(discriminantPtr+discriminantSize, maxVariantAlignment(rv))
+	valuePtr := AlignTo(discriminantPtr+discriminantSize, maxVariantAlignment(opts.Converters, rv))
 	return Read(opts, valuePtr, activeField.Addr().Interface())
 }
 
@@ -80,8 +80,8 @@ func WriteVariant(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free
 		return ptr, free, errors.New("variant struct missing fields")
 	}
 
-	size := SizeOf(value)
-	alignment := AlignmentOf(value)
+	size := opts.SizeOf(value)
+	alignment := opts.AlignmentOf(value)
 	if ptrHint != nil && *ptrHint != 0 {
 		ptr = AlignTo(*ptrHint, alignment)
 	} else {
@@ -94,8 +94,8 @@ func WriteVariant(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free
 	}
 
 	discriminantField := rv.Field(0)
-	discriminantSize := SizeOf(discriminantField.Interface())
-	discriminantAlign := AlignmentOf(discriminantField.Interface())
+	discriminantSize := opts.SizeOf(discriminantField.Interface())
+	discriminantAlign := opts.AlignmentOf(discriminantField.Interface())
 	discriminantPtr := AlignTo(ptr, discriminantAlign)
 
 	// Serialize discriminant bytes into linear memory
@@ -114,7 +114,7 @@ func WriteVariant(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free
 		return ptr, free, fmt.Errorf("discriminant field type %s not int/uint", discriminantField.Type())
 	}
 	if !opts.Memory.Write(discriminantPtr, bytes) {
-		return ptr, free, fmt.Errorf("failed to write discriminant at %d", discriminantPtr)
+		return ptr, free, writeError("variant discriminant", discriminantPtr, discriminantSize)
 	}
 
 	discriminantVal := uint64(0)
@@ -126,14 +126,14 @@ func WriteVariant(opts AbiOptions, value any, ptrHint *uint64) (ptr uint64, free
 	caseIndex := int(discriminantVal)
 	numCases := rv.NumField() - 1
 	if caseIndex < 0 || caseIndex >= numCases {
-		return ptr, free, fmt.Errorf("variant discriminant %d out of range [0,%d)", caseIndex, numCases)
+		return ptr, free, &DiscriminantError{Kind: "variant", Value: uint64(caseIndex), Cases: numCases}
 	}
 	activeField := rv.Field(caseIndex + 1)
 	if isAnonymousEmptyStruct(activeField) {
 		return ptr, free, nil
 	}
-	valuePtr := AlignTo(discriminantPtr+discriminantSize, maxVariantAlignment(rv))
-	_, valueFree, err := Write(opts, activeField.Interface(), &valuePtr)
+	valuePtr := AlignTo(discriminantPtr+discriminantSize, maxVariantAlignment(opts.Converters, rv))
+	_, valueFree, err := Write(opts, fieldValue(activeField), &valuePtr)
 	freeCallbacks = append(freeCallbacks, valueFree)
 	if err != nil {
 		return ptr, free, fmt.Errorf("failed to write variant payload: %w", err)
@@ -173,14 +173,14 @@ func WriteParameterVariant(opts AbiOptions, value any) (params []Parameter, free
 	caseIndex := int(discriminantUint)
 	numCases := rv.NumField() - 1
 	if caseIndex < 0 || caseIndex >= numCases {
-		return params, free, fmt.Errorf("variant discriminant %d out of range [0,%d)", caseIndex, numCases)
+		return params, free, &DiscriminantError{Kind: "variant", Value: uint64(caseIndex), Cases: numCases}
 	}
 
 	// Append discriminant first
 	discriminantParam := Parameter{
 		Value:     discriminantUint,
-		Size:      SizeOf(discriminantField.Interface()),
-		Alignment: AlignmentOf(discriminantField.Interface()),
+		Size:      opts.SizeOf(discriminantField.Interface()),
+		Alignment: opts.AlignmentOf(discriminantField.Interface()),
 	}
 	params = append(params, discriminantParam)
 
@@ -195,7 +195,7 @@ func WriteParameterVariant(opts AbiOptions, value any) (params []Parameter, free
 		if isAnonymousEmptyStruct(field) {
 			continue // empty payload contributes nothing
 		}
-		zeroVal := reflect.New(field.Type()).Elem().Interface()
+		zeroVal := reflect.New(field.Type()).Interface()
 		fieldParams, fieldFree, e := WriteParameter(opts, zeroVal)
 		freeCallbacks = append(freeCallbacks, fieldFree)
 		if e != nil {
@@ -219,7 +219,7 @@ func WriteParameterVariant(opts AbiOptions, value any) (params []Parameter, free
 	activeField := rv.Field(caseIndex + 1)
 	realParams := []Parameter{}
 	if !isAnonymousEmptyStruct(activeField) {
-		activeFieldParams, activeFieldFree, e := WriteParameter(opts, activeField.Interface())
+		activeFieldParams, activeFieldFree, e := WriteParameter(opts, fieldValue(activeField))
 		freeCallbacks = append(freeCallbacks, activeFieldFree)
 		if e != nil {
 			return params, free, fmt.Errorf("failed to write variant payload parameters: %w", e)
@@ -59,6 +59,10 @@ func GetRuntimeCallFromWazero(module api.Module) RuntimeCall {
 		if fn == nil {
 			return nil, fmt.Errorf("function %s not found in module", name)
 		}
-		return fn.Call(ctx, params...)
+		results, err := fn.Call(ctx, params...)
+		if err != nil {
+			return nil, newTrapError(name, err)
+		}
+		return results, nil
 	}
 }
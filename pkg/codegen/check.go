@@ -0,0 +1,149 @@
+package codegen
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// FileDiff is a file of generated bindings that differs from the file generated anew.
+type FileDiff struct {
+	// Path is the path of the file.
+	Path string
+	// Diff is a unified diff from the file to the file generated anew, or a summary of the difference
+	// for core modules and missing files.
+	Diff string
+}
+
+// CheckFromFile is like CheckFromFileWithOptions, for the bindings of GenerateFromFile.
+func CheckFromFile(inputPath string, outDir string) ([]FileDiff, error) {
+	return CheckFromFileWithOptions(inputPath, outDir, GenerateOptions{})
+}
+
+// CheckFromFileWithOptions generates the bindings of GenerateFromFileWithOptions in memory and
+// compares them with the files of outDir, including the core modules extracted from a component.
+// It returns the files that differ or are missing, and the generated Go files of outDir that would
+// no longer be generated, like the file of a removed interface; none when the bindings are up to
+// date. Core modules are not compared for bindings generated from WIT sources, and files of outDir
+// that are not generated are ignored.
+func CheckFromFileWithOptions(inputPath string, outDir string, opts GenerateOptions) ([]FileDiff, error) {
+	b, err := renderFromFile(inputPath, outDir, opts)
+	if err != nil {
+		return nil, err
+	}
+	var diffs []FileDiff
+	generated := map[string]bool{}
+	for _, file := range b.files {
+		generated[filepath.Clean(file.path)] = true
+		diff, err := compareFile(file.path, file.data, true)
+		if err != nil {
+			return nil, err
+		}
+		if diff != "" {
+			diffs = append(diffs, FileDiff{Path: file.path, Diff: diff})
+		}
+	}
+	stale, err := staleFiles(outDir, generated)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range stale {
+		diffs = append(diffs, FileDiff{Path: path, Diff: fmt.Sprintf("%s is no longer generated\n", path)})
+	}
+	if b.coreModule == nil {
+		return diffs, nil
+	}
+	for _, coreModuleFile := range b.coreModuleFiles {
+		diff, err := compareFile(coreModuleFile, b.coreModule, false)
+		if err != nil {
+			return nil, err
+		}
+		if diff != "" {
+			diffs = append(diffs, FileDiff{Path: coreModuleFile, Diff: diff})
+		}
+	}
+	return diffs, nil
+}
+
+// CheckFromConfig checks the bindings generated by GenerateFromConfig.
+func CheckFromConfig(configPath string) ([]FileDiff, error) {
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if config.Input == "" || config.Output == "" {
+		return nil, fmt.Errorf("configuration file %s must set input and output", configPath)
+	}
+	return CheckFromFileWithOptions(config.Input, config.Output, config.Options())
+}
+
+// staleFiles returns the Go files of dir and its subdirectories that were generated by witigo,
+// according to their header, but are not in generated.
+func staleFiles(dir string, generated map[string]bool) ([]string, error) {
+	var stale []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == dir {
+			return fs.SkipAll
+		}
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || filepath.Ext(path) != ".go" || generated[filepath.Clean(path)] {
+			return nil
+		}
+		ok, err := isGeneratedFile(path)
+		if ok {
+			stale = append(stale, path)
+		}
+		return err
+	})
+	return stale, err
+}
+
+// isGeneratedFile reports whether the first line of the file at path is the header of generated
+// bindings.
+func isGeneratedFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return false, scanner.Err()
+	}
+	return strings.TrimSpace(scanner.Text()) == "// "+generatedHeader, nil
+}
+
+// compareFile compares the file at path with its expected contents, and returns their differences
+// as a unified diff for text files, or a summary for binary files. It returns an empty string if
+// they are the same.
+func compareFile(path string, expected []byte, text bool) (string, error) {
+	actual, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Sprintf("%s is missing\n", path), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if bytes.Equal(actual, expected) {
+		return "", nil
+	}
+	if !text {
+		return fmt.Sprintf("%s differs: %d bytes, %d bytes when generated\n", path, len(actual), len(expected)), nil
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(actual)),
+		B:        difflib.SplitLines(string(expected)),
+		FromFile: path,
+		ToFile:   path + " (generated)",
+		Context:  3,
+	})
+}
@@ -0,0 +1,85 @@
+package codegen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	outDir := t.TempDir()
+	opts := GenerateOptions{Worlds: []string{"drawing", "measuring"}, ImportPath: "example.com/bindings", Wit: true}
+	require.NoError(t, GenerateFromFileWithOptions("testdata/worlds.wit", outDir, opts))
+
+	diffs, err := CheckFromFileWithOptions("testdata/worlds.wit", outDir, opts)
+	require.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	drawing := filepath.Join(outDir, "drawing", "drawing.go")
+	code, err := os.ReadFile(drawing)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(drawing, append(code, "\nvar edited = true\n"...), 0666))
+	require.NoError(t, os.Remove(filepath.Join(outDir, "types", "types.go")))
+
+	diffs, err = CheckFromFileWithOptions("testdata/worlds.wit", outDir, opts)
+	require.NoError(t, err)
+	require.Len(t, diffs, 2)
+	assert.Equal(t, filepath.Join(outDir, "types", "types.go"), diffs[0].Path)
+	assert.Contains(t, diffs[0].Diff, "is missing")
+	assert.Equal(t, drawing, diffs[1].Path)
+	assert.Contains(t, diffs[1].Diff, "--- "+drawing)
+	assert.Contains(t, diffs[1].Diff, "-var edited = true")
+}
+
+func TestCheckStaleFiles(t *testing.T) {
+	outDir := t.TempDir()
+	opts := GenerateOptions{Worlds: []string{"drawing"}, SplitFiles: true}
+	require.NoError(t, GenerateFromFileWithOptions("testdata/worlds.wit", outDir, opts))
+
+	// A file generated for an interface since removed is stale, unlike hand-written files.
+	shapes, err := os.ReadFile(filepath.Join(outDir, "shapes.go"))
+	require.NoError(t, err)
+	removed := filepath.Join(outDir, "removed.go")
+	require.NoError(t, os.WriteFile(removed, shapes, 0666))
+	require.NoError(t, os.WriteFile(filepath.Join(outDir, "convert.go"), []byte("package worlds\n"), 0666))
+
+	diffs, err := CheckFromFileWithOptions("testdata/worlds.wit", outDir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []FileDiff{{Path: removed, Diff: removed + " is no longer generated\n"}}, diffs)
+
+	diffs, err = CheckFromFileWithOptions("testdata/worlds.wit", filepath.Join(outDir, "missing"), opts)
+	require.NoError(t, err)
+	assert.Len(t, diffs, 3)
+}
+
+func TestCompareCoreModule(t *testing.T) {
+	coreModuleFile := filepath.Join(t.TempDir(), "worlds_core.wasm")
+	require.NoError(t, os.WriteFile(coreModuleFile, []byte("\x00asm\x01"), 0666))
+
+	diff, err := compareFile(coreModuleFile, []byte("\x00asm\x01"), false)
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+	diff, err = compareFile(coreModuleFile, []byte("\x00asm\x02\x03"), false)
+	require.NoError(t, err)
+	assert.Equal(t, coreModuleFile+" differs: 5 bytes, 6 bytes when generated\n", diff)
+}
+
+func TestSourceHash(t *testing.T) {
+	outDir := t.TempDir()
+	require.NoError(t, GenerateFromFileWithOptions("testdata/worlds.wit", outDir, GenerateOptions{Worlds: []string{"drawing"}, SplitFiles: true}))
+
+	hash, err := SourceHash("testdata/worlds.wit")
+	require.NoError(t, err)
+	assert.Regexp(t, "^sha256:[0-9a-f]{64}$", hash)
+	for _, name := range []string{"instance.go", "types.go", "shapes.go"} {
+		recorded, err := ReadSourceHash(filepath.Join(outDir, name))
+		require.NoError(t, err)
+		assert.Equal(t, hash, recorded, name)
+	}
+
+	_, err = ReadSourceHash("testdata/worlds.wit")
+	assert.ErrorContains(t, err, "no source hash")
+}
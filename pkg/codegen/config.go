@@ -0,0 +1,115 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileNames are the names of the configuration files looked up by FindConfig, in order.
+var ConfigFileNames = []string{"witigo.yaml", "witigo.yml", "witigo.json"}
+
+// Config configures the generation of bindings, as read from a `witigo.yaml` or `witigo.json`
+// file by LoadConfig. Its fields mirror GenerateOptions and the flags of `witigo generate`.
+type Config struct {
+	// Input is the component, `.wit` file or WIT directory to generate bindings from.
+	Input string `json:"input" yaml:"input"`
+	// Output is the directory to generate bindings into.
+	Output         string            `json:"output" yaml:"output"`
+	Worlds         []string          `json:"worlds,omitempty" yaml:"worlds,omitempty"`
+	Package        string            `json:"package,omitempty" yaml:"package,omitempty"`
+	ImportPath     string            `json:"import-path,omitempty" yaml:"import-path,omitempty"`
+	TypesPackage   string            `json:"types-package,omitempty" yaml:"types-package,omitempty"`
+	Split          bool              `json:"split,omitempty" yaml:"split,omitempty"`
+	Wit            bool              `json:"wit,omitempty" yaml:"wit,omitempty"`
+	Idiomatic      bool              `json:"idiomatic,omitempty" yaml:"idiomatic,omitempty"`
+	SealedVariants bool              `json:"sealed-variants,omitempty" yaml:"sealed-variants,omitempty"`
+	UnwrapResults  bool              `json:"unwrap-results,omitempty" yaml:"unwrap-results,omitempty"`
+	Mock           bool              `json:"mock,omitempty" yaml:"mock,omitempty"`
+	Guest          bool              `json:"guest,omitempty" yaml:"guest,omitempty"`
+	Names          map[string]string `json:"names,omitempty" yaml:"names,omitempty"`
+	Mappings       []TypeMapping     `json:"mappings,omitempty" yaml:"mappings,omitempty"`
+}
+
+// FindConfig returns the path of the configuration file of dir, or an error matching os.ErrNotExist
+// if it has none.
+func FindConfig(dir string) (string, error) {
+	for _, name := range ConfigFileNames {
+		configPath := filepath.Join(dir, name)
+		if _, err := os.Stat(configPath); err == nil {
+			return configPath, nil
+		}
+	}
+	return "", fmt.Errorf("no configuration file in %s: %w", dir, os.ErrNotExist)
+}
+
+// LoadConfig reads a configuration file, as JSON if its extension is `.json` and as YAML otherwise.
+// Unknown fields are errors. Input and Output are relative to the directory of the file.
+func LoadConfig(configPath string) (Config, error) {
+	var config Config
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return config, err
+	}
+	if filepath.Ext(configPath) == ".json" {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&config)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&config)
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return config, fmt.Errorf("error reading configuration file %s: %w", configPath, err)
+	}
+
+	dir := filepath.Dir(configPath)
+	for _, p := range []*string{&config.Input, &config.Output} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+	return config, nil
+}
+
+// Options returns the generation options of the configuration.
+func (c Config) Options() GenerateOptions {
+	return GenerateOptions{
+		Worlds:         c.Worlds,
+		TypesPackage:   c.TypesPackage,
+		ImportPath:     c.ImportPath,
+		PackageName:    c.Package,
+		SplitFiles:     c.Split,
+		Wit:            c.Wit,
+		Names:          c.Names,
+		Idiomatic:      c.Idiomatic,
+		SealedVariants: c.SealedVariants,
+		UnwrapResults:  c.UnwrapResults,
+		Mock:           c.Mock,
+		Guest:          c.Guest,
+		Mappings:       c.Mappings,
+	}
+}
+
+// GenerateFromConfig generates bindings as configured by a configuration file, which must set
+// Input and Output.
+func GenerateFromConfig(configPath string) error {
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	if config.Input == "" || config.Output == "" {
+		return fmt.Errorf("configuration file %s must set input and output", configPath)
+	}
+	return GenerateFromFileWithOptions(config.Input, config.Output, config.Options())
+}
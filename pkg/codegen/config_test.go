@@ -0,0 +1,81 @@
+package codegen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	_, err := FindConfig(dir)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	yamlPath := filepath.Join(dir, "witigo.yaml")
+	require.NoError(t, os.WriteFile(yamlPath, []byte(`input: wit
+output: /tmp/bindings
+worlds: [app]
+package: bindings
+split: true
+names:
+  customer: Client
+mappings:
+  - wit: timestamp
+    go: time.Time
+    imports: [time]
+    to-wit: timestampToWit
+    from-wit: timestampFromWit
+`), 0644))
+	found, err := FindConfig(dir)
+	require.NoError(t, err)
+	assert.Equal(t, yamlPath, found)
+
+	config, err := LoadConfig(yamlPath)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "wit"), config.Input)
+	assert.Equal(t, "/tmp/bindings", config.Output)
+	assert.Equal(t, GenerateOptions{
+		Worlds:      []string{"app"},
+		PackageName: "bindings",
+		SplitFiles:  true,
+		Names:       map[string]string{"customer": "Client"},
+		Mappings: []TypeMapping{{
+			Wit:     "timestamp",
+			Go:      "time.Time",
+			Imports: []string{"time"},
+			ToWit:   "timestampToWit",
+			FromWit: "timestampFromWit",
+		}},
+	}, config.Options())
+
+	jsonPath := filepath.Join(dir, "witigo.json")
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`{"input": "app.wasm", "output": "out", "sealed-variants": true}`), 0644))
+	config, err = LoadConfig(jsonPath)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "app.wasm"), config.Input)
+	assert.Equal(t, filepath.Join(dir, "out"), config.Output)
+	assert.True(t, config.Options().SealedVariants)
+
+	require.NoError(t, os.WriteFile(yamlPath, []byte("input: wit\nworld: app\n"), 0644))
+	_, err = LoadConfig(yamlPath)
+	assert.ErrorContains(t, err, "field world not found")
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`{"split": "yes"}`), 0644))
+	_, err = LoadConfig(jsonPath)
+	assert.ErrorContains(t, err, "error reading configuration file")
+}
+
+func TestGenerateFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	input, err := filepath.Abs("testdata/worlds.wit")
+	require.NoError(t, err)
+	configPath := filepath.Join(dir, "witigo.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte("input: "+input+"\noutput: out\nworlds: [drawing]\npackage: draw\n"), 0644))
+	require.NoError(t, GenerateFromConfig(configPath))
+	assert.FileExists(t, filepath.Join(dir, "out", "draw.go"))
+
+	require.NoError(t, os.WriteFile(configPath, []byte("worlds: [drawing]\n"), 0644))
+	assert.EqualError(t, GenerateFromConfig(configPath), "configuration file "+configPath+" must set input and output")
+}
@@ -0,0 +1,245 @@
+package codegen
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// e2eDir returns a directory inside the module, in which generated bindings are compiled and run
+// along with hand-written files importing them, and its import path. It is removed when the test
+// ends. The go command ignores testdata directories in patterns, so it does not build them otherwise.
+func e2eDir(t *testing.T) (dir string, importPath string) {
+	if testing.Short() {
+		t.Skip("compiles generated code")
+	}
+	dir, err := os.MkdirTemp("testdata", "e2e-")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir, "github.com/rioam2/witigo/pkg/codegen/" + filepath.ToSlash(dir)
+}
+
+// writeFiles writes files by path relative to dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+// runGo runs the go command with args and the environment variables env, and returns its output.
+func runGo(t *testing.T, env []string, args ...string) string {
+	cmd := exec.Command("go", args...)
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "go %s:\n%s", strings.Join(args, " "), out)
+	return string(out)
+}
+
+// roundTripWit is the world of the component written in Go by roundTripGuest, which round trip
+// tests call through host bindings generated in the mode they test.
+const roundTripWit = `package test:roundtrip;
+
+world roundtrip {
+  record point { x: s32, y: s32 }
+  variant shape { circle(f64), rect(tuple<f64, f64>), named(string), dot }
+  enum failure { missing, denied }
+  flags permissions { read, write, exec }
+  enum color { red, navy-blue }
+  record file { name: string, mode: permissions, color: color, icon: option<shape>, size: result<u32, string> }
+  export add: func(a: s32, b: s32) -> s32;
+  export greet: func(name: string) -> string;
+  export area: func(s: shape) -> s64;
+  export scale: func(points: list<point>, factor: f32) -> list<point>;
+  export sum: func(a: u8, b: u8, c: u8, d: u8, e: u8, f: u8, g: u8, h: u8, i: u8, j: u8, k: u8, l: u8, m: u8, n: u8, o: u8, p: u8, q: string) -> u32;
+  export divide: func(a: u32, b: u32) -> result<u32, string>;
+  export resize: func(s: shape, k: f64) -> shape;
+  export largest: func(shapes: list<shape>) -> option<shape>;
+  export get: func(key: string) -> result<u64, string>;
+  export put: func(key: string) -> result<_, failure>;
+  export peek: func(key: string) -> result<u32>;
+  export ping: func(ok: bool) -> result;
+  export stat: func(f: file) -> permissions;
+  export echo: func(s: string, spin: bool) -> string;
+  export pick: func(c: u8) -> u8;
+}
+`
+
+// roundTripGuest returns the program of the component implementing roundTripWit, with the guest
+// bindings of the package at importPath.
+func roundTripGuest(importPath string) string {
+	return `package main
+
+import (
+	"fmt"
+
+	"` + importPath + `"
+)
+
+type impl struct{}
+
+func (impl) Add(a, b int32) int32 { return a + b }
+
+func (impl) Greet(name string) string { return "hello, " + name + "!" }
+
+func (impl) Area(s roundtrip.ShapeVariant) int64 { return int64(area(s)) }
+
+func area(s roundtrip.ShapeVariant) float64 {
+	switch s.Type {
+	case roundtrip.ShapeVariantTypeCircle:
+		return 3 * s.Circle * s.Circle
+	case roundtrip.ShapeVariantTypeRect:
+		return s.Rect.Elem0 * s.Rect.Elem1
+	case roundtrip.ShapeVariantTypeNamed:
+		return float64(len(s.Named))
+	}
+	return 0
+}
+
+func (impl) Scale(points []roundtrip.PointRecord, factor float32) []roundtrip.PointRecord {
+	var scaled []roundtrip.PointRecord
+	for _, p := range points {
+		scaled = append(scaled, roundtrip.PointRecord{X: int32(float32(p.X) * factor), Y: int32(float32(p.Y) * factor)})
+	}
+	return scaled
+}
+
+func (impl) Sum(a, b, c, d, e, f, g, h, i, j, k, l, m, n, o, p uint8, q string) uint32 {
+	return uint32(a) + uint32(b) + uint32(c) + uint32(d) + uint32(e) + uint32(f) + uint32(g) + uint32(h) +
+		uint32(i) + uint32(j) + uint32(k) + uint32(l) + uint32(m) + uint32(n) + uint32(o) + uint32(p) + uint32(len(q))
+}
+
+func (impl) Divide(a, b uint32) roundtrip.Uint32StringResult {
+	if b == 0 {
+		return roundtrip.Uint32StringResult{IsErr: true, Error: fmt.Sprintf("cannot divide %d by zero", a)}
+	}
+	return roundtrip.Uint32StringResult{Ok: a / b}
+}
+
+func (impl) Resize(s roundtrip.ShapeVariant, k float64) roundtrip.ShapeVariant {
+	switch s.Type {
+	case roundtrip.ShapeVariantTypeCircle:
+		s.Circle *= k
+	case roundtrip.ShapeVariantTypeRect:
+		s.Rect.Elem0 *= k
+		s.Rect.Elem1 *= k
+	}
+	return s
+}
+
+func (impl) Largest(shapes []roundtrip.ShapeVariant) roundtrip.Option[roundtrip.ShapeVariant] {
+	var largest roundtrip.Option[roundtrip.ShapeVariant]
+	for _, s := range shapes {
+		if !largest.IsSome || area(s) > area(largest.Value) {
+			largest = roundtrip.Option[roundtrip.ShapeVariant]{IsSome: true, Value: s}
+		}
+	}
+	return largest
+}
+
+func (impl) Get(key string) roundtrip.Uint64StringResult {
+	if key == "" {
+		return roundtrip.Uint64StringResult{IsErr: true, Error: "empty key"}
+	}
+	return roundtrip.Uint64StringResult{Ok: uint64(len(key))}
+}
+
+func (impl) Put(key string) roundtrip.FailureEnumErrResult {
+	if key == "root" {
+		return roundtrip.FailureEnumErrResult{IsErr: true, Error: roundtrip.FailureEnumDenied}
+	}
+	return roundtrip.FailureEnumErrResult{}
+}
+
+func (impl) Peek(key string) roundtrip.Uint32OkResult {
+	return roundtrip.Uint32OkResult{IsErr: key == "", Ok: 7}
+}
+
+func (impl) Ping(ok bool) roundtrip.EmptyResult {
+	return roundtrip.EmptyResult{IsErr: !ok}
+}
+
+func (impl) Stat(f roundtrip.FileRecord) roundtrip.PermissionsFlags { return f.Mode }
+
+func (impl) Echo(s string, spin bool) string {
+	for spin {
+	}
+	return s
+}
+
+func (impl) Pick(c uint8) uint8 { return c * 5 }
+
+func init() { roundtrip.SetExports(impl{}) }
+
+func main() {}
+`
+}
+
+// roundTripModule is the core module of the component of roundTripGuest, built once for the tests.
+var roundTripModule struct {
+	once sync.Once
+	data []byte
+	err  error
+}
+
+// roundTripHost generates host bindings of roundTripWit into the package of dir/host named by
+// each key of bindings, with its options, embedding the core module of the component of
+// roundTripGuest. It returns dir, a new directory like e2eDir, and its import path, so that the
+// test writes the host program in dir/host.
+func roundTripHost(t *testing.T, bindings map[string]GenerateOptions) (dir string, importPath string) {
+	dir, importPath = e2eDir(t)
+	coreModule := roundTripCoreModule(t)
+	witPath := filepath.Join(dir, "roundtrip.wit")
+	writeFiles(t, dir, map[string]string{"roundtrip.wit": roundTripWit})
+	for pkg, opts := range bindings {
+		writeFiles(t, dir, map[string]string{filepath.Join("host", pkg, pkg+"_core.wasm"): string(coreModule)})
+		opts.PackageName = pkg
+		require.NoError(t, GenerateFromFileWithOptions(witPath, filepath.Join(dir, "host", pkg), opts))
+	}
+	return dir, importPath
+}
+
+// roundTripCoreModule returns the core module of the component of roundTripGuest, which is built
+// by the first test calling it.
+func roundTripCoreModule(t *testing.T) []byte {
+	roundTripModule.once.Do(func() {
+		roundTripModule.data, roundTripModule.err = buildRoundTripGuest()
+	})
+	require.NoError(t, roundTripModule.err)
+	return roundTripModule.data
+}
+
+// buildRoundTripGuest builds the component of roundTripGuest and returns its core module.
+func buildRoundTripGuest() ([]byte, error) {
+	dir, err := os.MkdirTemp("testdata", "e2e-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	importPath := "github.com/rioam2/witigo/pkg/codegen/" + filepath.ToSlash(dir) + "/guest/roundtrip"
+	witPath := filepath.Join(dir, "roundtrip.wit")
+	if err := os.WriteFile(witPath, []byte(roundTripWit), 0o644); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(roundTripGuest(importPath)), 0o644); err != nil {
+		return nil, err
+	}
+	err = GenerateFromFileWithOptions(witPath, filepath.Join(dir, "guest", "roundtrip"), GenerateOptions{PackageName: "roundtrip", Guest: true})
+	if err != nil {
+		return nil, err
+	}
+	coreModule := filepath.Join(dir, "roundtrip_core.wasm")
+	cmd := exec.Command("go", "build", "-buildmode=c-shared", "-o", coreModule, "./"+dir)
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("go build %s: %w\n%s", dir, err, out)
+	}
+	return os.ReadFile(coreModule)
+}
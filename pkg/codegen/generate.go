@@ -1,48 +1,455 @@
 package codegen
 
 import (
+	"bufio"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"go/token"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
 
+	"github.com/golang-cz/textcase"
+	"github.com/moznion/gowrtr/generator"
 	"github.com/rioam2/witigo/pkg/wasmtools"
 	"github.com/rioam2/witigo/pkg/wit"
 )
 
-func GenerateFromFile(componentPath string, outDir string) error {
-	componentWitJson, componentName, err := wasmtools.ExtractComponentWitJson(componentPath)
+// GenerateOptions controls which worlds bindings are generated for, and the shape of the generated
+// types.
+type GenerateOptions struct {
+	// Worlds lists the worlds to generate bindings for, by plain name or as `namespace:package/world`.
+	// When empty, bindings are generated for the only world of the root package.
+	Worlds []string
+	// TypesPackage is the import path of the `types` package shared by the bindings of multiple
+	// worlds. When empty, it is derived from ImportPath.
+	TypesPackage string
+	// ImportPath is the import path of the output directory, from which the import path of the
+	// `types` package is derived. When empty, it is derived from the go.mod file enclosing the
+	// output directory, which build systems that do not use go.mod files cannot rely on.
+	ImportPath string
+	// PackageName is the name of the Go package of the bindings of a single world. When empty, it is
+	// derived from the name of the component.
+	PackageName string
+	// SplitFiles splits the bindings of each package into files: `instance.go` for the instance and
+	// the exported functions, `types.go` for the types that belong to no interface, a file per
+	// interface for its types, like `streams.go`, and `mock.go` for the Mock if any.
+	SplitFiles bool
+	// Wit writes the WIT source of the definition next to the bindings, as `<name>.wit`, so the
+	// contract of the component can be reviewed and versioned along with them.
+	Wit bool
+	// Names overrides the Go names of types and exported functions, by WIT name. Types may be given
+	// by plain name, or qualified like `namespace:package/interface#name` to rename a single type.
+	Names map[string]string
+	// Idiomatic names types after their WIT name alone, like `Customer` rather than `CustomerRecord`,
+	// represents `list<u8>` as `[]byte`, and declares `Some` and `None` constructors for options.
+	Idiomatic bool
+	// SealedVariants declares variants as sealed interfaces, like `Shape`, implemented by a struct per
+	// case holding its payload, like `ShapeCircle{Value: 1.5}`, so that callers can use type switches.
+	SealedVariants bool
+	// UnwrapResults returns the payloads of a `result<T, E>` returned by an exported function as
+	// `(T, error)`. An error payload is returned as a *ResultError[E], accessed with errors.As.
+	UnwrapResults bool
+	// Mock declares a Mock implementing Component, whose methods call the functions set in its
+	// fields and record their calls, so that code depending on the component can be tested without
+	// instantiating it.
+	Mock bool
+	// Guest generates bindings for a component written in Go rather than for its host: an Exports
+	// interface that the component implements and sets with SetExports, a function per import, and
+	// the `//go:wasmexport` and `//go:wasmimport` glue lifting and lowering their values, in a
+	// `_wasm.go` file. Guest bindings embed no core module.
+	Guest bool
+	// Mappings represent WIT types with Go types of choice, like `time.Time`, converted to and from
+	// the Go types generated for the WIT types by the functions they set.
+	Mappings []TypeMapping
+}
+
+// GenerateFromFile generates bindings from a component, or from its WIT sources given as a `.wit`
+// file or a directory with a `deps/` subdirectory. Bindings generated from WIT sources embed a
+// `<name>_core.wasm` module that must be extracted from the component once it has been built.
+func GenerateFromFile(inputPath string, outDir string) error {
+	return GenerateFromFileWithOptions(inputPath, outDir, GenerateOptions{})
+}
+
+// GenerateFromFileWithOptions is like GenerateFromFile, for the worlds selected by opts. A single
+// world is generated into outDir. Multiple worlds are generated into a subdirectory each, named
+// after the world, with their type definitions in a shared `types` subdirectory.
+func GenerateFromFileWithOptions(inputPath string, outDir string, opts GenerateOptions) error {
+	b, err := renderFromFile(inputPath, outDir, opts)
 	if err != nil {
 		return err
 	}
+	for _, file := range b.files {
+		if err := writeOutput(file); err != nil {
+			return err
+		}
+	}
+	for _, coreModuleFile := range b.coreModuleFiles {
+		if b.coreModule == nil {
+			fmt.Printf("Core module not written: place the core module of the component at %s\n", coreModuleFile)
+			continue
+		}
+		err = os.WriteFile(coreModuleFile, b.coreModule, 0666)
+		if err != nil {
+			return fmt.Errorf("error writing core module to file %s: %w", coreModuleFile, err)
+		}
+		fmt.Printf("Core module written to %s\n", coreModuleFile)
+	}
+	return nil
+}
 
-	witDefinition, err := wit.NewFromJson(componentWitJson, componentName)
+// LoadDefinition loads the WIT definition of a component, or of WIT sources given as a `.wit`
+// file or a directory with a `deps/` subdirectory.
+func LoadDefinition(inputPath string) (wit.WitDefinition, error) {
+	info, err := os.Stat(inputPath)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	switch {
+	case info.IsDir():
+		return wit.ParseDir(inputPath)
+	case filepath.Ext(inputPath) == ".wit":
+		return wit.ParseFile(inputPath)
 	}
+	componentWitJson, componentName, err := wasmtools.ExtractComponentWitJson(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	return wit.NewFromJson(componentWitJson, componentName)
+}
+
+// bindings are the files of the bindings of a component or of WIT sources, rendered in memory.
+type bindings struct {
+	files []outputFile
+	// coreModuleFiles are the paths of the core modules that the bindings embed, and coreModule the
+	// core module extracted from the component, which is nil for WIT sources.
+	coreModuleFiles []string
+	coreModule      []byte
+}
 
-	codeGen := GenerateFromWorld(witDefinition.Worlds()[0], witDefinition.Name())
-	code, err := codeGen.EnableSyntaxChecking().Gofmt().Generate(0)
+// outputFile is a file of the bindings, rendered in memory.
+type outputFile struct {
+	path string
+	data []byte
+	// kind describes the contents of the file in messages, like `Generated code`.
+	kind string
+}
+
+// renderFromFile renders the bindings of GenerateFromFileWithOptions in memory.
+func renderFromFile(inputPath string, outDir string, opts GenerateOptions) (*bindings, error) {
+	info, err := os.Stat(inputPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	witSource := info.IsDir() || filepath.Ext(inputPath) == ".wit"
 
-	outputFile := fmt.Sprintf("%s/%s.go", outDir, componentName)
-	err = os.WriteFile(outputFile, []byte(code), 0666)
+	witDefinition, err := LoadDefinition(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := sourceHash(inputPath, witSource, witDefinition)
 	if err != nil {
-		return fmt.Errorf("error writing generated code to file %s: %w", outputFile, err)
+		return nil, err
+	}
+	files, coreModuleFiles, err := renderBindings(witDefinition, outDir, opts, hash)
+	if err != nil {
+		return nil, err
+	}
+	b := &bindings{files: files, coreModuleFiles: coreModuleFiles}
+	if witSource || len(coreModuleFiles) == 0 {
+		return b, nil
 	}
-	fmt.Printf("Generated code written to %s\n", outputFile)
 
-	coreModule, err := wasmtools.ExtractComponentCoreModule(componentPath)
+	b.coreModule, err = wasmtools.ExtractComponentCoreModule(inputPath)
 	if err != nil {
-		return fmt.Errorf("error extracting core module: %w", err)
+		return nil, fmt.Errorf("error extracting core module: %w", err)
 	}
+	return b, nil
+}
 
-	outputCoreModuleFile := fmt.Sprintf("%s/%s_core.wasm", outDir, componentName)
-	err = os.WriteFile(outputCoreModuleFile, coreModule, 0666)
+// SourceHash returns the hash recorded in the header of the files of bindings generated from
+// inputPath, like `sha256:<hex>`: the SHA-256 of a component, or of the WIT printed from WIT
+// sources. Bindings are stale when it differs from the hash read by ReadSourceHash.
+func SourceHash(inputPath string) (string, error) {
+	info, err := os.Stat(inputPath)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() && filepath.Ext(inputPath) != ".wit" {
+		return sourceHash(inputPath, false, nil)
+	}
+	witDefinition, err := LoadDefinition(inputPath)
 	if err != nil {
-		return fmt.Errorf("error writing core module to file %s: %w", outputCoreModuleFile, err)
+		return "", err
 	}
-	fmt.Printf("Core module written to %s\n", outputCoreModuleFile)
+	return sourceHash(inputPath, true, witDefinition)
+}
 
+func sourceHash(inputPath string, witSource bool, witDefinition wit.WitDefinition) (string, error) {
+	var source []byte
+	if witSource {
+		source = []byte(witDefinition.Wit())
+	} else {
+		var err error
+		source, err = os.ReadFile(inputPath)
+		if err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(source)), nil
+}
+
+// ReadSourceHash returns the source hash recorded in the header of a generated Go file, or an
+// error if it has none.
+func ReadSourceHash(goFile string) (string, error) {
+	f, err := os.Open(goFile)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if hash, ok := strings.CutPrefix(line, "// "+sourceHashLabel); ok {
+			return hash, nil
+		}
+		if !strings.HasPrefix(line, "//") {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no source hash in the header of %s", goFile)
+}
+
+// renderBindings renders the bindings of the worlds selected by opts, with the hash of their source
+// in the header of each Go file, and returns the paths of the core modules they embed, if any.
+func renderBindings(witDefinition wit.WitDefinition, outDir string, opts GenerateOptions, hash string) ([]outputFile, []string, error) {
+	var out []outputFile
+	if opts.Wit {
+		out = append(out, outputFile{
+			path: filepath.Join(outDir, textcase.SnakeCase(witDefinition.Name())+".wit"),
+			data: []byte(witDefinition.Wit()),
+			kind: "WIT source",
+		})
+	}
+	if opts.Guest && opts.Mock {
+		return nil, nil, errors.New("guest bindings declare no Mock, as the component implements the exports")
+	}
+	if opts.Guest && opts.UnwrapResults {
+		return nil, nil, errors.New("guest bindings cannot unwrap results, which the component returns as is")
+	}
+	if opts.PackageName != "" {
+		if !token.IsIdentifier(opts.PackageName) || textcase.SnakeCase(opts.PackageName) != opts.PackageName {
+			return nil, nil, fmt.Errorf("package name %q is not a lowercase Go identifier", opts.PackageName)
+		}
+		if len(opts.Worlds) > 1 {
+			return nil, nil, errors.New("a package name can only be set for the bindings of a single world")
+		}
+	}
+
+	if len(opts.Worlds) <= 1 {
+		name := ""
+		if len(opts.Worlds) == 1 {
+			name = opts.Worlds[0]
+		}
+		world, err := witDefinition.World(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		n, err := newNamer([]wit.WitWorldDefinition{world}, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		n.sourceHash = hash
+		packageName := witDefinition.Name()
+		if opts.PackageName != "" {
+			packageName = opts.PackageName
+		}
+		if opts.Guest {
+			files, err := generateGuestFiles(world, packageName, "", opts.SplitFiles, n)
+			if err != nil {
+				return nil, nil, err
+			}
+			out, err = appendFiles(out, files, outDir)
+			return out, nil, err
+		}
+		if opts.SplitFiles {
+			files, err := generateWorldFiles(world, packageName, "", n)
+			if err != nil {
+				return nil, nil, err
+			}
+			if out, err = appendFiles(out, files, outDir); err != nil {
+				return nil, nil, err
+			}
+		} else {
+			codeGen := generateWorld(world, packageName, "", n)
+			if out, err = appendCode(out, codeGen, fmt.Sprintf("%s/%s.go", outDir, packageName)); err != nil {
+				return nil, nil, err
+			}
+		}
+		return out, []string{fmt.Sprintf("%s/%s_core.wasm", outDir, textcase.SnakeCase(packageName))}, nil
+	}
+
+	var worlds []wit.WitWorldDefinition
+	for _, name := range opts.Worlds {
+		world, err := witDefinition.World(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		worlds = append(worlds, world)
+	}
+
+	typesImportPath := opts.TypesPackage
+	if typesImportPath == "" {
+		importPath := opts.ImportPath
+		if importPath == "" {
+			var err error
+			importPath, err = importPathOf(outDir)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error deriving the import path of the types package, set it explicitly: %w", err)
+			}
+		}
+		typesImportPath = path.Join(importPath, sharedTypesPackageName)
+	}
+
+	n, err := newNamer(worlds, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	n.sourceHash = hash
+	typesDir := filepath.Join(outDir, sharedTypesPackageName)
+	if opts.SplitFiles {
+		files, err := generateSharedTypeFiles(worlds, n)
+		if err != nil {
+			return nil, nil, err
+		}
+		if out, err = appendFiles(out, files, typesDir); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		typesGen, err := generateSharedTypes(worlds, n)
+		if err != nil {
+			return nil, nil, err
+		}
+		if out, err = appendCode(out, typesGen, filepath.Join(typesDir, sharedTypesPackageName+".go")); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var coreModuleFiles []string
+	for _, world := range worlds {
+		packageName := textcase.SnakeCase(world.Name())
+		worldDir := filepath.Join(outDir, packageName)
+		if opts.Guest {
+			files, err := generateGuestFiles(world, packageName, typesImportPath, opts.SplitFiles, n)
+			if err != nil {
+				return nil, nil, err
+			}
+			if out, err = appendFiles(out, files, worldDir); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if opts.SplitFiles {
+			files, err := generateWorldFiles(world, packageName, typesImportPath, n)
+			if err != nil {
+				return nil, nil, err
+			}
+			if out, err = appendFiles(out, files, worldDir); err != nil {
+				return nil, nil, err
+			}
+		} else {
+			codeGen := generateWorld(world, packageName, typesImportPath, n)
+			if out, err = appendCode(out, codeGen, filepath.Join(worldDir, packageName+".go")); err != nil {
+				return nil, nil, err
+			}
+		}
+		coreModuleFiles = append(coreModuleFiles, filepath.Join(worldDir, packageName+"_core.wasm"))
+	}
+	return out, coreModuleFiles, nil
+}
+
+// appendFiles renders the files of a package in dir and appends them to out.
+func appendFiles(out []outputFile, files []generatedFile, dir string) ([]outputFile, error) {
+	for _, file := range files {
+		var err error
+		if out, err = appendCode(out, file.code, filepath.Join(dir, file.name)); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// formatCode generates and formats the source of codeGen.
+func formatCode(codeGen *generator.Root) (string, error) {
+	return codeGen.EnableSyntaxChecking().Gofmt().Generate(0)
+}
+
+// appendCode renders the source of codeGen as the file at outputPath and appends it to out.
+func appendCode(out []outputFile, codeGen *generator.Root, outputPath string) ([]outputFile, error) {
+	code, err := formatCode(codeGen)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, outputFile{path: outputPath, data: []byte(code), kind: "Generated code"}), nil
+}
+
+// writeOutput writes a file of the bindings.
+func writeOutput(file outputFile) error {
+	if err := os.MkdirAll(filepath.Dir(file.path), 0755); err != nil {
+		return fmt.Errorf("error creating output directory: %w", err)
+	}
+	if err := os.WriteFile(file.path, file.data, 0666); err != nil {
+		return fmt.Errorf("error writing %s to file %s: %w", strings.ToLower(file.kind), file.path, err)
+	}
+	fmt.Printf("%s written to %s\n", file.kind, file.path)
 	return nil
 }
+
+// importPathOf returns the import path of dir, from the module path declared by the go.mod file
+// of dir or of its closest parent.
+func importPathOf(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for moduleDir := dir; ; moduleDir = filepath.Dir(moduleDir) {
+		modulePath, err := modulePathOf(filepath.Join(moduleDir, "go.mod"))
+		if err == nil {
+			rel, err := filepath.Rel(moduleDir, dir)
+			if err != nil {
+				return "", err
+			}
+			return path.Join(modulePath, filepath.ToSlash(rel)), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		if filepath.Dir(moduleDir) == moduleDir {
+			return "", fmt.Errorf("no go.mod file found for %s", dir)
+		}
+	}
+}
+
+func modulePathOf(goModFile string) (string, error) {
+	f, err := os.Open(goModFile)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if modulePath, ok := strings.CutPrefix(line, "module "); ok {
+			return strings.Trim(strings.TrimSpace(modulePath), `"`), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no module directive in %s", goModFile)
+}
@@ -0,0 +1,233 @@
+package codegen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+
+	"github.com/moznion/gowrtr/generator"
+	"github.com/rioam2/witigo/pkg/wit"
+)
+
+const (
+	// instanceFileName is the file holding the instance and the exported functions when output is
+	// split.
+	instanceFileName = "instance.go"
+	// typesFileName is the file holding Option and the types that belong to no interface when output
+	// is split.
+	typesFileName = "types.go"
+)
+
+// generatedFile is a Go source file of the bindings.
+type generatedFile struct {
+	name string
+	code *generator.Root
+}
+
+// fileImport is a package that generated declarations may refer to.
+type fileImport struct {
+	name string
+	path string
+}
+
+// fileImports are the packages that generated declarations refer to, by package name. Standard
+// packages come first.
+var fileImports = []fileImport{
+	{"context", "context"},
+	{"errors", "errors"},
+	{"fmt", "fmt"},
+	{"math", "math"},
+	{"abi", "github.com/rioam2/witigo/pkg/abi"},
+	{"guest", "github.com/rioam2/witigo/pkg/guest"},
+	{"wazero", "github.com/tetratelabs/wazero"},
+	{"api", "github.com/tetratelabs/wazero/api"},
+	{"wasi_snapshot_preview1", "github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"},
+}
+
+// generateWorldFiles generates the bindings of generateWorld split into files: instance.go for the
+// instance and the exported functions, mock.go for the Mock if any, types.go for Option and the
+// types that belong to no interface, and a file per interface for its types.
+func generateWorldFiles(w wit.WitWorldDefinition, packageName string, typesImportPath string, n *namer) ([]generatedFile, error) {
+	instance, err := n.newFile(
+		n.worldHeader(w, packageName, true),
+		generator.NewRoot(n.instanceDecls(w, packageName), n.functionDecls(w)),
+		typesImportPath,
+	)
+	if err != nil {
+		return nil, err
+	}
+	files := []generatedFile{{name: instanceFileName, code: instance}}
+	if n.mock {
+		mock, err := n.newFile(n.worldHeader(w, packageName, false), n.mockDecls(w), typesImportPath)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, generatedFile{name: mockFileName, code: mock})
+	}
+	for _, group := range typeFiles(worldTypedefs(n, w)) {
+		code, err := n.newFile(
+			n.worldHeader(w, packageName, false),
+			n.typeDecls(group.types, typesImportPath, group.name == typesFileName),
+			typesImportPath,
+		)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, generatedFile{name: group.name, code: code})
+	}
+	return files, nil
+}
+
+// generateSharedTypeFiles generates the package of generateSharedTypes split into files like
+// generateWorldFiles: types.go for Option and the types that belong to no interface, and a file
+// per interface for its types.
+func generateSharedTypeFiles(worlds []wit.WitWorldDefinition, n *namer) ([]generatedFile, error) {
+	types, err := sharedTypedefs(worlds, n)
+	if err != nil {
+		return nil, err
+	}
+	var files []generatedFile
+	for _, group := range typeFiles(types) {
+		code, err := n.newFile(n.sharedTypesHeader(), n.typeDecls(group.types, "", group.name == typesFileName), "")
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, generatedFile{name: group.name, code: code})
+	}
+	return files, nil
+}
+
+// typeFile is a file declaring types when output is split.
+type typeFile struct {
+	name  string
+	types []wit.WitType
+}
+
+// typeFiles groups types by file: types.go for the types that belong to no interface, which comes
+// first even if empty, then a file per interface named after it, like `streams.go`. Interfaces
+// whose names clash with each other or with other files are qualified by their package, like
+// `wasi-io-streams.go`, and numbered as a last resort.
+func typeFiles(types []wit.WitType) []typeFile {
+	files := []typeFile{{name: typesFileName}}
+	var keys []string
+	interfaces := map[string]wit.WitInterface{}
+	byInterface := map[string][]wit.WitType{}
+	for _, t := range types {
+		iface := resolveUse(t).OwnerInterface()
+		if iface == nil || iface.Name() == "" {
+			files[0].types = append(files[0].types, t)
+			continue
+		}
+		key := iface.QualifiedName()
+		if _, ok := interfaces[key]; !ok {
+			keys = append(keys, key)
+			interfaces[key] = iface
+		}
+		byInterface[key] = append(byInterface[key], t)
+	}
+
+	count := map[string]int{}
+	for _, key := range keys {
+		count[interfaces[key].Name()]++
+	}
+	taken := map[string]bool{
+		typesFileName:    true,
+		instanceFileName: true,
+		mockFileName:     true,
+		exportsFileName:  true,
+		guestFileName:    true,
+	}
+	for _, key := range keys {
+		iface := interfaces[key]
+		name := iface.Name()
+		if count[name] > 1 || taken[name+".go"] {
+			name = interfacePackageName(iface) + "-" + name
+		}
+		base := name
+		for i := 2; taken[name+".go"]; i++ {
+			name = fmt.Sprintf("%s-%d", base, i)
+		}
+		taken[name+".go"] = true
+		files = append(files, typeFile{name: name + ".go", types: byInterface[key]})
+	}
+	return files
+}
+
+// interfacePackageName returns the name of the package of an interface, without its version and
+// with its namespace separated by a dash, like `wasi-io`.
+func interfacePackageName(iface wit.WitInterface) string {
+	if iface.Package() == nil {
+		return "pkg"
+	}
+	name, _, _ := strings.Cut(iface.Package().Name(), "@")
+	return strings.ReplaceAll(name, ":", "-")
+}
+
+// usedPackages returns the generated code of declarations, and the names of the packages they
+// refer to, which are found by parsing them.
+func usedPackages(decls *generator.Root) (string, map[string]bool, error) {
+	code, err := decls.Generate(0)
+	if err != nil {
+		return "", nil, err
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+code, parser.ParseComments)
+	if err != nil {
+		return "", nil, fmt.Errorf("error parsing generated declarations: %w", err)
+	}
+	used := map[string]bool{}
+	ast.Inspect(f, func(node ast.Node) bool {
+		if selector, ok := node.(*ast.SelectorExpr); ok {
+			if ident, ok := selector.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+	return code, used, nil
+}
+
+// newFile returns a file made of header, declarations and the imports of the packages they refer
+// to.
+func (n *namer) newFile(header *generator.Root, decls *generator.Root, typesImportPath string) (*generator.Root, error) {
+	code, used, err := usedPackages(decls)
+	if err != nil {
+		return nil, err
+	}
+
+	// Standard packages are grouped apart from the others.
+	var std, others []string
+	if strings.Contains(code, "//go:embed") {
+		std = append(std, `_ "embed"`)
+	}
+	for _, imp := range append(fileImports, n.mappingImports()...) {
+		if !used[imp.name] {
+			continue
+		}
+		if strings.Contains(imp.path, ".") {
+			others = append(others, fmt.Sprintf("%q", imp.path))
+		} else {
+			std = append(std, fmt.Sprintf("%q", imp.path))
+		}
+	}
+	if typesImportPath != "" && used[sharedTypesPackageName] {
+		others = append(others, fmt.Sprintf("%q", typesImportPath))
+	}
+
+	root := generator.NewRoot(header)
+	if len(std)+len(others) > 0 {
+		root = root.AddStatements(generator.NewRawStatement("import ("))
+		for _, imp := range std {
+			root = root.AddStatements(generator.NewRawStatement(imp))
+		}
+		if len(std) > 0 && len(others) > 0 {
+			root = root.AddStatements(generator.NewNewline())
+		}
+		for _, imp := range others {
+			root = root.AddStatements(generator.NewRawStatement(imp))
+		}
+		root = root.AddStatements(generator.NewRawStatement(")"))
+	}
+	return root.AddStatements(generator.NewNewline(), decls), nil
+}
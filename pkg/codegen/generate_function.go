@@ -3,87 +3,192 @@ package codegen
 import (
 	"github.com/golang-cz/textcase"
 	"github.com/moznion/gowrtr/generator"
+	witigo "github.com/rioam2/witigo/pkg"
 	"github.com/rioam2/witigo/pkg/wit"
 )
 
+// GenerateFromFunction returns the method calling an exported function, without resolving clashes
+// with the names of other types.
 func GenerateFromFunction(w wit.WitFunction, receiver *generator.FuncReceiver) *generator.Func {
+	return (&namer{}).function(w, receiver)
+}
+
+// GenerateSignatureFromFunction returns the signature of the method calling an exported function,
+// without resolving clashes with the names of other types.
+func GenerateSignatureFromFunction(w wit.WitFunction) *generator.FuncSignature {
+	return (&namer{}).signature(w)
+}
+
+func (n *namer) function(w wit.WitFunction, receiver *generator.FuncReceiver) *generator.Func {
 	var parameterList string = ""
 	for idx, param := range w.Params() {
 		if idx > 0 {
 			parameterList += ", "
 		}
-		parameterList += textcase.CamelCase(param.Name())
+		if n.sealedVariants && param.Type().Kind() == witigo.AbiTypeVariant && !n.isMapped(param.Type()) {
+			// Variant interfaces are passed by pointer, so that the ABI sees the interface of nil values.
+			parameterList += "&"
+		}
+		parameterList += paramName(param.Name())
+	}
+	okType, errType, unwrap := n.unwrappedResult(w)
+	resultType := w.Returns()
+	if unwrap {
+		resultType = okType
+	}
+	// Results are named so that the deferred trace observes the values actually returned.
+	signature := n.signature(w).ReturnTypes()
+	if resultType != nil {
+		signature = signature.AddReturnTypeStatements(
+			generator.NewFuncReturnType(n.typeName(resultType), "result"),
+		)
+	}
+	signature = signature.AddReturnTypeStatements(generator.NewFuncReturnType("error", "err"))
+	fn := generator.NewFunc(receiver, signature)
+
+	// returnError returns the statement returning an error, along with the zero result if any.
+	returnError := func(format string, args ...any) *generator.RawStatement {
+		if resultType == nil {
+			return generator.NewRawStatementf("  return "+format, args...)
+		}
+		return generator.NewRawStatementf("  return result, "+format, args...)
+	}
+
+	traceArgs := ""
+	if parameterList != "" {
+		traceArgs = ", " + parameterList
 	}
-	fn := generator.NewFunc(
-		receiver,
-		GenerateSignatureFromFunction(w),
+	fn = fn.AddStatements(
+		generator.NewRawStatementf("done := abi.TraceCall(i.abiOpts, \"%s\"%s)", textcase.KebabCase(w.Name()), traceArgs),
 	)
+	if resultType == nil {
+		fn = fn.AddStatements(generator.NewRawStatement("defer func() { done(nil, err) }()"))
+	} else {
+		fn = fn.AddStatements(generator.NewRawStatement("defer func() { done(result, err) }()"))
+	}
 	fn = fn.AddStatements(generator.NewRawStatementf("var params []uint64"))
+	ret := "ret"
 	if w.Returns() == nil {
+		ret = "_"
+	}
+	fn = fn.AddStatements(
+		generator.NewRawStatementf("params, freeParams, err := abi.WriteParameters(i.abiOpts, %s)", parameterList),
+		generator.NewRawStatementf("if err != nil {"),
+		returnError("fmt.Errorf(\"failed to write parameters: %%w\", err)"),
+		generator.NewRawStatementf("}"),
+		generator.NewRawStatementf("defer freeParams()"),
+		generator.NewRawStatementf("%s, postReturn, err := abi.Call(i.abiOpts, \"%s\", params...)", ret, textcase.KebabCase(w.Name())),
+		generator.NewRawStatementf("if err != nil {"),
+		returnError("fmt.Errorf(\"failed to call %s: %%w\", err)", textcase.KebabCase(w.Name())),
+		generator.NewRawStatementf("}"),
+		generator.NewRawStatementf("defer postReturn()"),
+	)
+	switch {
+	case unwrap && okType == nil && errType == nil:
+		// A result without payloads is returned directly as its discriminant.
 		fn = fn.AddStatements(
-			generator.NewRawStatementf("params, freeParams, err := abi.WriteParameters(i.abiOpts, %s)", parameterList),
-			generator.NewRawStatementf("if err != nil {"),
-			generator.NewRawStatementf("  return fmt.Errorf(\"failed to write parameters: %%w\", err)"),
-			generator.NewRawStatementf("}"),
-			generator.NewRawStatementf("defer freeParams()"),
-			generator.NewRawStatementf("_, postReturn, err := abi.Call(i.abiOpts, \"%s\", params...)", textcase.KebabCase(w.Name())),
+			generator.NewRawStatement("if ret != 0 {"),
+			generator.NewRawStatement("  return &ResultError[struct{}]{}"),
+			generator.NewRawStatement("}"),
+			generator.NewRawStatement("return nil"),
+		)
+	case unwrap:
+		fn = fn.AddStatements(
+			generator.NewRawStatementf("var wrapped abi.Result[%s, %s]", n.typeName(okType), n.typeName(errType)),
+			generator.NewRawStatementf("err = abi.Read(i.abiOpts, ret, &wrapped)"),
 			generator.NewRawStatementf("if err != nil {"),
-			generator.NewRawStatementf("  return fmt.Errorf(\"failed to call %s: %%w\", err)", textcase.KebabCase(w.Name())),
+			returnError("fmt.Errorf(\"failed to read result: %%w\", err)"),
 			generator.NewRawStatementf("}"),
+			generator.NewRawStatement("if wrapped.IsErr {"),
+			returnError("&ResultError[%s]{Value: wrapped.Err}", n.typeName(errType)),
+			generator.NewRawStatement("}"),
 		)
-	} else {
+		if okType == nil {
+			fn = fn.AddStatements(generator.NewRawStatement("return nil"))
+		} else {
+			fn = fn.AddStatements(generator.NewRawStatement("return wrapped.Ok, nil"))
+		}
+	case resultType == nil:
+		fn = fn.AddStatements(generator.NewRawStatement("return nil"))
+	case resultType.Kind().IsPrimitive() && n.isMapped(resultType):
+		// Mapped primitive types are converted from their flat value.
+		m, _ := n.mapping(resultType)
 		fn = fn.AddStatements(
-			generator.NewRawStatementf("var result %s", GenerateTypenameFromType(w.Returns())),
-			generator.NewRawStatementf("params, freeParams, err := abi.WriteParameters(i.abiOpts, %s)", parameterList),
+			generator.NewRawStatementf("result, err = %s(%s(ret))", m.FromWit, n.declName(resultType)),
 			generator.NewRawStatementf("if err != nil {"),
-			generator.NewRawStatementf("  return result, fmt.Errorf(\"failed to write parameters: %%w\", err)"),
+			generator.NewRawStatementf("  return result, fmt.Errorf(\"failed to read result: %%w\", err)"),
 			generator.NewRawStatementf("}"),
-			generator.NewRawStatementf("defer freeParams()"),
-			generator.NewRawStatementf("ret, postReturn, err := abi.Call(i.abiOpts, \"%s\", params...)", textcase.KebabCase(w.Name())),
+			generator.NewRawStatement("return result, nil"),
+		)
+	case resultType.Kind() == witigo.AbiTypeEnum:
+		// Enums are returned directly as their discriminant, which must be one of the cases. Like
+		// abi.ReadEnum, invalid discriminants leave the zero value.
+		fn = fn.AddStatements(
+			generator.NewRawStatementf("if !%s(ret).IsValid() {", n.typeName(resultType)),
+			generator.NewRawStatementf(
+				"  return result, fmt.Errorf(\"failed to read result: %%w\", &abi.DiscriminantError{Kind: \"enum\", Value: ret, Cases: %d})",
+				len(resultType.SubTypes()),
+			),
+			generator.NewRawStatement("}"),
+			generator.NewRawStatementf("result = %s(ret)", n.typeName(resultType)),
+			generator.NewRawStatement("return result, nil"),
+		)
+	case resultType.Kind() == witigo.AbiTypeFlags:
+		// Flags are returned directly as their bits, like primitive types.
+		fn = fn.AddStatements(
+			generator.NewRawStatementf("result = %s(ret)", n.typeName(resultType)),
+			generator.NewRawStatement("return result, nil"),
+		)
+	case resultType.Kind().IsPrimitive():
+		// Special case: primitive types are returned directly as flat values.
+		fn = fn.AddStatements(
+			generator.NewRawStatementf("result = %s(ret)", n.typeName(resultType)),
+			generator.NewRawStatement("return result, nil"),
+		)
+	default:
+		fn = fn.AddStatements(
+			generator.NewRawStatementf("err = abi.Read(i.abiOpts, ret, &result)"),
 			generator.NewRawStatementf("if err != nil {"),
-			generator.NewRawStatementf("  return result, fmt.Errorf(\"failed to call %s: %%w\", err)", textcase.KebabCase(w.Name())),
+			generator.NewRawStatementf("  return result, fmt.Errorf(\"failed to read result: %%w\", err)"),
 			generator.NewRawStatementf("}"),
+			generator.NewRawStatement("return result, nil"),
 		)
 	}
-	fn = fn.AddStatements(
-		generator.NewRawStatementf("defer postReturn()"),
-	)
-	if w.Returns() != nil {
-		// Special case: primitive types are returned directly as flat values.
-		if w.Returns().Kind().IsPrimitive() {
-			fn = fn.AddStatements(
-				generator.NewRawStatementf("result = %s(ret)", GenerateTypenameFromType(w.Returns())),
-			)
-		} else {
-			fn = fn.AddStatements(
-				generator.NewRawStatementf("err = abi.Read(i.abiOpts, ret, &result)"),
-				generator.NewRawStatementf("if err != nil {"),
-				generator.NewRawStatementf("  return result, fmt.Errorf(\"failed to read result: %%w\", err)"),
-				generator.NewRawStatementf("}"),
-			)
-		}
-	}
-	if w.Returns() == nil {
-		fn = fn.AddStatements(generator.NewRawStatement("return nil"))
-	} else {
-		fn = fn.AddStatements(generator.NewRawStatement("return result, nil"))
-	}
 	return fn
 }
 
-func GenerateSignatureFromFunction(w wit.WitFunction) *generator.FuncSignature {
+func (n *namer) signature(w wit.WitFunction) *generator.FuncSignature {
 	parameters := make([]*generator.FuncParameter, len(w.Params()))
 	for idx, param := range w.Params() {
 		parameters[idx] = generator.NewFuncParameter(
-			textcase.CamelCase(param.Name()),
-			GenerateTypenameFromType(param.Type()),
+			paramName(param.Name()),
+			n.typeName(param.Type()),
 		)
 	}
-	signature := generator.NewFuncSignature(textcase.PascalCase(w.Name())).
-		AddParameters(parameters...)
-	if w.Returns() != nil {
-		signature = signature.AddReturnTypes(GenerateTypenameFromType(w.Returns()))
+	return generator.NewFuncSignature(n.methodName(w)).
+		AddParameters(parameters...).
+		AddReturnTypes(n.returnTypes(w)...)
+}
+
+// returnTypes returns the types returned by the method calling an exported function: its result if
+// any, then an error.
+func (n *namer) returnTypes(w wit.WitFunction) []string {
+	resultType := w.Returns()
+	if okType, _, unwrap := n.unwrappedResult(w); unwrap {
+		resultType = okType
+	}
+	if resultType == nil {
+		return []string{"error"}
+	}
+	return []string{n.typeName(resultType), "error"}
+}
+
+// unwrappedResult returns the types of the payloads of the result returned by a function, when it
+// is unwrapped into `(T, error)`. Absent payloads are nil.
+func (n *namer) unwrappedResult(w wit.WitFunction) (okType wit.WitType, errType wit.WitType, unwrap bool) {
+	if !n.unwrapResults || w.Returns() == nil || w.Returns().Kind() != witigo.AbiTypeResult || n.isMapped(w.Returns()) {
+		return nil, nil, false
 	}
-	signature = signature.AddReturnTypes("error")
-	return signature
+	payloads := w.Returns().SubTypes()
+	return payloads[0].Type(), payloads[1].Type(), true
 }
@@ -0,0 +1,421 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/moznion/gowrtr/generator"
+	witigo "github.com/rioam2/witigo/pkg"
+	"github.com/rioam2/witigo/pkg/abi"
+	"github.com/rioam2/witigo/pkg/wit"
+)
+
+const (
+	// exportsFileName is the file holding Exports when guest output is split.
+	exportsFileName = "exports.go"
+	// guestFileName is the file holding the glue of the exports and imports when guest output is
+	// split. Its suffix restricts it to WebAssembly builds.
+	guestFileName = "guest_wasm.go"
+)
+
+// Core WebAssembly types of flat values, as the Go types of the parameters and results of
+// `//go:wasmexport` and `//go:wasmimport` functions.
+const (
+	flatI32 = "uint32"
+	flatI64 = "uint64"
+	flatF32 = "float32"
+	flatF64 = "float64"
+)
+
+// flatTypes returns the core types of the flat values that values of w are passed as, following
+// the flattening of the canonical ABI.
+func flatTypes(w wit.WitType) []string {
+	switch w.Kind() {
+	case witigo.AbiTypeBool, witigo.AbiTypeS8, witigo.AbiTypeU8, witigo.AbiTypeS16, witigo.AbiTypeU16,
+		witigo.AbiTypeS32, witigo.AbiTypeU32, witigo.AbiTypeChar, witigo.AbiTypeEnum:
+		return []string{flatI32}
+	case witigo.AbiTypeS64, witigo.AbiTypeU64:
+		return []string{flatI64}
+	case witigo.AbiTypeF32:
+		return []string{flatF32}
+	case witigo.AbiTypeF64:
+		return []string{flatF64}
+	case witigo.AbiTypeString, witigo.AbiTypeList:
+		return []string{flatI32, flatI32}
+	case witigo.AbiTypeRecord, witigo.AbiTypeTuple:
+		var flat []string
+		for _, field := range w.SubTypes() {
+			flat = append(flat, flatTypes(field.Type())...)
+		}
+		return flat
+	case witigo.AbiTypeVariant, witigo.AbiTypeOption, witigo.AbiTypeResult:
+		var payloads []wit.WitType
+		if w.Kind() == witigo.AbiTypeOption {
+			payloads = []wit.WitType{w.SubType().Type()}
+		} else {
+			for _, c := range w.SubTypes() {
+				payloads = append(payloads, c.Type())
+			}
+		}
+		// The payloads of the cases share the flat values following the discriminant.
+		var joined []string
+		for _, payload := range payloads {
+			if payload == nil {
+				continue
+			}
+			for i, flat := range flatTypes(payload) {
+				if i < len(joined) {
+					joined[i] = joinFlatTypes(joined[i], flat)
+				} else {
+					joined = append(joined, flat)
+				}
+			}
+		}
+		return append([]string{flatI32}, joined...)
+	case witigo.AbiTypeFlags:
+		flat := make([]string, (len(w.SubTypes())+31)/32)
+		for i := range flat {
+			flat[i] = flatI32
+		}
+		return flat
+	default:
+		panic(fmt.Sprintf("flattening %s is not implemented", w.Kind()))
+	}
+}
+
+// joinFlatTypes returns the core type able to hold flat values of both a and b.
+func joinFlatTypes(a, b string) string {
+	switch {
+	case a == b:
+		return a
+	case a == flatI32 && b == flatF32, a == flatF32 && b == flatI32:
+		return flatI32
+	}
+	return flatI64
+}
+
+// checkGuestType returns an error if values of w cannot be passed between the guest and its host.
+func checkGuestType(w wit.WitType) error {
+	if w == nil {
+		return nil
+	}
+	switch w.Kind() {
+	case witigo.AbiTypeHandle, witigo.AbiTypeResource, witigo.AbiTypeOwn, witigo.AbiTypeBorrow,
+		witigo.AbiTypeStream, witigo.AbiTypeFuture, witigo.AbiTypeErrorContext:
+		return fmt.Errorf("%s types are not supported by guest bindings", w.Kind())
+	case witigo.AbiTypeFlags:
+		if len(w.SubTypes()) > 32 {
+			return fmt.Errorf("flags %s has %d flags, more than the 32 supported", w.Name(), len(w.SubTypes()))
+		}
+	case witigo.AbiTypeList, witigo.AbiTypeOption:
+		return checkGuestType(w.SubType().Type())
+	case witigo.AbiTypeRecord, witigo.AbiTypeTuple, witigo.AbiTypeVariant, witigo.AbiTypeResult:
+		for _, ref := range w.SubTypes() {
+			if err := checkGuestType(ref.Type()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkGuest returns an error if guest bindings cannot be generated for w.
+func checkGuest(w wit.WitWorldDefinition) error {
+	for _, f := range append(w.ExportedFunctions(), w.ImportedFunctions()...) {
+		for _, param := range f.Params() {
+			if err := checkGuestType(param.Type()); err != nil {
+				return fmt.Errorf("parameter %s of function %s: %w", param.Name(), f.Name(), err)
+			}
+		}
+		if err := checkGuestType(f.Returns()); err != nil {
+			return fmt.Errorf("result of function %s: %w", f.Name(), err)
+		}
+	}
+	return nil
+}
+
+// generateGuestFiles generates the guest bindings of w: a file holding the types and Exports, which
+// is split like the files of generateWorldFiles when n splits output, and a `_wasm.go` file holding
+// the glue of the exports and imports.
+func generateGuestFiles(w wit.WitWorldDefinition, packageName string, typesImportPath string, split bool, n *namer) ([]generatedFile, error) {
+	if err := checkGuest(w); err != nil {
+		return nil, err
+	}
+	var files []generatedFile
+	if split {
+		exports, err := n.newFile(n.worldHeader(w, packageName, true), n.exportsDecls(w), typesImportPath)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, generatedFile{name: exportsFileName, code: exports})
+		for _, group := range typeFiles(worldTypedefs(n, w)) {
+			code, err := n.newFile(
+				n.worldHeader(w, packageName, false),
+				n.typeDecls(group.types, typesImportPath, group.name == typesFileName),
+				typesImportPath,
+			)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, generatedFile{name: group.name, code: code})
+		}
+	} else {
+		code, err := n.newFile(
+			n.worldHeader(w, packageName, true),
+			generator.NewRoot(n.typeDecls(worldTypedefs(n, w), typesImportPath, true), n.exportsDecls(w)),
+			typesImportPath,
+		)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, generatedFile{name: packageName + ".go", code: code})
+	}
+
+	glue, err := n.newFile(n.worldHeader(w, packageName, false), n.guestDecls(w), typesImportPath)
+	if err != nil {
+		return nil, err
+	}
+	name := guestFileName
+	if !split {
+		name = packageName + "_wasm.go"
+	}
+	return append(files, generatedFile{name: name, code: glue}), nil
+}
+
+// exportsDecls declares Exports, the interface of the functions exported by w, and SetExports.
+func (n *namer) exportsDecls(w wit.WitWorldDefinition) *generator.Root {
+	if len(w.ExportedFunctions()) == 0 {
+		return generator.NewRoot()
+	}
+	var methods []*generator.FuncSignature
+	for _, f := range w.ExportedFunctions() {
+		methods = append(methods, n.guestSignature(f, n.methodName(f)))
+	}
+	return generator.NewRoot(
+		generator.NewNewline(),
+		generator.NewComment(" Exports are the functions exported by the component, which it implements and sets with"),
+		generator.NewComment(" SetExports."),
+		generator.NewInterface("Exports", methods...),
+		generator.NewNewline(),
+		generator.NewRawStatement("var exports Exports"),
+		generator.NewNewline(),
+		generator.NewComment(" SetExports sets the implementation of the functions exported by the component. It must be"),
+		generator.NewComment(" called before the host calls them, like from an init function."),
+		generator.NewFunc(nil,
+			generator.NewFuncSignature("SetExports").AddParameters(generator.NewFuncParameter("e", "Exports")),
+			generator.NewRawStatement("exports = e"),
+		),
+	)
+}
+
+// guestSignature returns the signature of a function of the guest, which returns the result of f
+// as is.
+func (n *namer) guestSignature(f wit.WitFunction, name string) *generator.FuncSignature {
+	signature := generator.NewFuncSignature(name)
+	for _, param := range f.Params() {
+		signature = signature.AddParameters(generator.NewFuncParameter(guestParamName(param.Name()), n.typeName(param.Type())))
+	}
+	if f.Returns() != nil {
+		signature = signature.AddReturnTypes(n.typeName(f.Returns()))
+	}
+	return signature
+}
+
+// abiArg returns the argument passing a value of w to pkg/abi. Variant interfaces are passed by
+// pointer, so that the ABI sees the interface of nil values.
+func (n *namer) abiArg(name string, w wit.WitType) string {
+	if n.sealedVariants && w.Kind() == witigo.AbiTypeVariant && !n.isMapped(w) {
+		return "&" + name
+	}
+	return name
+}
+
+// guestDecls declares the glue of the functions exported and imported by w, which lift and lower
+// their values with pkg/guest.
+func (n *namer) guestDecls(w wit.WitWorldDefinition) *generator.Root {
+	root := generator.NewRoot()
+	if len(n.mappings) > 0 {
+		root = root.AddStatements(
+			generator.NewNewline(),
+			generator.NewRawStatement("func init() {"),
+			generator.NewRawStatement("guest.SetConverters(Converters)"),
+			generator.NewRawStatement("}"),
+		)
+	}
+	for _, f := range w.ExportedFunctions() {
+		root = root.AddStatements(generator.NewNewline(), n.guestExport(f))
+	}
+	for _, f := range w.ImportedFunctions() {
+		root = root.AddStatements(generator.NewNewline(), n.guestImport(f))
+	}
+	return root
+}
+
+// flatParams returns the core types of the flat parameters of f, which are passed in linear memory
+// as a single pointer when there are more than abi.MAX_FLAT_PARAMS.
+func flatParams(f wit.WitFunction) (flat []string, indirect bool) {
+	for _, param := range f.Params() {
+		flat = append(flat, flatTypes(param.Type())...)
+	}
+	if len(flat) > abi.MAX_FLAT_PARAMS {
+		return []string{flatI32}, true
+	}
+	return flat, false
+}
+
+// flatResult reports whether the result of f is returned as a single flat value, rather than in
+// linear memory, and returns its core type.
+func flatResult(f wit.WitFunction) (string, bool) {
+	flat := flatTypes(f.Returns())
+	if len(flat) > abi.MAX_FLAT_RESULTS {
+		return flatI32, false
+	}
+	return flat[0], true
+}
+
+// toFlat returns the expression converting a flat value of core type flatType to uint64, as pkg/abi
+// represents flat values.
+func toFlat(value string, flatType string) string {
+	switch flatType {
+	case flatI64:
+		return value
+	case flatF32:
+		return fmt.Sprintf("uint64(math.Float32bits(%s))", value)
+	case flatF64:
+		return fmt.Sprintf("math.Float64bits(%s)", value)
+	}
+	return fmt.Sprintf("uint64(%s)", value)
+}
+
+// fromFlat returns the expression converting a flat value represented as uint64 to core type
+// flatType.
+func fromFlat(value string, flatType string) string {
+	switch flatType {
+	case flatI64:
+		return value
+	case flatF32:
+		return fmt.Sprintf("math.Float32frombits(uint32(%s))", value)
+	case flatF64:
+		return fmt.Sprintf("math.Float64frombits(%s)", value)
+	}
+	return fmt.Sprintf("%s(%s)", flatType, value)
+}
+
+// guestExport returns the `//go:wasmexport` function of an exported function, which lifts its
+// parameters, calls Exports and lowers its result, along with the post-return function freeing a
+// result returned in linear memory.
+func (n *namer) guestExport(f wit.WitFunction) *generator.Root {
+	name := n.methodName(f)
+	flat, indirect := flatParams(f)
+	signature := generator.NewFuncSignature("wasmexport" + name)
+	var flatArgs, args, results []string
+	for i, flatType := range flat {
+		signature = signature.AddParameters(generator.NewFuncParameter(fmt.Sprintf("p%d", i), flatType))
+		flatArgs = append(flatArgs, toFlat(fmt.Sprintf("p%d", i), flatType))
+	}
+	var body []generator.Statement
+	for _, param := range f.Params() {
+		local := guestParamName(param.Name())
+		body = append(body, generator.NewRawStatementf("var %s %s", local, n.typeName(param.Type())))
+		args = append(args, local)
+		results = append(results, "&"+local)
+	}
+	if indirect {
+		body = append(body, generator.NewRawStatementf("guest.LiftIndirectParams(uint64(p0), %s)", strings.Join(results, ", ")))
+	} else if len(results) > 0 {
+		body = append(body, generator.NewRawStatementf("guest.LiftParams([]uint64{%s}, %s)", strings.Join(flatArgs, ", "), strings.Join(results, ", ")))
+	}
+	call := fmt.Sprintf("exports.%s(%s)", name, strings.Join(args, ", "))
+
+	root := generator.NewRoot(generator.NewComment("go:wasmexport " + f.Name()))
+	if f.Returns() == nil {
+		body = append(body, generator.NewRawStatement(call))
+		return root.AddStatements(generator.NewFunc(nil, signature, body...))
+	}
+	body = append(body, generator.NewRawStatement("result := "+call))
+	flatType, ok := flatResult(f)
+	signature = signature.AddReturnTypes(flatType)
+	if ok {
+		lowered := fmt.Sprintf("guest.LowerResult(%s)", n.abiArg("result", f.Returns()))
+		body = append(body, generator.NewRawStatement("return "+fromFlat(lowered, flatType)))
+		return root.AddStatements(generator.NewFunc(nil, signature, body...))
+	}
+	body = append(body, generator.NewRawStatementf("return uint32(guest.LowerIndirectResult(%q, %s))", f.Name(), n.abiArg("result", f.Returns())))
+	return root.AddStatements(
+		generator.NewFunc(nil, signature, body...),
+		generator.NewNewline(),
+		generator.NewComment("go:wasmexport cabi_post_"+f.Name()),
+		generator.NewFunc(nil,
+			generator.NewFuncSignature("wasmpostreturn"+name).AddParameters(generator.NewFuncParameter("_", flatI32)),
+			generator.NewRawStatementf("guest.PostReturn(%q)", f.Name()),
+		),
+	)
+}
+
+// guestImport returns the `//go:wasmimport` function of an imported function, along with the
+// function of the package calling it, which lowers its parameters and lifts its result.
+func (n *namer) guestImport(f wit.WitFunction) *generator.Root {
+	name := n.methodName(f)
+	flat, indirect := flatParams(f)
+	var importParams []string
+	for i, flatType := range flat {
+		importParams = append(importParams, fmt.Sprintf("p%d %s", i, flatType))
+	}
+	importResult := ""
+
+	var values, callArgs []string
+	for _, param := range f.Params() {
+		values = append(values, n.abiArg(guestParamName(param.Name()), param.Type()))
+	}
+	var body []generator.Statement
+	switch {
+	case indirect:
+		body = append(body,
+			generator.NewRawStatementf("params, freeParams := guest.LowerIndirectParams(%s)", strings.Join(values, ", ")),
+			generator.NewRawStatement("defer freeParams()"),
+		)
+		callArgs = append(callArgs, "uint32(params)")
+	case len(values) > 0:
+		body = append(body,
+			generator.NewRawStatementf("params, freeParams := guest.LowerParams(%s)", strings.Join(values, ", ")),
+			generator.NewRawStatement("defer freeParams()"),
+		)
+		for i, flatType := range flat {
+			callArgs = append(callArgs, fromFlat(fmt.Sprintf("params[%d]", i), flatType))
+		}
+	}
+
+	if f.Returns() == nil {
+		body = append(body, generator.NewRawStatementf("wasmimport%s(%s)", name, strings.Join(callArgs, ", ")))
+	} else {
+		// Results are lifted into the variable returned, including variant interfaces.
+		result := "&result"
+		body = append(body, generator.NewRawStatementf("var result %s", n.typeName(f.Returns())))
+		if flatType, ok := flatResult(f); ok {
+			importResult = " " + flatType
+			body = append(body,
+				generator.NewRawStatementf("ret := wasmimport%s(%s)", name, strings.Join(callArgs, ", ")),
+				generator.NewRawStatementf("guest.LiftResult(%s, %s)", toFlat("ret", flatType), result),
+			)
+		} else {
+			// Results that do not fit a flat value are returned into a return area given as a last
+			// parameter.
+			importParams = append(importParams, fmt.Sprintf("p%d %s", len(flat), flatI32))
+			body = append(body,
+				generator.NewRawStatementf("ret, freeResult := guest.ReturnArea(%s)", result),
+				generator.NewRawStatement("defer freeResult()"),
+				generator.NewRawStatementf("wasmimport%s(%s)", name, strings.Join(append(callArgs, "uint32(ret)"), ", ")),
+				generator.NewRawStatementf("guest.LiftIndirectResult(ret, %s)", result),
+			)
+		}
+		body = append(body, generator.NewRawStatement("return result"))
+	}
+
+	return generator.NewRoot(
+		generator.NewComment("go:wasmimport $root "+f.Name()),
+		generator.NewRawStatementf("func wasmimport%s(%s)%s", name, strings.Join(importParams, ", "), importResult),
+		generator.NewNewline(),
+		docComment(f.Docs()),
+		generator.NewFunc(nil, n.guestSignature(f, name), body...),
+	)
+}
@@ -0,0 +1,129 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/moznion/gowrtr/generator"
+	"github.com/rioam2/witigo/pkg/wit"
+)
+
+// mockFileName is the file holding the Mock when output is split.
+const mockFileName = "mock.go"
+
+// mockMethod is a method of Component implemented by the Mock.
+type mockMethod struct {
+	name string
+	// params are the names and types of the parameters of the method.
+	params  [][2]string
+	returns []string
+}
+
+// mockFuncField returns the name of the field of the Mock holding the function called by a method.
+func mockFuncField(method string) string {
+	return method + "Func"
+}
+
+// mockMethods returns the methods of Component, in the order of its declaration.
+func (n *namer) mockMethods(w wit.WitWorldDefinition) []mockMethod {
+	methods := []mockMethod{
+		{name: "Close", params: [][2]string{{"ctx", contextType}}, returns: []string{"error"}},
+		{name: "SetHooks", params: [][2]string{{"hooks", "abi.Hooks"}}},
+	}
+	for _, f := range w.ExportedFunctions() {
+		var params [][2]string
+		for _, param := range f.Params() {
+			params = append(params, [2]string{paramName(param.Name()), n.typeName(param.Type())})
+		}
+		methods = append(methods, mockMethod{name: n.methodName(f), params: params, returns: n.returnTypes(f)})
+	}
+	return methods
+}
+
+// mockDecls declares the Mock, which implements Component by calling the functions set in its
+// fields, and records the calls of its methods with an embedded abi.MockRecorder. Methods whose
+// function is not set return an error matching abi.ErrNotMocked, except Close and SetHooks, which
+// do nothing.
+func (n *namer) mockDecls(w wit.WitWorldDefinition) *generator.Root {
+	methods := n.mockMethods(w)
+	root := generator.NewRoot(
+		generator.NewNewline(),
+		generator.NewComment(" Mock implements Component without the component, for tests of code depending on it. Its"),
+		generator.NewComment(" methods record their calls, returned by Calls, and call the function set in the field named"),
+		generator.NewComment(" after them, like CloseFunc. Methods whose function is not set return an error matching"),
+		generator.NewComment(" abi.ErrNotMocked, except Close and SetHooks, which do nothing."),
+		generator.NewRawStatement("type Mock struct {"),
+	)
+	for _, m := range methods {
+		root = root.AddStatements(generator.NewRawStatementf("%s %s", mockFuncField(m.name), funcType(m.params, m.returns)))
+	}
+	root = root.AddStatements(
+		generator.NewRawStatement("abi.MockRecorder"),
+		generator.NewRawStatement("}"),
+		generator.NewNewline(),
+		generator.NewRawStatement("var _ Component = &Mock{}"),
+	)
+
+	for _, m := range methods {
+		var args []string
+		var params []*generator.FuncParameter
+		for _, param := range m.params {
+			args = append(args, param[0])
+			params = append(params, generator.NewFuncParameter(param[0], param[1]))
+		}
+		recordArgs := ""
+		if len(args) > 0 {
+			recordArgs = ", " + strings.Join(args, ", ")
+		}
+		field := mockFuncField(m.name)
+		call := fmt.Sprintf("i.%s(%s)", field, strings.Join(args, ", "))
+
+		signature := generator.NewFuncSignature(m.name).AddParameters(params...)
+		notSet := []generator.Statement{generator.NewRawStatement("  return")}
+		switch len(m.returns) {
+		case 0:
+		case 1:
+			signature = signature.AddReturnTypes(m.returns...)
+			notSet = []generator.Statement{generator.NewRawStatementf("  return fmt.Errorf(\"%s is %%w\", abi.ErrNotMocked)", m.name)}
+		default:
+			signature = signature.AddReturnTypeStatements(
+				generator.NewFuncReturnType(m.returns[0], "result"),
+				generator.NewFuncReturnType("error", "err"),
+			)
+			notSet = []generator.Statement{generator.NewRawStatementf("  return result, fmt.Errorf(\"%s is %%w\", abi.ErrNotMocked)", m.name)}
+		}
+		if m.name == "Close" {
+			notSet = []generator.Statement{generator.NewRawStatement("  return nil")}
+		}
+
+		fn := generator.NewFunc(generator.NewFuncReceiver("i", "*Mock"), signature,
+			generator.NewRawStatementf("i.Record(\"%s\"%s)", m.name, recordArgs),
+			generator.NewRawStatementf("if i.%s == nil {", field),
+		)
+		fn = fn.AddStatements(notSet...).AddStatements(generator.NewRawStatement("}"))
+		if len(m.returns) == 0 {
+			fn = fn.AddStatements(generator.NewRawStatement(call))
+		} else {
+			fn = fn.AddStatements(generator.NewRawStatement("return " + call))
+		}
+		root = root.AddStatements(generator.NewNewline(), fn)
+	}
+	return root
+}
+
+// funcType returns the type of a function with params and returns, like
+// `func(a int32, b int32) (int32, error)`.
+func funcType(params [][2]string, returns []string) string {
+	var paramList []string
+	for _, param := range params {
+		paramList = append(paramList, param[0]+" "+param[1])
+	}
+	typ := "func(" + strings.Join(paramList, ", ") + ")"
+	switch len(returns) {
+	case 0:
+		return typ
+	case 1:
+		return typ + " " + returns[0]
+	}
+	return typ + " (" + strings.Join(returns, ", ") + ")"
+}
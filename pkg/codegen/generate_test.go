@@ -0,0 +1,935 @@
+package codegen
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rioam2/witigo/pkg/wit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSelectedWorld(t *testing.T) {
+	outDir := t.TempDir()
+	err := GenerateFromFileWithOptions("testdata/worlds.wit", outDir, GenerateOptions{Worlds: []string{"measuring"}})
+	require.NoError(t, err)
+
+	code, err := os.ReadFile(filepath.Join(outDir, "worlds.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(code), "// World: measuring")
+	assert.Contains(t, string(code), "type PointRecord struct")
+	assert.NotContains(t, string(code), "ColorEnum")
+
+	err = GenerateFromFile("testdata/worlds.wit", outDir)
+	assert.EqualError(t, err, "package test:worlds@1.0.0 defines multiple worlds, select one of: drawing, measuring")
+}
+
+func TestGenerateWit(t *testing.T) {
+	outDir := t.TempDir()
+	err := GenerateFromFileWithOptions("testdata/worlds.wit", outDir, GenerateOptions{Worlds: []string{"drawing"}, Wit: true})
+	require.NoError(t, err)
+
+	def, err := wit.ParseFile(filepath.Join(outDir, "worlds.wit"))
+	require.NoError(t, err)
+	world, err := def.World("drawing")
+	require.NoError(t, err)
+	assert.Len(t, world.ExportedFunctions(), 1)
+}
+
+func TestGenerateDocs(t *testing.T) {
+	outDir := t.TempDir()
+	err := GenerateFromFileWithOptions("testdata/worlds.wit", outDir, GenerateOptions{Worlds: []string{"drawing"}})
+	require.NoError(t, err)
+
+	file, err := parser.ParseFile(token.NewFileSet(), filepath.Join(outDir, "worlds.go"), nil, parser.ParseComments)
+	require.NoError(t, err)
+	docs := map[string]string{}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			docs[decl.Name.Name] = decl.Doc.Text()
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					docs[spec.Name.Name] = decl.Doc.Text()
+					if st, ok := spec.Type.(*ast.StructType); ok {
+						for _, field := range st.Fields.List {
+							docs[spec.Name.Name+"."+field.Names[0].Name] = field.Doc.Text()
+						}
+					}
+				case *ast.ValueSpec:
+					docs[spec.Names[0].Name] = decl.Doc.Text()
+				}
+			}
+		}
+	}
+	assert.Equal(t, "Draws shapes on a canvas.\n", file.Doc.Text())
+	assert.Equal(t, "A point on the canvas.\n", docs["PointRecord"])
+	assert.Equal(t, "Distance from the left edge.\n", docs["PointRecord.X"])
+	assert.Equal(t, "", docs["PointRecord.Y"])
+	assert.Equal(t, "The color of errors.\n", docs["ColorEnumRed"])
+	assert.Equal(t, "Draws a point and returns its id.\n\nPoints outside of the canvas are ignored.\n", docs["Draw"])
+}
+
+func TestGenerateMultipleWorlds(t *testing.T) {
+	outDir := t.TempDir()
+	err := GenerateFromFileWithOptions("testdata/worlds.wit", outDir, GenerateOptions{
+		Worlds:       []string{"drawing", "test:worlds/measuring"},
+		TypesPackage: "example.com/bindings/types",
+	})
+	require.NoError(t, err)
+
+	fset := token.NewFileSet()
+	types, err := parser.ParseFile(fset, filepath.Join(outDir, "types", "types.go"), nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "types", types.Name.Name)
+	assert.NotNil(t, types.Scope.Lookup("PointRecord"))
+	assert.NotNil(t, types.Scope.Lookup("ColorEnum"))
+	assert.NotNil(t, types.Scope.Lookup("ColorEnumGreen"))
+
+	for world, aliases := range map[string][]string{
+		"drawing": {
+			"type PointRecord = types.PointRecord",
+			"type ColorEnum = types.ColorEnum",
+			"const ColorEnumRed = types.ColorEnumRed",
+		},
+		"measuring": {
+			"type PointRecord = types.PointRecord",
+		},
+	} {
+		path := filepath.Join(outDir, world, world+".go")
+		file, err := parser.ParseFile(fset, path, nil, parser.ImportsOnly)
+		require.NoError(t, err)
+		assert.Equal(t, world, file.Name.Name)
+		var imports []string
+		for _, spec := range file.Imports {
+			imports = append(imports, spec.Path.Value)
+		}
+		assert.Contains(t, imports, `"example.com/bindings/types"`)
+
+		code, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(code), "type Option[T any] = types.Option[T]")
+		for _, alias := range aliases {
+			assert.Contains(t, string(code), alias)
+		}
+	}
+}
+
+func TestGenerateSharedTypesQualified(t *testing.T) {
+	def, err := wit.Parse("conflict.wit", `package test:conflict;
+
+interface a {
+  record point { x: s32 }
+}
+
+interface b {
+  record point { x: f64 }
+}
+
+world one {
+  use a.{point};
+  export f: func(p: point);
+}
+
+world two {
+  use b.{point};
+  export g: func(p: point);
+}
+`)
+	require.NoError(t, err)
+	one, err := def.World("one")
+	require.NoError(t, err)
+	two, err := def.World("two")
+	require.NoError(t, err)
+	worlds := []wit.WitWorldDefinition{one, two}
+
+	types, err := GenerateSharedTypes(worlds)
+	require.NoError(t, err)
+	code, err := formatCode(types)
+	require.NoError(t, err)
+	assert.Contains(t, code, "type APointRecord struct")
+	assert.Contains(t, code, "type BPointRecord struct")
+
+	code, err = formatCode(GenerateFromWorldWithSharedTypes(two, "two", "example.com/bindings/types", worlds...))
+	require.NoError(t, err)
+	assert.Contains(t, code, "type BPointRecord = types.BPointRecord")
+	assert.Contains(t, code, "G(p BPointRecord) error")
+}
+
+func TestGenerateSplitFiles(t *testing.T) {
+	outDir := t.TempDir()
+	err := GenerateFromFileWithOptions("testdata/worlds.wit", outDir, GenerateOptions{
+		Worlds:      []string{"drawing"},
+		PackageName: "drawingapi",
+		SplitFiles:  true,
+	})
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(outDir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{"instance.go", "shapes.go", "types.go"}, names)
+
+	fset := token.NewFileSet()
+	for name, decls := range map[string][]string{
+		"instance.go": {"Instance", "New"},
+		"shapes.go":   {"PointRecord", "ColorEnum", "ColorEnumRed", "ParseColorEnum"},
+		"types.go":    {"Option"},
+	} {
+		file, err := parser.ParseFile(fset, filepath.Join(outDir, name), nil, 0)
+		require.NoError(t, err, name)
+		assert.Equal(t, "drawingapi", file.Name.Name)
+		for _, decl := range decls {
+			assert.NotNil(t, file.Scope.Lookup(decl), "%s in %s", decl, name)
+		}
+	}
+
+	err = GenerateFromFileWithOptions("testdata/worlds.wit", outDir, GenerateOptions{Worlds: []string{"drawing"}, PackageName: "Drawing"})
+	assert.EqualError(t, err, `package name "Drawing" is not a lowercase Go identifier`)
+}
+
+func TestGenerateSplitFilesMultipleWorlds(t *testing.T) {
+	outDir := t.TempDir()
+	err := GenerateFromFileWithOptions("testdata/worlds.wit", outDir, GenerateOptions{
+		Worlds:     []string{"drawing", "measuring"},
+		ImportPath: "example.com/bindings",
+		SplitFiles: true,
+	})
+	require.NoError(t, err)
+
+	for _, path := range []string{
+		"types/types.go",
+		"types/shapes.go",
+		"drawing/instance.go",
+		"drawing/types.go",
+		"measuring/shapes.go",
+	} {
+		assert.FileExists(t, filepath.Join(outDir, path))
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), filepath.Join(outDir, "drawing", "shapes.go"), nil, 0)
+	require.NoError(t, err)
+	require.Len(t, file.Imports, 1)
+	assert.Equal(t, `"example.com/bindings/types"`, file.Imports[0].Path.Value)
+	assert.NotNil(t, file.Scope.Lookup("PointRecord"))
+
+	err = GenerateFromFileWithOptions("testdata/worlds.wit", outDir, GenerateOptions{
+		Worlds:      []string{"drawing", "measuring"},
+		ImportPath:  "example.com/bindings",
+		PackageName: "bindings",
+	})
+	assert.EqualError(t, err, "a package name can only be set for the bindings of a single world")
+}
+
+func TestTypeFiles(t *testing.T) {
+	def, err := wit.Parse("files.wit", `package test:files;
+
+interface types {
+  record a { x: s32 }
+}
+
+interface streams {
+  record b { x: s32 }
+}
+
+world app {
+  use types.{a};
+  use streams.{b};
+  record c { x: s32 }
+  export f: func(a: a, b: b, c: c);
+}
+`)
+	require.NoError(t, err)
+	world, err := def.World("app")
+	require.NoError(t, err)
+	n, err := newNamer([]wit.WitWorldDefinition{world}, GenerateOptions{})
+	require.NoError(t, err)
+
+	files := map[string][]string{}
+	var order []string
+	for _, file := range typeFiles(worldTypedefs(n, world)) {
+		order = append(order, file.name)
+		for _, typ := range file.types {
+			files[file.name] = append(files[file.name], typ.Name())
+		}
+	}
+	assert.Equal(t, []string{"types.go", "test-files-types.go", "streams.go"}, order)
+	assert.Equal(t, []string{"c"}, files["types.go"])
+	assert.Equal(t, []string{"a"}, files["test-files-types.go"])
+	assert.Equal(t, []string{"b"}, files["streams.go"])
+}
+
+func TestGenerateMockClash(t *testing.T) {
+	def, err := wit.Parse("clash.wit", `package test:clash;
+
+world app {
+  export calls: func() -> u32;
+  export get: func() -> u32;
+  export get-func: func() -> u32;
+}
+`)
+	require.NoError(t, err)
+	world, err := def.World("")
+	require.NoError(t, err)
+	_, err = newNamer([]wit.WitWorldDefinition{world}, GenerateOptions{Mock: true})
+	assert.EqualError(t, err, "function calls is named Calls, which is declared by the generated code, set a name override for it")
+	_, err = newNamer([]wit.WitWorldDefinition{world}, GenerateOptions{Mock: true, Names: map[string]string{"calls": "Count"}})
+	assert.EqualError(t, err, "function get-func is named like the field of the mock calling function get, set a name override for it")
+	n, err := newNamer([]wit.WitWorldDefinition{world}, GenerateOptions{Mock: true, Names: map[string]string{"calls": "Count", "get-func": "Fetch"}})
+	require.NoError(t, err)
+	code, err := formatCode(generateWorld(world, "app", "", n))
+	require.NoError(t, err)
+	assert.Contains(t, code, "func (i *Mock) Count() (result uint32, err error) {")
+}
+
+func TestGenerateTypeMappings(t *testing.T) {
+	def, err := wit.Parse("mapped.wit", `package test:mapped;
+
+interface clock {
+  record timestamp { seconds: u64, nanos: u32 }
+}
+
+world app {
+  use clock.{timestamp};
+  record request {
+    at: timestamp,
+    headers: list<tuple<string, string>>,
+  }
+  export send: func(req: request, deadline: option<timestamp>) -> list<tuple<string, string>>;
+  export elapsed: func() -> u64;
+}
+`)
+	require.NoError(t, err)
+	world, err := def.World("")
+	require.NoError(t, err)
+	mappings := []TypeMapping{
+		{Wit: "test:mapped/clock#timestamp", Go: "time.Time", Imports: []string{"time"}, ToWit: "timestampToWit", FromWit: "timestampFromWit"},
+		{Wit: "list<tuple<string,string>>", Go: "map[string]string", ToWit: "headersToWit", FromWit: "headersFromWit"},
+		{Wit: "u64", Go: "Duration", ToWit: "durationToWit", FromWit: "durationFromWit"},
+	}
+
+	n, err := newNamer([]wit.WitWorldDefinition{world}, GenerateOptions{Mappings: mappings})
+	require.NoError(t, err)
+	code, err := formatCode(generateWorld(world, "app", "", n))
+	require.NoError(t, err)
+	for _, expected := range []string{
+		"\t\"fmt\"\n\t\"time\"\n",
+		"var Converters = abi.NewConverters(\n\tabi.NewConverter(timestampToWit, timestampFromWit),\n\tabi.NewConverter(headersToWit, headersFromWit),\n",
+		"\t\tConverters:     Converters,\n",
+		"type TimestampRecord struct {\n\tSeconds Duration\n",
+		"\tAt      time.Time\n\tHeaders map[string]string\n",
+		"type StringStringTuple struct",
+		"deadline Option[time.Time],\n\t) (map[string]string, error)",
+		"Elapsed() (Duration, error)",
+		"\tresult, err = durationFromWit(uint64(ret))\n",
+	} {
+		assert.Contains(t, code, expected)
+	}
+
+	for _, tc := range []struct {
+		mapping TypeMapping
+		err     string
+	}{
+		{TypeMapping{Wit: "point", Go: "image.Point", ToWit: "a", FromWit: "b"}, "type mapping point matches no type"},
+		{TypeMapping{Wit: "timestamp", Go: "time.Time"}, `type mapping "timestamp" must set the WIT type, the Go type and both converters`},
+		{TypeMapping{Wit: "timestamp", Go: "time.", ToWit: "a", FromWit: "b"}, `type mapping timestamp maps to "time.", which is not a Go type`},
+		{TypeMapping{Wit: "list<tuple<string, string>>", Go: "Headers", ToWit: "a", FromWit: "b"}, "type list<tuple<string, string>> is mapped twice"},
+		{TypeMapping{Wit: "request", Go: "string", ToWit: "a", FromWit: "b"}, "type mapping request maps to string, which is predeclared or unnamed, map to a named type"},
+		{TypeMapping{Wit: "request", Go: "[]byte", ToWit: "a", FromWit: "b"}, "type mapping request maps to []byte, which is predeclared or unnamed, map to a named type"},
+		{TypeMapping{Wit: "request", Go: "*time.Time", ToWit: "a", FromWit: "b"}, "type mapping request maps to *time.Time, which is predeclared or unnamed, map to a named type"},
+		{TypeMapping{Wit: "request", Go: "time.Time", ToWit: "a", FromWit: "b"}, "types test:mapped/clock#timestamp and request are both mapped to time.Time"},
+	} {
+		_, err := newNamer([]wit.WitWorldDefinition{world}, GenerateOptions{Mappings: append(mappings[:2:2], tc.mapping)})
+		assert.EqualError(t, err, tc.err)
+	}
+}
+
+func TestGenerateGuest(t *testing.T) {
+	inputPath := filepath.Join(t.TempDir(), "calc.wit")
+	require.NoError(t, os.WriteFile(inputPath, []byte(`package test:calc;
+
+world calc {
+  record point { x: s32, y: s32 }
+  import log: func(message: string);
+  import lookup: func(key: string) -> option<string>;
+  export add: func(a: s32, b: s32) -> s32;
+  export greet: func(name: string) -> string;
+  export many: func(a: u8, b: u8, c: u8, d: u8, e: u8, f: u8, g: u8, h: u8, i: u8, j: u8, k: u8, l: u8, m: u8, n: u8, o: u8, p: u8, q: u8) -> point;
+}
+`), 0o644))
+	outDir := t.TempDir()
+	err := GenerateFromFileWithOptions(inputPath, outDir, GenerateOptions{Guest: true})
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(outDir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{"calc.go", "calc_wasm.go"}, names)
+
+	err = GenerateFromFileWithOptions(inputPath, t.TempDir(), GenerateOptions{Guest: true, Mock: true})
+	assert.EqualError(t, err, "guest bindings declare no Mock, as the component implements the exports")
+
+	require.NoError(t, os.WriteFile(inputPath, []byte(`package test:res;
+
+world app {
+  resource counter;
+  export make: func() -> counter;
+}
+`), 0o644))
+	err = GenerateFromFileWithOptions(inputPath, t.TempDir(), GenerateOptions{Guest: true})
+	assert.ErrorContains(t, err, "result of function make")
+}
+
+func TestTypeMappingsRoundTrip(t *testing.T) {
+	dir, importPath := e2eDir(t)
+	writeFiles(t, dir, map[string]string{
+		"app.wit": `package test:mapped;
+
+world app {
+  record timestamp { seconds: u64, nanos: u32 }
+  record request { at: timestamp, headers: list<tuple<string, string>> }
+  export send: func(r: request) -> list<tuple<string, string>>;
+}
+`,
+		"clock.wit": `package test:clock;
+
+world clock {
+  record instant { millis: u64 }
+  export now: func() -> instant;
+}
+`,
+		"app/app_core.wasm":     "\x00asm\x01\x00\x00\x00",
+		"clock/clock_core.wasm": "\x00asm\x01\x00\x00\x00",
+		"clock/convert.go": `package clock
+
+import "time"
+
+func instantToWit(t time.Time) (InstantRecord, error) {
+	return InstantRecord{Millis: uint64(t.UnixMilli())}, nil
+}
+
+func instantFromWit(r InstantRecord) (time.Time, error) {
+	return time.UnixMilli(int64(r.Millis)).UTC(), nil
+}
+`,
+		"app/convert.go": `package app
+
+import (
+	"sort"
+	"time"
+)
+
+func timestampToWit(t time.Time) (TimestampRecord, error) {
+	return TimestampRecord{Seconds: uint64(t.Unix()), Nanos: uint32(t.Nanosecond())}, nil
+}
+
+func timestampFromWit(r TimestampRecord) (time.Time, error) {
+	return time.Unix(int64(r.Seconds), int64(r.Nanos)).UTC(), nil
+}
+
+func headersToWit(h map[string]string) ([]StringStringTuple, error) {
+	pairs := []StringStringTuple{}
+	for k, v := range h {
+		pairs = append(pairs, StringStringTuple{k, v})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Elem0 < pairs[j].Elem0 })
+	return pairs, nil
+}
+
+func headersFromWit(pairs []StringStringTuple) (map[string]string, error) {
+	h := map[string]string{}
+	for _, pair := range pairs {
+		h[pair.Elem0] = pair.Elem1
+	}
+	return h, nil
+}
+`,
+		"main.go": `package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rioam2/witigo/pkg/abi"
+	"github.com/rioam2/witigo/pkg/abi/abitest"
+	"` + importPath + `/app"
+	"` + importPath + `/clock"
+)
+
+func main() {
+	rt := abitest.NewRuntime()
+	opts := rt.AbiOptions()
+	opts.Converters = app.Converters
+	request := app.RequestRecord{
+		At:      time.Unix(1700000000, 5).UTC(),
+		Headers: map[string]string{"host": "example.com", "accept": "text/plain"},
+	}
+	ptr, free, err := abi.Write(opts, request, nil)
+	if err != nil {
+		panic(err)
+	}
+	var decoded app.RequestRecord
+	if err := abi.Read(opts, ptr, &decoded); err != nil {
+		panic(err)
+	}
+	free()
+	params, freeParams, err := abi.WriteParameters(opts, request.Headers)
+	if err != nil {
+		panic(err)
+	}
+	freeParams()
+	fmt.Println(decoded.At.Unix(), decoded.At.Nanosecond(), decoded.Headers, len(params))
+
+	// The clock bindings map time.Time to another record in the same program.
+	opts.Converters = clock.Converters
+	params, freeParams, err = abi.WriteParameters(opts, request.At)
+	if err != nil {
+		panic(err)
+	}
+	freeParams()
+	fmt.Println(params)
+	if err := rt.CheckLeaks(); err != nil {
+		panic(err)
+	}
+}
+`,
+	})
+	err := GenerateFromFileWithOptions(filepath.Join(dir, "app.wit"), filepath.Join(dir, "app"), GenerateOptions{
+		PackageName: "app",
+		Mappings: []TypeMapping{
+			{Wit: "timestamp", Go: "time.Time", Imports: []string{"time"}, ToWit: "timestampToWit", FromWit: "timestampFromWit"},
+			{Wit: "list<tuple<string, string>>", Go: "map[string]string", ToWit: "headersToWit", FromWit: "headersFromWit"},
+		},
+	})
+	require.NoError(t, err)
+	err = GenerateFromFileWithOptions(filepath.Join(dir, "clock.wit"), filepath.Join(dir, "clock"), GenerateOptions{
+		PackageName: "clock",
+		Mappings: []TypeMapping{
+			{Wit: "instant", Go: "time.Time", Imports: []string{"time"}, ToWit: "instantToWit", FromWit: "instantFromWit"},
+		},
+	})
+	require.NoError(t, err)
+
+	out := runGo(t, nil, "run", "./"+dir)
+	assert.Equal(t, "1700000000 5 map[accept:text/plain host:example.com] 2\n[1700000000000]\n", out)
+}
+
+func TestGuestRoundTrip(t *testing.T) {
+	dir, importPath := roundTripHost(t, map[string]GenerateOptions{"roundtrip": {}})
+	writeFiles(t, dir, map[string]string{"host/main.go": `package main
+
+import (
+	"context"
+	"fmt"
+
+	"` + importPath + `/host/roundtrip"
+)
+
+func main() {
+	ctx := context.Background()
+	instance, err := roundtrip.New(ctx)
+	if err != nil {
+		panic(err)
+	}
+	defer instance.Close(ctx)
+	// Calls are repeated so that the guest reuses the memory freed by post-returns.
+	for range 100 {
+		sum, err := instance.Add(-2, 5)
+		check(err)
+		greeting, err := instance.Greet("world")
+		check(err)
+		circle, err := instance.Area(roundtrip.ShapeVariant{Type: roundtrip.ShapeVariantTypeCircle, Circle: 2})
+		check(err)
+		named, err := instance.Area(roundtrip.ShapeVariant{Type: roundtrip.ShapeVariantTypeNamed, Named: "abcd"})
+		check(err)
+		scaled, err := instance.Scale([]roundtrip.PointRecord{{X: 1, Y: 2}, {X: -3, Y: 4}}, 2.5)
+		check(err)
+		total, err := instance.Sum(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, "xyz")
+		check(err)
+		quotient, err := instance.Divide(7, 2)
+		check(err)
+		failed, err := instance.Divide(7, 0)
+		check(err)
+		fmt.Println(sum, greeting, circle, named, scaled, total, quotient, failed)
+	}
+}
+
+func check(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+`})
+
+	out := runGo(t, nil, "run", "./"+filepath.Join(dir, "host"))
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	assert.Len(t, lines, 100)
+	assert.Equal(t, "3 hello, world! 12 4 [{2 5} {-7 10}] 139 {false 3 } {true 0 cannot divide 7 by zero}", lines[0])
+	assert.Equal(t, lines[0], lines[99])
+}
+
+func TestSealedVariantsRoundTrip(t *testing.T) {
+	// The component is written with variants as structs, and called with them as sealed interfaces.
+	dir, importPath := roundTripHost(t, map[string]GenerateOptions{"roundtrip": {SealedVariants: true}})
+	writeFiles(t, dir, map[string]string{"host/main.go": `package main
+
+import (
+	"context"
+	"fmt"
+
+	"` + importPath + `/host/roundtrip"
+)
+
+func main() {
+	ctx := context.Background()
+	instance, err := roundtrip.New(ctx)
+	if err != nil {
+		panic(err)
+	}
+	defer instance.Close(ctx)
+	for _, s := range []roundtrip.ShapeVariant{
+		roundtrip.ShapeVariantCircle{Value: 2},
+		roundtrip.ShapeVariantRect{Value: roundtrip.Float64Float64Tuple{Elem0: 1, Elem1: 5}},
+		roundtrip.ShapeVariantNamed{Value: "unit"},
+		roundtrip.ShapeVariantDot{},
+	} {
+		scaled, err := instance.Resize(s, 3)
+		fmt.Printf("%#v %v\n", scaled, err)
+	}
+	largest, err := instance.Largest([]roundtrip.ShapeVariant{roundtrip.ShapeVariantDot{}, roundtrip.ShapeVariantCircle{Value: 1}, roundtrip.ShapeVariantRect{Value: roundtrip.Float64Float64Tuple{Elem0: 2, Elem1: 2}}})
+	fmt.Printf("%#v %v\n", largest.Value, err)
+	none, err := instance.Largest(nil)
+	fmt.Println(none.IsSome, none.Value == nil, err)
+}
+`})
+
+	out := runGo(t, nil, "run", "./"+filepath.Join(dir, "host"))
+	assert.Equal(t, `roundtrip.ShapeVariantCircle{Value:6} <nil>
+roundtrip.ShapeVariantRect{Value:roundtrip.Float64Float64Tuple{Elem0:3, Elem1:15}} <nil>
+roundtrip.ShapeVariantNamed{Value:"unit"} <nil>
+roundtrip.ShapeVariantDot{} <nil>
+roundtrip.ShapeVariantRect{Value:roundtrip.Float64Float64Tuple{Elem0:2, Elem1:2}} <nil>
+false true <nil>
+`, out)
+}
+
+func TestUnwrapResultsRoundTrip(t *testing.T) {
+	dir, importPath := roundTripHost(t, map[string]GenerateOptions{"roundtrip": {UnwrapResults: true}})
+	writeFiles(t, dir, map[string]string{"host/main.go": `package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"` + importPath + `/host/roundtrip"
+)
+
+func main() {
+	ctx := context.Background()
+	instance, err := roundtrip.New(ctx)
+	if err != nil {
+		panic(err)
+	}
+	defer instance.Close(ctx)
+	n, err := instance.Get("key")
+	fmt.Println(n, err)
+	_, err = instance.Get("")
+	var getErr *roundtrip.ResultError[string]
+	fmt.Println(errors.As(err, &getErr), getErr.Value)
+	fmt.Println(instance.Put("key"))
+	err = instance.Put("root")
+	var putErr *roundtrip.ResultError[roundtrip.FailureEnum]
+	fmt.Println(errors.As(err, &putErr), putErr.Value == roundtrip.FailureEnumDenied)
+	fmt.Println(instance.Peek("key"))
+	_, err = instance.Peek("")
+	var peekErr *roundtrip.ResultError[struct{}]
+	fmt.Println(errors.As(err, &peekErr))
+	fmt.Println(instance.Ping(true))
+	var pingErr *roundtrip.ResultError[struct{}]
+	fmt.Println(errors.As(instance.Ping(false), &pingErr))
+}
+`})
+
+	out := runGo(t, nil, "run", "./"+filepath.Join(dir, "host"))
+	assert.Equal(t, "3 <nil>\ntrue empty key\n<nil>\ntrue true\n7 <nil>\ntrue\n<nil>\ntrue\n", out)
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	dir, importPath := roundTripHost(t, map[string]GenerateOptions{"app": {}, "sealed": {SealedVariants: true}})
+	writeFiles(t, dir, map[string]string{"host/main.go": `package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/rioam2/witigo/pkg/abi"
+	"` + importPath + `/host/app"
+	"` + importPath + `/host/sealed"
+)
+
+// roundTrip prints the JSON form of value, and whether it decodes to value again.
+func roundTrip[T any](value T) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+	var decoded T
+	err = json.Unmarshal(data, &decoded)
+	fmt.Println(string(data), err, reflect.DeepEqual(value, decoded))
+}
+
+func main() {
+	roundTrip(app.FileRecord{
+		Name:  "a.txt",
+		Mode:  app.PermissionsFlagsRead | app.PermissionsFlagsExec,
+		Color: app.ColorEnumNavyBlue,
+		Icon:  app.Option[app.ShapeVariant]{IsSome: true, Value: app.ShapeVariant{Type: app.ShapeVariantTypeCircle, Circle: 1.5}},
+		Size:  app.Uint32StringResult{IsErr: true, Error: "unknown"},
+	})
+	roundTrip(app.FileRecord{Icon: app.Option[app.ShapeVariant]{IsSome: true, Value: app.ShapeVariant{Type: app.ShapeVariantTypeDot}}, Size: app.Uint32StringResult{Ok: 3}})
+	roundTrip(sealed.FileRecord{
+		Mode: sealed.PermissionsFlagsWrite,
+		Icon: sealed.Option[sealed.ShapeVariant]{IsSome: true, Value: sealed.ShapeVariantCircle{Value: 2}},
+	})
+	// encoding/json cannot decode into an interface, only into the Option holding it.
+	var shape sealed.ShapeVariant
+	err := abi.UnmarshalSealedVariantJSON([]byte(` + "`{\"circle\":3}`" + `), &shape)
+	fmt.Printf("%#v %v\n", shape, err)
+
+	var color app.ColorEnum
+	fmt.Println(json.Unmarshal([]byte(` + "`\"purple\"`" + `), &color) != nil)
+	var mode app.PermissionsFlags
+	fmt.Println(json.Unmarshal([]byte(` + "`[\"read\", \"delete\"]`" + `), &mode) != nil)
+
+	// Values decoded from JSON are passed to the component like any other.
+	var file app.FileRecord
+	if err := json.Unmarshal([]byte(` + "`{\"Mode\":[\"write\",\"exec\"],\"Icon\":null,\"Size\":{\"ok\":1}}`" + `), &file); err != nil {
+		panic(err)
+	}
+	ctx := context.Background()
+	instance, err := app.New(ctx)
+	if err != nil {
+		panic(err)
+	}
+	defer instance.Close(ctx)
+	stat, err := instance.Stat(file)
+	data, _ := json.Marshal(stat)
+	fmt.Println(string(data), err)
+}
+`})
+
+	out := runGo(t, nil, "run", "./"+filepath.Join(dir, "host"))
+	assert.Equal(t, `{"Name":"a.txt","Mode":["read","exec"],"Color":"navy-blue","Icon":{"circle":1.5},"Size":{"err":"unknown"}} <nil> true
+{"Name":"","Mode":[],"Color":"red","Icon":"dot","Size":{"ok":3}} <nil> true
+{"Name":"","Mode":["write"],"Color":"red","Icon":{"circle":2},"Size":{"ok":0}} <nil> true
+sealed.ShapeVariantCircle{Value:3} <nil>
+true
+true
+["write","exec"] <nil>
+`, out)
+}
+
+func TestEnumHelpers(t *testing.T) {
+	dir, importPath := e2eDir(t)
+	writeFiles(t, dir, map[string]string{
+		"enums.wit": `package test:enums;
+
+interface palette {
+  enum color { red, navy-blue }
+}
+
+world app {
+  use palette.{color};
+  export pick: func(c: color) -> color;
+}
+
+world other {
+  use palette.{color};
+  export mix: func(a: color, b: color) -> color;
+}
+`,
+		"bindings/app/app_core.wasm":     "\x00asm\x01\x00\x00\x00",
+		"bindings/other/other_core.wasm": "\x00asm\x01\x00\x00\x00",
+		"main.go": `package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rioam2/witigo/pkg/abi"
+	"` + importPath + `/bindings/app"
+	"` + importPath + `/bindings/types"
+)
+
+func main() {
+	// The helpers are declared by the bindings of each world, on the color of the shared types.
+	fmt.Println(app.ColorEnumNavyBlue, app.ColorEnumValues(), app.ColorEnum(2))
+	color, err := app.ParseColorEnum("navy-blue")
+	fmt.Println(color == types.ColorEnumNavyBlue, err)
+	_, err = app.ParseColorEnum("purple")
+	fmt.Println(err)
+	fmt.Println(types.ColorEnumRed.IsValid(), types.ColorEnum(2).IsValid())
+	_, err = types.ColorEnum(2).MarshalText()
+	fmt.Println(errors.Is(err, abi.ErrInvalidDiscriminant))
+	fmt.Println(len(types.ColorEnumValues()))
+}
+`,
+	})
+	err := GenerateFromFileWithOptions(filepath.Join(dir, "enums.wit"), filepath.Join(dir, "bindings"), GenerateOptions{
+		Worlds:     []string{"app", "other"},
+		ImportPath: importPath + "/bindings",
+	})
+	require.NoError(t, err)
+
+	out := runGo(t, nil, "run", "./"+dir)
+	assert.Equal(t, "navy-blue [red navy-blue] ColorEnum(2)\ntrue <nil>\nunknown case \"purple\" of types.ColorEnum\ntrue false\ntrue\n2\n", out)
+}
+
+func TestMock(t *testing.T) {
+	dir, importPath := roundTripHost(t, map[string]GenerateOptions{"roundtrip": {Mock: true, UnwrapResults: true}})
+	writeFiles(t, dir, map[string]string{"host/main.go": `package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rioam2/witigo/pkg/abi"
+	"` + importPath + `/host/roundtrip"
+)
+
+// double is the code under test, which depends on the component through its interface.
+func double(c roundtrip.Component, n int32) (int32, error) {
+	return c.Add(n, n)
+}
+
+func main() {
+	mock := &roundtrip.Mock{
+		AddFunc: func(a, b int32) (int32, error) { return a + b, nil },
+		DivideFunc: func(a, b uint32) (uint32, error) {
+			if b == 0 {
+				return 0, &roundtrip.ResultError[string]{Value: "cannot divide by zero"}
+			}
+			return a / b, nil
+		},
+	}
+	fmt.Println(double(mock, 21))
+	fmt.Println(mock.Divide(7, 2))
+	_, err := mock.Divide(7, 0)
+	fmt.Println(err)
+	err = mock.Ping(true)
+	fmt.Println(errors.Is(err, abi.ErrNotMocked), err)
+	fmt.Println(mock.Close(context.Background()))
+	fmt.Println(len(mock.Calls()), mock.Calls("Add"))
+
+	// The code under test runs the same against the component.
+	ctx := context.Background()
+	instance, err := roundtrip.New(ctx)
+	if err != nil {
+		panic(err)
+	}
+	defer instance.Close(ctx)
+	fmt.Println(double(instance, 21))
+}
+`})
+
+	out := runGo(t, nil, "run", "./"+filepath.Join(dir, "host"))
+	assert.Equal(t, "42 <nil>\n3 <nil>\ncannot divide by zero\ntrue Ping is not mocked\n<nil>\n5 [{Add [21 21]}]\n42 <nil>\n", out)
+}
+
+func TestBudgetExceededRecyclesInstance(t *testing.T) {
+	dir, importPath := roundTripHost(t, map[string]GenerateOptions{"roundtrip": {}})
+	writeFiles(t, dir, map[string]string{"host/main.go": `package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rioam2/witigo/pkg/abi"
+	"` + importPath + `/host/roundtrip"
+)
+
+func main() {
+	ctx := context.Background()
+	instance, err := roundtrip.NewWithLimits(ctx, abi.Limits{Timeout: 500 * time.Millisecond})
+	if err != nil {
+		panic(err)
+	}
+	defer instance.Close(ctx)
+	// The parameters are allocated in the guest, and freed after the call.
+	long := strings.Repeat("x", 1000)
+	for range 3 {
+		_, err := instance.Echo(long, true)
+		fmt.Println(errors.Is(err, abi.ErrBudgetExceeded))
+		s, err := instance.Echo(long+"!", false)
+		fmt.Println(len(s), err)
+	}
+}
+`})
+
+	out := runGo(t, nil, "run", "./"+filepath.Join(dir, "host"))
+	assert.Equal(t, strings.Repeat("true\n1001 <nil>\n", 3), out)
+}
+
+func TestEnumResultOutOfRange(t *testing.T) {
+	dir, importPath := e2eDir(t)
+	// The component returns a u8 out of the range of the enum that the host reads it as.
+	writeFiles(t, dir, map[string]string{
+		"app.wit": `package test:enums;
+
+world app {
+  enum color { red, navy-blue }
+  export pick: func(c: color) -> color;
+}
+`,
+		"host/app/app_core.wasm": string(roundTripCoreModule(t)),
+		"host/main.go": `package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rioam2/witigo/pkg/abi"
+	"` + importPath + `/host/app"
+)
+
+func main() {
+	ctx := context.Background()
+	instance, err := app.New(ctx)
+	if err != nil {
+		panic(err)
+	}
+	defer instance.Close(ctx)
+	color, err := instance.Pick(app.ColorEnumRed)
+	fmt.Println(color, err)
+	color, err = instance.Pick(app.ColorEnumNavyBlue)
+	var discriminantErr *abi.DiscriminantError
+	fmt.Println(color == app.ColorEnumRed, errors.As(err, &discriminantErr))
+}
+`,
+	})
+	err := GenerateFromFileWithOptions(filepath.Join(dir, "app.wit"), filepath.Join(dir, "host", "app"), GenerateOptions{PackageName: "app"})
+	require.NoError(t, err)
+
+	out := runGo(t, nil, "run", "./"+filepath.Join(dir, "host"))
+	assert.Equal(t, "red <nil>\ntrue true\n", out)
+}
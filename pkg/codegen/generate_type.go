@@ -2,6 +2,8 @@ package codegen
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/golang-cz/textcase"
 	"github.com/moznion/gowrtr/generator"
@@ -11,7 +13,28 @@ import (
 
 const emptyStructGolangTypename = "struct{}"
 
+// GenerateTypenameFromType returns the Go name of a type, without resolving clashes with the names
+// of other types.
 func GenerateTypenameFromType(w wit.WitType) string {
+	return (&namer{}).typeName(w)
+}
+
+// GenerateTypedefFromType returns the Go declaration of a type, or nil if it needs none, without
+// resolving clashes with the names of other types.
+func GenerateTypedefFromType(w wit.WitType) *generator.Root {
+	return (&namer{}).typedef(w)
+}
+
+// typeName returns the Go type referring to a type, which is the Go type it is mapped to, if any.
+func (n *namer) typeName(w wit.WitType) string {
+	if m, ok := n.mapping(w); ok {
+		return m.Go
+	}
+	return n.declName(w)
+}
+
+// declName returns the Go name of a type regardless of its mapping, which its declaration has.
+func (n *namer) declName(w wit.WitType) string {
 	if w == nil {
 		return emptyStructGolangTypename
 	}
@@ -24,21 +47,25 @@ func GenerateTypenameFromType(w wit.WitType) string {
 
 	switch kind {
 	case witigo.AbiTypeTuple:
-		return generateTupleTypenameFromType(w)
+		return n.generateTupleTypenameFromType(w)
 	case witigo.AbiTypeOption:
-		return generateOptionTypenameFromType(w)
+		return n.generateOptionTypenameFromType(w)
 	case witigo.AbiTypeList:
-		return generateListTypenameFromType(w)
+		return n.generateListTypenameFromType(w)
 	case witigo.AbiTypeRecord:
-		return generateRecordTypenameFromType(w)
+		return n.generateRecordTypenameFromType(w)
 	case witigo.AbiTypeResult:
-		return generateResultTypenameFromType(w)
+		return n.generateResultTypenameFromType(w)
 	case witigo.AbiTypeEnum:
-		return generateEnumTypenameFromType(w)
+		return n.generateEnumTypenameFromType(w)
 	case witigo.AbiTypeVariant:
-		return generateVariantTypenameFromType(w)
+		return n.generateVariantTypenameFromType(w)
+	case witigo.AbiTypeFlags:
+		return n.namedTypeName(w)
 	case witigo.AbiTypeHandle:
-		return generateHandleTypenameFromType(w)
+		return n.generateHandleTypenameFromType(w)
+	case witigo.AbiTypeResource:
+		return n.namedTypeName(w)
 	default:
 		if w.Name() != "" && w.Name() != "(none)" {
 			return w.Name()
@@ -80,137 +107,326 @@ func generatePrimitiveTypenameFromType(w wit.WitType) string {
 	}
 }
 
-func generateTupleTypenameFromType(w wit.WitType) string {
+func (n *namer) generateTupleTypenameFromType(w wit.WitType) string {
 	subTypes := w.SubTypes()
 	if len(subTypes) == 0 {
 		return "EmptyTuple"
 	}
 	result := ""
 	for _, t := range subTypes {
-		result += "-" + GenerateTypenameFromType(t.Type())
+		result += "-" + n.declName(t.Type())
 	}
 	return textcase.PascalCase(result) + "Tuple"
 }
 
-func generateOptionTypenameFromType(w wit.WitType) string {
+func (n *namer) generateOptionTypenameFromType(w wit.WitType) string {
 	subType := w.SubType()
 	if subType == nil {
 		panic("Option type must have a subtype")
 	}
-	return "Option[" + GenerateTypenameFromType(subType.Type()) + "]"
+	return "Option[" + n.typeName(subType.Type()) + "]"
 }
 
-func generateListTypenameFromType(w wit.WitType) string {
+func (n *namer) generateListTypenameFromType(w wit.WitType) string {
 	subType := w.SubType()
 	if subType == nil {
 		return "[]" + emptyStructGolangTypename
 	}
-	return "[]" + GenerateTypenameFromType(subType.Type())
+	if n.idiomatic && subType.Type().Kind() == witigo.AbiTypeU8 {
+		return "[]byte"
+	}
+	return "[]" + n.typeName(subType.Type())
 }
 
-func generateRecordTypenameFromType(w wit.WitType) string {
-	return textcase.PascalCase(w.Name()) + "Record"
+func (n *namer) generateRecordTypenameFromType(w wit.WitType) string {
+	return n.namedTypeName(w)
 }
 
-func generateResultTypenameFromType(w wit.WitType) string {
+func (n *namer) generateResultTypenameFromType(w wit.WitType) string {
 	subTypes := w.SubTypes()
 	if len(subTypes) != 2 {
 		panic(fmt.Sprintf("Expected 2 subtypes for Result type, got %d", len(subTypes)))
 	}
-	okType := GenerateTypenameFromType(subTypes[0].Type())
-	errType := GenerateTypenameFromType(subTypes[1].Type())
-	return textcase.PascalCase(okType+"-"+errType) + "Result"
+	// Results without payloads are named after the payload they have, if any, like
+	// `StringErrResult` for `result<_, string>`, rather than after the struct{} placeholder.
+	okType, errType := subTypes[0].Type(), subTypes[1].Type()
+	switch {
+	case okType == nil && errType == nil:
+		return "EmptyResult"
+	case okType == nil:
+		return textcase.PascalCase(n.declName(errType)) + "ErrResult"
+	case errType == nil:
+		return textcase.PascalCase(n.declName(okType)) + "OkResult"
+	}
+	return textcase.PascalCase(n.declName(okType)+"-"+n.declName(errType)) + "Result"
 }
 
-func generateEnumTypenameFromType(w wit.WitType) string {
-	return textcase.PascalCase(w.Name()) + "Enum"
+func (n *namer) generateEnumTypenameFromType(w wit.WitType) string {
+	return n.namedTypeName(w)
 }
 
-func generateVariantTypenameFromType(w wit.WitType) string {
-	return textcase.PascalCase(w.Name()) + "Variant"
+func (n *namer) generateVariantTypenameFromType(w wit.WitType) string {
+	return n.namedTypeName(w)
 }
 
-func generateHandleTypenameFromType(w wit.WitType) string {
+func (n *namer) generateHandleTypenameFromType(w wit.WitType) string {
 	subType := w.SubType()
 	if subType == nil {
 		return "Handle"
 	}
-	return textcase.PascalCase(GenerateTypenameFromType(subType.Type()) + "Handle")
+	return n.declName(subType.Type()) + "Handle"
 }
 
-func GenerateTypedefFromType(w wit.WitType) *generator.Root {
+func (n *namer) typedef(w wit.WitType) *generator.Root {
 	switch w.Kind() {
 	case witigo.AbiTypeRecord:
-		return generateRecordTypedefFromType(w)
+		return n.generateRecordTypedefFromType(w)
 	case witigo.AbiTypeResult:
-		return generateResultTypedefFromType(w)
+		return n.generateResultTypedefFromType(w)
 	case witigo.AbiTypeTuple:
-		return generateTupleTypedefFromType(w)
+		return n.generateTupleTypedefFromType(w)
 	case witigo.AbiTypeEnum:
-		return generateEnumTypedefFromType(w)
+		return n.generateEnumTypedefFromType(w)
 	case witigo.AbiTypeVariant:
-		return generateVariantTypedefFromType(w)
+		return n.generateVariantTypedefFromType(w)
+	case witigo.AbiTypeFlags:
+		return n.generateFlagsTypedefFromType(w)
 	case witigo.AbiTypeHandle:
-		return generateHandleTypedefFromType(w)
+		return n.generateHandleTypedefFromType(w)
 	default:
 		// Remaining types are either primitive or do not require a typedef
 		return nil
 	}
 }
 
-func generateRecordTypedefFromType(w wit.WitType) *generator.Root {
-	typeDef := generator.NewStruct(GenerateTypenameFromType(w))
-	for _, field := range w.SubTypes() {
-		typeDef = typeDef.AddField(
-			textcase.PascalCase(field.Name()),
-			GenerateTypenameFromType(field.Type()),
+// typedefNames returns the names of the types, constants and functions declared by
+// GenerateTypedefFromType.
+func (n *namer) typedefNames(w wit.WitType) (typeNames []string, constNames []string, funcNames []string) {
+	switch w.Kind() {
+	case witigo.AbiTypeRecord, witigo.AbiTypeResult, witigo.AbiTypeTuple, witigo.AbiTypeHandle:
+		return []string{n.declName(w)}, nil, nil
+	case witigo.AbiTypeEnum, witigo.AbiTypeFlags:
+		for _, c := range w.SubTypes() {
+			constNames = append(constNames, n.declName(w)+textcase.PascalCase(c.Name()))
+		}
+		if w.Kind() == witigo.AbiTypeEnum {
+			funcNames = []string{"Parse" + n.declName(w), n.declName(w) + "Values"}
+		}
+		return []string{n.declName(w)}, constNames, funcNames
+	case witigo.AbiTypeVariant:
+		if n.sealedVariants {
+			return append([]string{n.declName(w)}, n.variantCaseNames(w)...), nil, nil
+		}
+		enumTypedefName := n.declName(w) + "Type"
+		for _, c := range w.SubTypes() {
+			constNames = append(constNames, enumTypedefName+textcase.PascalCase(c.Name()))
+		}
+		return []string{enumTypedefName, n.declName(w)}, constNames, nil
+	default:
+		return nil, nil, nil
+	}
+}
+
+// subTypeNames returns the names of the fields or cases of a type.
+func subTypeNames(w wit.WitType) []string {
+	var names []string
+	for _, sub := range w.SubTypes() {
+		names = append(names, sub.Name())
+	}
+	return names
+}
+
+// quotedNames returns names as a list of Go string literals, like `"a", "b"`.
+func quotedNames(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = strconv.Quote(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// structField is a field of a struct declared by newStruct.
+type structField struct {
+	name string
+	typ  string
+	docs string
+}
+
+// newStruct declares a struct whose fields may be documented, which generator.Struct does not
+// support.
+func newStruct(name string, docs string, fields []structField) *generator.Root {
+	root := generator.NewRoot(docComment(docs), generator.NewRawStatementf("type %s struct {", name))
+	for _, field := range fields {
+		root = root.AddStatements(
+			docComment(field.docs),
+			generator.NewRawStatementf("%s %s", field.name, field.typ),
 		)
 	}
-	return generator.NewRoot(typeDef)
+	return root.AddStatements(generator.NewRawStatement("}"))
 }
 
-func generateResultTypedefFromType(w wit.WitType) *generator.Root {
-	okType := GenerateTypenameFromType(w.SubTypes()[0].Type())
-	errType := GenerateTypenameFromType(w.SubTypes()[1].Type())
+func (n *namer) generateRecordTypedefFromType(w wit.WitType) *generator.Root {
+	var fields []structField
+	names := fieldNames(subTypeNames(w))
+	for i, field := range w.SubTypes() {
+		fields = append(fields, structField{
+			name: names[i],
+			typ:  n.typeName(field.Type()),
+			docs: field.Docs(),
+		})
+	}
 	return generator.NewRoot(
-		generator.NewStruct(GenerateTypenameFromType(w)).
+		newStruct(n.declName(w), typeDocs(w), fields),
+		n.witKindMethod(w, "KindRecord"),
+	)
+}
+
+func (n *namer) generateResultTypedefFromType(w wit.WitType) *generator.Root {
+	name := n.declName(w)
+	okType := n.typeName(w.SubTypes()[0].Type())
+	errType := n.typeName(w.SubTypes()[1].Type())
+	// Results are laid out like abi.Result, which their fields do not tell in any mode.
+	return generator.NewRoot(
+		docComment(typeDocs(w)),
+		generator.NewStruct(name).
+			AddField("IsErr", "bool").
 			AddField("Ok", okType).
 			AddField("Error", errType),
+		kindMethod(name, "KindResult"),
+		generator.NewNewline(),
+		generator.NewRawStatementf("func (v %s) MarshalJSON() ([]byte, error) {", name),
+		generator.NewRawStatement("return abi.MarshalResultJSON(v.IsErr, v.Ok, v.Error)"),
+		generator.NewRawStatement("}"),
+		generator.NewNewline(),
+		generator.NewRawStatementf("func (v *%s) UnmarshalJSON(data []byte) error {", name),
+		generator.NewRawStatement("return abi.UnmarshalResultJSON(data, &v.IsErr, &v.Ok, &v.Error)"),
+		generator.NewRawStatement("}"),
 	)
 }
 
-func generateTupleTypedefFromType(w wit.WitType) *generator.Root {
+func (n *namer) generateTupleTypedefFromType(w wit.WitType) *generator.Root {
 	subTypes := w.SubTypes()
-	typeDef := generator.NewStruct(GenerateTypenameFromType(w))
+	typeDef := generator.NewStruct(n.declName(w))
 	for i, subType := range subTypes {
 		typeDef = typeDef.AddField(
 			textcase.PascalCase(fmt.Sprintf("Elem%d", i)),
-			GenerateTypenameFromType(subType.Type()),
+			n.typeName(subType.Type()),
 		)
 	}
-	return generator.NewRoot(typeDef)
+	// Tuples are laid out like records, which their names do not tell in any mode.
+	return generator.NewRoot(docComment(typeDocs(w)), typeDef, kindMethod(n.declName(w), "KindRecord"))
 }
 
-func generateEnumTypedefFromType(w wit.WitType) *generator.Root {
-	root := generator.NewRoot()
+func (n *namer) generateEnumTypedefFromType(w wit.WitType) *generator.Root {
+	name := n.declName(w)
+	root := generator.NewRoot(docComment(typeDocs(w)))
 	discriminantType := fmt.Sprintf("uint%d", discriminantSize(len(w.SubTypes())))
-	enumTypedef := generator.NewRawStatementf("type %s %s", GenerateTypenameFromType(w), discriminantType)
+	enumTypedef := generator.NewRawStatementf("type %s %s", name, discriminantType)
 	root = root.AddStatements(enumTypedef)
 	for i, c := range w.SubTypes() {
 		statement := generator.NewRawStatementf(
-			"const %s = %d",
-			GenerateTypenameFromType(w)+textcase.PascalCase(c.Name()),
+			"const %s %s = %d",
+			name+textcase.PascalCase(c.Name()),
+			name,
+			i,
+		)
+		root = root.AddStatements(docComment(c.Docs()), statement)
+	}
+	cases := quotedNames(subTypeNames(w))
+	return root.AddStatements(
+		n.witKindMethod(w, "KindEnum"),
+		generator.NewNewline(),
+		generator.NewComment(" String returns the WIT name of the case."),
+		generator.NewRawStatementf("func (v %s) String() string {", name),
+		generator.NewRawStatementf("return abi.EnumString(v, %s)", cases),
+		generator.NewRawStatement("}"),
+		generator.NewNewline(),
+		generator.NewComment(" IsValid reports whether the value is one of the cases."),
+		generator.NewRawStatementf("func (v %s) IsValid() bool {", name),
+		generator.NewRawStatementf("return v < %d", len(w.SubTypes())),
+		generator.NewRawStatement("}"),
+		generator.NewNewline(),
+		generator.NewRawStatementf("func (v %s) MarshalText() ([]byte, error) {", name),
+		generator.NewRawStatementf("return abi.MarshalEnumText(v, %s)", cases),
+		generator.NewRawStatement("}"),
+		generator.NewNewline(),
+		generator.NewRawStatementf("func (v *%s) UnmarshalText(text []byte) error {", name),
+		generator.NewRawStatementf("return abi.UnmarshalEnumText(text, v, %s)", cases),
+		generator.NewRawStatement("}"),
+		generator.NewNewline(),
+		n.typeFuncs(w),
+	)
+}
+
+// typeFuncs declares the functions of a type. Unlike types and constants, they are declared again
+// rather than aliased by the bindings of each world in shared types mode.
+func (n *namer) typeFuncs(w wit.WitType) *generator.Root {
+	if w.Kind() != witigo.AbiTypeEnum {
+		return generator.NewRoot()
+	}
+	name := n.declName(w)
+	_, constNames, funcNames := n.typedefNames(w)
+	return generator.NewRoot(
+		generator.NewCommentf(" %s returns the case of %s with the given WIT name.", funcNames[0], name),
+		generator.NewRawStatementf("func %s(s string) (%s, error) {", funcNames[0], name),
+		generator.NewRawStatementf("var v %s", name),
+		generator.NewRawStatement("err := v.UnmarshalText([]byte(s))"),
+		generator.NewRawStatement("return v, err"),
+		generator.NewRawStatement("}"),
+		generator.NewNewline(),
+		generator.NewCommentf(" %s returns the cases of %s, in order.", funcNames[1], name),
+		generator.NewRawStatementf("func %s() []%s {", funcNames[1], name),
+		generator.NewRawStatementf("return []%s{%s}", name, strings.Join(constNames, ", ")),
+		generator.NewRawStatement("}"),
+		generator.NewNewline(),
+	)
+}
+
+// generateFlagsTypedefFromType declares flags as an unsigned integer holding flag i in bit i, with
+// a constant per flag.
+func (n *namer) generateFlagsTypedefFromType(w wit.WitType) *generator.Root {
+	name := n.declName(w)
+	flags := w.SubTypes()
+	if len(flags) > 32 {
+		panic(fmt.Sprintf("Flags %s has %d flags, more than the 32 supported", w.Name(), len(flags)))
+	}
+	bits := 8
+	for bits < len(flags) {
+		bits *= 2
+	}
+	root := generator.NewRoot(
+		docComment(typeDocs(w)),
+		generator.NewRawStatementf("type %s uint%d", name, bits),
+	)
+	for i, flag := range flags {
+		statement := generator.NewRawStatementf(
+			"const %s %s = 1 << %d",
+			name+textcase.PascalCase(flag.Name()),
+			name,
 			i,
 		)
-		root = root.AddStatements(statement)
+		root = root.AddStatements(docComment(flag.Docs()), statement)
 	}
-	return root
+	names := quotedNames(subTypeNames(w))
+	return root.AddStatements(
+		generator.NewNewline(),
+		generator.NewRawStatementf("func (v %s) MarshalJSON() ([]byte, error) {", name),
+		generator.NewRawStatementf("return abi.MarshalFlagsJSON(v, %s)", names),
+		generator.NewRawStatement("}"),
+		generator.NewNewline(),
+		generator.NewRawStatementf("func (v *%s) UnmarshalJSON(data []byte) error {", name),
+		generator.NewRawStatementf("return abi.UnmarshalFlagsJSON(data, v, %s)", names),
+		generator.NewRawStatement("}"),
+	)
 }
 
-func generateVariantTypedefFromType(w wit.WitType) *generator.Root {
+func (n *namer) generateVariantTypedefFromType(w wit.WitType) *generator.Root {
+	if n.sealedVariants {
+		return n.generateSealedVariantTypedefFromType(w)
+	}
 	root := generator.NewRoot()
 	discriminantType := fmt.Sprintf("uint%d", discriminantSize(len(w.SubTypes())))
-	enumTypedefName := GenerateTypenameFromType(w) + "Type"
+	enumTypedefName := n.declName(w) + "Type"
 	enumTypedef := generator.NewRawStatementf("type %s %s", enumTypedefName, discriminantType)
 	root = root.AddStatements(enumTypedef)
 	for i, c := range w.SubTypes() {
@@ -219,34 +435,121 @@ func generateVariantTypedefFromType(w wit.WitType) *generator.Root {
 			enumTypedefName+textcase.PascalCase(c.Name()),
 			i,
 		)
-		root = root.AddStatements(statement)
+		root = root.AddStatements(docComment(c.Docs()), statement)
 	}
-	structTypedef := generator.NewStruct(GenerateTypenameFromType(w))
-	structTypedef = structTypedef.AddField(
-		textcase.PascalCase("Type"),
-		enumTypedefName,
-	)
-	for _, field := range w.SubTypes() {
+	fields := []structField{{name: "Type", typ: enumTypedefName}}
+	names := fieldNames(subTypeNames(w), "Type")
+	for i, field := range w.SubTypes() {
 		fieldType := "struct{}"
 		if field.Type() != nil {
-			fieldType = GenerateTypenameFromType(field.Type())
+			fieldType = n.typeName(field.Type())
+		}
+		fields = append(fields, structField{
+			name: names[i],
+			typ:  fieldType,
+			docs: field.Docs(),
+		})
+	}
+
+	cases := quotedNames(subTypeNames(w))
+	return root.AddStatements(
+		newStruct(n.declName(w), typeDocs(w), fields),
+		n.witKindMethod(w, "KindVariant"),
+		generator.NewNewline(),
+		generator.NewRawStatementf("func (v %s) MarshalJSON() ([]byte, error) {", n.declName(w)),
+		generator.NewRawStatementf("return abi.MarshalVariantJSON(v, %s)", cases),
+		generator.NewRawStatement("}"),
+		generator.NewNewline(),
+		generator.NewRawStatementf("func (v *%s) UnmarshalJSON(data []byte) error {", n.declName(w)),
+		generator.NewRawStatementf("return abi.UnmarshalVariantJSON(data, v, %s)", cases),
+		generator.NewRawStatement("}"),
+	)
+}
+
+// variantCaseNames returns the names of the types of the cases of a variant declared as a sealed
+// interface.
+func (n *namer) variantCaseNames(w wit.WitType) []string {
+	var names []string
+	for _, c := range w.SubTypes() {
+		names = append(names, n.declName(w)+textcase.PascalCase(c.Name()))
+	}
+	return names
+}
+
+// generateSealedVariantTypedefFromType declares a variant as a sealed interface, implemented by a
+// struct per case holding its payload, and registers its cases with the ABI.
+func (n *namer) generateSealedVariantTypedefFromType(w wit.WitType) *generator.Root {
+	name := n.declName(w)
+	marker := "is" + name
+	root := generator.NewRoot(
+		docComment(typeDocs(w)),
+		generator.NewRawStatementf("type %s interface {", name),
+		generator.NewRawStatementf("%s()", marker),
+		generator.NewRawStatement("}"),
+		generator.NewNewline(),
+	)
+	caseNames := n.variantCaseNames(w)
+	for i, c := range w.SubTypes() {
+		typedef := generator.NewRoot(
+			docComment(c.Docs()),
+			generator.NewRawStatementf("type %s struct{}", caseNames[i]),
+		)
+		payload, payloadPtr := "nil", "nil"
+		if c.Type() != nil {
+			typedef = newStruct(caseNames[i], c.Docs(), []structField{{name: "Value", typ: n.typeName(c.Type())}})
+			payload, payloadPtr = "v.Value", "&v.Value"
 		}
-		structTypedef = structTypedef.AddField(
-			textcase.PascalCase(field.Name()),
-			fieldType,
+		root = root.AddStatements(
+			typedef,
+			generator.NewNewline(),
+			generator.NewRawStatementf("func (%s) %s() {}", caseNames[i], marker),
+			generator.NewNewline(),
+			generator.NewRawStatementf("func (v %s) MarshalJSON() ([]byte, error) {", caseNames[i]),
+			generator.NewRawStatementf("return abi.MarshalCaseJSON(%q, %s)", c.Name(), payload),
+			generator.NewRawStatement("}"),
+			generator.NewNewline(),
+			generator.NewRawStatementf("func (v *%s) UnmarshalJSON(data []byte) error {", caseNames[i]),
+			generator.NewRawStatementf("return abi.UnmarshalCaseJSON(data, %q, %s)", c.Name(), payloadPtr),
+			generator.NewRawStatement("}"),
+			generator.NewNewline(),
 		)
 	}
+	cases := ""
+	for i, caseName := range caseNames {
+		if i > 0 {
+			cases += ", "
+		}
+		cases += caseName + "{}"
+	}
+	return root.AddStatements(
+		generator.NewRawStatement("func init() {"),
+		generator.NewRawStatementf("abi.RegisterVariant[%s](%s)", name, cases),
+		generator.NewRawStatement("}"),
+	)
+}
 
-	root = root.AddStatements(structTypedef)
-	return root
+// witKindMethod returns the method declaring the ABI kind of a type in idiomatic mode, whose names
+// do not tell it.
+func (n *namer) witKindMethod(w wit.WitType, kind string) *generator.Root {
+	if !n.idiomatic {
+		return generator.NewRoot()
+	}
+	return kindMethod(n.declName(w), kind)
+}
+
+// kindMethod returns the method declaring the ABI kind of the type named name.
+func kindMethod(name string, kind string) *generator.Root {
+	return generator.NewRoot(
+		generator.NewRawStatementf("func (%s) WitKind() abi.Kind { return abi.%s }", name, kind),
+	)
 }
 
-func generateHandleTypedefFromType(w wit.WitType) *generator.Root {
+func (n *namer) generateHandleTypedefFromType(w wit.WitType) *generator.Root {
 	return generator.NewRoot(
-		generator.NewStruct(GenerateTypenameFromType(w)).
+		generator.NewStruct(n.declName(w)).
 			AddField(
 				textcase.PascalCase("Type"),
-				textcase.PascalCase(GenerateTypenameFromType(w.SubType().Type())),
+				n.typeName(w.SubType().Type()),
 			),
 	)
 }
@@ -11,105 +11,444 @@ import (
 const contextType = "context.Context"
 const instancePointerType = "*Instance"
 
+// sharedTypesPackageName is the name of the package generated by GenerateSharedTypes.
+const sharedTypesPackageName = "types"
+
 func GenerateFromWorld(w wit.WitWorldDefinition, packageName string) *generator.Root {
-	instanceFuncs := []*generator.FuncSignature{
-		generator.NewFuncSignature("Close").
-			AddParameters(generator.NewFuncParameter("ctx", contextType)).
-			AddReturnTypes("error"),
+	return generateWorld(w, packageName, "", mustNamer([]wit.WitWorldDefinition{w}))
+}
+
+// GenerateFromWorldWithSharedTypes generates bindings for w that declare its types as aliases of
+// the definitions generated by GenerateSharedTypes, imported from typesImportPath. worlds are the
+// worlds given to GenerateSharedTypes, so that types are named the same; they default to w.
+func GenerateFromWorldWithSharedTypes(w wit.WitWorldDefinition, packageName string, typesImportPath string, worlds ...wit.WitWorldDefinition) *generator.Root {
+	if len(worlds) == 0 {
+		worlds = []wit.WitWorldDefinition{w}
 	}
-	for _, f := range w.ExportedFunctions() {
-		instanceFuncs = append(instanceFuncs, GenerateSignatureFromFunction(f))
+	return generateWorld(w, packageName, typesImportPath, mustNamer(worlds))
+}
+
+// mustNamer names the types and functions of worlds, and panics if their names clash.
+func mustNamer(worlds []wit.WitWorldDefinition) *namer {
+	n, err := newNamer(worlds, GenerateOptions{})
+	if err != nil {
+		panic(err)
 	}
+	return n
+}
 
-	root := generator.NewRoot().AddStatements(
-		generator.NewComment(" Code generated by witigo -- DO NOT EDIT"),
-		generator.NewComment(" World: "+w.Name()),
-		generator.NewNewline(),
-		generator.NewPackage(textcase.SnakeCase(packageName)),
+func generateWorld(w wit.WitWorldDefinition, packageName string, typesImportPath string, n *namer) *generator.Root {
+	decls := generator.NewRoot(
+		n.instanceDecls(w, packageName),
+		n.typeDecls(worldTypedefs(n, w), typesImportPath, true),
+		n.functionDecls(w),
+	)
+	if n.mock {
+		decls = decls.AddStatements(n.mockDecls(w))
+	}
+	return generator.NewRoot(
+		n.worldHeader(w, packageName, true),
 		generator.NewRawStatement("import ("),
 		generator.NewRawStatement("	_ \"embed\""),
+		generator.NewRawStatement("\"errors\""),
 		generator.NewRawStatement("\"fmt\""),
 		generator.NewRawStatement("\"context\""),
+		rawImports(n.usedMappingImports(decls, true)),
 		generator.NewNewline(),
 		generator.NewRawStatement("\"github.com/rioam2/witigo/pkg/abi\""),
 		generator.NewRawStatement("\"github.com/tetratelabs/wazero\""),
 		generator.NewRawStatement("\"github.com/tetratelabs/wazero/api\""),
 		generator.NewRawStatement("\"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1\""),
+		typesImport(typesImportPath),
+		rawImports(n.usedMappingImports(decls, false)),
 		generator.NewRawStatement(")"),
+		decls,
+	)
+}
+
+// rawImports returns the specs of the imports of paths, within an import declaration.
+func rawImports(paths []string) *generator.Root {
+	root := generator.NewRoot()
+	for _, importPath := range paths {
+		root = root.AddStatements(generator.NewRawStatementf("%q", importPath))
+	}
+	return root
+}
+
+// generatedHeader is the first line of the Go files of generated bindings, after "// ".
+const generatedHeader = "Code generated by witigo -- DO NOT EDIT"
+
+// worldHeader returns the header of a file of the bindings of w, up to its package clause. Only
+// one file of a package carries the docs of the world.
+func (n *namer) worldHeader(w wit.WitWorldDefinition, packageName string, withDocs bool) *generator.Root {
+	root := generator.NewRoot(
+		generator.NewComment(" "+generatedHeader),
+		generator.NewComment(" World: "+w.Name()),
+		n.sourceHashComment(),
+		generator.NewNewline(),
+	)
+	if withDocs {
+		root = root.AddStatements(docComment(w.Docs()))
+	}
+	return root.AddStatements(generator.NewPackage(textcase.SnakeCase(packageName)))
+}
+
+// sourceHashLabel labels the source hash in the header of generated files.
+const sourceHashLabel = "Source: "
+
+// sourceHashComment returns the comment recording the source hash in the header of generated files,
+// if any.
+func (n *namer) sourceHashComment() *generator.Root {
+	if n.sourceHash == "" {
+		return generator.NewRoot()
+	}
+	return generator.NewRoot(generator.NewComment(" " + sourceHashLabel + n.sourceHash))
+}
+
+// typesImport returns the import of the shared types package, if any.
+func typesImport(typesImportPath string) *generator.Root {
+	if typesImportPath == "" {
+		return generator.NewRoot()
+	}
+	return rawImports([]string{typesImportPath})
+}
+
+// instanceDecls declares the instance of the bindings of w, which holds the runtime of the
+// component.
+func (n *namer) instanceDecls(w wit.WitWorldDefinition, packageName string) *generator.Root {
+	instanceFuncs := []*generator.FuncSignature{
+		generator.NewFuncSignature("Close").
+			AddParameters(generator.NewFuncParameter("ctx", contextType)).
+			AddReturnTypes("error"),
+		generator.NewFuncSignature("SetHooks").
+			AddParameters(generator.NewFuncParameter("hooks", "abi.Hooks")),
+	}
+	for _, f := range w.ExportedFunctions() {
+		instanceFuncs = append(instanceFuncs, n.signature(f))
+	}
+
+	root := generator.NewRoot(
 		generator.NewNewline(),
 		generator.NewComment(fmt.Sprintf("go:embed %s_core.wasm", textcase.SnakeCase(packageName))),
 		generator.NewRawStatement("var coreModule []byte"),
-		generator.NewInterface("instance", instanceFuncs...),
+		generator.NewNewline(),
+		componentDoc(n.mock),
+		generator.NewInterface("Component", instanceFuncs...),
 		generator.NewNewline(),
 		generator.NewStruct("Instance").
 			AddField("runtime", "wazero.Runtime").
+			AddField("compiled", "wazero.CompiledModule").
 			AddField("module", "api.Module").
+			AddField("meter", "*abi.Meter").
 			AddField("abiOpts", "abi.AbiOptions").
 			AddField("ctx", contextType),
 		generator.NewNewline(),
-		generator.NewRawStatement("var _ instance = &Instance{}"),
+		generator.NewRawStatement("var _ Component = &Instance{}"),
 		generator.NewNewline(),
 		generator.NewFunc(nil,
 			generator.NewFuncSignature("New").
 				AddParameters(generator.NewFuncParameter("ctx", contextType)).
 				AddReturnTypes(instancePointerType, "error")).
 			Statements(
-				generator.NewRawStatement("c := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)"),
+				generator.NewRawStatement("return NewWithLimits(ctx, abi.Limits{})"),
+			),
+		generator.NewNewline(),
+		generator.NewComment(" NewWithLimits creates an instance whose calls are bounded by limits. When a call exceeds"),
+		generator.NewComment(" its budget, an error matching abi.ErrBudgetExceeded is returned and the instance is"),
+		generator.NewComment(" recycled, so that it can be used for further calls."),
+		generator.NewFunc(nil,
+			generator.NewFuncSignature("NewWithLimits").
+				AddParameters(
+					generator.NewFuncParameter("ctx", contextType),
+					generator.NewFuncParameter("limits", "abi.Limits"),
+				).
+				AddReturnTypes(instancePointerType, "error")).
+			Statements(
+				generator.NewRawStatement("meter := abi.NewMeter(limits)"),
+				generator.NewRawStatement("c := meter.RuntimeConfig(wazero.NewRuntimeConfig().WithCloseOnContextDone(true))"),
 				generator.NewRawStatement("r := wazero.NewRuntimeWithConfig(ctx, c)"),
 				generator.NewRawStatement("if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {"),
+				generator.NewRawStatement("  r.Close(ctx)"),
 				generator.NewRawStatement("  return nil, fmt.Errorf(\"failed to instantiate WASI: %w\", err)"),
 				generator.NewRawStatement("}"),
-				generator.NewRawStatement("cm, err := r.CompileModule(ctx, coreModule)"),
+				generator.NewRawStatement("cm, err := r.CompileModule(meter.Context(ctx), coreModule)"),
 				generator.NewRawStatement("if err != nil {"),
+				generator.NewRawStatement("  r.Close(ctx)"),
 				generator.NewRawStatement("  return nil, fmt.Errorf(\"failed to compile module: %w\", err)"),
 				generator.NewRawStatement("}"),
-				generator.NewRawStatement("moduleConfig := wazero.NewModuleConfig().WithName(\"\")"),
-				generator.NewRawStatement("module, err := r.InstantiateModule(ctx, cm, moduleConfig)"),
-				generator.NewRawStatement("if err != nil {"),
-				generator.NewRawStatement("  return nil, fmt.Errorf(\"failed to instantiate module: %w\", err)"),
-				generator.NewRawStatement("}"),
-				generator.NewRawStatement("abiOpts := abi.AbiOptions{"),
-				generator.NewRawStatement("  StringEncoding: abi.StringEncodingUTF8,"),
-				generator.NewRawStatement("  Memory: abi.GetRuntimeMemoryFromWazero(module),"),
-				generator.NewRawStatement("  Context: ctx,"),
-				generator.NewRawStatement("  Call: abi.GetRuntimeCallFromWazero(module),"),
+				generator.NewRawStatement("i := &Instance{runtime: r, compiled: cm, meter: meter, ctx: ctx}"),
+				generator.NewRawStatement("if err := i.instantiate(); err != nil {"),
+				generator.NewRawStatement("  r.Close(ctx)"),
+				generator.NewRawStatement("  return nil, err"),
 				generator.NewRawStatement("}"),
-				generator.NewRawStatement("return &Instance{runtime: r, module: module, ctx: ctx, abiOpts: abiOpts}, nil"),
+				generator.NewRawStatement("return i, nil"),
 			),
 		generator.NewNewline(),
+		generator.NewComment(" instantiate replaces the module of the instance with a fresh instance of the core module."),
+		generator.NewFunc(
+			generator.NewFuncReceiver("i", instancePointerType),
+			generator.NewFuncSignature("instantiate").
+				AddReturnTypes("error"),
+			generator.NewRawStatement("if i.module != nil {"),
+			generator.NewRawStatement("  i.module.Close(i.ctx)"),
+			generator.NewRawStatement("}"),
+			generator.NewRawStatement("moduleConfig := wazero.NewModuleConfig().WithName(\"\")"),
+			generator.NewRawStatement("module, err := i.runtime.InstantiateModule(i.meter.Context(i.ctx), i.compiled, moduleConfig)"),
+			generator.NewRawStatement("if err != nil {"),
+			generator.NewRawStatement("  return fmt.Errorf(\"failed to instantiate module: %w\", err)"),
+			generator.NewRawStatement("}"),
+			generator.NewRawStatement("call := i.meter.Call(abi.GetRuntimeCallFromWazero(module))"),
+			generator.NewRawStatement("// Reactors, like components written in Go, are initialized before their exports are called."),
+			generator.NewRawStatement("if module.ExportedFunction(\"_initialize\") != nil {"),
+			generator.NewRawStatement("  if _, err := call(i.ctx, \"_initialize\"); err != nil {"),
+			generator.NewRawStatement("    module.Close(i.ctx)"),
+			generator.NewRawStatement("    return fmt.Errorf(\"failed to initialize module: %w\", err)"),
+			generator.NewRawStatement("  }"),
+			generator.NewRawStatement("}"),
+			generator.NewRawStatement("i.module = module"),
+			generator.NewRawStatement("i.abiOpts = abi.AbiOptions{"),
+			generator.NewRawStatement("  StringEncoding: abi.StringEncodingUTF8,"),
+			generator.NewRawStatement("  Memory: abi.GetRuntimeMemoryFromWazero(module),"),
+			generator.NewRawStatement("  Context: i.ctx,"),
+			generator.NewRawStatement("  Hooks: i.abiOpts.Hooks,"),
+			n.converterOption(),
+			generator.NewRawStatement("  Call: func(ctx context.Context, name string, params ...uint64) ([]uint64, error) {"),
+			generator.NewRawStatement("    if i.module != module {"),
+			generator.NewRawStatement("      // The module was recycled during the call holding these options, so the memory that its"),
+			generator.NewRawStatement("      // deferred frees and post-returns release is gone with it."),
+			generator.NewRawStatement("      return nil, fmt.Errorf(\"%s not called: the instance was recycled during the call\", name)"),
+			generator.NewRawStatement("    }"),
+			generator.NewRawStatement("    results, err := call(ctx, name, params...)"),
+			generator.NewRawStatement("    if errors.Is(err, abi.ErrBudgetExceeded) && i.module == module {"),
+			generator.NewRawStatement("      // The guest was interrupted at an arbitrary point, so its state can no longer be trusted."),
+			generator.NewRawStatement("      if recycleErr := i.instantiate(); recycleErr != nil {"),
+			generator.NewRawStatement("        err = errors.Join(err, fmt.Errorf(\"failed to recycle instance: %w\", recycleErr))"),
+			generator.NewRawStatement("      }"),
+			generator.NewRawStatement("    }"),
+			generator.NewRawStatement("    return results, err"),
+			generator.NewRawStatement("  },"),
+			generator.NewRawStatement("}"),
+			generator.NewRawStatement("return nil"),
+		),
+		generator.NewNewline(),
+		generator.NewComment(" SetHooks registers hooks that observe the calls made through the instance."),
+		generator.NewFunc(
+			generator.NewFuncReceiver("i", instancePointerType),
+			generator.NewFuncSignature("SetHooks").
+				AddParameters(generator.NewFuncParameter("hooks", "abi.Hooks")),
+			generator.NewRawStatement("i.abiOpts.Hooks = hooks"),
+		),
+		generator.NewNewline(),
 		generator.NewFunc(
 			generator.NewFuncReceiver("i", instancePointerType),
 			generator.NewFuncSignature("Close").
 				AddParameters(generator.NewFuncParameter("ctx", contextType)).
 				AddReturnTypes("error"),
-			generator.NewRawStatement("return i.module.Close(ctx)"),
+			generator.NewRawStatement("return i.runtime.Close(ctx)"),
 		),
 	)
 
-	// Add static type declaration for Option types
-	root = root.AddStatements(
-		generator.NewRawStatement("type Option[T any] struct {"),
-		generator.NewRawStatement("	IsSome bool"),
-		generator.NewRawStatement("	Value  T"),
-		generator.NewRawStatement("}"),
-		generator.NewNewline(),
+	if n.unwrapResults {
+		root = root.AddStatements(
+			generator.NewComment(" ResultError is returned by functions whose result holds an error."),
+			generator.NewRawStatement("type ResultError[E any] = abi.ResultError[E]"),
+			generator.NewNewline(),
+		)
+	}
+	return root
+}
+
+// componentDoc returns the doc comment of Component.
+func componentDoc(mock bool) *generator.Root {
+	if mock {
+		return generator.NewRoot(
+			generator.NewComment(" Component is the interface of the component, implemented by Instance and by Mock, so that"),
+			generator.NewComment(" code depending on the component can be tested without it."),
+		)
+	}
+	return generator.NewRoot(
+		generator.NewComment(" Component is the interface of the component, implemented by Instance, so that code"),
+		generator.NewComment(" depending on the component can be tested against a fake."),
 	)
+}
 
-	for _, t := range w.Types() {
-		typeGen := GenerateTypedefFromType(t)
-		if typeGen == nil {
-			continue
+// typeDecls declares types of the bindings, along with Option if withOption is set. With shared
+// types, they are aliases of the definitions of the types package.
+func (n *namer) typeDecls(types []wit.WitType, typesImportPath string, withOption bool) *generator.Root {
+	root := generator.NewRoot()
+	if typesImportPath == "" {
+		if withOption {
+			root = root.AddStatements(n.optionTypedef(), n.converterDecls(false))
 		}
-		root = root.AddStatements(typeGen, generator.NewNewline())
+		for _, t := range types {
+			typeGen := n.typedef(t)
+			if typeGen == nil {
+				continue
+			}
+			root = root.AddStatements(typeGen, generator.NewNewline())
+		}
+		return root
 	}
 
+	if withOption {
+		root = root.AddStatements(
+			generator.NewRawStatementf("type Option[T any] = %s.Option[T]", sharedTypesPackageName),
+			generator.NewNewline(),
+			n.optionConstructors(),
+			n.converterDecls(true),
+		)
+	}
+	for _, t := range types {
+		typeNames, constNames, _ := n.typedefNames(t)
+		for _, name := range typeNames {
+			if name == n.declName(t) {
+				root = root.AddStatements(docComment(typeDocs(t)))
+			}
+			root = root.AddStatements(generator.NewRawStatementf("type %s = %s.%s", name, sharedTypesPackageName, name))
+		}
+		for _, name := range constNames {
+			root = root.AddStatements(generator.NewRawStatementf("const %s = %s.%s", name, sharedTypesPackageName, name))
+		}
+		root = root.AddStatements(n.typeFuncs(t))
+	}
+	return root.AddStatements(generator.NewNewline())
+}
+
+// functionDecls declares the methods of the instance calling the functions exported by w.
+func (n *namer) functionDecls(w wit.WitWorldDefinition) *generator.Root {
+	root := generator.NewRoot()
 	for _, f := range w.ExportedFunctions() {
-		funcGen := GenerateFromFunction(f, generator.NewFuncReceiver("i", instancePointerType))
+		funcGen := n.function(f, generator.NewFuncReceiver("i", instancePointerType))
 		if funcGen == nil {
 			continue
 		}
-		root = root.AddStatements(funcGen, generator.NewNewline())
+		root = root.AddStatements(docComment(f.Docs()), funcGen, generator.NewNewline())
 	}
-
 	return root
 }
+
+// GenerateSharedTypes generates a package holding the type definitions of all worlds, for the
+// bindings generated by GenerateFromWorldWithSharedTypes. Worlds may share types, but types with the
+// same name are qualified by their interface.
+func GenerateSharedTypes(worlds []wit.WitWorldDefinition) (*generator.Root, error) {
+	n, err := newNamer(worlds, GenerateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return generateSharedTypes(worlds, n)
+}
+
+func generateSharedTypes(worlds []wit.WitWorldDefinition, n *namer) (*generator.Root, error) {
+	types, err := sharedTypedefs(worlds, n)
+	if err != nil {
+		return nil, err
+	}
+	decls := n.typeDecls(types, "", true)
+	imports := append(n.usedMappingImports(decls, true), "github.com/rioam2/witigo/pkg/abi")
+	return generator.NewRoot(
+		n.sharedTypesHeader(),
+		generator.NewNewline(),
+		generator.NewImport(append(imports, n.usedMappingImports(decls, false)...)...),
+		generator.NewNewline(),
+		decls,
+	), nil
+}
+
+// sharedTypesHeader returns the header of a file of the shared types package, up to its package
+// clause.
+func (n *namer) sharedTypesHeader() *generator.Root {
+	return generator.NewRoot(
+		generator.NewComment(" "+generatedHeader),
+		n.sourceHashComment(),
+		generator.NewNewline(),
+		generator.NewPackage(sharedTypesPackageName),
+	)
+}
+
+// sharedTypedefs returns the types of worlds that need a declaration, once per Go name. Types of
+// different worlds with the same name must have the same definition.
+func sharedTypedefs(worlds []wit.WitWorldDefinition, n *namer) ([]wit.WitType, error) {
+	var types []wit.WitType
+	definedBy := map[string]string{}
+	definitions := map[string]string{}
+	for _, w := range worlds {
+		for _, t := range worldTypedefs(n, w) {
+			typeGen := n.typedef(t)
+			if typeGen == nil {
+				continue
+			}
+			definition, err := typeGen.Generate(0)
+			if err != nil {
+				return nil, err
+			}
+			name := n.declName(t)
+			if existing, ok := definitions[name]; ok {
+				if existing != definition {
+					return nil, fmt.Errorf("type %s is defined differently by worlds %s and %s", name, definedBy[name], w.Name())
+				}
+				continue
+			}
+			definitions[name] = definition
+			definedBy[name] = w.Name()
+			types = append(types, t)
+		}
+	}
+	return types, nil
+}
+
+// worldTypedefs returns the types of a world that have a distinct Go name. Owned and borrowed
+// handles of a resource share a Go type.
+func worldTypedefs(n *namer, w wit.WitWorldDefinition) []wit.WitType {
+	var types []wit.WitType
+	seen := map[string]bool{}
+	for _, t := range w.Types() {
+		name := n.declName(t)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		types = append(types, t)
+	}
+	return types
+}
+
+// optionTypedef declares the Option type, which is abi.Option in idiomatic mode.
+func (n *namer) optionTypedef() *generator.Root {
+	if n.idiomatic {
+		return generator.NewRoot(
+			generator.NewRawStatement("type Option[T any] = abi.Option[T]"),
+			generator.NewNewline(),
+			n.optionConstructors(),
+		)
+	}
+	return generator.NewRoot(
+		generator.NewRawStatement("type Option[T any] struct {"),
+		generator.NewRawStatement("	IsSome bool"),
+		generator.NewRawStatement("	Value  T"),
+		generator.NewRawStatement("}"),
+		generator.NewNewline(),
+		generator.NewRawStatement("func (o Option[T]) MarshalJSON() ([]byte, error) {"),
+		generator.NewRawStatement("return abi.MarshalOptionJSON(o.IsSome, o.Value)"),
+		generator.NewRawStatement("}"),
+		generator.NewNewline(),
+		generator.NewRawStatement("func (o *Option[T]) UnmarshalJSON(data []byte) error {"),
+		generator.NewRawStatement("return abi.UnmarshalOptionJSON(data, &o.IsSome, &o.Value)"),
+		generator.NewRawStatement("}"),
+		generator.NewNewline(),
+	)
+}
+
+// optionConstructors declares the constructors of Option in idiomatic mode.
+func (n *namer) optionConstructors() *generator.Root {
+	if !n.idiomatic {
+		return generator.NewRoot()
+	}
+	return generator.NewRoot(
+		generator.NewComment(" Some returns an Option holding value."),
+		generator.NewRawStatement("func Some[T any](value T) Option[T] { return abi.Some(value) }"),
+		generator.NewNewline(),
+		generator.NewComment(" None returns an Option holding no value."),
+		generator.NewRawStatement("func None[T any]() Option[T] { return abi.None[T]() }"),
+		generator.NewNewline(),
+	)
+}
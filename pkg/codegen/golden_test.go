@@ -0,0 +1,100 @@
+package codegen
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rioam2/witigo/pkg/wit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of TestGolden")
+
+// goldenModes are the options that bindings are generated with for each fixture, by the suffix of
+// the golden files they are compared with.
+var goldenModes = []struct {
+	suffix string
+	opts   GenerateOptions
+}{
+	{"", GenerateOptions{}},
+	{".idiomatic", GenerateOptions{Idiomatic: true}},
+	{".sealed", GenerateOptions{SealedVariants: true}},
+	{".unwrap", GenerateOptions{UnwrapResults: true}},
+	{".mock", GenerateOptions{Mock: true}},
+	{".guest", GenerateOptions{Guest: true}},
+}
+
+// TestGolden generates bindings for each fixture in testdata/golden, either WIT JSON or a WIT
+// source file, in every mode of goldenModes, and compares them with the checked-in golden files:
+// <name>.go.golden in the default mode, <name>.idiomatic.go.golden in idiomatic mode, and so on,
+// like <name>.guest_wasm.go.golden for the second file of guest bindings.
+// Run `go test ./pkg/codegen -run TestGolden -update` to accept changes to the generated code.
+func TestGolden(t *testing.T) {
+	fixtures, err := filepath.Glob("testdata/golden/*.json")
+	require.NoError(t, err)
+	require.NotEmpty(t, fixtures)
+	sources, err := filepath.Glob("testdata/golden/*.wit")
+	require.NoError(t, err)
+	fixtures = append(fixtures, sources...)
+
+	for _, fixture := range fixtures {
+		for _, mode := range goldenModes {
+			testGolden(t, fixture, mode.suffix, mode.opts)
+		}
+	}
+}
+
+// testGolden compares the bindings generated for fixture with opts with its golden files.
+func testGolden(t *testing.T, fixture string, suffix string, opts GenerateOptions) {
+	ext := filepath.Ext(fixture)
+	name := strings.TrimSuffix(filepath.Base(fixture), ext)
+	t.Run(name+suffix, func(t *testing.T) {
+		raw, err := os.ReadFile(fixture)
+		require.NoError(t, err)
+		// generate returns the code of each file of the bindings, by the suffix of its name after
+		// the package name, like `.go`, or `_wasm.go` for guest bindings.
+		generate := func() map[string]string {
+			var def wit.WitDefinition
+			if ext == ".wit" {
+				def, err = wit.Parse(filepath.Base(fixture), string(raw))
+			} else {
+				def, err = wit.NewFromJson(raw, name)
+			}
+			require.NoError(t, err)
+			world, err := def.World("")
+			require.NoError(t, err)
+			n, err := newNamer([]wit.WitWorldDefinition{world}, opts)
+			require.NoError(t, err)
+			var files []generatedFile
+			if opts.Guest {
+				files, err = generateGuestFiles(world, def.Name(), "", false, n)
+				require.NoError(t, err)
+			} else {
+				files = []generatedFile{{name: def.Name() + ".go", code: generateWorld(world, def.Name(), "", n)}}
+			}
+			codes := map[string]string{}
+			for _, file := range files {
+				code, err := formatCode(file.code)
+				require.NoError(t, err)
+				codes[strings.TrimPrefix(file.name, def.Name())] = code
+			}
+			return codes
+		}
+		codes := generate()
+		require.Equal(t, codes, generate(), "generated code is not deterministic")
+
+		for file, code := range codes {
+			golden := strings.TrimSuffix(fixture, ext) + suffix + file + ".golden"
+			if *update {
+				require.NoError(t, os.WriteFile(golden, []byte(code), 0666))
+			}
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), code)
+		}
+	})
+}
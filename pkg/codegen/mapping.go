@@ -0,0 +1,265 @@
+package codegen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/types"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/moznion/gowrtr/generator"
+	witigo "github.com/rioam2/witigo/pkg"
+	"github.com/rioam2/witigo/pkg/wit"
+)
+
+// TypeMapping represents a WIT type with a Go type of choice, like `time.Time` for a
+// `record timestamp { seconds: u64, nanos: u32 }`. Values are converted to and from the Go type
+// generated for the WIT type, like `TimestampRecord`, by functions that the bindings pass to the
+// ABI as abi.Converters. The functions are either imported, or declared next to the generated
+// types: in the package of the bindings, or in the shared `types` package of multiple worlds.
+type TypeMapping struct {
+	// Wit is the WIT type: a named type, by name or qualified like `namespace:package/interface#name`,
+	// or an anonymous type, like `list<tuple<string, string>>`.
+	Wit string `json:"wit" yaml:"wit"`
+	// Go is the Go type, like `time.Time` or `map[string]string`: a named type other than the
+	// predeclared ones, or a map. Every value of the Go type in the bindings is converted, so it must
+	// not be a type that the bindings also use for other WIT types, like `string` or `[]byte`.
+	Go string `json:"go" yaml:"go"`
+	// ToWit converts values of the Go type to the Go type generated for the WIT type, like
+	// `func(time.Time) (TimestampRecord, error)`.
+	ToWit string `json:"to-wit" yaml:"to-wit"`
+	// FromWit converts values of the Go type generated for the WIT type to the Go type, like
+	// `func(TimestampRecord) (time.Time, error)`.
+	FromWit string `json:"from-wit" yaml:"from-wit"`
+	// Imports are the import paths of the packages that Go, ToWit and FromWit refer to, which must
+	// be named after the last element of their path, like `time`.
+	Imports []string `json:"imports,omitempty" yaml:"imports,omitempty"`
+}
+
+// newMappings returns type mappings by WIT type, without spaces, and in order.
+func newMappings(mappings []TypeMapping) (map[string]*TypeMapping, []*TypeMapping, error) {
+	byWit := map[string]*TypeMapping{}
+	var list []*TypeMapping
+	for i, m := range mappings {
+		if m.Wit == "" || m.Go == "" || m.ToWit == "" || m.FromWit == "" {
+			return nil, nil, fmt.Errorf("type mapping %q must set the WIT type, the Go type and both converters", m.Wit)
+		}
+		expr, err := parser.ParseExpr(m.Go)
+		if err != nil {
+			return nil, nil, fmt.Errorf("type mapping %s maps to %q, which is not a Go type", m.Wit, m.Go)
+		}
+		if !mappableGoType(expr) {
+			return nil, nil, fmt.Errorf("type mapping %s maps to %s, which is predeclared or unnamed, map to a named type", m.Wit, m.Go)
+		}
+		key := witTypeKey(m.Wit)
+		if _, ok := byWit[key]; ok {
+			return nil, nil, fmt.Errorf("type %s is mapped twice", m.Wit)
+		}
+		for _, other := range list {
+			if witTypeKey(other.Go) == witTypeKey(m.Go) {
+				return nil, nil, fmt.Errorf("types %s and %s are both mapped to %s", other.Wit, m.Wit, m.Go)
+			}
+		}
+		byWit[key] = &mappings[i]
+		list = append(list, &mappings[i])
+	}
+	return byWit, list, nil
+}
+
+// mappableGoType reports whether expr is a Go type that WIT types can be mapped to: a named type,
+// possibly generic, other than the predeclared ones, or a map, which the bindings declare for no WIT
+// type. The converters of the other types, like `string` or `[]byte`, would also convert the values
+// of the WIT types represented by them.
+func mappableGoType(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return types.Universe.Lookup(e.Name) == nil
+	case *ast.SelectorExpr:
+		_, ok := e.X.(*ast.Ident)
+		return ok
+	case *ast.IndexExpr:
+		return mappableGoType(e.X)
+	case *ast.IndexListExpr:
+		return mappableGoType(e.X)
+	case *ast.MapType:
+		return true
+	}
+	return false
+}
+
+// witTypeKey returns a WIT type without spaces, so that `tuple<string,string>` and
+// `tuple<string, string>` are the same type.
+func witTypeKey(witType string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, witType)
+}
+
+// mapping returns the type mapping of a type, if any. Named types are looked up by qualified name,
+// then by name; anonymous types by their WIT type, like `list<tuple<string,string>>`.
+func (n *namer) mapping(w wit.WitType) (*TypeMapping, bool) {
+	if len(n.mappings) == 0 || w == nil {
+		return nil, false
+	}
+	var keys []string
+	if key, ok := namedTypeKey(w); ok {
+		keys = append(keys, key)
+	}
+	keys = append(keys, witTypeKey(witTypeExpr(w)))
+	for _, key := range keys {
+		if m, ok := n.mappings[key]; ok {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// witTypeExpr returns the WIT type of a reference to w, like `point` or `list<tuple<string, u8>>`.
+func witTypeExpr(w wit.WitType) string {
+	if w == nil {
+		return "_"
+	}
+	kind := w.Kind()
+	if kind.IsPrimitive() && kind != witigo.AbiTypeEnum || kind == witigo.AbiTypeString {
+		// Primitive types are named after the reference they were reached through, not their type.
+		return kind.String()
+	}
+	if name := resolveUse(w).Name(); name != "" && name != "(none)" {
+		return name
+	}
+	var params []string
+	for _, sub := range w.SubTypes() {
+		params = append(params, witTypeExpr(sub.Type()))
+	}
+	switch kind {
+	case witigo.AbiTypeList, witigo.AbiTypeOption:
+		return fmt.Sprintf("%s<%s>", kind, witTypeExpr(w.SubType().Type()))
+	case witigo.AbiTypeHandle:
+		handle := "own"
+		if w.IsBorrow() {
+			handle = "borrow"
+		}
+		return fmt.Sprintf("%s<%s>", handle, witTypeExpr(w.SubType().Type()))
+	case witigo.AbiTypeResult:
+		switch {
+		case params[0] == "_" && params[1] == "_":
+			return "result"
+		case params[1] == "_":
+			return fmt.Sprintf("result<%s>", params[0])
+		}
+	}
+	return fmt.Sprintf("%s<%s>", kind, strings.Join(params, ", "))
+}
+
+// checkMappings reports type mappings that match no type of worlds.
+func (n *namer) checkMappings(worlds []wit.WitWorldDefinition) error {
+	used := map[*TypeMapping]bool{}
+	var visit func(w wit.WitType)
+	visit = func(w wit.WitType) {
+		if w == nil {
+			return
+		}
+		if m, ok := n.mapping(w); ok {
+			used[m] = true
+		}
+		for _, sub := range w.SubTypes() {
+			visit(sub.Type())
+		}
+	}
+	for _, w := range worlds {
+		for _, t := range w.Types() {
+			visit(t)
+		}
+		for _, f := range w.ExportedFunctions() {
+			for _, param := range f.Params() {
+				visit(param.Type())
+			}
+			visit(f.Returns())
+		}
+	}
+	for _, m := range n.mappingList {
+		if !used[m] {
+			return fmt.Errorf("type mapping %s matches no type", m.Wit)
+		}
+	}
+	return nil
+}
+
+// mappingImports returns the packages that the type mappings refer to, by package name.
+func (n *namer) mappingImports() []fileImport {
+	var imports []fileImport
+	seen := map[string]bool{}
+	for _, m := range n.mappingList {
+		for _, importPath := range m.Imports {
+			if seen[importPath] {
+				continue
+			}
+			seen[importPath] = true
+			imports = append(imports, fileImport{name: path.Base(importPath), path: importPath})
+		}
+	}
+	return imports
+}
+
+// converterDecls declares Converters, the converters of the type mappings that the bindings pass to
+// the ABI. The package declaring the types creates them, and bindings using the shared types of
+// multiple worlds refer to those of the types package.
+func (n *namer) converterDecls(shared bool) *generator.Root {
+	if len(n.mappings) == 0 {
+		return generator.NewRoot()
+	}
+	root := generator.NewRoot(
+		generator.NewComment(" Converters converts the Go types that WIT types are mapped to, for the ABI."),
+	)
+	if shared {
+		return root.AddStatements(
+			generator.NewRawStatementf("var Converters = %s.Converters", sharedTypesPackageName),
+			generator.NewNewline(),
+		)
+	}
+	root = root.AddStatements(generator.NewRawStatement("var Converters = abi.NewConverters("))
+	for _, m := range n.mappingList {
+		root = root.AddStatements(generator.NewRawStatementf("abi.NewConverter(%s, %s),", m.ToWit, m.FromWit))
+	}
+	return root.AddStatements(generator.NewRawStatement(")"), generator.NewNewline())
+}
+
+// converterOption sets the Converters of the AbiOptions of an instance, if any.
+func (n *namer) converterOption() *generator.Root {
+	if len(n.mappings) == 0 {
+		return generator.NewRoot()
+	}
+	return generator.NewRoot(generator.NewRawStatement("  Converters: Converters,"))
+}
+
+// usedMappingImports returns the import paths of the packages of the type mappings that decls
+// refer to, either standard packages or not.
+func (n *namer) usedMappingImports(decls *generator.Root, std bool) []string {
+	imports := n.mappingImports()
+	if len(imports) == 0 {
+		return nil
+	}
+	_, used, err := usedPackages(decls)
+	var paths []string
+	for _, imp := range imports {
+		if strings.Contains(imp.path, ".") == std {
+			continue
+		}
+		// Declarations that do not parse keep all imports, so that the error shows up in the code.
+		if err != nil || used[imp.name] {
+			paths = append(paths, imp.path)
+		}
+	}
+	return paths
+}
+
+// isMapped reports whether a type is mapped to a Go type.
+func (n *namer) isMapped(w wit.WitType) bool {
+	_, ok := n.mapping(w)
+	return ok
+}
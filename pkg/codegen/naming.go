@@ -0,0 +1,409 @@
+package codegen
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+
+	"github.com/golang-cz/textcase"
+	witigo "github.com/rioam2/witigo/pkg"
+	"github.com/rioam2/witigo/pkg/wit"
+)
+
+// reservedTypeNames are declared by the generated bindings themselves.
+var reservedTypeNames = map[string]bool{
+	"Component":     true,
+	"Instance":      true,
+	"New":           true,
+	"NewWithLimits": true,
+	"Option":        true,
+}
+
+// reservedIdiomaticTypeNames are declared by the generated bindings in idiomatic mode only.
+var reservedIdiomaticTypeNames = map[string]bool{
+	"Some": true,
+	"None": true,
+}
+
+// reservedGuestNames are declared by the generated bindings in guest mode only.
+var reservedGuestNames = map[string]bool{
+	"Exports":    true,
+	"SetExports": true,
+}
+
+// reservedMethodNames are methods of the generated Instance.
+var reservedMethodNames = map[string]bool{
+	"Close":    true,
+	"SetHooks": true,
+}
+
+// reservedMockMethodNames are methods and fields of the generated Mock, other than the methods of
+// Instance and the fields calling them.
+var reservedMockMethodNames = map[string]bool{
+	"Calls":        true,
+	"Record":       true,
+	"MockRecorder": true,
+	"CloseFunc":    true,
+	"SetHooksFunc": true,
+}
+
+// reservedLocalNames are the receiver, locals and packages used by the generated function bodies,
+// which parameters must not shadow.
+var reservedLocalNames = map[string]bool{
+	"i":          true,
+	"done":       true,
+	"params":     true,
+	"freeParams": true,
+	"ret":        true,
+	"wrapped":    true,
+	"postReturn": true,
+	"result":     true,
+	"err":        true,
+	"abi":        true,
+	"fmt":        true,
+	"errors":     true,
+	"context":    true,
+}
+
+// reservedGuestLocalNames are the variable and packages used by the generated glue of guest
+// bindings, which parameters must not shadow either.
+var reservedGuestLocalNames = map[string]bool{
+	"exports":    true,
+	"freeResult": true,
+	"guest":      true,
+	"math":       true,
+}
+
+// namer maps WIT names to Go identifiers. Named types are named after their WIT name and kind,
+// like `PointRecord`, or after their WIT name alone in idiomatic mode. Types of different
+// interfaces with the same name are qualified by their interface or world, then by their package,
+// and numbered as a last resort. Identifiers clashing with Go keywords or with identifiers of the
+// generated code get a trailing underscore.
+//
+// The zero namer names types without looking for clashes.
+type namer struct {
+	// overrides maps WIT names to Go names. Types are given by name or qualified name, like `point`
+	// or `ns:pkg/iface#point`, and functions by name.
+	overrides map[string]string
+	// types holds the names of the named types of the worlds, by qualified name.
+	types map[string]string
+	// idiomatic drops the kind suffixes of named types, which then declare their kind to the ABI
+	// with a WitKind method, and represents `list<u8>` as `[]byte`.
+	idiomatic bool
+	// sealedVariants declares variants as sealed interfaces implemented by a type per case.
+	sealedVariants bool
+	// unwrapResults returns the payloads of results returned by exported functions as `(T, error)`.
+	unwrapResults bool
+	// mock declares a Mock implementing the exported functions with a function field each.
+	mock bool
+	// guest generates bindings for a component written in Go, which implements the exported functions
+	// and calls the imported ones.
+	guest bool
+	// mappings maps WIT types to Go types, by WIT type without spaces. mappingList holds them in
+	// order.
+	mappings    map[string]*TypeMapping
+	mappingList []*TypeMapping
+	// sourceHash is the hash of the component or WIT the bindings are generated from, recorded in the
+	// header of their files when set.
+	sourceHash string
+}
+
+// newNamer names the types and functions of worlds, which share the same Go names, following the
+// naming options of opts.
+func newNamer(worlds []wit.WitWorldDefinition, opts GenerateOptions) (*namer, error) {
+	overrides := opts.Names
+	for name, override := range overrides {
+		if !token.IsIdentifier(override) || !token.IsExported(override) {
+			return nil, fmt.Errorf("name override %s=%s is not an exported Go identifier", name, override)
+		}
+	}
+	mappings, mappingList, err := newMappings(opts.Mappings)
+	if err != nil {
+		return nil, err
+	}
+	n := &namer{
+		overrides:      overrides,
+		types:          map[string]string{},
+		idiomatic:      opts.Idiomatic,
+		sealedVariants: opts.SealedVariants,
+		unwrapResults:  opts.UnwrapResults,
+		mock:           opts.Mock,
+		guest:          opts.Guest,
+		mappings:       mappings,
+		mappingList:    mappingList,
+	}
+	used := map[string]bool{}
+
+	var keys []string
+	named := map[string]wit.WitType{}
+	overridden := map[string]bool{}
+	for _, w := range worlds {
+		for _, t := range w.Types() {
+			key, ok := namedTypeKey(t)
+			if !ok || named[key] != nil {
+				continue
+			}
+			t = resolveUse(t)
+			keys = append(keys, key)
+			named[key] = t
+			n.types[key] = n.baseTypeName(t)
+			for _, name := range []string{t.Name(), key} {
+				if override, ok := overrides[name]; ok {
+					n.types[key] = override
+					overridden[key] = true
+					used[name] = true
+				}
+			}
+		}
+	}
+
+	clashing := func(name string) bool {
+		if n.reservedTypeName(name) {
+			return true
+		}
+		count := 0
+		for _, key := range keys {
+			if n.types[key] == name {
+				count++
+			}
+		}
+		return count > 1
+	}
+	rename := func(qualify func(t wit.WitType, name string) string) {
+		var renamed []string
+		for _, key := range keys {
+			if !overridden[key] && clashing(n.types[key]) {
+				renamed = append(renamed, key)
+			}
+		}
+		for _, key := range renamed {
+			n.types[key] = qualify(named[key], n.types[key])
+		}
+	}
+	rename(func(t wit.WitType, name string) string {
+		switch {
+		case t.OwnerInterface() != nil && t.OwnerInterface().Name() != "":
+			return textcase.PascalCase(t.OwnerInterface().Name()) + name
+		case t.OwnerWorld() != nil:
+			return textcase.PascalCase(t.OwnerWorld().Name()) + name
+		}
+		return name
+	})
+	rename(func(t wit.WitType, name string) string {
+		pkg := ""
+		switch {
+		case t.OwnerInterface() != nil && t.OwnerInterface().Package() != nil:
+			pkg = t.OwnerInterface().Package().Name()
+		case t.OwnerWorld() != nil:
+			pkg = t.OwnerWorld().Package()
+		}
+		pkg, _, _ = strings.Cut(pkg, "@")
+		return textcase.PascalCase(strings.ReplaceAll(pkg, ":", "-")) + name
+	})
+	for _, key := range keys {
+		if overridden[key] || !clashing(n.types[key]) {
+			continue
+		}
+		base := n.types[key]
+		for i := 2; clashing(n.types[key]); i++ {
+			n.types[key] = fmt.Sprintf("%s%d", base, i)
+		}
+	}
+
+	// Derived names, like those of constants and anonymous types, may still clash.
+	declared := map[string]string{}
+	declare := func(name string, what string) error {
+		if n.reservedTypeName(name) {
+			return fmt.Errorf("%s is named %s, which is declared by the generated code, set a name override for it", what, name)
+		}
+		if other, ok := declared[name]; ok && other != what {
+			return fmt.Errorf("%s and %s are both named %s, set a name override for one of them", other, what, name)
+		}
+		declared[name] = what
+		return nil
+	}
+	for _, w := range worlds {
+		methods := map[string]string{}
+		for _, f := range w.ExportedFunctions() {
+			name := n.methodName(f)
+			if _, ok := overrides[f.Name()]; ok {
+				used[f.Name()] = true
+				if n.reservedMethodName(name) {
+					return nil, fmt.Errorf("function %s is named %s, which is declared by the generated code, set another name override for it", f.Name(), name)
+				}
+			} else if n.reservedMethodName(name) {
+				return nil, fmt.Errorf("function %s is named %s, which is declared by the generated code, set a name override for it", f.Name(), name)
+			}
+			if other, ok := methods[name]; ok {
+				return nil, fmt.Errorf("functions %s and %s of world %s are both named %s, set a name override for one of them", other, f.Name(), w.Name(), name)
+			}
+			methods[name] = f.Name()
+		}
+		if n.guest {
+			// Imported functions are declared as functions of the package, alongside the types.
+			for _, f := range w.ImportedFunctions() {
+				if _, ok := overrides[f.Name()]; ok {
+					used[f.Name()] = true
+				}
+				if err := declare(n.methodName(f), "function "+f.Name()); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if n.mock {
+			for _, f := range w.ExportedFunctions() {
+				if other, ok := methods[mockFuncField(n.methodName(f))]; ok {
+					return nil, fmt.Errorf("function %s is named like the field of the mock calling function %s, set a name override for it", other, f.Name())
+				}
+			}
+		}
+		for _, t := range w.Types() {
+			what := "type " + n.declName(t)
+			if key, ok := namedTypeKey(t); ok {
+				what = "type " + key
+			}
+			typeNames, constNames, funcNames := n.typedefNames(t)
+			for _, name := range append(append(typeNames, constNames...), funcNames...) {
+				if err := declare(name, what); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if err := n.checkMappings(worlds); err != nil {
+		return nil, err
+	}
+	for name := range overrides {
+		if !used[name] {
+			return nil, fmt.Errorf("name override %s matches no type or exported function", name)
+		}
+	}
+	return n, nil
+}
+
+// namedTypeKey returns the qualified name of types named by the namer, like `ns:pkg/iface#point`.
+// Types brought in scope by `use` without renaming them have the key of the type they refer to.
+func namedTypeKey(t wit.WitType) (string, bool) {
+	switch t.Kind() {
+	case witigo.AbiTypeRecord, witigo.AbiTypeEnum, witigo.AbiTypeVariant, witigo.AbiTypeFlags, witigo.AbiTypeResource:
+	default:
+		return "", false
+	}
+	t = resolveUse(t)
+	owner := t.Owner()
+	if owner == nil {
+		return "", false
+	}
+	return *owner + "#" + t.Name(), true
+}
+
+// resolveUse follows aliases that keep the name of the type they refer to.
+func resolveUse(t wit.WitType) wit.WitType {
+	for t.AliasOf() != nil && t.AliasOf().Name() == t.Name() {
+		t = t.AliasOf()
+	}
+	return t
+}
+
+// reservedTypeName reports whether name is declared by the generated bindings themselves.
+func (n *namer) reservedTypeName(name string) bool {
+	return reservedTypeNames[name] ||
+		n.idiomatic && reservedIdiomaticTypeNames[name] ||
+		n.unwrapResults && name == "ResultError" ||
+		n.mock && name == "Mock" ||
+		len(n.mappings) > 0 && name == "Converters" ||
+		n.guest && reservedGuestNames[name]
+}
+
+// baseTypeName returns the name of a named type before resolving clashes: its WIT name followed
+// by its kind, unless the name already ends with it or in idiomatic mode.
+func (n *namer) baseTypeName(t wit.WitType) string {
+	if n.idiomatic {
+		return textcase.PascalCase(t.Name())
+	}
+	suffix := ""
+	switch t.Kind() {
+	case witigo.AbiTypeRecord:
+		suffix = "Record"
+	case witigo.AbiTypeEnum:
+		suffix = "Enum"
+	case witigo.AbiTypeVariant:
+		suffix = "Variant"
+	case witigo.AbiTypeFlags:
+		suffix = "Flags"
+	}
+	name := textcase.PascalCase(t.Name())
+	if strings.HasSuffix(name, suffix) && name != suffix {
+		return name
+	}
+	return name + suffix
+}
+
+// namedTypeName returns the name of a named type.
+func (n *namer) namedTypeName(t wit.WitType) string {
+	if key, ok := namedTypeKey(t); ok {
+		if name, ok := n.types[key]; ok {
+			return name
+		}
+	}
+	if override, ok := n.overrides[t.Name()]; ok {
+		return override
+	}
+	return n.baseTypeName(t)
+}
+
+// methodName returns the name of the method calling an exported function.
+func (n *namer) methodName(f wit.WitFunction) string {
+	if override, ok := n.overrides[f.Name()]; ok {
+		return override
+	}
+	return textcase.PascalCase(f.Name())
+}
+
+// reservedMethodName reports whether name is a method declared by the generated bindings
+// themselves.
+func (n *namer) reservedMethodName(name string) bool {
+	return !n.guest && reservedMethodNames[name] || n.mock && reservedMockMethodNames[name]
+}
+
+// guestParamName returns the name of a parameter of a function of guest bindings, which must not
+// shadow the names of their glue either, nor the flat parameters of exports, like `p0`.
+func guestParamName(name string) string {
+	name = escape(paramName(name), reservedGuestLocalNames)
+	if digits, ok := strings.CutPrefix(name, "p"); ok && digits != "" && strings.Trim(digits, "0123456789") == "" {
+		return name + "_"
+	}
+	return name
+}
+
+// paramName returns the name of a parameter of a generated function.
+func paramName(name string) string {
+	return escape(textcase.CamelCase(name), reservedLocalNames)
+}
+
+// fieldNames returns the names of the fields of a struct, after the given leading fields.
+func fieldNames(names []string, leading ...string) []string {
+	seen := map[string]bool{}
+	for _, name := range leading {
+		seen[name] = true
+	}
+	fields := make([]string, len(names))
+	for i, name := range names {
+		field := textcase.PascalCase(name)
+		for seen[field] {
+			field += "_"
+		}
+		seen[field] = true
+		fields[i] = field
+	}
+	return fields
+}
+
+// escape appends an underscore to Go keywords and reserved names.
+func escape(name string, reserved map[string]bool) string {
+	if token.IsKeyword(name) || reserved[name] {
+		return name + "_"
+	}
+	return name
+}
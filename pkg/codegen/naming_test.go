@@ -0,0 +1,130 @@
+package codegen
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/rioam2/witigo/pkg/wit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNaming(t *testing.T) {
+	def, err := wit.Parse("naming.wit", `package test:naming;
+
+interface a {
+  record error { code: u32 }
+}
+
+interface b {
+  record error { message: string }
+}
+
+interface c {
+  use a.{error};
+  record wrapper { e: error }
+}
+
+world app {
+  use b.{error};
+  use c.{wrapper};
+  record foo-record { x: u32, x1: u32 }
+  variant choice { %type(u32), other }
+  export close: func();
+  export check: func(%type: u32, i: u32, result: string, %func: wrapper, e: error) -> foo-record;
+  export pick: func() -> choice;
+}
+`)
+	require.NoError(t, err)
+	world, err := def.World("")
+	require.NoError(t, err)
+
+	// Functions named like the methods of Instance are not renamed behind the back of callers.
+	_, err = newNamer([]wit.WitWorldDefinition{world}, GenerateOptions{})
+	assert.EqualError(t, err, "function close is named Close, which is declared by the generated code, set a name override for it")
+
+	n, err := newNamer([]wit.WitWorldDefinition{world}, GenerateOptions{Names: map[string]string{"close": "Shutdown"}})
+	require.NoError(t, err)
+	code, err := formatCode(generateWorld(world, "app", "", n))
+	require.NoError(t, err)
+	for _, expected := range []string{
+		"type FooRecord struct",
+		"type AErrorRecord struct",
+		"type BErrorRecord struct",
+		"Type_ uint32",
+		"func (i *Instance) Shutdown() (err error)",
+		"func (i *Instance) Check(\n\ttype_ uint32,\n\ti_ uint32,\n\tresult_ string,\n\tfunc_ WrapperRecord,\n\te BErrorRecord,\n) (result FooRecord, err error)",
+	} {
+		assert.Contains(t, code, expected)
+	}
+	assert.NotContains(t, code, "FooRecordRecord")
+
+	n, err = newNamer([]wit.WitWorldDefinition{world}, GenerateOptions{Names: map[string]string{
+		"test:naming/b#error": "Failure",
+		"foo-record":          "Foo",
+		"close":               "Shutdown",
+	}})
+	require.NoError(t, err)
+	code, err = formatCode(generateWorld(world, "app", "", n))
+	require.NoError(t, err)
+	for _, expected := range []string{
+		"type Foo struct",
+		"type Failure struct",
+		"type ErrorRecord struct",
+		"func (i *Instance) Shutdown() (err error)",
+	} {
+		assert.Contains(t, code, expected)
+	}
+
+	for _, test := range []struct {
+		overrides map[string]string
+		expected  string
+	}{
+		{map[string]string{"foo-record": "ChoiceVariantType"}, "type app#foo-record and type app#choice are both named ChoiceVariantType, set a name override for one of them"},
+		{map[string]string{"choice": "Instance"}, "type app#choice is named Instance, which is declared by the generated code, set a name override for it"},
+		{map[string]string{"pick": "Check"}, "functions check and pick of world app are both named Check, set a name override for one of them"},
+		{map[string]string{"close": "SetHooks"}, "function close is named SetHooks, which is declared by the generated code, set another name override for it"},
+		{map[string]string{"close": "shutdown"}, "name override close=shutdown is not an exported Go identifier"},
+		{map[string]string{"test:naming/c#error": "Error"}, "name override test:naming/c#error matches no type or exported function"},
+	} {
+		overrides := map[string]string{"close": "Shutdown"}
+		maps.Copy(overrides, test.overrides)
+		_, err := newNamer([]wit.WitWorldDefinition{world}, GenerateOptions{Names: overrides})
+		assert.EqualError(t, err, test.expected)
+	}
+}
+
+func TestNamingIdiomatic(t *testing.T) {
+	def, err := wit.Parse("idiomatic.wit", `package test:idiomatic;
+
+world app {
+  record customer { picture: list<u8> }
+  record some { x: u32 }
+  enum color { red, green }
+  export check: func(c: customer, s: some) -> color;
+}
+`)
+	require.NoError(t, err)
+	world, err := def.World("")
+	require.NoError(t, err)
+
+	n, err := newNamer([]wit.WitWorldDefinition{world}, GenerateOptions{Idiomatic: true})
+	require.NoError(t, err)
+	code, err := formatCode(generateWorld(world, "app", "", n))
+	require.NoError(t, err)
+	for _, expected := range []string{
+		"type Customer struct {\n\tPicture []byte\n}",
+		"func (Customer) WitKind() abi.Kind { return abi.KindRecord }",
+		"type AppSome struct",
+		"type Color uint8",
+		"func (Color) WitKind() abi.Kind { return abi.KindEnum }",
+		"type Option[T any] = abi.Option[T]",
+		"s AppSome",
+		") (result Color, err error)",
+	} {
+		assert.Contains(t, code, expected)
+	}
+
+	_, err = newNamer([]wit.WitWorldDefinition{world}, GenerateOptions{Idiomatic: true, Names: map[string]string{"customer": "ParseColor"}})
+	assert.EqualError(t, err, "type app#customer and type app#color are both named ParseColor, set a name override for one of them")
+}
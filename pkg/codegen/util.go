@@ -2,6 +2,10 @@ package codegen
 
 import (
 	"math"
+	"strings"
+
+	"github.com/moznion/gowrtr/generator"
+	"github.com/rioam2/witigo/pkg/wit"
 )
 
 // discriminantSize returns the size in bits of the discriminant needed to represent n cases.
@@ -16,3 +20,28 @@ func discriminantSize(n int) int {
 		return 32
 	}
 }
+
+// docComment returns the lines of a Go comment holding WIT docs, which is empty if there are no
+// docs.
+func docComment(docs string) *generator.Root {
+	root := generator.NewRoot()
+	if docs == "" {
+		return root
+	}
+	for _, line := range strings.Split(docs, "\n") {
+		if line != "" {
+			line = " " + line
+		}
+		root = root.AddStatements(generator.NewComment(line))
+	}
+	return root
+}
+
+// typeDocs returns the docs of a type, or of the type it is an alias of if it has none, so that
+// types brought in scope by `use` are documented like their definition.
+func typeDocs(t wit.WitType) string {
+	for t.Docs() == "" && t.AliasOf() != nil {
+		t = t.AliasOf()
+	}
+	return t.Docs()
+}
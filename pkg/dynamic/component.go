@@ -0,0 +1,290 @@
+// Package dynamic invokes the exports of WebAssembly components whose interfaces are only known at
+// runtime. Instead of generated Go types, arguments and results are represented by the Value tree
+// and lifted or lowered according to the WIT definition extracted from the component.
+package dynamic
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	witigo "github.com/rioam2/witigo/pkg"
+	"github.com/rioam2/witigo/pkg/abi"
+	"github.com/rioam2/witigo/pkg/wasmtools"
+	"github.com/rioam2/witigo/pkg/wit"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// Component is a WebAssembly component that has been decoded but not yet instantiated.
+type Component struct {
+	Definition wit.WitDefinition
+	World      wit.WitWorldDefinition
+	CoreModule []byte
+}
+
+// LoadFile decodes the WIT definition and core module of the component at path. world selects the
+// world of the component like wit.WitDefinition.World: by name or as `namespace:package/world`, or
+// the only world of the root package when empty.
+func LoadFile(path string, world string) (*Component, error) {
+	witJson, name, err := wasmtools.ExtractComponentWitJson(path)
+	if err != nil {
+		return nil, err
+	}
+	definition, err := wit.NewFromJson(witJson, name)
+	if err != nil {
+		return nil, err
+	}
+	selected, err := definition.World(world)
+	if err != nil {
+		return nil, fmt.Errorf("component %s: %w", path, err)
+	}
+	coreModule, err := wasmtools.ExtractComponentCoreModule(path)
+	if err != nil {
+		return nil, fmt.Errorf("error extracting core module: %w", err)
+	}
+	return &Component{Definition: definition, World: selected, CoreModule: coreModule}, nil
+}
+
+// Load decodes the WIT definition and core module of a component from its binary encoding, for the
+// world selected like LoadFile.
+func Load(component []byte, world string) (*Component, error) {
+	dir, err := os.MkdirTemp("", "witigo-dynamic-*")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temporary directory for component: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "component.wasm")
+	if err := os.WriteFile(path, component, 0600); err != nil {
+		return nil, fmt.Errorf("error writing component to %s: %w", path, err)
+	}
+	return LoadFile(path, world)
+}
+
+// Instance is an instantiated component whose exports can be called by name.
+type Instance struct {
+	component *Component
+	runtime   wazero.Runtime
+	module    api.Module
+	abiOpts   abi.AbiOptions
+	functions map[string]wit.WitFunction
+}
+
+// Instantiate compiles and instantiates the core module of the component with wazero.
+func (c *Component) Instantiate(ctx context.Context) (*Instance, error) {
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+		r.Close(ctx)
+		return nil, fmt.Errorf("failed to instantiate WASI: %w", err)
+	}
+	cm, err := r.CompileModule(ctx, c.CoreModule)
+	if err != nil {
+		r.Close(ctx)
+		return nil, fmt.Errorf("failed to compile module: %w", err)
+	}
+	module, err := r.InstantiateModule(ctx, cm, wazero.NewModuleConfig().WithName(""))
+	if err != nil {
+		r.Close(ctx)
+		return nil, fmt.Errorf("failed to instantiate module: %w", err)
+	}
+	// Reactors, like components written in Go, are initialized before their exports are called.
+	if initialize := module.ExportedFunction("_initialize"); initialize != nil {
+		if _, err := initialize.Call(ctx); err != nil {
+			r.Close(ctx)
+			return nil, fmt.Errorf("failed to initialize module: %w", err)
+		}
+	}
+	functions := map[string]wit.WitFunction{}
+	for _, f := range c.World.ExportedFunctions() {
+		functions[f.Name()] = f
+	}
+	return &Instance{
+		component: c,
+		runtime:   r,
+		module:    module,
+		functions: functions,
+		abiOpts: abi.AbiOptions{
+			StringEncoding: abi.StringEncodingUTF8,
+			Memory:         abi.GetRuntimeMemoryFromWazero(module),
+			Context:        ctx,
+			Call:           abi.GetRuntimeCallFromWazero(module),
+		},
+	}, nil
+}
+
+// New loads the component at path for the world selected like LoadFile, and instantiates it.
+func New(ctx context.Context, path string, world string) (*Instance, error) {
+	component, err := LoadFile(path, world)
+	if err != nil {
+		return nil, err
+	}
+	return component.Instantiate(ctx)
+}
+
+// NewWithOptions creates an instance on top of an existing runtime, for example the fake runtime
+// from pkg/abi/abitest. Close is a no-op for such instances.
+func NewWithOptions(world wit.WitWorldDefinition, opts abi.AbiOptions) *Instance {
+	functions := map[string]wit.WitFunction{}
+	for _, f := range world.ExportedFunctions() {
+		functions[f.Name()] = f
+	}
+	return &Instance{component: &Component{World: world}, abiOpts: opts, functions: functions}
+}
+
+// World returns the world definition of the instantiated component.
+func (i *Instance) World() wit.WitWorldDefinition {
+	return i.component.World
+}
+
+// Function returns the exported function with the given name.
+func (i *Instance) Function(name string) (wit.WitFunction, bool) {
+	f, ok := i.functions[name]
+	return f, ok
+}
+
+// Close releases the wazero runtime backing the instance.
+func (i *Instance) Close(ctx context.Context) error {
+	if i.runtime == nil {
+		return nil
+	}
+	return i.runtime.Close(ctx)
+}
+
+// SetHooks registers hooks that observe the calls made through the instance.
+func (i *Instance) SetHooks(hooks abi.Hooks) {
+	i.abiOpts.Hooks = hooks
+}
+
+// Call invokes the exported function with the given name. Arguments are lowered according to the
+// WIT parameter types of the function, and the lifted result (if any) is returned.
+func (i *Instance) Call(ctx context.Context, name string, args ...Value) (results []Value, err error) {
+	opts := i.abiOpts
+	opts.Context = ctx
+	traceArgs := make([]any, len(args))
+	for idx, arg := range args {
+		traceArgs[idx] = arg
+	}
+	done := abi.TraceCall(opts, name, traceArgs...)
+	defer func() {
+		var result any
+		if len(results) > 0 {
+			result = results[0]
+		}
+		done(result, err)
+	}()
+
+	f, ok := i.functions[name]
+	if !ok {
+		return nil, fmt.Errorf("component does not export function %s", name)
+	}
+	params := f.Params()
+	if len(args) != len(params) {
+		return nil, fmt.Errorf("function %s expects %d arguments, got %d", name, len(params), len(args))
+	}
+	paramTypes := make([]wit.WitType, len(params))
+	for idx, param := range params {
+		paramTypes[idx] = param.Type()
+		if err := checkSupported(paramTypes[idx]); err != nil {
+			return nil, fmt.Errorf("parameter %s of %s: %w", param.Name(), name, err)
+		}
+	}
+	resultType := f.Returns()
+	if resultType != nil {
+		if err := checkSupported(resultType); err != nil {
+			return nil, fmt.Errorf("result of %s: %w", name, err)
+		}
+	}
+
+	l := &lowering{opts: opts}
+	defer l.free()
+
+	flatParams, err := l.lowerParams(paramTypes, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write parameters: %w", err)
+	}
+	ret, postReturn, err := abi.Call(opts, name, flatParams...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", name, err)
+	}
+	defer postReturn()
+
+	if resultType == nil {
+		return []Value{}, nil
+	}
+	resultFlat, err := flatten(resultType)
+	if err != nil {
+		return nil, fmt.Errorf("result of %s: %w", name, err)
+	}
+	var result Value
+	if len(resultFlat) > abi.MAX_FLAT_RESULTS {
+		result, err = load(opts, resultType, ret)
+	} else {
+		result, err = liftFlat(opts, resultType, &[]uint64{ret})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result: %w", err)
+	}
+	return []Value{result}, nil
+}
+
+// lowerParams lowers the arguments of a call, spilling them into linear memory when they do not
+// fit into MAX_FLAT_PARAMS flat values.
+func (l *lowering) lowerParams(types []wit.WitType, args []Value) ([]uint64, error) {
+	flatCount := 0
+	for _, t := range types {
+		flat, err := flatten(t)
+		if err != nil {
+			return nil, err
+		}
+		flatCount += len(flat)
+	}
+	if flatCount <= abi.MAX_FLAT_PARAMS {
+		flat := []uint64{}
+		for idx, arg := range args {
+			argFlat, err := l.lowerFlat(arg, types[idx])
+			if err != nil {
+				return nil, fmt.Errorf("parameter %d: %w", idx, err)
+			}
+			flat = append(flat, argFlat...)
+		}
+		return flat, nil
+	}
+
+	tuple := &tupleType{elems: types}
+	size, alignment, err := layoutOf(tuple)
+	if err != nil {
+		return nil, err
+	}
+	ptr, err := l.malloc(size, alignment)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.store(Tuple(args), tuple, ptr); err != nil {
+		return nil, err
+	}
+	return []uint64{ptr}, nil
+}
+
+// checkSupported returns an error if t (or a type nested in it) cannot be lifted or lowered.
+func checkSupported(t wit.WitType) error {
+	if t == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case witigo.AbiTypeHandle, witigo.AbiTypeResource, witigo.AbiTypeOwn, witigo.AbiTypeBorrow,
+		witigo.AbiTypeStream, witigo.AbiTypeFuture, witigo.AbiTypeErrorContext:
+		return unsupportedError(t)
+	case witigo.AbiTypeList, witigo.AbiTypeOption:
+		return checkSupported(t.SubType().Type())
+	case witigo.AbiTypeRecord, witigo.AbiTypeTuple, witigo.AbiTypeVariant, witigo.AbiTypeResult:
+		for _, ref := range t.SubTypes() {
+			if err := checkSupported(ref.Type()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package dynamic_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/rioam2/witigo/pkg/codegen"
+	"github.com/rioam2/witigo/pkg/dynamic"
+	"github.com/rioam2/witigo/pkg/wit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testdata/calc.wasm is built from testdata/calc.wat and testdata/calc.wit (see calc.wat).
+func TestCallComponent(t *testing.T) {
+	ctx := context.Background()
+	instance, err := dynamic.New(ctx, "testdata/calc.wasm", "")
+	require.NoError(t, err)
+	defer instance.Close(ctx)
+
+	t.Run("flat params and result", func(t *testing.T) {
+		results, err := instance.Call(ctx, "add", dynamic.U32(2), dynamic.U32(40))
+		require.NoError(t, err)
+		assert.Equal(t, []dynamic.Value{dynamic.U32(42)}, results)
+	})
+
+	t.Run("result returned through memory", func(t *testing.T) {
+		results, err := instance.Call(ctx, "echo", dynamic.String("hello, 世界"))
+		require.NoError(t, err)
+		assert.Equal(t, []dynamic.Value{dynamic.String("hello, 世界")}, results)
+	})
+
+	t.Run("params spilled to memory", func(t *testing.T) {
+		args := make([]dynamic.Value, 17)
+		for i := range args {
+			args[i] = dynamic.U32(i + 1)
+		}
+		results, err := instance.Call(ctx, "sum", args...)
+		require.NoError(t, err)
+		assert.Equal(t, []dynamic.Value{dynamic.U32(153)}, results)
+	})
+
+	t.Run("option result", func(t *testing.T) {
+		results, err := instance.Call(ctx, "classify", dynamic.S32(7))
+		require.NoError(t, err)
+		assert.Equal(t, []dynamic.Value{dynamic.Some(dynamic.U8(7))}, results)
+
+		results, err = instance.Call(ctx, "classify", dynamic.S32(-1))
+		require.NoError(t, err)
+		assert.Equal(t, []dynamic.Value{dynamic.None()}, results)
+	})
+
+	t.Run("argument errors", func(t *testing.T) {
+		_, err := instance.Call(ctx, "missing")
+		assert.ErrorContains(t, err, "does not export function missing")
+
+		_, err = instance.Call(ctx, "add", dynamic.U32(1))
+		assert.ErrorContains(t, err, "expects 2 arguments, got 1")
+
+		_, err = instance.Call(ctx, "add", dynamic.U32(1), dynamic.String("2"))
+		assert.ErrorContains(t, err, "expected u32 value, got string")
+	})
+}
+
+func TestLoadFileWorld(t *testing.T) {
+	// The WIT definition extracted from a component describes its world as root:component/root.
+	component, err := dynamic.LoadFile("testdata/calc.wasm", "root:component/root")
+	require.NoError(t, err)
+	assert.Equal(t, "root", component.World.Name())
+
+	_, err = dynamic.LoadFile("testdata/calc.wasm", "missing")
+	assert.ErrorContains(t, err, "component testdata/calc.wasm: ")
+	assert.ErrorContains(t, err, "missing")
+}
+
+// TestCallGoComponent calls a component written in Go, whose runtime is initialized by
+// `_initialize` before its exports are called.
+func TestCallGoComponent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles a component written in Go")
+	}
+	// The go command ignores testdata directories in patterns, so it does not build the guest otherwise.
+	dir, err := os.MkdirTemp("testdata", "guest-")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	witSource := `package test:greeter;
+
+world greeter {
+  export greet: func(name: string) -> string;
+}
+`
+	witPath := filepath.Join(dir, "greeter.wit")
+	require.NoError(t, os.WriteFile(witPath, []byte(witSource), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import "github.com/rioam2/witigo/pkg/dynamic/`+filepath.ToSlash(dir)+`/greeter"
+
+// greetings is set by the initialization of the package, which _initialize runs.
+var greetings = map[string]string{"en": "hello"}
+
+type impl struct{}
+
+func (impl) Greet(name string) string { return greetings["en"] + ", " + name + "!" }
+
+func init() { greeter.SetExports(impl{}) }
+
+func main() {}
+`), 0o644))
+	err = codegen.GenerateFromFileWithOptions(witPath, filepath.Join(dir, "greeter"), codegen.GenerateOptions{PackageName: "greeter", Guest: true})
+	require.NoError(t, err)
+	coreModule := filepath.Join(dir, "greeter.wasm")
+	cmd := exec.Command("go", "build", "-buildmode=c-shared", "-o", coreModule, "./"+dir)
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "%s", out)
+
+	def, err := wit.Parse(witPath, witSource)
+	require.NoError(t, err)
+	world, err := def.World("")
+	require.NoError(t, err)
+	module, err := os.ReadFile(coreModule)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	component := &dynamic.Component{Definition: def, World: world, CoreModule: module}
+	instance, err := component.Instantiate(ctx)
+	require.NoError(t, err)
+	defer instance.Close(ctx)
+	for range 10 {
+		results, err := instance.Call(ctx, "greet", dynamic.String("world"))
+		require.NoError(t, err)
+		assert.Equal(t, []dynamic.Value{dynamic.String("hello, world!")}, results)
+	}
+}
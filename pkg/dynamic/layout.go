@@ -0,0 +1,257 @@
+package dynamic
+
+import (
+	"fmt"
+
+	witigo "github.com/rioam2/witigo/pkg"
+	"github.com/rioam2/witigo/pkg/abi"
+	"github.com/rioam2/witigo/pkg/wit"
+)
+
+// flatType is a core WebAssembly value type used by the flattened calling convention.
+type flatType int
+
+const (
+	flatI32 flatType = iota
+	flatI64
+	flatF32
+	flatF64
+)
+
+// discriminantSize returns the size in bytes of a discriminant able to represent n cases.
+func discriminantSize(n int) uint64 {
+	switch {
+	case n <= 1<<8:
+		return 1
+	case n <= 1<<16:
+		return 2
+	default:
+		return 4
+	}
+}
+
+// flagsSize returns the size in bytes of a flags value with n flags.
+func flagsSize(n int) uint64 {
+	switch {
+	case n == 0:
+		return 0
+	case n <= 8:
+		return 1
+	case n <= 16:
+		return 2
+	default:
+		return 4 * uint64((n+31)/32)
+	}
+}
+
+// flagsFlatCount returns the number of flat values of a flags value with n flags.
+func flagsFlatCount(n int) int {
+	return (n + 31) / 32
+}
+
+// unsupportedError returns the error of a type whose values cannot be lifted or lowered.
+func unsupportedError(t wit.WitType) error {
+	return fmt.Errorf("%s types are not supported", t.Kind())
+}
+
+// caseTypes returns the payload types of a variant-like type (variant, option or result), or nil
+// for other types. A nil entry represents a case without a payload.
+func caseTypes(t wit.WitType) []wit.WitType {
+	switch t.Kind() {
+	case witigo.AbiTypeOption:
+		return []wit.WitType{nil, t.SubType().Type()}
+	case witigo.AbiTypeVariant, witigo.AbiTypeResult:
+		refs := t.SubTypes()
+		types := make([]wit.WitType, len(refs))
+		for i, ref := range refs {
+			types[i] = ref.Type()
+		}
+		return types
+	default:
+		return nil
+	}
+}
+
+// fieldTypes returns the element types of a record or tuple.
+func fieldTypes(t wit.WitType) []wit.WitType {
+	refs := t.SubTypes()
+	types := make([]wit.WitType, len(refs))
+	for i, ref := range refs {
+		types[i] = ref.Type()
+	}
+	return types
+}
+
+// layoutOf returns the size and alignment of values of type t in linear memory.
+func layoutOf(t wit.WitType) (size uint64, alignment uint64, err error) {
+	switch t.Kind() {
+	case witigo.AbiTypeBool, witigo.AbiTypeS8, witigo.AbiTypeU8:
+		return 1, 1, nil
+	case witigo.AbiTypeS16, witigo.AbiTypeU16:
+		return 2, 2, nil
+	case witigo.AbiTypeS32, witigo.AbiTypeU32, witigo.AbiTypeF32, witigo.AbiTypeChar:
+		return 4, 4, nil
+	case witigo.AbiTypeS64, witigo.AbiTypeU64, witigo.AbiTypeF64:
+		return 8, 8, nil
+	case witigo.AbiTypeString, witigo.AbiTypeList:
+		return 8, 4, nil
+	case witigo.AbiTypeRecord, witigo.AbiTypeTuple:
+		alignment = 1
+		for _, field := range fieldTypes(t) {
+			fieldSize, fieldAlignment, err := layoutOf(field)
+			if err != nil {
+				return 0, 0, err
+			}
+			size = abi.AlignTo(size, fieldAlignment) + fieldSize
+			alignment = max(alignment, fieldAlignment)
+		}
+		return abi.AlignTo(size, alignment), alignment, nil
+	case witigo.AbiTypeVariant, witigo.AbiTypeOption, witigo.AbiTypeResult:
+		offset, payloadSize, alignment, err := variantLayout(t)
+		if err != nil {
+			return 0, 0, err
+		}
+		return abi.AlignTo(offset+payloadSize, alignment), alignment, nil
+	case witigo.AbiTypeEnum:
+		size = discriminantSize(len(t.SubTypes()))
+		return size, size, nil
+	case witigo.AbiTypeFlags:
+		size = flagsSize(len(t.SubTypes()))
+		return size, max(min(size, 4), 1), nil
+	default:
+		return 0, 0, unsupportedError(t)
+	}
+}
+
+// variantLayout returns the offset from its start and the size of the payload of a variant-like
+// type, and its alignment.
+func variantLayout(t wit.WitType) (payloadOffset uint64, payloadSize uint64, alignment uint64, err error) {
+	cases := caseTypes(t)
+	discriminant := discriminantSize(len(cases))
+	alignment = discriminant
+	for _, c := range cases {
+		if c == nil {
+			continue
+		}
+		caseSize, caseAlignment, err := layoutOf(c)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		payloadSize = max(payloadSize, caseSize)
+		alignment = max(alignment, caseAlignment)
+	}
+	return abi.AlignTo(discriminant, alignment), payloadSize, alignment, nil
+}
+
+// flatten returns the flat types of the flattened representation of values of type t.
+func flatten(t wit.WitType) ([]flatType, error) {
+	switch t.Kind() {
+	case witigo.AbiTypeBool, witigo.AbiTypeS8, witigo.AbiTypeU8, witigo.AbiTypeS16, witigo.AbiTypeU16,
+		witigo.AbiTypeS32, witigo.AbiTypeU32, witigo.AbiTypeChar, witigo.AbiTypeEnum:
+		return []flatType{flatI32}, nil
+	case witigo.AbiTypeS64, witigo.AbiTypeU64:
+		return []flatType{flatI64}, nil
+	case witigo.AbiTypeF32:
+		return []flatType{flatF32}, nil
+	case witigo.AbiTypeF64:
+		return []flatType{flatF64}, nil
+	case witigo.AbiTypeString, witigo.AbiTypeList:
+		return []flatType{flatI32, flatI32}, nil
+	case witigo.AbiTypeRecord, witigo.AbiTypeTuple:
+		flat := []flatType{}
+		for _, field := range fieldTypes(t) {
+			fieldFlat, err := flatten(field)
+			if err != nil {
+				return nil, err
+			}
+			flat = append(flat, fieldFlat...)
+		}
+		return flat, nil
+	case witigo.AbiTypeVariant, witigo.AbiTypeOption, witigo.AbiTypeResult:
+		payload := []flatType{}
+		for _, c := range caseTypes(t) {
+			if c == nil {
+				continue
+			}
+			caseFlat, err := flatten(c)
+			if err != nil {
+				return nil, err
+			}
+			for i, ft := range caseFlat {
+				if i < len(payload) {
+					payload[i] = join(payload[i], ft)
+				} else {
+					payload = append(payload, ft)
+				}
+			}
+		}
+		return append([]flatType{flatI32}, payload...), nil
+	case witigo.AbiTypeFlags:
+		flat := make([]flatType, flagsFlatCount(len(t.SubTypes())))
+		for i := range flat {
+			flat[i] = flatI32
+		}
+		return flat, nil
+	default:
+		return nil, unsupportedError(t)
+	}
+}
+
+// join returns the flat type able to hold values of both a and b.
+func join(a, b flatType) flatType {
+	if a == b {
+		return a
+	}
+	if (a == flatI32 && b == flatF32) || (a == flatF32 && b == flatI32) {
+		return flatI32
+	}
+	return flatI64
+}
+
+// tupleType is a synthetic tuple of the given element types, used to lay out spilled parameters.
+type tupleType struct {
+	elems []wit.WitType
+}
+
+var _ wit.WitType = &tupleType{}
+
+func (t *tupleType) Name() string                       { return "(none)" }
+func (t *tupleType) Kind() witigo.AbiType               { return witigo.AbiTypeTuple }
+func (t *tupleType) Docs() string                       { return "" }
+func (t *tupleType) Stability() *wit.Stability          { return nil }
+func (t *tupleType) Owner() *string                     { return nil }
+func (t *tupleType) OwnerInterface() wit.WitInterface   { return nil }
+func (t *tupleType) OwnerWorld() wit.WitWorldDefinition { return nil }
+func (t *tupleType) AliasOf() wit.WitType               { return nil }
+func (t *tupleType) IsBorrow() bool                     { return false }
+func (t *tupleType) SubType() wit.WitTypeReference      { return nil }
+func (t *tupleType) IsPrimitive() bool                  { return false }
+
+func (t *tupleType) SubTypes() []wit.WitTypeReference {
+	refs := make([]wit.WitTypeReference, len(t.elems))
+	for i, elem := range t.elems {
+		refs[i] = &typeRef{t: elem}
+	}
+	return refs
+}
+
+func (t *tupleType) String() string {
+	s := "tuple<"
+	for i, elem := range t.elems {
+		if i > 0 {
+			s += ", "
+		}
+		s += elem.String()
+	}
+	return s + ">"
+}
+
+// typeRef is an unnamed reference to a type.
+type typeRef struct {
+	t wit.WitType
+}
+
+func (r *typeRef) Name() string      { return "(none)" }
+func (r *typeRef) Type() wit.WitType { return r.t }
+func (r *typeRef) Docs() string      { return "" }
+func (r *typeRef) String() string    { return r.t.String() }
@@ -0,0 +1,46 @@
+package dynamic
+
+import (
+	"testing"
+
+	"github.com/rioam2/witigo/pkg/wit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayout(t *testing.T) {
+	def, err := wit.Parse("layout.wit", `package test:layout;
+
+world layout {
+  resource counter;
+  record point { x: u8, y: u64 }
+  variant shape { dot, circle(f32), named(string) }
+  export f: func(p: point, s: shape, c: counter);
+}
+`)
+	require.NoError(t, err)
+	world, err := def.World("")
+	require.NoError(t, err)
+	params := world.ExportedFunctions()[0].Params()
+	point, shape, counter := params[0].Type(), params[1].Type(), params[2].Type()
+
+	size, alignment, err := layoutOf(point)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{16, 8}, []uint64{size, alignment})
+	flat, err := flatten(point)
+	require.NoError(t, err)
+	assert.Equal(t, []flatType{flatI32, flatI64}, flat)
+
+	size, alignment, err = layoutOf(shape)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{12, 4}, []uint64{size, alignment})
+	flat, err = flatten(shape)
+	require.NoError(t, err)
+	assert.Equal(t, []flatType{flatI32, flatI32, flatI32}, flat)
+
+	// Unsupported types are reported instead of laid out.
+	_, _, err = layoutOf(&tupleType{elems: []wit.WitType{point, counter}})
+	assert.ErrorContains(t, err, "types are not supported")
+	_, err = flatten(&tupleType{elems: []wit.WitType{counter}})
+	assert.ErrorContains(t, err, "types are not supported")
+}
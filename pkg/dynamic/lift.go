@@ -0,0 +1,308 @@
+package dynamic
+
+import (
+	"fmt"
+	"math"
+	"unicode/utf8"
+
+	witigo "github.com/rioam2/witigo/pkg"
+	"github.com/rioam2/witigo/pkg/abi"
+	"github.com/rioam2/witigo/pkg/wit"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// load reads a value of type t from linear memory at ptr using the canonical ABI memory layout.
+func load(opts abi.AbiOptions, t wit.WitType, ptr uint64) (Value, error) {
+	switch t.Kind() {
+	case witigo.AbiTypeString:
+		var s string
+		if err := abi.ReadString(opts, ptr, &s); err != nil {
+			return nil, err
+		}
+		return String(s), nil
+	case witigo.AbiTypeList:
+		dataPtr, ok := opts.Memory.ReadUint32Le(ptr)
+		if !ok {
+			return nil, &abi.MemoryError{Op: "read", What: "list data pointer", Ptr: ptr, Size: 4}
+		}
+		length, ok := opts.Memory.ReadUint32Le(ptr + 4)
+		if !ok {
+			return nil, &abi.MemoryError{Op: "read", What: "list length", Ptr: ptr + 4, Size: 4}
+		}
+		return loadList(opts, t.SubType().Type(), uint64(dataPtr), uint64(length))
+	case witigo.AbiTypeRecord, witigo.AbiTypeTuple:
+		values := []Value{}
+		offset := uint64(0)
+		for i, field := range fieldTypes(t) {
+			size, alignment, err := layoutOf(field)
+			if err != nil {
+				return nil, err
+			}
+			offset = abi.AlignTo(offset, alignment)
+			value, err := load(opts, field, ptr+offset)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load field %d at %d: %w", i, ptr+offset, err)
+			}
+			values = append(values, value)
+			offset += size
+		}
+		return aggregate(t, values), nil
+	case witigo.AbiTypeVariant, witigo.AbiTypeOption, witigo.AbiTypeResult:
+		cases := caseTypes(t)
+		discriminant, err := readUint(opts, ptr, discriminantSize(len(cases)))
+		if err != nil {
+			return nil, err
+		}
+		if discriminant >= uint64(len(cases)) {
+			return nil, &abi.DiscriminantError{Kind: t.Kind().String(), Value: discriminant, Cases: len(cases)}
+		}
+		payloadOffset, _, _, err := variantLayout(t)
+		if err != nil {
+			return nil, err
+		}
+		var payload Value
+		if caseType := cases[discriminant]; caseType != nil {
+			payload, err = load(opts, caseType, ptr+payloadOffset)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load variant payload: %w", err)
+			}
+		}
+		return variant(t, int(discriminant), payload), nil
+	case witigo.AbiTypeFlags:
+		size := flagsSize(len(t.SubTypes()))
+		bits := uint64(0)
+		for offset := uint64(0); offset < size; offset += 4 {
+			chunk, err := readUint(opts, ptr+offset, min(size-offset, 4))
+			if err != nil {
+				return nil, err
+			}
+			bits |= chunk << (8 * offset)
+		}
+		return flags(t, bits), nil
+	default:
+		size, _, err := layoutOf(t)
+		if err != nil {
+			return nil, err
+		}
+		bits, err := readUint(opts, ptr, size)
+		if err != nil {
+			return nil, err
+		}
+		return scalar(t, bits)
+	}
+}
+
+// loadList reads length consecutive elements of type elemType starting at ptr.
+func loadList(opts abi.AbiOptions, elemType wit.WitType, ptr uint64, length uint64) (List, error) {
+	elemSize, _, err := layoutOf(elemType)
+	if err != nil {
+		return nil, err
+	}
+	list := make(List, length)
+	for i := range length {
+		elem, err := load(opts, elemType, ptr+i*elemSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load element %d at %d: %w", i, ptr+i*elemSize, err)
+		}
+		list[i] = elem
+	}
+	return list, nil
+}
+
+// liftFlat lifts a value of type t from its flattened core WebAssembly representation. The
+// consumed flat values are removed from the front of flat.
+func liftFlat(opts abi.AbiOptions, t wit.WitType, flat *[]uint64) (Value, error) {
+	next := func() (uint64, error) {
+		if len(*flat) == 0 {
+			return 0, fmt.Errorf("not enough flat values to lift %s", t.Kind())
+		}
+		v := (*flat)[0]
+		*flat = (*flat)[1:]
+		return v, nil
+	}
+	switch t.Kind() {
+	case witigo.AbiTypeString, witigo.AbiTypeList:
+		ptr, err := next()
+		if err != nil {
+			return nil, err
+		}
+		length, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if t.Kind() == witigo.AbiTypeList {
+			return loadList(opts, t.SubType().Type(), uint64(uint32(ptr)), uint64(uint32(length)))
+		}
+		return liftString(opts, uint64(uint32(ptr)), uint64(uint32(length)))
+	case witigo.AbiTypeRecord, witigo.AbiTypeTuple:
+		values := []Value{}
+		for i, field := range fieldTypes(t) {
+			value, err := liftFlat(opts, field, flat)
+			if err != nil {
+				return nil, fmt.Errorf("failed to lift field %d: %w", i, err)
+			}
+			values = append(values, value)
+		}
+		return aggregate(t, values), nil
+	case witigo.AbiTypeVariant, witigo.AbiTypeOption, witigo.AbiTypeResult:
+		cases := caseTypes(t)
+		variantFlat, err := flatten(t)
+		if err != nil {
+			return nil, err
+		}
+		payloadCount := len(variantFlat) - 1
+		discriminant, err := next()
+		if err != nil {
+			return nil, err
+		}
+		discriminant = uint64(uint32(discriminant))
+		if discriminant >= uint64(len(cases)) {
+			return nil, &abi.DiscriminantError{Kind: t.Kind().String(), Value: discriminant, Cases: len(cases)}
+		}
+		if len(*flat) < payloadCount {
+			return nil, fmt.Errorf("not enough flat values to lift %s", t.Kind())
+		}
+		payloadFlat := append([]uint64(nil), (*flat)[:payloadCount]...)
+		*flat = (*flat)[payloadCount:]
+		var payload Value
+		if caseType := cases[discriminant]; caseType != nil {
+			payload, err = liftFlat(opts, caseType, &payloadFlat)
+			if err != nil {
+				return nil, fmt.Errorf("failed to lift variant payload: %w", err)
+			}
+		}
+		return variant(t, int(discriminant), payload), nil
+	case witigo.AbiTypeFlags:
+		bits := uint64(0)
+		for i := range flagsFlatCount(len(t.SubTypes())) {
+			chunk, err := next()
+			if err != nil {
+				return nil, err
+			}
+			bits |= uint64(uint32(chunk)) << (32 * i)
+		}
+		return flags(t, bits), nil
+	default:
+		bits, err := next()
+		if err != nil {
+			return nil, err
+		}
+		scalarFlat, err := flatten(t)
+		if err != nil {
+			return nil, err
+		}
+		if scalarFlat[0] == flatI32 {
+			bits = uint64(uint32(bits))
+		}
+		return scalar(t, bits)
+	}
+}
+
+// liftString decodes a string of the given number of code units stored at ptr.
+func liftString(opts abi.AbiOptions, ptr uint64, codeUnits uint64) (Value, error) {
+	byteLength := codeUnits * opts.StringEncoding.CodeUnitSize()
+	data, ok := opts.Memory.Read(ptr, byteLength)
+	if !ok {
+		return nil, &abi.MemoryError{Op: "read", What: fmt.Sprintf("%d bytes of string data", byteLength), Ptr: ptr, Size: byteLength}
+	}
+	if opts.StringEncoding == abi.StringEncodingUTF16 {
+		decoder := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewDecoder()
+		str, err := decoder.String(string(data))
+		if err != nil {
+			return nil, err
+		}
+		return String(str), nil
+	}
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("string data at %d: %w", ptr, abi.ErrInvalidUTF8)
+	}
+	return String(data), nil
+}
+
+// scalar converts the bit pattern of a primitive or enum value into a Value.
+func scalar(t wit.WitType, bits uint64) (Value, error) {
+	switch t.Kind() {
+	case witigo.AbiTypeBool:
+		return Bool(bits != 0), nil
+	case witigo.AbiTypeS8:
+		return S8(int8(bits)), nil
+	case witigo.AbiTypeS16:
+		return S16(int16(bits)), nil
+	case witigo.AbiTypeS32:
+		return S32(int32(bits)), nil
+	case witigo.AbiTypeS64:
+		return S64(int64(bits)), nil
+	case witigo.AbiTypeU8:
+		return U8(bits), nil
+	case witigo.AbiTypeU16:
+		return U16(bits), nil
+	case witigo.AbiTypeU32:
+		return U32(bits), nil
+	case witigo.AbiTypeU64:
+		return U64(bits), nil
+	case witigo.AbiTypeF32:
+		return F32(math.Float32frombits(uint32(bits))), nil
+	case witigo.AbiTypeF64:
+		return F64(math.Float64frombits(bits)), nil
+	case witigo.AbiTypeChar:
+		if bits > math.MaxInt32 || !utf8.ValidRune(rune(bits)) {
+			return nil, fmt.Errorf("char %d is not a valid unicode scalar value", bits)
+		}
+		return Char(rune(bits)), nil
+	case witigo.AbiTypeEnum:
+		cases := t.SubTypes()
+		if bits >= uint64(len(cases)) {
+			return nil, &abi.DiscriminantError{Kind: "enum", Value: bits, Cases: len(cases)}
+		}
+		return Enum(cases[bits].Name()), nil
+	default:
+		return nil, fmt.Errorf("lifting %s is not implemented", t.Kind())
+	}
+}
+
+// aggregate builds a Record or Tuple value from field values in declaration order.
+func aggregate(t wit.WitType, values []Value) Value {
+	if t.Kind() == witigo.AbiTypeTuple {
+		return Tuple(values)
+	}
+	record := make(Record, len(values))
+	for i, field := range t.SubTypes() {
+		record[i] = Field{Name: field.Name(), Value: values[i]}
+	}
+	return record
+}
+
+// variant builds a Variant, Option or Result value from a case index and payload.
+func variant(t wit.WitType, caseIndex int, payload Value) Value {
+	switch t.Kind() {
+	case witigo.AbiTypeOption:
+		return Option{Value: payload}
+	case witigo.AbiTypeResult:
+		return Result{IsErr: caseIndex == 1, Value: payload}
+	default:
+		return Variant{Case: t.SubTypes()[caseIndex].Name(), Value: payload}
+	}
+}
+
+// flags builds a Flags value from a bit vector.
+func flags(t wit.WitType, bits uint64) Value {
+	set := Flags{}
+	for i, name := range t.SubTypes() {
+		if i < 64 && bits&(1<<i) != 0 {
+			set = append(set, name.Name())
+		}
+	}
+	return set
+}
+
+func readUint(opts abi.AbiOptions, ptr uint64, size uint64) (uint64, error) {
+	bytes, ok := opts.Memory.Read(ptr, size)
+	if !ok {
+		return 0, &abi.MemoryError{Op: "read", What: fmt.Sprintf("%d bytes", size), Ptr: ptr, Size: size}
+	}
+	value := uint64(0)
+	for i, b := range bytes {
+		value |= uint64(b) << (8 * i)
+	}
+	return value, nil
+}
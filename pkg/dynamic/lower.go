@@ -0,0 +1,359 @@
+package dynamic
+
+import (
+	"fmt"
+	"math"
+	"unicode/utf8"
+
+	witigo "github.com/rioam2/witigo/pkg"
+	"github.com/rioam2/witigo/pkg/abi"
+	"github.com/rioam2/witigo/pkg/wit"
+)
+
+// lowering accumulates the free callbacks of every allocation made while lowering values.
+type lowering struct {
+	opts          abi.AbiOptions
+	freeCallbacks []abi.AbiFreeCallback
+}
+
+func (l *lowering) free() error {
+	for _, cb := range l.freeCallbacks {
+		if err := cb(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *lowering) malloc(size, alignment uint64) (uint64, error) {
+	ptr, free, err := abi.Malloc(l.opts, size, alignment)
+	if err != nil {
+		return 0, err
+	}
+	l.freeCallbacks = append(l.freeCallbacks, free)
+	return ptr, nil
+}
+
+// lowerFlat lowers a value into its flattened core WebAssembly representation.
+func (l *lowering) lowerFlat(v Value, t wit.WitType) ([]uint64, error) {
+	if err := checkKind(v, t); err != nil {
+		return nil, err
+	}
+	switch t.Kind() {
+	case witigo.AbiTypeString:
+		params, free, err := abi.WriteParameterString(l.opts, string(v.(String)))
+		l.freeCallbacks = append(l.freeCallbacks, free)
+		if err != nil {
+			return nil, err
+		}
+		return []uint64{params[0].Value, params[1].Value}, nil
+	case witigo.AbiTypeList:
+		ptr, length, err := l.lowerList(v.(List), t.SubType().Type())
+		if err != nil {
+			return nil, err
+		}
+		return []uint64{ptr, length}, nil
+	case witigo.AbiTypeRecord, witigo.AbiTypeTuple:
+		values, err := fieldValues(v, t)
+		if err != nil {
+			return nil, err
+		}
+		flat := []uint64{}
+		for i, field := range fieldTypes(t) {
+			fieldFlat, err := l.lowerFlat(values[i], field)
+			if err != nil {
+				return nil, fmt.Errorf("failed to lower field %d: %w", i, err)
+			}
+			flat = append(flat, fieldFlat...)
+		}
+		return flat, nil
+	case witigo.AbiTypeVariant, witigo.AbiTypeOption, witigo.AbiTypeResult:
+		caseIndex, payload, err := variantCase(v, t)
+		if err != nil {
+			return nil, err
+		}
+		variantFlat, err := flatten(t)
+		if err != nil {
+			return nil, err
+		}
+		flat := make([]uint64, len(variantFlat))
+		flat[0] = uint64(caseIndex)
+		if caseType := caseTypes(t)[caseIndex]; caseType != nil {
+			payloadFlat, err := l.lowerFlat(payload, caseType)
+			if err != nil {
+				return nil, fmt.Errorf("failed to lower variant payload: %w", err)
+			}
+			// Joined flat types share the bit patterns of the wazero representation of each
+			// case type, so payload values can be copied into their slots unchanged.
+			copy(flat[1:], payloadFlat)
+		}
+		return flat, nil
+	case witigo.AbiTypeFlags:
+		bits, err := flagBits(v.(Flags), t)
+		if err != nil {
+			return nil, err
+		}
+		flat := make([]uint64, flagsFlatCount(len(t.SubTypes())))
+		for i := range flat {
+			flat[i] = uint64(uint32(bits >> (32 * i)))
+		}
+		return flat, nil
+	default:
+		scalar, err := scalarBits(v, t)
+		if err != nil {
+			return nil, err
+		}
+		return []uint64{scalar}, nil
+	}
+}
+
+// store writes a value into linear memory at ptr using the canonical ABI memory layout.
+func (l *lowering) store(v Value, t wit.WitType, ptr uint64) error {
+	if err := checkKind(v, t); err != nil {
+		return err
+	}
+	switch t.Kind() {
+	case witigo.AbiTypeString, witigo.AbiTypeList:
+		flat, err := l.lowerFlat(v, t)
+		if err != nil {
+			return err
+		}
+		if !l.opts.Memory.WriteUint32Le(ptr, uint32(flat[0])) || !l.opts.Memory.WriteUint32Le(ptr+4, uint32(flat[1])) {
+			return &abi.MemoryError{Op: "write", What: t.Kind().String() + " descriptor", Ptr: ptr, Size: 8}
+		}
+		return nil
+	case witigo.AbiTypeRecord, witigo.AbiTypeTuple:
+		values, err := fieldValues(v, t)
+		if err != nil {
+			return err
+		}
+		offset := uint64(0)
+		for i, field := range fieldTypes(t) {
+			size, alignment, err := layoutOf(field)
+			if err != nil {
+				return err
+			}
+			offset = abi.AlignTo(offset, alignment)
+			if err := l.store(values[i], field, ptr+offset); err != nil {
+				return fmt.Errorf("failed to store field %d at %d: %w", i, ptr+offset, err)
+			}
+			offset += size
+		}
+		return nil
+	case witigo.AbiTypeVariant, witigo.AbiTypeOption, witigo.AbiTypeResult:
+		caseIndex, payload, err := variantCase(v, t)
+		if err != nil {
+			return err
+		}
+		if err := writeUint(l.opts, ptr, uint64(caseIndex), discriminantSize(len(caseTypes(t)))); err != nil {
+			return err
+		}
+		payloadOffset, _, _, err := variantLayout(t)
+		if err != nil {
+			return err
+		}
+		if caseType := caseTypes(t)[caseIndex]; caseType != nil {
+			if err := l.store(payload, caseType, ptr+payloadOffset); err != nil {
+				return fmt.Errorf("failed to store variant payload: %w", err)
+			}
+		}
+		return nil
+	case witigo.AbiTypeFlags:
+		bits, err := flagBits(v.(Flags), t)
+		if err != nil {
+			return err
+		}
+		size := flagsSize(len(t.SubTypes()))
+		for offset := uint64(0); offset < size; offset += 4 {
+			if err := writeUint(l.opts, ptr+offset, bits>>(8*offset), min(size-offset, 4)); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		scalar, err := scalarBits(v, t)
+		if err != nil {
+			return err
+		}
+		size, _, err := layoutOf(t)
+		if err != nil {
+			return err
+		}
+		return writeUint(l.opts, ptr, scalar, size)
+	}
+}
+
+// lowerList writes the elements of a list to a new allocation and returns its pointer and length.
+func (l *lowering) lowerList(list List, elemType wit.WitType) (ptr uint64, length uint64, err error) {
+	elemSize, elemAlignment, err := layoutOf(elemType)
+	if err != nil {
+		return 0, 0, err
+	}
+	ptr, err = l.malloc(elemSize*uint64(len(list)), elemAlignment)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to allocate memory for list data: %w", err)
+	}
+	for i, elem := range list {
+		if err := l.store(elem, elemType, ptr+uint64(i)*elemSize); err != nil {
+			return 0, 0, fmt.Errorf("failed to store element %d: %w", i, err)
+		}
+	}
+	return ptr, uint64(len(list)), nil
+}
+
+// scalarBits returns the flat bit pattern of a primitive or enum value.
+func scalarBits(v Value, t wit.WitType) (uint64, error) {
+	switch v := v.(type) {
+	case Bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case S8:
+		return uint64(uint32(int32(v))), nil
+	case S16:
+		return uint64(uint32(int32(v))), nil
+	case S32:
+		return uint64(uint32(v)), nil
+	case S64:
+		return uint64(v), nil
+	case U8:
+		return uint64(v), nil
+	case U16:
+		return uint64(v), nil
+	case U32:
+		return uint64(v), nil
+	case U64:
+		return uint64(v), nil
+	case F32:
+		return uint64(math.Float32bits(float32(v))), nil
+	case F64:
+		return math.Float64bits(float64(v)), nil
+	case Char:
+		if !utf8.ValidRune(rune(v)) {
+			return 0, fmt.Errorf("char %U is not a valid unicode scalar value", rune(v))
+		}
+		return uint64(v), nil
+	case Enum:
+		for i, c := range t.SubTypes() {
+			if c.Name() == string(v) {
+				return uint64(i), nil
+			}
+		}
+		return 0, fmt.Errorf("enum %s has no case %q", t.Name(), string(v))
+	default:
+		return 0, fmt.Errorf("cannot lower %T as %s", v, t.Kind())
+	}
+}
+
+// fieldValues returns the values of a record or tuple in declaration order.
+func fieldValues(v Value, t wit.WitType) ([]Value, error) {
+	switch v := v.(type) {
+	case Tuple:
+		if len(v) != len(t.SubTypes()) {
+			return nil, fmt.Errorf("expected tuple of %d values, got %d", len(t.SubTypes()), len(v))
+		}
+		return v, nil
+	case Record:
+		fields := t.SubTypes()
+		values := make([]Value, len(fields))
+		for i, field := range fields {
+			value, ok := v.Get(field.Name())
+			if !ok {
+				return nil, fmt.Errorf("record %s is missing field %q", t.Name(), field.Name())
+			}
+			values[i] = value
+		}
+		if len(v) != len(fields) {
+			return nil, fmt.Errorf("record %s expects %d fields, got %d", t.Name(), len(fields), len(v))
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("cannot lower %T as %s", v, t.Kind())
+	}
+}
+
+// variantCase returns the case index and payload of a variant, option or result value.
+func variantCase(v Value, t wit.WitType) (int, Value, error) {
+	cases := caseTypes(t)
+	var caseIndex int
+	var payload Value
+	switch v := v.(type) {
+	case Option:
+		if v.Value != nil {
+			caseIndex = 1
+		}
+		payload = v.Value
+	case Result:
+		if v.IsErr {
+			caseIndex = 1
+		}
+		payload = v.Value
+	case Variant:
+		caseIndex = -1
+		for i, c := range t.SubTypes() {
+			if c.Name() == v.Case {
+				caseIndex = i
+				break
+			}
+		}
+		if caseIndex < 0 {
+			return 0, nil, fmt.Errorf("variant %s has no case %q", t.Name(), v.Case)
+		}
+		payload = v.Value
+	default:
+		return 0, nil, fmt.Errorf("cannot lower %T as %s", v, t.Kind())
+	}
+	if cases[caseIndex] != nil && payload == nil {
+		return 0, nil, fmt.Errorf("case %d of %s requires a payload", caseIndex, t.Kind())
+	}
+	if cases[caseIndex] == nil && payload != nil {
+		return 0, nil, fmt.Errorf("case %d of %s has no payload, got %T", caseIndex, t.Kind(), payload)
+	}
+	return caseIndex, payload, nil
+}
+
+// flagBits packs a set of flag names into a bit vector.
+func flagBits(v Flags, t wit.WitType) (uint64, error) {
+	names := t.SubTypes()
+	if len(names) > 64 {
+		return 0, fmt.Errorf("flags with more than 64 members are not supported")
+	}
+	bits := uint64(0)
+	for _, flag := range v {
+		found := false
+		for i, name := range names {
+			if name.Name() == flag {
+				bits |= 1 << i
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("flags %s has no flag %q", t.Name(), flag)
+		}
+	}
+	return bits, nil
+}
+
+// checkKind returns an error if the value cannot represent a WIT value of type t.
+func checkKind(v Value, t wit.WitType) error {
+	if v == nil {
+		return fmt.Errorf("expected %s value, got nil", t.Kind())
+	}
+	if v.Kind() != t.Kind() {
+		return fmt.Errorf("expected %s value, got %s (%T)", t.Kind(), v.Kind(), v)
+	}
+	return nil
+}
+
+func writeUint(opts abi.AbiOptions, ptr uint64, value uint64, size uint64) error {
+	bytes := make([]byte, size)
+	for i := range size {
+		bytes[i] = byte(value >> (8 * i))
+	}
+	if !opts.Memory.Write(ptr, bytes) {
+		return &abi.MemoryError{Op: "write", What: fmt.Sprintf("%d bytes", size), Ptr: ptr, Size: size}
+	}
+	return nil
+}
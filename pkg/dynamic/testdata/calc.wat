@@ -0,0 +1,51 @@
+;; Core module of the calc test component. Rebuild calc.wasm with:
+;;   wasm-tools component embed calc.wit calc.wat -o embedded.wasm
+;;   wasm-tools component new embedded.wasm -o calc.wasm
+(module
+  (memory (export "memory") 1)
+  (global $heap (mut i32) (i32.const 1024))
+
+  ;; Bump allocator; frees are ignored.
+  (func $realloc (export "cabi_realloc") (param $old i32) (param $old_size i32) (param $align i32) (param $size i32) (result i32)
+    (local $ptr i32)
+    (if (i32.eqz (local.get $size)) (then (return (i32.const 0))))
+    (local.set $ptr
+      (i32.and
+        (i32.add (global.get $heap) (i32.sub (local.get $align) (i32.const 1)))
+        (i32.sub (i32.const 0) (local.get $align))))
+    (global.set $heap (i32.add (local.get $ptr) (local.get $size)))
+    (local.get $ptr))
+
+  (func (export "add") (param i32 i32) (result i32)
+    (i32.add (local.get 0) (local.get 1)))
+
+  (func (export "echo") (param $ptr i32) (param $len i32) (result i32)
+    (local $ret i32)
+    (local.set $ret (call $realloc (i32.const 0) (i32.const 0) (i32.const 4) (i32.const 8)))
+    (i32.store (local.get $ret) (local.get $ptr))
+    (i32.store offset=4 (local.get $ret) (local.get $len))
+    (local.get $ret))
+  (func (export "cabi_post_echo") (param i32))
+
+  ;; More than 16 flat parameters are passed through memory as a pointer to a tuple.
+  (func (export "sum") (param $args i32) (result i32)
+    (local $i i32)
+    (local $total i32)
+    (loop $next
+      (local.set $total
+        (i32.add (local.get $total)
+          (i32.load (i32.add (local.get $args) (i32.mul (local.get $i) (i32.const 4))))))
+      (local.set $i (i32.add (local.get $i) (i32.const 1)))
+      (br_if $next (i32.lt_u (local.get $i) (i32.const 17))))
+    (local.get $total))
+
+  (func (export "classify") (param $n i32) (result i32)
+    (local $ret i32)
+    (local.set $ret (call $realloc (i32.const 0) (i32.const 0) (i32.const 1) (i32.const 2)))
+    (if (i32.lt_s (local.get $n) (i32.const 0))
+      (then (i32.store8 (local.get $ret) (i32.const 0)))
+      (else
+        (i32.store8 (local.get $ret) (i32.const 1))
+        (i32.store8 offset=1 (local.get $ret) (local.get $n))))
+    (local.get $ret))
+)
@@ -0,0 +1,230 @@
+package dynamic
+
+import (
+	"strconv"
+	"strings"
+
+	witigo "github.com/rioam2/witigo/pkg"
+)
+
+// Value is a dynamically typed WIT value. Every WIT type has a corresponding Go type in this
+// package that implements Value:
+//
+//	bool                 Bool
+//	s8 .. s64, u8 .. u64 S8 .. S64, U8 .. U64
+//	f32, f64             F32, F64
+//	char                 Char
+//	string               String
+//	list<T>              List
+//	record               Record
+//	tuple<...>           Tuple
+//	variant              Variant
+//	enum                 Enum
+//	option<T>            Option
+//	result<T, E>         Result
+//	flags                Flags
+//
+// String renders values in a WIT-like text form, for example `{id: 1, tags: ["a"]}`.
+type Value interface {
+	Kind() witigo.AbiType
+	String() string
+}
+
+type Bool bool
+type S8 int8
+type S16 int16
+type S32 int32
+type S64 int64
+type U8 uint8
+type U16 uint16
+type U32 uint32
+type U64 uint64
+type F32 float32
+type F64 float64
+type Char rune
+type String string
+
+// List is a homogeneous sequence of values.
+type List []Value
+
+// Field is a named record field.
+type Field struct {
+	Name  string
+	Value Value
+}
+
+// Record is an ordered list of named fields.
+type Record []Field
+
+// Tuple is an ordered list of unnamed values.
+type Tuple []Value
+
+// Variant is a variant value. Value is nil for cases without a payload.
+type Variant struct {
+	Case  string
+	Value Value
+}
+
+// Enum is an enum value, identified by its case name.
+type Enum string
+
+// Option is an option value. A nil Value represents none.
+type Option struct {
+	Value Value
+}
+
+// Result is a result value. Value is nil when the active case has no payload.
+type Result struct {
+	IsErr bool
+	Value Value
+}
+
+// Flags is a set of flag values, identified by their names.
+type Flags []string
+
+// Some returns an option holding v.
+func Some(v Value) Option {
+	return Option{Value: v}
+}
+
+// None returns an empty option.
+func None() Option {
+	return Option{}
+}
+
+// Ok returns a successful result holding v, which may be nil.
+func Ok(v Value) Result {
+	return Result{Value: v}
+}
+
+// Err returns a failed result holding v, which may be nil.
+func Err(v Value) Result {
+	return Result{IsErr: true, Value: v}
+}
+
+// Get returns the value of the named field.
+func (r Record) Get(name string) (Value, bool) {
+	for _, f := range r {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// IsSome reports whether the option holds a value.
+func (o Option) IsSome() bool {
+	return o.Value != nil
+}
+
+// Has reports whether the named flag is set.
+func (f Flags) Has(name string) bool {
+	for _, n := range f {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (Bool) Kind() witigo.AbiType    { return witigo.AbiTypeBool }
+func (S8) Kind() witigo.AbiType      { return witigo.AbiTypeS8 }
+func (S16) Kind() witigo.AbiType     { return witigo.AbiTypeS16 }
+func (S32) Kind() witigo.AbiType     { return witigo.AbiTypeS32 }
+func (S64) Kind() witigo.AbiType     { return witigo.AbiTypeS64 }
+func (U8) Kind() witigo.AbiType      { return witigo.AbiTypeU8 }
+func (U16) Kind() witigo.AbiType     { return witigo.AbiTypeU16 }
+func (U32) Kind() witigo.AbiType     { return witigo.AbiTypeU32 }
+func (U64) Kind() witigo.AbiType     { return witigo.AbiTypeU64 }
+func (F32) Kind() witigo.AbiType     { return witigo.AbiTypeF32 }
+func (F64) Kind() witigo.AbiType     { return witigo.AbiTypeF64 }
+func (Char) Kind() witigo.AbiType    { return witigo.AbiTypeChar }
+func (String) Kind() witigo.AbiType  { return witigo.AbiTypeString }
+func (List) Kind() witigo.AbiType    { return witigo.AbiTypeList }
+func (Record) Kind() witigo.AbiType  { return witigo.AbiTypeRecord }
+func (Tuple) Kind() witigo.AbiType   { return witigo.AbiTypeTuple }
+func (Variant) Kind() witigo.AbiType { return witigo.AbiTypeVariant }
+func (Enum) Kind() witigo.AbiType    { return witigo.AbiTypeEnum }
+func (Option) Kind() witigo.AbiType  { return witigo.AbiTypeOption }
+func (Result) Kind() witigo.AbiType  { return witigo.AbiTypeResult }
+func (Flags) Kind() witigo.AbiType   { return witigo.AbiTypeFlags }
+
+func (v Bool) String() string { return strconv.FormatBool(bool(v)) }
+func (v S8) String() string   { return strconv.FormatInt(int64(v), 10) }
+func (v S16) String() string  { return strconv.FormatInt(int64(v), 10) }
+func (v S32) String() string  { return strconv.FormatInt(int64(v), 10) }
+func (v S64) String() string  { return strconv.FormatInt(int64(v), 10) }
+func (v U8) String() string   { return strconv.FormatUint(uint64(v), 10) }
+func (v U16) String() string  { return strconv.FormatUint(uint64(v), 10) }
+func (v U32) String() string  { return strconv.FormatUint(uint64(v), 10) }
+func (v U64) String() string  { return strconv.FormatUint(uint64(v), 10) }
+func (v F32) String() string  { return strconv.FormatFloat(float64(v), 'g', -1, 32) }
+func (v F64) String() string  { return strconv.FormatFloat(float64(v), 'g', -1, 64) }
+func (v Char) String() string { return strconv.QuoteRune(rune(v)) }
+func (v String) String() string {
+	return strconv.Quote(string(v))
+}
+
+func (v List) String() string {
+	return "[" + joinValues(v) + "]"
+}
+
+func (v Record) String() string {
+	parts := make([]string, len(v))
+	for i, f := range v {
+		parts[i] = f.Name + ": " + valueString(f.Value)
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func (v Tuple) String() string {
+	return "(" + joinValues(v) + ")"
+}
+
+func (v Variant) String() string {
+	if v.Value == nil {
+		return v.Case
+	}
+	return v.Case + "(" + v.Value.String() + ")"
+}
+
+func (v Enum) String() string {
+	return string(v)
+}
+
+func (v Option) String() string {
+	if v.Value == nil {
+		return "none"
+	}
+	return "some(" + v.Value.String() + ")"
+}
+
+func (v Result) String() string {
+	name := "ok"
+	if v.IsErr {
+		name = "err"
+	}
+	if v.Value == nil {
+		return name
+	}
+	return name + "(" + v.Value.String() + ")"
+}
+
+func (v Flags) String() string {
+	return "{" + strings.Join(v, ", ") + "}"
+}
+
+func joinValues(values []Value) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = valueString(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func valueString(v Value) string {
+	if v == nil {
+		return "_"
+	}
+	return v.String()
+}
@@ -0,0 +1,136 @@
+package dynamic_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/rioam2/witigo/pkg/abi/abitest"
+	"github.com/rioam2/witigo/pkg/dynamic"
+	"github.com/rioam2/witigo/pkg/wit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testdata/values.json is generated from testdata/values.wit with `wasm-tools component wit -j`.
+func newValuesInstance(t *testing.T) (*dynamic.Instance, *abitest.Runtime) {
+	t.Helper()
+	witJson, err := os.ReadFile("testdata/values.json")
+	require.NoError(t, err)
+	definition, err := wit.NewFromJson(witJson, "values")
+	require.NoError(t, err)
+
+	runtime := abitest.NewRuntime()
+	return dynamic.NewWithOptions(definition.Worlds()[0], runtime.AbiOptions()), runtime
+}
+
+func TestValueRoundTrip(t *testing.T) {
+	instance, runtime := newValuesInstance(t)
+
+	// The parameters of identity do not fit into flat values, so they are passed as a pointer to a
+	// tuple in memory. A single-element tuple has the layout of its element, which means the fake
+	// guest can hand the same pointer back as the result.
+	runtime.Export("identity", func(ctx context.Context, params ...uint64) ([]uint64, error) {
+		return []uint64{params[0]}, nil
+	})
+
+	everything := dynamic.Record{
+		{Name: "flag", Value: dynamic.Bool(true)},
+		{Name: "small", Value: dynamic.S8(-8)},
+		{Name: "byte", Value: dynamic.U8(200)},
+		{Name: "short", Value: dynamic.S16(-1600)},
+		{Name: "ushort", Value: dynamic.U16(60000)},
+		{Name: "int", Value: dynamic.S32(-320000)},
+		{Name: "uint", Value: dynamic.U32(4000000000)},
+		{Name: "long", Value: dynamic.S64(-6400000000)},
+		{Name: "ulong", Value: dynamic.U64(18000000000000000000)},
+		{Name: "single", Value: dynamic.F32(1.5)},
+		{Name: "double", Value: dynamic.F64(-2.25)},
+		{Name: "letter", Value: dynamic.Char('界')},
+		{Name: "text", Value: dynamic.String("hello")},
+		{Name: "color", Value: dynamic.Enum("blue")},
+		{Name: "perms", Value: dynamic.Flags{"read", "exec"}},
+		{Name: "points", Value: dynamic.List{
+			dynamic.Record{{Name: "x", Value: dynamic.S16(1)}, {Name: "y", Value: dynamic.U64(2)}},
+			dynamic.Record{{Name: "x", Value: dynamic.S16(-3)}, {Name: "y", Value: dynamic.U64(4)}},
+		}},
+		{Name: "shapes", Value: dynamic.List{
+			dynamic.Variant{Case: "empty"},
+			dynamic.Variant{Case: "circle", Value: dynamic.F32(0.5)},
+			dynamic.Variant{Case: "label", Value: dynamic.String("triangle")},
+			dynamic.Variant{Case: "polygon", Value: dynamic.List{
+				dynamic.Record{{Name: "x", Value: dynamic.S16(5)}, {Name: "y", Value: dynamic.U64(6)}},
+			}},
+		}},
+		{Name: "maybe", Value: dynamic.Some(dynamic.String("present"))},
+		{Name: "outcome", Value: dynamic.Err(dynamic.String("failed"))},
+		{Name: "pair", Value: dynamic.Tuple{dynamic.String("left"), dynamic.U8(9)}},
+	}
+
+	results, err := instance.Call(context.Background(), "identity", everything)
+	require.NoError(t, err)
+	assert.Equal(t, []dynamic.Value{everything}, results)
+	assert.NoError(t, runtime.CheckLeaks())
+}
+
+func TestFlatValues(t *testing.T) {
+	instance, runtime := newValuesInstance(t)
+	ctx := context.Background()
+
+	t.Run("flags", func(t *testing.T) {
+		runtime.Export("toggle", func(ctx context.Context, params ...uint64) ([]uint64, error) {
+			assert.Equal(t, []uint64{0b101}, params)
+			return []uint64{params[0] ^ 0b111}, nil
+		})
+		results, err := instance.Call(ctx, "toggle", dynamic.Flags{"read", "exec"})
+		require.NoError(t, err)
+		assert.Equal(t, []dynamic.Value{dynamic.Flags{"write"}}, results)
+	})
+
+	t.Run("variant payloads share joined slots", func(t *testing.T) {
+		var got []uint64
+		runtime.Export("pick", func(ctx context.Context, params ...uint64) ([]uint64, error) {
+			got = params
+			return []uint64{params[0]}, nil
+		})
+
+		// circle(f32) and square(u64) share an i64 slot, label(string) and polygon(list) use two
+		// i32 slots.
+		_, err := instance.Call(ctx, "pick", dynamic.Variant{Case: "square", Value: dynamic.U64(1 << 40)})
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{2, 1 << 40, 0}, got)
+
+		results, err := instance.Call(ctx, "pick", dynamic.Variant{Case: "empty"})
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{0, 0, 0}, got)
+		assert.Equal(t, []dynamic.Value{dynamic.U32(0)}, results)
+	})
+
+	t.Run("lowering errors", func(t *testing.T) {
+		_, err := instance.Call(ctx, "pick", dynamic.Variant{Case: "hexagon"})
+		assert.ErrorContains(t, err, `has no case "hexagon"`)
+
+		_, err = instance.Call(ctx, "pick", dynamic.Variant{Case: "circle"})
+		assert.ErrorContains(t, err, "requires a payload")
+
+		_, err = instance.Call(ctx, "toggle", dynamic.Flags{"delete"})
+		assert.ErrorContains(t, err, `has no flag "delete"`)
+	})
+
+	assert.NoError(t, runtime.CheckLeaks())
+}
+
+func TestValueString(t *testing.T) {
+	value := dynamic.Record{
+		{Name: "id", Value: dynamic.U32(1)},
+		{Name: "tags", Value: dynamic.List{dynamic.String("a")}},
+		{Name: "shape", Value: dynamic.Variant{Case: "circle", Value: dynamic.F32(0.5)}},
+		{Name: "maybe", Value: dynamic.None()},
+		{Name: "outcome", Value: dynamic.Ok(nil)},
+		{Name: "perms", Value: dynamic.Flags{"read", "write"}},
+		{Name: "pair", Value: dynamic.Tuple{dynamic.Char('x'), dynamic.Enum("red")}},
+	}
+	assert.Equal(t,
+		`{id: 1, tags: ["a"], shape: circle(0.5), maybe: none, outcome: ok, perms: {read, write}, pair: ('x', red)}`,
+		value.String())
+}
@@ -0,0 +1,77 @@
+package guest
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/rioam2/witigo/pkg/abi"
+)
+
+// heap is the allocator of the module.
+var heap = &allocator{blocks: map[uint64]block{}}
+
+// allocator allocates memory for lowered values from the Go heap, keeping each block referenced
+// until it is freed so that the garbage collector does not reclaim it while the host uses it.
+type allocator struct {
+	mu     sync.Mutex
+	blocks map[uint64]block
+}
+
+// block is a block of memory handed out by the allocator.
+type block struct {
+	data []byte
+	// host is set for blocks allocated by the host through cabi_realloc, to lower the parameters of
+	// exports and the results of imports. The guest frees them once they are lifted.
+	host bool
+}
+
+// realloc implements cabi_realloc: it allocates newSize bytes aligned to alignment, copies the
+// contents of the block at oldPtr, if any, into them, and frees that block. A newSize of zero only
+// frees the block at oldPtr and returns a null pointer. Freeing a pointer that the allocator did
+// not hand out, or already freed, does nothing, as the host may free the parameters of exports that
+// the guest already freed once lifted.
+func (a *allocator) realloc(oldPtr, oldSize, alignment, newSize uint64, host bool) (uint64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	old, ok := a.blocks[oldPtr]
+	delete(a.blocks, oldPtr)
+	if newSize == 0 {
+		return 0, nil
+	}
+	if alignment == 0 || alignment&(alignment-1) != 0 {
+		return 0, fmt.Errorf("alignment %d is not a power of two", alignment)
+	}
+
+	data := make([]byte, newSize+alignment-1)
+	base := uint64(uintptr(unsafe.Pointer(unsafe.SliceData(data))))
+	offset := abi.AlignTo(base, alignment) - base
+	data = data[offset : offset+newSize]
+	if ok {
+		copy(data, old.data[:min(oldSize, uint64(len(old.data)))])
+	}
+	ptr := base + offset
+	a.blocks[ptr] = block{data: data, host: host}
+	return ptr, nil
+}
+
+// freeHost frees the blocks allocated by the host.
+func (a *allocator) freeHost() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for ptr, b := range a.blocks {
+		if b.host {
+			delete(a.blocks, ptr)
+		}
+	}
+}
+
+// hostRealloc is cabi_realloc as called by the host.
+func hostRealloc(oldPtr, oldSize, alignment, newSize uint64) uint64 {
+	ptr, err := heap.realloc(oldPtr, oldSize, alignment, newSize, true)
+	if err != nil {
+		panic(fmt.Sprintf("cabi_realloc: %v", err))
+	}
+	return ptr
+}
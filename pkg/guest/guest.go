@@ -0,0 +1,93 @@
+// Package guest implements the canonical ABI from within a component written in Go, for the guest
+// bindings generated by `witigo generate -guest`. Values are lifted and lowered by pkg/abi, over the
+// linear memory of the module itself, and memory is allocated by an allocator that also serves the
+// cabi_realloc function exported to the host.
+//
+// The functions of this package are called by the generated glue from the exports and imports of
+// the module, where an invalid value cannot be reported to the host otherwise than by trapping.
+// They therefore panic instead of returning errors.
+package guest
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"unsafe"
+
+	"github.com/rioam2/witigo/pkg/abi"
+)
+
+// converters converts the Go types that WIT types are mapped to, if any.
+var converters *abi.Converters
+
+// SetConverters sets the converters of the Go types that WIT types are mapped to, which the guest
+// bindings generated with type mappings set when the module is initialized.
+func SetConverters(c *abi.Converters) {
+	converters = c
+}
+
+// Options returns the AbiOptions lifting and lowering values in the linear memory of the module,
+// with memory allocated by the allocator of the module.
+func Options() abi.AbiOptions {
+	return abi.AbiOptions{
+		StringEncoding: abi.StringEncodingUTF8,
+		Memory:         linearMemory{},
+		Call:           call,
+		Context:        context.Background(),
+		Converters:     converters,
+	}
+}
+
+// call serves the calls of pkg/abi to cabi_realloc, which allocate and free memory for the guest.
+// Other functions cannot be called from within the guest.
+func call(ctx context.Context, name string, params ...uint64) ([]uint64, error) {
+	if name != "cabi_realloc" || len(params) != 4 {
+		return nil, fmt.Errorf("guest cannot call %s", name)
+	}
+	ptr, err := heap.realloc(params[0], params[1], params[2], params[3], false)
+	if err != nil {
+		return nil, err
+	}
+	return []uint64{ptr}, nil
+}
+
+// linearMemory is the linear memory of the module, addressed by the pointers of the guest itself.
+type linearMemory struct{}
+
+var _ abi.RuntimeMemory = linearMemory{}
+
+// Size returns the largest size, as the module does not track the size of its memory. Accesses
+// beyond its end trap.
+func (linearMemory) Size() uint64 {
+	return math.MaxUint64
+}
+
+// Read returns a copy of the bytes, so that values lifted from them do not refer to memory that is
+// freed once lifted.
+func (linearMemory) Read(offset, byteCount uint64) ([]byte, bool) {
+	return bytes.Clone(memoryAt(offset, byteCount)), true
+}
+
+func (linearMemory) ReadUint32Le(offset uint64) (uint32, bool) {
+	return binary.LittleEndian.Uint32(memoryAt(offset, 4)), true
+}
+
+func (linearMemory) Write(offset uint64, v []byte) bool {
+	copy(memoryAt(offset, uint64(len(v))), v)
+	return true
+}
+
+func (linearMemory) WriteUint32Le(offset uint64, v uint32) bool {
+	binary.LittleEndian.PutUint32(memoryAt(offset, 4), v)
+	return true
+}
+
+// memoryAt returns the byteCount bytes of linear memory at offset.
+func memoryAt(offset, byteCount uint64) []byte {
+	if byteCount == 0 {
+		return []byte{}
+	}
+	return unsafe.Slice((*byte)(unsafe.Add(nil, offset)), byteCount)
+}
@@ -0,0 +1,88 @@
+package guest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRealloc(t *testing.T) {
+	a := &allocator{blocks: map[uint64]block{}}
+
+	ptr, err := a.realloc(0, 0, 8, 3, false)
+	require.NoError(t, err)
+	assert.Zero(t, ptr%8)
+	copy(memoryAt(ptr, 3), "abc")
+
+	grown, err := a.realloc(ptr, 3, 16, 5, true)
+	require.NoError(t, err)
+	assert.Zero(t, grown%16)
+	assert.Equal(t, []byte("abc\x00\x00"), memoryAt(grown, 5))
+	assert.NotContains(t, a.blocks, ptr)
+
+	// Freeing a pointer twice does nothing.
+	freed, err := a.realloc(ptr, 0, 0, 0, false)
+	require.NoError(t, err)
+	assert.Zero(t, freed)
+
+	a.freeHost()
+	assert.Empty(t, a.blocks)
+
+	_, err = a.realloc(0, 0, 3, 1, false)
+	assert.ErrorContains(t, err, "alignment 3 is not a power of two")
+}
+
+func TestLowerAndLiftParams(t *testing.T) {
+	flatParams, free := LowerParams("hello", int8(-2), float32(1.5))
+	defer free()
+	require.Len(t, flatParams, 4)
+
+	var s string
+	var i int8
+	var f float32
+	LiftParams(flatParams, &s, &i, &f)
+	assert.Equal(t, "hello", s)
+	assert.Equal(t, int8(-2), i)
+	assert.Equal(t, float32(1.5), f)
+
+	assert.PanicsWithValue(t, "failed to lift parameters: 1 flat parameters left after reading 3 parameters", func() {
+		LiftParams(append(flatParams, 0), &s, &i, &f)
+	})
+}
+
+func TestLowerAndLiftIndirectParams(t *testing.T) {
+	ptr, free := LowerIndirectParams(uint8(1), uint64(2), true)
+	defer free()
+
+	var a uint8
+	var b uint64
+	var c bool
+	LiftIndirectParams(ptr, &a, &b, &c)
+	assert.Equal(t, uint8(1), a)
+	assert.Equal(t, uint64(2), b)
+	assert.True(t, c)
+}
+
+func TestLowerResult(t *testing.T) {
+	assert.Equal(t, uint64(7), LowerResult(uint16(7)))
+
+	var result int32
+	LiftResult(LowerResult(int32(-7)), &result)
+	assert.Equal(t, int32(-7), result)
+
+	assert.Panics(t, func() { LowerResult("two flat values") })
+}
+
+func TestPostReturn(t *testing.T) {
+	ptr := LowerIndirectResult("greet", uint64(42))
+	assert.Contains(t, heap.blocks, ptr)
+
+	var result uint64
+	LiftIndirectResult(ptr, &result)
+	assert.Equal(t, uint64(42), result)
+
+	PostReturn("greet")
+	assert.NotContains(t, heap.blocks, ptr)
+	PostReturn("greet")
+}
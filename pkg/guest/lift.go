@@ -0,0 +1,152 @@
+package guest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/rioam2/witigo/pkg/abi"
+)
+
+var (
+	postReturnsMu sync.Mutex
+	// postReturns holds the callbacks freeing the results of exports returned through linear memory,
+	// by export, until the host calls their post-return function.
+	postReturns = map[string]abi.AbiFreeCallback{}
+)
+
+// LiftParams lifts the parameters of an export from its flat parameters into results, which point
+// to the values in the order of the parameters, and frees the memory the host allocated for them.
+func LiftParams(flatParams []uint64, results ...any) {
+	defer FreeHostAllocations()
+	must(abi.ReadParameters(Options(), flatParams, results...), "failed to lift parameters")
+}
+
+// LiftIndirectParams lifts the parameters of an export that the host passed in linear memory, as
+// a tuple at ptr, into results, and frees the memory the host allocated for them.
+func LiftIndirectParams(ptr uint64, results ...any) {
+	defer FreeHostAllocations()
+	opts := Options()
+	offset := uint64(0)
+	for i, result := range results {
+		offset = abi.AlignTo(offset, Options().AlignmentOf(result))
+		must(abi.Read(opts, ptr+offset, result), fmt.Sprintf("failed to lift parameter %d", i))
+		offset += Options().SizeOf(result)
+	}
+}
+
+// LowerResult lowers the result of an export that is returned as a single flat value.
+func LowerResult(value any) uint64 {
+	params, free, err := abi.WriteParameter(Options(), value)
+	must(err, "failed to lower result")
+	must(free(), "failed to lower result")
+	if len(params) != 1 {
+		panic(fmt.Sprintf("failed to lower result: %T is not a single flat value", value))
+	}
+	return params[0].Value
+}
+
+// LowerIndirectResult lowers the result of an export into linear memory and returns its pointer.
+// The memory is freed by PostReturn, once the host has lifted the result.
+func LowerIndirectResult(export string, value any) uint64 {
+	ptr, free, err := abi.Write(Options(), value, nil)
+	must(err, "failed to lower result")
+	postReturnsMu.Lock()
+	defer postReturnsMu.Unlock()
+	postReturns[export] = free
+	return ptr
+}
+
+// PostReturn frees the result of the last call of an export, which the host has lifted.
+func PostReturn(export string) {
+	postReturnsMu.Lock()
+	free := postReturns[export]
+	delete(postReturns, export)
+	postReturnsMu.Unlock()
+	if free != nil {
+		must(free(), "failed to free result")
+	}
+}
+
+// LowerParams lowers the parameters of an import into flat parameters. The returned callback frees
+// the memory allocated for them, once the import returns.
+func LowerParams(values ...any) ([]uint64, func()) {
+	var flatParams []uint64
+	var frees []abi.AbiFreeCallback
+	for i, value := range values {
+		params, free, err := abi.WriteParameter(Options(), value)
+		frees = append(frees, free)
+		must(err, fmt.Sprintf("failed to lower parameter %d", i))
+		for _, param := range params {
+			flatParams = append(flatParams, param.Value)
+		}
+	}
+	return flatParams, freeAll(frees)
+}
+
+// LowerIndirectParams lowers the parameters of an import into linear memory, as a tuple, and
+// returns its pointer. The returned callback frees the memory, once the import returns.
+func LowerIndirectParams(values ...any) (uint64, func()) {
+	opts := Options()
+	size := uint64(0)
+	alignment := uint64(1)
+	for _, value := range values {
+		size = abi.AlignTo(size, Options().AlignmentOf(value)) + Options().SizeOf(value)
+		alignment = max(alignment, Options().AlignmentOf(value))
+	}
+	ptr, free, err := abi.Malloc(opts, size, alignment)
+	must(err, "failed to allocate parameters")
+	frees := []abi.AbiFreeCallback{free}
+	offset := uint64(0)
+	for i, value := range values {
+		offset = abi.AlignTo(offset, Options().AlignmentOf(value))
+		valuePtr := ptr + offset
+		_, free, err := abi.Write(opts, value, &valuePtr)
+		frees = append(frees, free)
+		must(err, fmt.Sprintf("failed to lower parameter %d", i))
+		offset += Options().SizeOf(value)
+	}
+	return ptr, freeAll(frees)
+}
+
+// LiftResult lifts the result of an import returned as a single flat value into result.
+func LiftResult(flatResult uint64, result any) {
+	must(abi.ReadParameters(Options(), []uint64{flatResult}, result), "failed to lift result")
+}
+
+// ReturnArea allocates the memory into which an import returns a result that does not fit a single
+// flat value, for result to be lifted from, and returns its pointer. The returned callback frees
+// the memory.
+func ReturnArea(result any) (uint64, func()) {
+	ptr, free, err := abi.Malloc(Options(), Options().SizeOf(result), Options().AlignmentOf(result))
+	must(err, "failed to allocate the return area")
+	return ptr, freeAll([]abi.AbiFreeCallback{free})
+}
+
+// LiftIndirectResult lifts the result of an import from the return area at ptr into result, and
+// frees the memory the host allocated for it.
+func LiftIndirectResult(ptr uint64, result any) {
+	defer FreeHostAllocations()
+	must(abi.Read(Options(), ptr, result), "failed to lift result")
+}
+
+// FreeHostAllocations frees the memory that the host allocated through cabi_realloc, once the
+// values lowered into it are lifted.
+func FreeHostAllocations() {
+	heap.freeHost()
+}
+
+// freeAll returns a callback calling frees, in reverse order.
+func freeAll(frees []abi.AbiFreeCallback) func() {
+	return func() {
+		for i := len(frees) - 1; i >= 0; i-- {
+			must(frees[i](), "failed to free memory")
+		}
+	}
+}
+
+// must panics with the context of err, if any, which traps the call of the guest.
+func must(err error, context string) {
+	if err != nil {
+		panic(fmt.Sprintf("%s: %v", context, err))
+	}
+}
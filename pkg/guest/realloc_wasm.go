@@ -0,0 +1,9 @@
+package guest
+
+// cabiRealloc is the cabi_realloc function that the host calls to allocate memory in the module,
+// for the parameters of exports and the results of imports.
+//
+//go:wasmexport cabi_realloc
+func cabiRealloc(oldPtr, oldSize, alignment, newSize uint32) uint32 {
+	return uint32(hostRealloc(uint64(oldPtr), uint64(oldSize), uint64(alignment), uint64(newSize)))
+}
@@ -71,6 +71,7 @@ func ExtractComponentCoreModule(path string) (result []byte, err error) {
 		ctx, os.Stdin, stdoutBuffer, stderrBuffer, fsMap,
 		"component", "unbundle",
 		"--module-dir", coreModuleDir,
+		"--threshold", "0",
 		componentAbsolutePath,
 		"-t",
 	)
@@ -2,44 +2,116 @@ package wit
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 )
 
 type WitDefinition interface {
 	Name() string
+	Packages() []WitPackage
+	Interfaces() []WitInterface
 	Worlds() []WitWorldDefinition
+	World(name string) (WitWorldDefinition, error)
 	Types() []WitType
+	Wit() string
 	String() string
 }
 
+// WitDefinitionImpl is a definition decoded from its JSON form. Worlds, types and functions are
+// views into the decoded model.
 type WitDefinitionImpl struct {
-	name string
-	Raw  json.RawMessage
+	name       string
+	Raw        json.RawMessage
+	model      *model
+	packages   []WitPackage
+	interfaces []WitInterface
+	worlds     []WitWorldDefinition
+	types      []WitType
 }
 
 var _ WitDefinition = &WitDefinitionImpl{}
 
+// Packages returns the packages of the definition. Packages come after the packages they depend
+// on, so the last package is the root package.
+func (w *WitDefinitionImpl) Packages() []WitPackage {
+	return w.packages
+}
+
+// Interfaces returns every interface of the definition, including interfaces declared inline in
+// worlds.
+func (w *WitDefinitionImpl) Interfaces() []WitInterface {
+	return w.interfaces
+}
+
 func (w *WitDefinitionImpl) Worlds() []WitWorldDefinition {
-	var data struct {
-		Worlds []json.RawMessage `json:"worlds"`
+	return w.worlds
+}
+
+// World returns the world named name, which may be qualified with its package as in
+// `ns:pkg/world` or `ns:pkg/world@1.0.0`. Unqualified names prefer the worlds of the root package,
+// which is the last package of the definition. An empty name selects the only world of the root
+// package.
+func (w *WitDefinitionImpl) World(name string) (WitWorldDefinition, error) {
+	packages := w.packageNames()
+	rootPackage := ""
+	if len(packages) > 0 {
+		rootPackage = packages[len(packages)-1]
 	}
-	json.Unmarshal(w.Raw, &data)
-	var worlds []WitWorldDefinition
-	for _, world := range data.Worlds {
-		worlds = append(worlds, &WitWorldDefinitionImpl{world, w})
+
+	var matches, rootMatches []WitWorldDefinition
+	var rootWorlds []string
+	for _, world := range w.Worlds() {
+		pkg := world.Package()
+		if pkg == rootPackage {
+			rootWorlds = append(rootWorlds, world.Name())
+		}
+		unversioned, version, versioned := strings.Cut(pkg, "@")
+		switch name {
+		case "", world.Name():
+			if pkg == rootPackage {
+				rootMatches = append(rootMatches, world)
+			}
+			matches = append(matches, world)
+		case pkg + "/" + world.Name(), unversioned + "/" + world.Name():
+			matches = append(matches, world)
+		default:
+			if versioned && name == unversioned+"/"+world.Name()+"@"+version {
+				matches = append(matches, world)
+			}
+		}
 	}
-	return worlds
-}
 
-func (w *WitDefinitionImpl) Types() []WitType {
-	var data struct {
-		Types []json.RawMessage `json:"types"`
+	switch {
+	case len(rootMatches) == 1:
+		return rootMatches[0], nil
+	case name == "" && len(rootWorlds) == 0:
+		return nil, fmt.Errorf("package %s does not define any world", rootPackage)
+	case name == "":
+		return nil, fmt.Errorf("package %s defines multiple worlds, select one of: %s", rootPackage, strings.Join(rootWorlds, ", "))
+	case len(matches) == 1:
+		return matches[0], nil
+	case len(matches) == 0:
+		return nil, fmt.Errorf("world %s not found", name)
+	default:
+		var qualified []string
+		for _, world := range matches {
+			qualified = append(qualified, world.Package()+"/"+world.Name())
+		}
+		return nil, fmt.Errorf("world %s is ambiguous, select one of: %s", name, strings.Join(qualified, ", "))
 	}
-	json.Unmarshal(w.Raw, &data)
-	var types []WitType
-	for _, t := range data.Types {
-		types = append(types, &WitTypeImpl{t, w})
+}
+
+// packageNames returns the names of the packages of the definition by index.
+func (w *WitDefinitionImpl) packageNames() []string {
+	names := make([]string, len(w.model.packages))
+	for i, pkg := range w.model.packages {
+		names[i] = pkg.name
 	}
-	return types
+	return names
+}
+
+func (w *WitDefinitionImpl) Types() []WitType {
+	return w.types
 }
 
 func (w *WitDefinitionImpl) String() string {
@@ -54,7 +126,28 @@ func (w *WitDefinitionImpl) Name() string {
 	return w.name
 }
 
-func NewFromJson(json json.RawMessage, name string) (WitDefinition, error) {
-
-	return &WitDefinitionImpl{Raw: json, name: name}, nil
+// NewFromJson decodes the JSON form of a definition, as printed by `wasm-tools component wit -j`.
+func NewFromJson(raw json.RawMessage, name string) (WitDefinition, error) {
+	m, err := decodeModel(raw)
+	if err != nil {
+		return nil, err
+	}
+	def := &WitDefinitionImpl{Raw: raw, name: name, model: m}
+	def.packages = make([]WitPackage, len(m.packages))
+	for i := range m.packages {
+		def.packages[i] = &WitPackageImpl{def: def, index: i}
+	}
+	def.interfaces = make([]WitInterface, len(m.interfaces))
+	for i := range m.interfaces {
+		def.interfaces[i] = &WitInterfaceImpl{def: def, index: i}
+	}
+	def.worlds = make([]WitWorldDefinition, len(m.worlds))
+	for i := range m.worlds {
+		def.worlds[i] = &WitWorldDefinitionImpl{def: def, index: i}
+	}
+	def.types = make([]WitType, len(m.types))
+	for i := range m.types {
+		def.types[i] = &WitTypeImpl{def: def, index: i}
+	}
+	return def, nil
 }
@@ -0,0 +1,488 @@
+package wit
+
+import (
+	"fmt"
+	"strings"
+
+	witigo "github.com/rioam2/witigo/pkg"
+)
+
+// ChangeKind tells whether an item was added, removed or changed.
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// Change is a difference between two versions of a world.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	// Item is "function", "interface" or "type".
+	Item string `json:"item"`
+	// Path identifies the item, like `export run`, `import wasi:io/streams@0.2.0#[method]input-stream.read`
+	// or `wasi:io/streams@0.2.0#stream-error` for types.
+	Path string `json:"path"`
+	// Breaking is set if a host built against the old world cannot use a component of the new one.
+	Breaking bool `json:"breaking"`
+	// Details describe the changes of changed items.
+	Details []string `json:"details,omitempty"`
+}
+
+func (c Change) String() string {
+	severity := "compatible"
+	if c.Breaking {
+		severity = "breaking"
+	}
+	s := fmt.Sprintf("%s: %s %s %s", severity, c.Kind, c.Item, c.Path)
+	for _, detail := range c.Details {
+		s += "\n  - " + detail
+	}
+	return s
+}
+
+// Comparison lists the changes between two versions of a world.
+type Comparison struct {
+	Changes []Change `json:"changes"`
+}
+
+// Breaking reports whether any change is breaking.
+func (c *Comparison) Breaking() bool {
+	for _, change := range c.Changes {
+		if change.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Comparison) String() string {
+	if len(c.Changes) == 0 {
+		return "no changes\n"
+	}
+	var s string
+	for _, change := range c.Changes {
+		s += change.String() + "\n"
+	}
+	return s
+}
+
+// flow tells which way values of a type travel between the host and the component.
+type flow uint8
+
+const (
+	// flowIn is set for values the host passes to the component, like the parameters of exports.
+	flowIn flow = 1 << iota
+	// flowOut is set for values the component passes to the host, like the results of exports.
+	flowOut
+)
+
+// Compare compares the old and new versions of a world from the point of view of a host built
+// against the old one, which calls the exports of the component and provides its imports.
+//
+// Removing exports and adding imports is breaking. Changes to functions and types are breaking
+// unless values keep their representation and remain readable by their receiver: cases and flags
+// may be added to types the host only passes to the component, and removed from types the
+// component only passes to the host. Renamed parameters and record fields are compatible, since
+// values are passed by position, while renamed cases change the meaning of values.
+func Compare(old WitWorldDefinition, new WitWorldDefinition) *Comparison {
+	c := &Comparison{Changes: []Change{}}
+	oldItems, newItems := newWorldItems(old), newWorldItems(new)
+
+	for _, path := range oldItems.order {
+		before := oldItems.items[path]
+		after, ok := newItems.items[path]
+		switch {
+		case !ok && before.parent != "" && newItems.items[before.parent] == nil:
+			// Functions of removed interfaces are not listed.
+		case !ok:
+			c.Changes = append(c.Changes, Change{Kind: ChangeRemoved, Item: before.item(), Path: path, Breaking: before.export})
+		case before.function != nil && after.function != nil:
+			if breaking, details := compareFunctions(before, after); len(details) > 0 {
+				c.Changes = append(c.Changes, Change{Kind: ChangeChanged, Item: "function", Path: path, Breaking: breaking, Details: details})
+			}
+		case before.function != nil || after.function != nil:
+			c.Changes = append(c.Changes, Change{Kind: ChangeChanged, Item: after.item(), Path: path, Breaking: true, Details: []string{"changes from " + before.item() + " to " + after.item()}})
+		}
+	}
+	for _, path := range newItems.order {
+		after := newItems.items[path]
+		if _, ok := oldItems.items[path]; ok || after.parent != "" && oldItems.items[after.parent] == nil {
+			continue
+		}
+		c.Changes = append(c.Changes, Change{Kind: ChangeAdded, Item: after.item(), Path: path, Breaking: !after.export})
+	}
+
+	for _, key := range oldItems.typeOrder {
+		before := oldItems.types[key]
+		after, ok := newItems.types[key]
+		if !ok {
+			c.Changes = append(c.Changes, Change{Kind: ChangeRemoved, Item: "type", Path: key})
+			continue
+		}
+		cmp := &typeComparison{flow: oldItems.usage[key] | newItems.usage[key]}
+		cmp.definitions(before, after)
+		if len(cmp.details) > 0 {
+			c.Changes = append(c.Changes, Change{Kind: ChangeChanged, Item: "type", Path: key, Breaking: cmp.breaking, Details: cmp.details})
+		}
+	}
+	for _, key := range newItems.typeOrder {
+		if _, ok := oldItems.types[key]; !ok {
+			c.Changes = append(c.Changes, Change{Kind: ChangeAdded, Item: "type", Path: key})
+		}
+	}
+	return c
+}
+
+// scopeItem is a function or interface imported or exported by a world.
+type scopeItem struct {
+	export   bool
+	function WitFunction
+	// parent is the path of the interface of interface functions.
+	parent string
+}
+
+func (s *scopeItem) item() string {
+	if s.function != nil {
+		return "function"
+	}
+	return "interface"
+}
+
+// worldItems holds the functions and interfaces of a world by path, and the named types they refer
+// to by qualified name along with the ways their values flow.
+type worldItems struct {
+	items     map[string]*scopeItem
+	order     []string
+	types     map[string]WitType
+	typeOrder []string
+	usage     map[string]flow
+}
+
+func newWorldItems(w WitWorldDefinition) *worldItems {
+	s := &worldItems{items: map[string]*scopeItem{}, types: map[string]WitType{}, usage: map[string]flow{}}
+	add := func(path string, item *scopeItem) {
+		if _, ok := s.items[path]; !ok {
+			s.order = append(s.order, path)
+		}
+		s.items[path] = item
+	}
+	for _, direction := range []string{"import", "export"} {
+		items := w.Imports()
+		if direction == "export" {
+			items = w.Exports()
+		}
+		export := direction == "export"
+		for _, item := range items {
+			path := direction + " " + item.Key()
+			switch {
+			case item.Function() != nil:
+				add(path, &scopeItem{export: export, function: item.Function()})
+				s.useFunction(item.Function(), export)
+			case item.Interface() != nil:
+				add(path, &scopeItem{export: export})
+				for _, function := range item.Interface().Functions() {
+					add(path+"#"+function.Name(), &scopeItem{export: export, function: function, parent: path})
+					s.useFunction(function, export)
+				}
+			}
+		}
+	}
+	return s
+}
+
+// useFunction records the types of the parameters and result of a function. The host passes the
+// parameters of exports to the component, and receives their results.
+func (s *worldItems) useFunction(function WitFunction, export bool) {
+	params, result := flowOut, flowIn
+	if export {
+		params, result = flowIn, flowOut
+	}
+	for _, param := range function.Params() {
+		s.use(param.Type(), params)
+	}
+	s.use(function.Returns(), result)
+}
+
+func (s *worldItems) use(t WitType, f flow) {
+	if t == nil {
+		return
+	}
+	if key, named := typeKey(t); named {
+		if _, ok := s.types[key]; !ok {
+			s.types[key] = t
+			s.typeOrder = append(s.typeOrder, key)
+		}
+		if s.usage[key]&f == f {
+			return
+		}
+		s.usage[key] |= f
+	}
+	if alias := t.AliasOf(); alias != nil {
+		s.use(alias, f)
+		return
+	}
+	for _, child := range children(t) {
+		s.use(child, f)
+	}
+}
+
+// typeKey returns the qualified name of named types, like `wasi:io/streams@0.2.0#stream-error`.
+func typeKey(t WitType) (string, bool) {
+	owner := t.Owner()
+	if owner == nil {
+		return "", false
+	}
+	return *owner + "#" + t.Name(), true
+}
+
+// children returns the types a type is made of. Cases without payload are nil.
+func children(t WitType) []WitType {
+	switch t.Kind() {
+	case witigo.AbiTypeList, witigo.AbiTypeOption, witigo.AbiTypeHandle, witigo.AbiTypeFuture, witigo.AbiTypeStream:
+		if elem := t.SubType(); elem != nil {
+			return []WitType{elem.Type()}
+		}
+		return []WitType{nil}
+	case witigo.AbiTypeRecord, witigo.AbiTypeVariant, witigo.AbiTypeTuple, witigo.AbiTypeResult:
+		var types []WitType
+		for _, sub := range t.SubTypes() {
+			types = append(types, sub.Type())
+		}
+		return types
+	default:
+		return nil
+	}
+}
+
+// render returns the type expression of a reference. Named types are rendered by name, qualified
+// if qualified is set.
+func render(t WitType, qualified bool) string {
+	if t == nil {
+		return "_"
+	}
+	if key, named := typeKey(t); named {
+		if qualified {
+			return key
+		}
+		return t.Name()
+	}
+	elems := children(t)
+	renderAll := func() string {
+		rendered := make([]string, len(elems))
+		for i, elem := range elems {
+			rendered[i] = render(elem, qualified)
+		}
+		return strings.Join(rendered, ", ")
+	}
+	switch t.Kind() {
+	case witigo.AbiTypeList, witigo.AbiTypeOption, witigo.AbiTypeFuture, witigo.AbiTypeStream:
+		return t.Kind().String() + "<" + renderAll() + ">"
+	case witigo.AbiTypeHandle:
+		if t.IsBorrow() {
+			return "borrow<" + renderAll() + ">"
+		}
+		return "own<" + renderAll() + ">"
+	case witigo.AbiTypeTuple, witigo.AbiTypeResult:
+		return t.Kind().String() + "<" + renderAll() + ">"
+	case witigo.AbiTypeErrorContext:
+		return "error-context"
+	default:
+		return t.Kind().String()
+	}
+}
+
+func compareFunctions(before *scopeItem, after *scopeItem) (bool, []string) {
+	params, result := flowOut, flowIn
+	if after.export {
+		params, result = flowIn, flowOut
+	}
+	old, new := before.function, after.function
+	cmp := &typeComparison{}
+	if old.Kind() != new.Kind() {
+		cmp.breakingf("changes from %s to %s function", old.Kind(), new.Kind())
+	}
+	oldParams, newParams := old.Params(), new.Params()
+	for i := 0; i < len(oldParams) || i < len(newParams); i++ {
+		switch {
+		case i >= len(newParams):
+			cmp.breakingf("removes parameter `%s`", oldParams[i].Name())
+		case i >= len(oldParams):
+			cmp.breakingf("adds parameter `%s`", newParams[i].Name())
+		default:
+			if oldParams[i].Name() != newParams[i].Name() {
+				cmp.compatiblef("renames parameter `%s` to `%s`", oldParams[i].Name(), newParams[i].Name())
+			}
+			cmp.flow = params
+			cmp.refs("parameter `"+newParams[i].Name()+"`", oldParams[i].Type(), newParams[i].Type())
+		}
+	}
+	cmp.flow = result
+	switch {
+	case old.Returns() == nil && new.Returns() != nil:
+		cmp.breakingf("adds result %s", render(new.Returns(), false))
+	case old.Returns() != nil && new.Returns() == nil:
+		cmp.breakingf("removes result %s", render(old.Returns(), false))
+	default:
+		cmp.refs("result", old.Returns(), new.Returns())
+	}
+	return cmp.breaking, cmp.details
+}
+
+// typeComparison collects the differences between two versions of a type whose values flow as
+// given.
+type typeComparison struct {
+	flow     flow
+	breaking bool
+	details  []string
+}
+
+func (c *typeComparison) breakingf(format string, args ...any) {
+	c.breaking = true
+	c.details = append(c.details, fmt.Sprintf(format, args...))
+}
+
+func (c *typeComparison) compatiblef(format string, args ...any) {
+	c.details = append(c.details, fmt.Sprintf(format, args...))
+}
+
+// refs compares type references. Named types are the same if they have the same qualified name;
+// changes to their definition are reported for the type itself.
+func (c *typeComparison) refs(what string, old WitType, new WitType) {
+	if render(old, true) != render(new, true) {
+		c.breakingf("%s changes from %s to %s", what, render(old, false), render(new, false))
+	}
+}
+
+// definitions compares the definitions of two versions of a named type.
+func (c *typeComparison) definitions(old WitType, new WitType) {
+	oldAlias, newAlias := old.AliasOf(), new.AliasOf()
+	if oldAlias != nil || newAlias != nil {
+		if oldAlias == nil || newAlias == nil {
+			c.breakingf("changes from %s to %s", describeKind(old), describeKind(new))
+			return
+		}
+		c.refs("alias", oldAlias, newAlias)
+		return
+	}
+	if old.Kind() != new.Kind() {
+		c.breakingf("changes from %s to %s", describeKind(old), describeKind(new))
+		return
+	}
+	switch new.Kind() {
+	case witigo.AbiTypeRecord:
+		oldFields, newFields := old.SubTypes(), new.SubTypes()
+		for i := 0; i < len(oldFields) || i < len(newFields); i++ {
+			switch {
+			case i >= len(newFields):
+				c.breakingf("removes field `%s`", oldFields[i].Name())
+			case i >= len(oldFields):
+				c.breakingf("adds field `%s`", newFields[i].Name())
+			default:
+				if oldFields[i].Name() != newFields[i].Name() {
+					c.compatiblef("renames field `%s` to `%s`", oldFields[i].Name(), newFields[i].Name())
+				}
+				c.refs("field `"+newFields[i].Name()+"`", oldFields[i].Type(), newFields[i].Type())
+			}
+		}
+	case witigo.AbiTypeVariant, witigo.AbiTypeEnum, witigo.AbiTypeFlags:
+		c.cases(new.Kind(), old.SubTypes(), new.SubTypes())
+	case witigo.AbiTypeResource:
+	default:
+		c.refs("type", anonymousOf(old), anonymousOf(new))
+	}
+}
+
+// anonymousOf returns a named type stripped of its name, so that named lists, tuples and the like
+// compare by structure.
+func anonymousOf(t WitType) WitType {
+	return &anonymousType{t}
+}
+
+type anonymousType struct {
+	WitType
+}
+
+func (t *anonymousType) Owner() *string { return nil }
+
+func describeKind(t WitType) string {
+	if alias := t.AliasOf(); alias != nil {
+		return "alias of " + render(alias, false)
+	}
+	return t.Kind().String()
+}
+
+// cases compares the cases of variants and enums, or the flags of flags. Cases are identified by
+// position, so they can only be added or removed at the end. Adding cases is compatible for values
+// the host passes to the component, and removing cases for values it receives, as long as the
+// representation of values does not change.
+func (c *typeComparison) cases(kind witigo.AbiType, old []WitTypeReference, new []WitTypeReference) {
+	what := "case"
+	if kind == witigo.AbiTypeFlags {
+		what = "flag"
+	}
+	common := min(len(old), len(new))
+	for i := 0; i < common; i++ {
+		if old[i].Name() != new[i].Name() {
+			c.breakingf("renames %s `%s` to `%s`", what, old[i].Name(), new[i].Name())
+		}
+		if kind == witigo.AbiTypeVariant {
+			c.refs(what+" `"+new[i].Name()+"`", old[i].Type(), new[i].Type())
+		}
+	}
+
+	// Extra cases keep the representation if they need no more space than the common ones.
+	sameLayout := representationSize(kind, len(old)) == representationSize(kind, len(new))
+	payloads := map[string]bool{"_": true}
+	if kind == witigo.AbiTypeVariant {
+		for i := 0; i < common; i++ {
+			payloads[render(new[i].Type(), true)] = true
+		}
+	}
+	extra, added := new[common:], true
+	if len(old) > len(new) {
+		extra, added = old[common:], false
+	}
+	for _, extraCase := range extra {
+		if kind == witigo.AbiTypeVariant && !payloads[render(extraCase.Type(), true)] {
+			sameLayout = false
+		}
+	}
+	for _, extraCase := range extra {
+		var reason string
+		switch {
+		case !sameLayout:
+			reason = "changes the representation of values"
+		case added && c.flow&flowOut != 0:
+			reason = "the host cannot read it"
+		case !added && c.flow&flowIn != 0:
+			reason = "the component no longer accepts it"
+		}
+		verb := "adds"
+		if !added {
+			verb = "removes"
+		}
+		if reason != "" {
+			c.breakingf("%s %s `%s`: %s", verb, what, extraCase.Name(), reason)
+		} else {
+			c.compatiblef("%s %s `%s`", verb, what, extraCase.Name())
+		}
+	}
+}
+
+// representationSize returns the size of the discriminant of variants and enums with n cases, or
+// of flags with n flags, in bytes.
+func representationSize(kind witigo.AbiType, n int) int {
+	if kind == witigo.AbiTypeFlags {
+		switch {
+		case n <= 8:
+			return 1
+		case n <= 16:
+			return 2
+		default:
+			return 4 * ((n + 31) / 32)
+		}
+	}
+	return discriminantSize(n) / 8
+}
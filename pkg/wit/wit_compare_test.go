@@ -0,0 +1,161 @@
+package wit_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/rioam2/witigo/pkg/wit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	const types = `
+interface types {
+  variant shape { circle(f32), square(f32) }
+  enum mode { read, write }
+  flags perms { a, b, c, d, e, f, g, h }
+  record point { x: s32, y: s32 }
+}
+`
+	tests := []struct {
+		name     string
+		old      string
+		new      string
+		expected string
+		breaking bool
+	}{
+		{
+			name:     "identical",
+			old:      `world app { export run: func(a: u32) -> string; }`,
+			new:      `world app { export run: func(a: u32) -> string; }`,
+			expected: "no changes\n",
+		},
+		{
+			name: "exports and imports",
+			old:  `world app { import log: func(msg: string); export run: func(); export stop: func(); }`,
+			new:  `world app { import now: func() -> u64; export run: func(); export start: func(); }`,
+			expected: `compatible: removed function import log
+breaking: removed function export stop
+breaking: added function import now
+compatible: added function export start
+`,
+			breaking: true,
+		},
+		{
+			name: "parameters and results",
+			old:  `world app { export run: func(a: u32, b: string) -> u32; export get: func() -> u32; }`,
+			new:  `world app { export run: func(a: u64, c: string, d: option<u32>); export get: func() -> u32; }`,
+			expected: "breaking: changed function export run\n" +
+				"  - parameter `a` changes from u32 to u64\n" +
+				"  - renames parameter `b` to `c`\n" +
+				"  - adds parameter `d`\n" +
+				"  - removes result u32\n",
+			breaking: true,
+		},
+		{
+			name: "interfaces",
+			old:  types + "interface api { use types.{point}; get: func() -> point; put: func(p: point); }\nworld app { export api; }",
+			new:  types + "interface api { use types.{point}; get: func() -> point; }\ninterface extra { f: func(); }\nworld app { export api; export extra; }",
+			expected: "breaking: removed function export test:pkg/api#put\n" +
+				"compatible: added interface export test:pkg/extra\n",
+			breaking: true,
+		},
+		{
+			name: "cases passed to the component",
+			old:  types + "world app { use types.{shape, mode, perms}; export draw: func(s: shape, m: mode, p: perms); }",
+			new: `
+interface types {
+  variant shape { circle(f32), square(f32), triangle(f32), dot }
+  enum mode { read, write, append }
+  flags perms { a, b, c, d, e, f, g, h, i }
+  record point { x: s32, y: s32 }
+}
+world app { use types.{shape, mode, perms}; export draw: func(s: shape, m: mode, p: perms); }`,
+			expected: "compatible: changed type test:pkg/types#shape\n" +
+				"  - adds case `triangle`\n" +
+				"  - adds case `dot`\n" +
+				"compatible: changed type test:pkg/types#mode\n" +
+				"  - adds case `append`\n" +
+				"breaking: changed type test:pkg/types#perms\n" +
+				"  - adds flag `i`: changes the representation of values\n",
+			breaking: true,
+		},
+		{
+			name: "cases passed to the host",
+			old:  types + "world app { use types.{shape, mode}; export get: func() -> tuple<shape, mode>; }",
+			new: `
+interface types {
+  variant shape { circle(f32), square(f64) }
+  enum mode { read, write, append }
+  record point { x: s32, y: s32 }
+}
+world app { use types.{shape, mode}; export get: func() -> tuple<shape, mode>; }`,
+			expected: "breaking: changed type test:pkg/types#shape\n" +
+				"  - case `square` changes from f32 to f64\n" +
+				"breaking: changed type test:pkg/types#mode\n" +
+				"  - adds case `append`: the host cannot read it\n",
+			breaking: true,
+		},
+		{
+			name: "removed cases",
+			old:  types + "world app { use types.{mode}; export get: func() -> mode; import set: func(m: mode); }",
+			new:  "interface types { enum mode { read } }\nworld app { use types.{mode}; export get: func() -> mode; import set: func(m: mode); }",
+			expected: "compatible: changed type test:pkg/types#mode\n" +
+				"  - removes case `write`\n",
+		},
+		{
+			name: "records",
+			old:  types + "world app { use types.{point}; export get: func() -> point; }",
+			new:  "interface types { record point { x: s32, z: s32, w: s32 } }\nworld app { use types.{point}; export get: func() -> point; }",
+			expected: "breaking: changed type test:pkg/types#point\n" +
+				"  - renames field `y` to `z`\n" +
+				"  - adds field `w`\n",
+			breaking: true,
+		},
+		{
+			name: "renamed types",
+			old:  types + "world app { use types.{point}; export get: func() -> list<point>; }",
+			new:  "interface types { record coord { x: s32, y: s32 } }\nworld app { use types.{coord}; export get: func() -> list<coord>; }",
+			expected: "breaking: changed function export get\n" +
+				"  - result changes from list<point> to list<coord>\n" +
+				"compatible: removed type app#point\n" +
+				"compatible: removed type test:pkg/types#point\n" +
+				"compatible: added type app#coord\n" +
+				"compatible: added type test:pkg/types#coord\n",
+			breaking: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			world := func(source string) wit.WitWorldDefinition {
+				def, err := wit.Parse("test.wit", "package test:pkg;\n"+source)
+				require.NoError(t, err)
+				world, err := def.World("app")
+				require.NoError(t, err)
+				return world
+			}
+			comparison := wit.Compare(world(tt.old), world(tt.new))
+			assert.Equal(t, tt.expected, comparison.String())
+			assert.Equal(t, tt.breaking, comparison.Breaking())
+		})
+	}
+}
+
+func TestCompareJson(t *testing.T) {
+	old, err := wit.Parse("test.wit", "package test:pkg;\nworld app { export run: func(); }")
+	require.NoError(t, err)
+	new, err := wit.Parse("test.wit", "package test:pkg;\nworld app { export run: func(a: u32); import log: func(); }")
+	require.NoError(t, err)
+	oldWorld, err := old.World("")
+	require.NoError(t, err)
+	newWorld, err := new.World("")
+	require.NoError(t, err)
+
+	raw, err := json.Marshal(wit.Compare(oldWorld, newWorld))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"changes": [
+		{"kind": "changed", "item": "function", "path": "export run", "breaking": true, "details": ["adds parameter `+"`a`"+`"]},
+		{"kind": "added", "item": "function", "path": "import log", "breaking": true}
+	]}`, string(raw))
+}
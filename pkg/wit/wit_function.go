@@ -1,58 +1,61 @@
 package wit
 
-import (
-	"encoding/json"
-	"strings"
-)
-
 type WitFunction interface {
 	Name() string
+	Kind() string
+	Resource() WitType
+	Docs() string
+	Stability() *Stability
 	Params() []WitTypeReference
 	Returns() WitType
 	String() string
 	ReferencesType(w WitType) bool
 }
 
+// WitFunctionImpl is a view of a function of a definition.
 type WitFunctionImpl struct {
-	Raw  json.RawMessage
-	Root WitDefinition
+	def  *WitDefinitionImpl
+	data *functionData
 }
 
 var _ WitFunction = &WitFunctionImpl{}
 
 func (w *WitFunctionImpl) Name() string {
-	var data struct {
-		Name string `json:"name"`
+	return w.data.name
+}
+
+// Kind returns "freestanding", or "method", "static" or "constructor" for functions of resources.
+func (w *WitFunctionImpl) Kind() string {
+	return w.data.kind
+}
+
+// Resource returns the resource of methods, static functions and constructors, or nil for
+// freestanding functions.
+func (w *WitFunctionImpl) Resource() WitType {
+	if w.data.resource == nil {
+		return nil
 	}
-	json.Unmarshal(w.Raw, &data)
-	return data.Name
+	return w.def.types[*w.data.resource]
+}
+
+func (w *WitFunctionImpl) Docs() string {
+	return w.data.docs
+}
+
+func (w *WitFunctionImpl) Stability() *Stability {
+	return w.data.stability
 }
 
 func (w *WitFunctionImpl) Params() []WitTypeReference {
-	var data struct {
-		Params []json.RawMessage `json:"params"`
-	}
-	json.Unmarshal(w.Raw, &data)
 	var params []WitTypeReference
-	for _, param := range data.Params {
-		params = append(params, &WitTypeReferenceImpl{Raw: param, Root: w.Root})
+	for _, param := range w.data.params {
+		params = append(params, &WitTypeReferenceImpl{def: w.def, name: param.name, ref: param.ref, docs: param.docs})
 	}
 	return params
 }
 
 func (w *WitFunctionImpl) Returns() WitType {
-	var data struct {
-		Result any `json:"result"`
-	}
-	json.Unmarshal(w.Raw, &data)
-	switch t := data.Result.(type) {
-	case string:
-		return &WitTypeImpl{[]byte("{\"type\":\"" + t + "\"}"), w.Root}
-	case float64:
-		return w.Root.Types()[int(t)]
-	default:
-		return nil
-	}
+	return w.def.typeOf(w.data.result, "")
 }
 
 func (w *WitFunctionImpl) String() string {
@@ -66,22 +69,10 @@ func (w *WitFunctionImpl) String() string {
 	return w.Name() + ": func (" + params + ") -> " + w.Returns().String()
 }
 
+// ReferencesType reports whether t is reachable from the parameters or the result of the function.
+// t must be a type of the same definition.
 func (w *WitFunctionImpl) ReferencesType(t WitType) bool {
-	testTypeString := t.String()
-
-	for _, param := range w.Params() {
-		paramTypeString := param.Type().String()
-		if paramTypeString != "" && strings.Contains(paramTypeString, testTypeString) {
-			return true
-		}
-	}
-
-	if w.Returns() != nil {
-		returnTypeString := w.Returns().String()
-		if returnTypeString != "" && strings.Contains(returnTypeString, testTypeString) {
-			return true
-		}
-	}
-
-	return false
+	return w.def.references(t, func(reachable map[int]bool, visit func(ref *typeRef)) {
+		w.def.model.walkFunction(w.data, reachable, visit)
+	})
 }
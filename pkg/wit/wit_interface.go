@@ -0,0 +1,99 @@
+package wit
+
+import "strings"
+
+type WitInterface interface {
+	Name() string
+	QualifiedName() string
+	Package() WitPackage
+	Docs() string
+	Stability() *Stability
+	Types() []WitType
+	Functions() []WitFunction
+}
+
+// WitInterfaceImpl is a view of the interface at index in a definition.
+type WitInterfaceImpl struct {
+	def   *WitDefinitionImpl
+	index int
+}
+
+var _ WitInterface = &WitInterfaceImpl{}
+
+func (w *WitInterfaceImpl) data() *interfaceData {
+	return &w.def.model.interfaces[w.index]
+}
+
+// Name returns the name of the interface, or an empty string for interfaces declared inline in a
+// world.
+func (w *WitInterfaceImpl) Name() string {
+	if name := w.data().name; name != nil {
+		return *name
+	}
+	return ""
+}
+
+// QualifiedName returns the name of the interface qualified with its package, for example
+// `wasi:io/streams@0.2.0`. Interfaces declared inline in a world are named after their import or
+// export, like `world.name`.
+func (w *WitInterfaceImpl) QualifiedName() string {
+	data := w.data()
+	if data.name == nil {
+		for _, world := range w.def.model.worlds {
+			for _, items := range [][]worldItem{world.imports, world.exports} {
+				for _, item := range items {
+					if item.iface != nil && *item.iface == w.index {
+						return world.name + "." + item.key
+					}
+				}
+			}
+		}
+		return ""
+	}
+	if data.pkg == nil {
+		return *data.name
+	}
+	unversioned, version, versioned := strings.Cut(w.def.model.packages[*data.pkg].name, "@")
+	if versioned {
+		return unversioned + "/" + *data.name + "@" + version
+	}
+	return unversioned + "/" + *data.name
+}
+
+// Package returns the package of the interface, or nil if it is unknown.
+func (w *WitInterfaceImpl) Package() WitPackage {
+	pkg := w.data().pkg
+	if pkg == nil {
+		return nil
+	}
+	return w.def.packages[*pkg]
+}
+
+func (w *WitInterfaceImpl) Docs() string {
+	return w.data().docs
+}
+
+func (w *WitInterfaceImpl) Stability() *Stability {
+	return w.data().stability
+}
+
+// Types returns the types of the interface, including the types it uses from other interfaces,
+// in the order they are declared.
+func (w *WitInterfaceImpl) Types() []WitType {
+	var types []WitType
+	for _, t := range w.data().types {
+		types = append(types, w.def.types[t.index])
+	}
+	return types
+}
+
+// Functions returns the functions of the interface, including the functions of its resources, in
+// the order they are declared.
+func (w *WitInterfaceImpl) Functions() []WitFunction {
+	data := w.data()
+	functions := make([]WitFunction, len(data.functions))
+	for i := range data.functions {
+		functions[i] = &WitFunctionImpl{def: w.def, data: &data.functions[i]}
+	}
+	return functions
+}
@@ -0,0 +1,302 @@
+package wit
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenVersion
+	tokenLBrace
+	tokenRBrace
+	tokenLParen
+	tokenRParen
+	tokenLAngle
+	tokenRAngle
+	tokenComma
+	tokenSemicolon
+	tokenColon
+	tokenEquals
+	tokenPeriod
+	tokenSlash
+	tokenArrow
+	tokenStar
+	tokenAt
+	tokenUnderscore
+)
+
+var tokenNames = map[tokenKind]string{
+	tokenEOF:        "end of file",
+	tokenIdent:      "identifier",
+	tokenVersion:    "version",
+	tokenLBrace:     "`{`",
+	tokenRBrace:     "`}`",
+	tokenLParen:     "`(`",
+	tokenRParen:     "`)`",
+	tokenLAngle:     "`<`",
+	tokenRAngle:     "`>`",
+	tokenComma:      "`,`",
+	tokenSemicolon:  "`;`",
+	tokenColon:      "`:`",
+	tokenEquals:     "`=`",
+	tokenPeriod:     "`.`",
+	tokenSlash:      "`/`",
+	tokenArrow:      "`->`",
+	tokenStar:       "`*`",
+	tokenAt:         "`@`",
+	tokenUnderscore: "`_`",
+}
+
+func (k tokenKind) String() string {
+	return tokenNames[k]
+}
+
+var punctuation = map[rune]tokenKind{
+	'{': tokenLBrace,
+	'}': tokenRBrace,
+	'(': tokenLParen,
+	')': tokenRParen,
+	'<': tokenLAngle,
+	'>': tokenRAngle,
+	',': tokenComma,
+	';': tokenSemicolon,
+	':': tokenColon,
+	'=': tokenEquals,
+	'.': tokenPeriod,
+	'/': tokenSlash,
+	'*': tokenStar,
+	'@': tokenAt,
+	'_': tokenUnderscore,
+}
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  Position
+	// escaped is set for identifiers prefixed with `%`, which are never keywords.
+	escaped bool
+	// docs holds the doc comments preceding the token.
+	docs string
+}
+
+// isKeyword reports whether t is the unescaped keyword kw.
+func (t token) isKeyword(kw string) bool {
+	return t.kind == tokenIdent && !t.escaped && t.text == kw
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenIdent:
+		if t.escaped {
+			return "`%" + t.text + "`"
+		}
+		return "`" + t.text + "`"
+	case tokenVersion:
+		return "`" + t.text + "`"
+	default:
+		return t.kind.String()
+	}
+}
+
+type lexer struct {
+	file string
+	src  []rune
+	off  int
+	line int
+	col  int
+}
+
+// tokenize splits the WIT source of file into tokens. Comments are attached as documentation to
+// the token that follows them, like wasm-tools does.
+func tokenize(file string, src string) ([]token, error) {
+	l := &lexer{file: file, src: []rune(src), line: 1, col: 1}
+	var tokens []token
+	var docs []string
+	for {
+		if err := l.skipSpace(&docs); err != nil {
+			return nil, err
+		}
+		pos := l.pos()
+		if l.off >= len(l.src) {
+			tokens = append(tokens, token{kind: tokenEOF, pos: pos})
+			return tokens, nil
+		}
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		tok.pos = pos
+		tok.docs = docContents(docs)
+		docs = nil
+		tokens = append(tokens, tok)
+	}
+}
+
+func (l *lexer) pos() Position {
+	return Position{File: l.file, Line: l.line, Column: l.col}
+}
+
+func (l *lexer) peek(n int) rune {
+	if l.off+n >= len(l.src) {
+		return 0
+	}
+	return l.src[l.off+n]
+}
+
+func (l *lexer) advance() rune {
+	r := l.src[l.off]
+	l.off++
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return r
+}
+
+func (l *lexer) errorf(pos Position, format string, args ...any) error {
+	return &ParseError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// skipSpace skips whitespace and comments, collecting the contents of comments into docs.
+func (l *lexer) skipSpace(docs *[]string) error {
+	for l.off < len(l.src) {
+		switch {
+		case unicode.IsSpace(l.peek(0)):
+			l.advance()
+		case l.peek(0) == '/' && l.peek(1) == '/':
+			start := l.off
+			for l.off < len(l.src) && l.peek(0) != '\n' {
+				l.advance()
+			}
+			*docs = append(*docs, strings.TrimLeft(string(l.src[start:l.off]), "/"))
+		case l.peek(0) == '/' && l.peek(1) == '*':
+			pos := l.pos()
+			start := l.off
+			l.advance()
+			l.advance()
+			for depth := 1; depth > 0; {
+				switch {
+				case l.off >= len(l.src):
+					return l.errorf(pos, "unterminated block comment")
+				case l.peek(0) == '/' && l.peek(1) == '*':
+					l.advance()
+					l.advance()
+					depth++
+				case l.peek(0) == '*' && l.peek(1) == '/':
+					l.advance()
+					l.advance()
+					depth--
+				default:
+					l.advance()
+				}
+			}
+			comment := string(l.src[start:l.off])
+			if strings.HasPrefix(comment, "/**") && len(comment) >= len("/***/") {
+				*docs = append(*docs, comment[len("/**"):len(comment)-len("*/")])
+			} else {
+				*docs = append(*docs, strings.TrimLeft(comment, "/"))
+			}
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// docContents joins the contents of the comments preceding a token. The space separating the
+// comment markers from the text is removed when every comment has one.
+func docContents(comments []string) string {
+	strip := true
+	for _, comment := range comments {
+		if comment != "" && !strings.HasPrefix(comment, " ") {
+			strip = false
+		}
+	}
+	lines := make([]string, 0, len(comments))
+	for _, comment := range comments {
+		if strip {
+			comment = strings.TrimPrefix(comment, " ")
+		}
+		for _, line := range strings.Split(comment, "\n") {
+			lines = append(lines, strings.TrimRightFunc(line, unicode.IsSpace))
+		}
+	}
+	return strings.TrimRightFunc(strings.Join(lines, "\n"), unicode.IsSpace)
+}
+
+func (l *lexer) next() (token, error) {
+	pos := l.pos()
+	r := l.peek(0)
+	switch {
+	case r == '-' && l.peek(1) == '>':
+		l.advance()
+		l.advance()
+		return token{kind: tokenArrow}, nil
+	case r == '%' || isIdentStart(r):
+		escaped := r == '%'
+		if escaped {
+			l.advance()
+		}
+		start := l.off
+		for l.off < len(l.src) && isIdentPart(l.peek(0)) {
+			l.advance()
+		}
+		text := string(l.src[start:l.off])
+		if err := validateIdent(text); err != nil {
+			return token{}, l.errorf(pos, "invalid identifier `%s`: %s", text, err)
+		}
+		return token{kind: tokenIdent, text: text, escaped: escaped}, nil
+	case r >= '0' && r <= '9':
+		start := l.off
+		// A period ends the version when it is not followed by another component, as in
+		// `use a:b/c@1.0.0.{d}`.
+		for l.off < len(l.src) && isVersionPart(l.peek(0)) && (l.peek(0) != '.' || isIdentPart(l.peek(1))) {
+			l.advance()
+		}
+		return token{kind: tokenVersion, text: string(l.src[start:l.off])}, nil
+	}
+	if kind, ok := punctuation[r]; ok {
+		l.advance()
+		return token{kind: kind}, nil
+	}
+	return token{}, l.errorf(pos, "unexpected character %q", r)
+}
+
+func isIdentStart(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+func isIdentPart(r rune) bool {
+	return isIdentStart(r) || r >= '0' && r <= '9' || r == '-'
+}
+
+func isVersionPart(r rune) bool {
+	return isIdentPart(r) || r == '.' || r == '+'
+}
+
+// validateIdent checks that ident is kebab-case: words separated by single dashes, each either
+// all lowercase or all uppercase, the first one starting with a letter.
+func validateIdent(ident string) error {
+	if ident == "" {
+		return fmt.Errorf("identifiers must not be empty")
+	}
+	for i, word := range strings.Split(ident, "-") {
+		if word == "" {
+			return fmt.Errorf("identifiers must not contain empty words")
+		}
+		if i == 0 && !isIdentStart(rune(word[0])) {
+			return fmt.Errorf("identifiers must start with a letter")
+		}
+		if strings.ToLower(word) != word && strings.ToUpper(word) != word {
+			return fmt.Errorf("word `%s` mixes upper and lower case", word)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,762 @@
+package wit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	witigo "github.com/rioam2/witigo/pkg"
+)
+
+// model is a WIT definition decoded from its JSON form. Worlds, interfaces, types and packages are
+// indexed like the arrays of the JSON document, so references between them are plain indices.
+type model struct {
+	worlds     []worldData
+	interfaces []interfaceData
+	types      []typeData
+	packages   []packageData
+}
+
+// typeRef refers either to a primitive type, by name, or to a type of the definition, by index.
+type typeRef struct {
+	prim  string
+	index int
+}
+
+type namedRef struct {
+	name string
+	// ref is nil for enum cases, flags and variant cases without payload.
+	ref  *typeRef
+	docs string
+}
+
+type typeData struct {
+	name      *string
+	owner     ownerData
+	docs      string
+	stability *Stability
+	// kind is the kind of the type, with aliases resolved to the kind of the type they refer to.
+	kind witigo.AbiType
+	// alias is set for `type a = b` definitions and uses of types defined elsewhere.
+	alias *typeRef
+	// elem is the element of lists, options, futures and streams, and the resource of handles.
+	elem *typeRef
+	// borrow tells borrowed handles from owned ones.
+	borrow bool
+	// fields are the fields of records, and the cases of variants, enums and flags.
+	fields []namedRef
+	// tuple lists the element types of tuples.
+	tuple []typeRef
+	// ok and err are the payloads of results.
+	ok, err *typeRef
+}
+
+type ownerData struct {
+	world *int
+	iface *int
+}
+
+type functionData struct {
+	name string
+	kind string
+	// resource is the index of the resource of methods, static functions and constructors.
+	resource  *int
+	params    []namedRef
+	result    *typeRef
+	docs      string
+	stability *Stability
+}
+
+type worldItem struct {
+	key      string
+	function *functionData
+	iface    *int
+	typ      *int
+	// stability is the stability of interface items.
+	stability *Stability
+}
+
+type worldData struct {
+	name      string
+	imports   []worldItem
+	exports   []worldItem
+	pkg       *int
+	docs      string
+	stability *Stability
+}
+
+type interfaceData struct {
+	name      *string
+	types     []namedIndex
+	functions []functionData
+	pkg       *int
+	docs      string
+	stability *Stability
+}
+
+type packageData struct {
+	name       string
+	interfaces []namedIndex
+	worlds     []namedIndex
+	docs       string
+}
+
+type namedIndex struct {
+	name  string
+	index int
+}
+
+var primitiveKinds = map[string]witigo.AbiType{
+	"bool":          witigo.AbiTypeBool,
+	"s8":            witigo.AbiTypeS8,
+	"s16":           witigo.AbiTypeS16,
+	"s32":           witigo.AbiTypeS32,
+	"s64":           witigo.AbiTypeS64,
+	"u8":            witigo.AbiTypeU8,
+	"u16":           witigo.AbiTypeU16,
+	"u32":           witigo.AbiTypeU32,
+	"u64":           witigo.AbiTypeU64,
+	"f32":           witigo.AbiTypeF32,
+	"f64":           witigo.AbiTypeF64,
+	"char":          witigo.AbiTypeChar,
+	"string":        witigo.AbiTypeString,
+	"error-context": witigo.AbiTypeErrorContext,
+}
+
+type docsData struct {
+	Contents *string `json:"contents"`
+}
+
+func (d *docsData) text() string {
+	if d == nil || d.Contents == nil {
+		return ""
+	}
+	return *d.Contents
+}
+
+type stabilityData struct {
+	Stable *struct {
+		Since      string `json:"since"`
+		Deprecated string `json:"deprecated"`
+	} `json:"stable"`
+	Unstable *struct {
+		Feature    string `json:"feature"`
+		Deprecated string `json:"deprecated"`
+	} `json:"unstable"`
+}
+
+func (d *stabilityData) stability() *Stability {
+	switch {
+	case d == nil:
+		return nil
+	case d.Stable != nil:
+		return &Stability{Since: d.Stable.Since, Deprecated: d.Stable.Deprecated}
+	case d.Unstable != nil:
+		return &Stability{Feature: d.Unstable.Feature, Deprecated: d.Unstable.Deprecated}
+	default:
+		return nil
+	}
+}
+
+// decodeModel decodes the JSON form of a definition, as printed by `wasm-tools component wit -j`.
+func decodeModel(raw json.RawMessage) (*model, error) {
+	var data struct {
+		Worlds     []json.RawMessage `json:"worlds"`
+		Interfaces []json.RawMessage `json:"interfaces"`
+		Types      []json.RawMessage `json:"types"`
+		Packages   []json.RawMessage `json:"packages"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("error decoding WIT definition: %w", err)
+	}
+	m := &model{
+		worlds:     make([]worldData, len(data.Worlds)),
+		interfaces: make([]interfaceData, len(data.Interfaces)),
+		types:      make([]typeData, len(data.Types)),
+		packages:   make([]packageData, len(data.Packages)),
+	}
+	d := &decoder{m: m}
+	for i, rawType := range data.Types {
+		if err := d.decodeType(rawType, &m.types[i]); err != nil {
+			return nil, fmt.Errorf("error decoding type %d: %w", i, err)
+		}
+	}
+	for i, rawInterface := range data.Interfaces {
+		if err := d.decodeInterface(rawInterface, &m.interfaces[i]); err != nil {
+			return nil, fmt.Errorf("error decoding interface %d: %w", i, err)
+		}
+	}
+	for i, rawWorld := range data.Worlds {
+		if err := d.decodeWorld(rawWorld, &m.worlds[i]); err != nil {
+			return nil, fmt.Errorf("error decoding world %d: %w", i, err)
+		}
+	}
+	for i, rawPackage := range data.Packages {
+		if err := d.decodePackage(rawPackage, &m.packages[i]); err != nil {
+			return nil, fmt.Errorf("error decoding package %d: %w", i, err)
+		}
+	}
+	if err := m.resolveAliases(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+type decoder struct {
+	m *model
+}
+
+func (d *decoder) typeRef(raw json.RawMessage) (*typeRef, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var ref any
+	if err := json.Unmarshal(raw, &ref); err != nil {
+		return nil, err
+	}
+	switch ref := ref.(type) {
+	case string:
+		if _, ok := primitiveKinds[ref]; !ok {
+			return nil, fmt.Errorf("unknown primitive type %q", ref)
+		}
+		return &typeRef{prim: ref, index: -1}, nil
+	case float64:
+		return d.index(int(ref), len(d.m.types), "type")
+	default:
+		return nil, fmt.Errorf("invalid type reference %s", raw)
+	}
+}
+
+func (d *decoder) index(index int, count int, what string) (*typeRef, error) {
+	if index < 0 || index >= count {
+		return nil, fmt.Errorf("%s index %d out of range", what, index)
+	}
+	return &typeRef{index: index}, nil
+}
+
+func (d *decoder) checkIndex(index *int, count int, what string) error {
+	if index == nil {
+		return nil
+	}
+	_, err := d.index(*index, count, what)
+	return err
+}
+
+func (d *decoder) decodeType(raw json.RawMessage, t *typeData) error {
+	var data struct {
+		Name  *string         `json:"name"`
+		Kind  json.RawMessage `json:"kind"`
+		Owner *struct {
+			World     *int `json:"world"`
+			Interface *int `json:"interface"`
+		} `json:"owner"`
+		Docs      *docsData      `json:"docs"`
+		Stability *stabilityData `json:"stability"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return err
+	}
+	t.name = data.Name
+	t.docs = data.Docs.text()
+	t.stability = data.Stability.stability()
+	if data.Owner != nil {
+		t.owner = ownerData{world: data.Owner.World, iface: data.Owner.Interface}
+	}
+
+	var kindName string
+	if json.Unmarshal(data.Kind, &kindName) == nil {
+		switch kindName {
+		case "resource":
+			t.kind = witigo.AbiTypeResource
+			return nil
+		default:
+			return fmt.Errorf("unknown type kind %q", kindName)
+		}
+	}
+
+	var kind map[string]json.RawMessage
+	if err := json.Unmarshal(data.Kind, &kind); err != nil || len(kind) != 1 {
+		return fmt.Errorf("invalid type kind %s", data.Kind)
+	}
+	for name, rawKind := range kind {
+		switch name {
+		case "type":
+			ref, err := d.typeRef(rawKind)
+			if err != nil {
+				return err
+			}
+			t.alias = ref
+		case "list", "option", "future", "stream":
+			ref, err := d.typeRef(rawKind)
+			if err != nil {
+				return err
+			}
+			t.elem = ref
+			t.kind = map[string]witigo.AbiType{
+				"list":   witigo.AbiTypeList,
+				"option": witigo.AbiTypeOption,
+				"future": witigo.AbiTypeFuture,
+				"stream": witigo.AbiTypeStream,
+			}[name]
+		case "handle":
+			var handle struct {
+				Own    json.RawMessage `json:"own"`
+				Borrow json.RawMessage `json:"borrow"`
+			}
+			if err := json.Unmarshal(rawKind, &handle); err != nil {
+				return err
+			}
+			resource := handle.Own
+			if handle.Borrow != nil {
+				resource = handle.Borrow
+				t.borrow = true
+			}
+			ref, err := d.typeRef(resource)
+			if err != nil {
+				return err
+			}
+			t.kind = witigo.AbiTypeHandle
+			t.elem = ref
+		case "record":
+			var record struct {
+				Fields []json.RawMessage `json:"fields"`
+			}
+			if err := json.Unmarshal(rawKind, &record); err != nil {
+				return err
+			}
+			fields, err := d.namedRefs(record.Fields)
+			if err != nil {
+				return err
+			}
+			t.kind = witigo.AbiTypeRecord
+			t.fields = fields
+		case "variant":
+			var variant struct {
+				Cases []json.RawMessage `json:"cases"`
+			}
+			if err := json.Unmarshal(rawKind, &variant); err != nil {
+				return err
+			}
+			cases, err := d.namedRefs(variant.Cases)
+			if err != nil {
+				return err
+			}
+			t.kind = witigo.AbiTypeVariant
+			t.fields = cases
+		case "enum":
+			var enum struct {
+				Cases []json.RawMessage `json:"cases"`
+			}
+			if err := json.Unmarshal(rawKind, &enum); err != nil {
+				return err
+			}
+			cases, err := d.namedRefs(enum.Cases)
+			if err != nil {
+				return err
+			}
+			t.kind = witigo.AbiTypeEnum
+			t.fields = cases
+		case "flags":
+			var flags struct {
+				Flags []json.RawMessage `json:"flags"`
+			}
+			if err := json.Unmarshal(rawKind, &flags); err != nil {
+				return err
+			}
+			names, err := d.namedRefs(flags.Flags)
+			if err != nil {
+				return err
+			}
+			t.kind = witigo.AbiTypeFlags
+			t.fields = names
+		case "tuple":
+			var tuple struct {
+				Types []json.RawMessage `json:"types"`
+			}
+			if err := json.Unmarshal(rawKind, &tuple); err != nil {
+				return err
+			}
+			for _, rawElem := range tuple.Types {
+				ref, err := d.typeRef(rawElem)
+				if err != nil {
+					return err
+				}
+				if ref == nil {
+					return fmt.Errorf("tuple element without type")
+				}
+				t.tuple = append(t.tuple, *ref)
+			}
+			t.kind = witigo.AbiTypeTuple
+		case "result":
+			var result struct {
+				Ok  json.RawMessage `json:"ok"`
+				Err json.RawMessage `json:"err"`
+			}
+			if err := json.Unmarshal(rawKind, &result); err != nil {
+				return err
+			}
+			ok, err := d.typeRef(result.Ok)
+			if err != nil {
+				return err
+			}
+			errRef, err := d.typeRef(result.Err)
+			if err != nil {
+				return err
+			}
+			t.kind = witigo.AbiTypeResult
+			t.ok, t.err = ok, errRef
+		default:
+			return fmt.Errorf("unknown type kind %q", name)
+		}
+	}
+	return nil
+}
+
+func (d *decoder) namedRefs(raw []json.RawMessage) ([]namedRef, error) {
+	refs := make([]namedRef, 0, len(raw))
+	for _, rawRef := range raw {
+		var data struct {
+			Name string          `json:"name"`
+			Type json.RawMessage `json:"type"`
+			Docs *docsData       `json:"docs"`
+		}
+		if err := json.Unmarshal(rawRef, &data); err != nil {
+			return nil, err
+		}
+		ref, err := d.typeRef(data.Type)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, namedRef{name: data.Name, ref: ref, docs: data.Docs.text()})
+	}
+	return refs, nil
+}
+
+func (d *decoder) decodeFunction(raw json.RawMessage) (functionData, error) {
+	var data struct {
+		Name      string            `json:"name"`
+		Kind      json.RawMessage   `json:"kind"`
+		Params    []json.RawMessage `json:"params"`
+		Result    json.RawMessage   `json:"result"`
+		Docs      *docsData         `json:"docs"`
+		Stability *stabilityData    `json:"stability"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return functionData{}, err
+	}
+	params, err := d.namedRefs(data.Params)
+	if err != nil {
+		return functionData{}, fmt.Errorf("function %s: %w", data.Name, err)
+	}
+	result, err := d.typeRef(data.Result)
+	if err != nil {
+		return functionData{}, fmt.Errorf("function %s: %w", data.Name, err)
+	}
+	f := functionData{name: data.Name, params: params, result: result, docs: data.Docs.text(), stability: data.Stability.stability()}
+	// The kind is either "freestanding" or an object like {"method": 3}.
+	var kindName string
+	if json.Unmarshal(data.Kind, &kindName) == nil {
+		f.kind = kindName
+		return f, nil
+	}
+	var kind map[string]int
+	if err := json.Unmarshal(data.Kind, &kind); err != nil || len(kind) != 1 {
+		return functionData{}, fmt.Errorf("function %s: invalid kind %s", data.Name, data.Kind)
+	}
+	for name, resource := range kind {
+		if err := d.checkIndex(&resource, len(d.m.types), "type"); err != nil {
+			return functionData{}, fmt.Errorf("function %s: %w", data.Name, err)
+		}
+		f.kind = name
+		f.resource = &resource
+	}
+	return f, nil
+}
+
+func (d *decoder) decodeInterface(raw json.RawMessage, i *interfaceData) error {
+	var data struct {
+		Name      *string         `json:"name"`
+		Types     json.RawMessage `json:"types"`
+		Functions json.RawMessage `json:"functions"`
+		Package   *int            `json:"package"`
+		Docs      *docsData       `json:"docs"`
+		Stability *stabilityData  `json:"stability"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return err
+	}
+	i.name = data.Name
+	i.pkg = data.Package
+	i.docs = data.Docs.text()
+	i.stability = data.Stability.stability()
+	if err := d.checkIndex(i.pkg, len(d.m.packages), "package"); err != nil {
+		return err
+	}
+	types, err := d.namedIndices(data.Types, len(d.m.types), "type")
+	if err != nil {
+		return err
+	}
+	i.types = types
+	return decodeObject(data.Functions, func(_ string, rawFunction json.RawMessage) error {
+		f, err := d.decodeFunction(rawFunction)
+		if err != nil {
+			return err
+		}
+		i.functions = append(i.functions, f)
+		return nil
+	})
+}
+
+func (d *decoder) decodeWorld(raw json.RawMessage, w *worldData) error {
+	var data struct {
+		Name      string          `json:"name"`
+		Imports   json.RawMessage `json:"imports"`
+		Exports   json.RawMessage `json:"exports"`
+		Package   *int            `json:"package"`
+		Docs      *docsData       `json:"docs"`
+		Stability *stabilityData  `json:"stability"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return err
+	}
+	w.name = data.Name
+	w.pkg = data.Package
+	w.docs = data.Docs.text()
+	w.stability = data.Stability.stability()
+	if err := d.checkIndex(w.pkg, len(d.m.packages), "package"); err != nil {
+		return err
+	}
+	var err error
+	if w.imports, err = d.worldItems(data.Imports); err != nil {
+		return fmt.Errorf("world %s: %w", w.name, err)
+	}
+	if w.exports, err = d.worldItems(data.Exports); err != nil {
+		return fmt.Errorf("world %s: %w", w.name, err)
+	}
+	return nil
+}
+
+func (d *decoder) worldItems(raw json.RawMessage) ([]worldItem, error) {
+	var items []worldItem
+	err := decodeObject(raw, func(key string, rawItem json.RawMessage) error {
+		var data struct {
+			Function  json.RawMessage `json:"function"`
+			Interface *struct {
+				Id        int            `json:"id"`
+				Stability *stabilityData `json:"stability"`
+			} `json:"interface"`
+			Type *int `json:"type"`
+		}
+		if err := json.Unmarshal(rawItem, &data); err != nil {
+			return err
+		}
+		item := worldItem{key: key}
+		switch {
+		case data.Function != nil:
+			f, err := d.decodeFunction(data.Function)
+			if err != nil {
+				return err
+			}
+			item.function = &f
+		case data.Interface != nil:
+			if err := d.checkIndex(&data.Interface.Id, len(d.m.interfaces), "interface"); err != nil {
+				return err
+			}
+			item.iface = &data.Interface.Id
+			item.stability = data.Interface.Stability.stability()
+		case data.Type != nil:
+			if err := d.checkIndex(data.Type, len(d.m.types), "type"); err != nil {
+				return err
+			}
+			item.typ = data.Type
+		default:
+			return fmt.Errorf("unknown world item %s", key)
+		}
+		items = append(items, item)
+		return nil
+	})
+	return items, err
+}
+
+func (d *decoder) decodePackage(raw json.RawMessage, p *packageData) error {
+	var data struct {
+		Name       string          `json:"name"`
+		Interfaces json.RawMessage `json:"interfaces"`
+		Worlds     json.RawMessage `json:"worlds"`
+		Docs       *docsData       `json:"docs"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return err
+	}
+	p.name = data.Name
+	p.docs = data.Docs.text()
+	var err error
+	if p.interfaces, err = d.namedIndices(data.Interfaces, len(d.m.interfaces), "interface"); err != nil {
+		return err
+	}
+	if p.worlds, err = d.namedIndices(data.Worlds, len(d.m.worlds), "world"); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *decoder) namedIndices(raw json.RawMessage, count int, what string) ([]namedIndex, error) {
+	var indices []namedIndex
+	err := decodeObject(raw, func(name string, rawIndex json.RawMessage) error {
+		var index int
+		if err := json.Unmarshal(rawIndex, &index); err != nil {
+			return err
+		}
+		if err := d.checkIndex(&index, count, what); err != nil {
+			return err
+		}
+		indices = append(indices, namedIndex{name: name, index: index})
+		return nil
+	})
+	return indices, err
+}
+
+// decodeObject calls fn for each member of a JSON object, in the order of the document.
+func decodeObject(raw json.RawMessage, fn func(key string, value json.RawMessage) error) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return fmt.Errorf("expected a JSON object, got %s", raw)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		if err := fn(tok.(string), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveAliases sets the kind of aliases to the kind of the type they refer to.
+func (m *model) resolveAliases() error {
+	for i := range m.types {
+		seen := map[int]bool{}
+		t := &m.types[i]
+		for t.alias != nil {
+			if t.alias.index < 0 {
+				m.types[i].kind = primitiveKinds[t.alias.prim]
+				break
+			}
+			if seen[t.alias.index] {
+				return fmt.Errorf("type %d is an alias of itself", i)
+			}
+			seen[t.alias.index] = true
+			t = &m.types[t.alias.index]
+			m.types[i].kind = t.kind
+		}
+	}
+	return nil
+}
+
+// aliasTarget follows the aliases of the type at index and returns the index of the type they
+// refer to, or -1 if they refer to a primitive type.
+func (m *model) aliasTarget(index int) int {
+	for m.types[index].alias != nil {
+		index = m.types[index].alias.index
+		if index < 0 {
+			return -1
+		}
+	}
+	return index
+}
+
+// walkWorld calls visit with every type reference reachable from the functions imported and
+// exported by the world, directly or through interfaces, and adds the indices of the types they
+// refer to to reachable. Each type is walked once.
+func (m *model) walkWorld(w *worldData, reachable map[int]bool, visit func(ref *typeRef)) {
+	for _, items := range [][]worldItem{w.imports, w.exports} {
+		for _, item := range items {
+			switch {
+			case item.function != nil:
+				m.walkFunction(item.function, reachable, visit)
+			case item.iface != nil:
+				functions := m.interfaces[*item.iface].functions
+				for i := range functions {
+					m.walkFunction(&functions[i], reachable, visit)
+				}
+			}
+		}
+	}
+}
+
+// walkFunction calls visit with every type reference reachable from the parameters and result of
+// f, and adds the indices of the types they refer to to reachable.
+func (m *model) walkFunction(f *functionData, reachable map[int]bool, visit func(ref *typeRef)) {
+	for _, param := range f.params {
+		m.walk(param.ref, reachable, visit)
+	}
+	m.walk(f.result, reachable, visit)
+}
+
+// walk calls visit with ref and every type reference reachable from it, and adds the indices of
+// the types they refer to to reachable. visit may be nil.
+func (m *model) walk(ref *typeRef, reachable map[int]bool, visit func(ref *typeRef)) {
+	if ref == nil {
+		return
+	}
+	if visit != nil {
+		visit(ref)
+	}
+	if ref.index < 0 || reachable[ref.index] {
+		return
+	}
+	reachable[ref.index] = true
+	t := &m.types[ref.index]
+	m.walk(t.alias, reachable, visit)
+	m.walk(t.elem, reachable, visit)
+	m.walk(t.ok, reachable, visit)
+	m.walk(t.err, reachable, visit)
+	for _, field := range t.fields {
+		m.walk(field.ref, reachable, visit)
+	}
+	for i := range t.tuple {
+		m.walk(&t.tuple[i], reachable, visit)
+	}
+}
+
+// shape returns a key identifying the type referenced by ref up to aliases: named types are
+// identified by the index of the type their aliases refer to, and anonymous types by their
+// structure, so that identical anonymous types declared in different places have the same shape.
+func (m *model) shape(ref *typeRef) string {
+	for ref.index >= 0 && m.types[ref.index].alias != nil {
+		ref = m.types[ref.index].alias
+	}
+	if ref.index < 0 {
+		return ref.prim
+	}
+	t := &m.types[ref.index]
+	if t.name != nil {
+		return fmt.Sprintf("#%d", ref.index)
+	}
+	var parts []string
+	for _, child := range []*typeRef{t.elem, t.ok, t.err} {
+		if child == nil {
+			parts = append(parts, "_")
+		} else {
+			parts = append(parts, m.shape(child))
+		}
+	}
+	for i := range t.tuple {
+		parts = append(parts, m.shape(&t.tuple[i]))
+	}
+	kind := t.kind.String()
+	if t.borrow {
+		kind = "borrow"
+	}
+	return kind + "<" + strings.Join(parts, ",") + ">"
+}
@@ -0,0 +1,47 @@
+package wit
+
+type WitPackage interface {
+	Name() string
+	Docs() string
+	Interfaces() []WitInterface
+	Worlds() []WitWorldDefinition
+}
+
+// WitPackageImpl is a view of the package at index in a definition.
+type WitPackageImpl struct {
+	def   *WitDefinitionImpl
+	index int
+}
+
+var _ WitPackage = &WitPackageImpl{}
+
+func (w *WitPackageImpl) data() *packageData {
+	return &w.def.model.packages[w.index]
+}
+
+// Name returns the name of the package, for example `wasi:cli@0.2.0`.
+func (w *WitPackageImpl) Name() string {
+	return w.data().name
+}
+
+func (w *WitPackageImpl) Docs() string {
+	return w.data().docs
+}
+
+// Interfaces returns the named interfaces of the package, in the order they are declared.
+func (w *WitPackageImpl) Interfaces() []WitInterface {
+	var interfaces []WitInterface
+	for _, iface := range w.data().interfaces {
+		interfaces = append(interfaces, w.def.interfaces[iface.index])
+	}
+	return interfaces
+}
+
+// Worlds returns the worlds of the package, in the order they are declared.
+func (w *WitPackageImpl) Worlds() []WitWorldDefinition {
+	var worlds []WitWorldDefinition
+	for _, world := range w.data().worlds {
+		worlds = append(worlds, w.def.worlds[world.index])
+	}
+	return worlds
+}
@@ -0,0 +1,169 @@
+package wit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Position is a location in a WIT source file. Lines and columns start at 1.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// ParseError is returned when a WIT source file is malformed or refers to something that does
+// not exist.
+type ParseError struct {
+	Pos Position
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// Parse parses the WIT source of a single file, which must declare its package. filename is
+// used in error positions and, without its extension, as the name of the definition.
+func Parse(filename string, source string) (WitDefinition, error) {
+	f, err := parseFile(filename, source)
+	if err != nil {
+		return nil, err
+	}
+	return resolveFiles(nameOf(filename), nil, []*astFile{f})
+}
+
+// ParseFile parses a single `.wit` file. The definition is named after the file, without its
+// extension.
+func ParseFile(path string) (WitDefinition, error) {
+	f, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return resolveFiles(nameOf(path), nil, []*astFile{f})
+}
+
+// ParseDir parses the package made of the `.wit` files of a directory. Packages it depends on
+// are read from the `deps/` subdirectory, which contains either a directory or a single `.wit`
+// file per package. The definition is named after the directory.
+func ParseDir(path string) (WitDefinition, error) {
+	files, err := readDir(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .wit files found in %s", path)
+	}
+
+	var deps [][]*astFile
+	entries, err := os.ReadDir(filepath.Join(path, "deps"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading dependencies: %w", err)
+	}
+	for _, entry := range entries {
+		depPath := filepath.Join(path, "deps", entry.Name())
+		switch {
+		case entry.IsDir():
+			dep, err := readDir(depPath)
+			if err != nil {
+				return nil, err
+			}
+			if len(dep) > 0 {
+				deps = append(deps, dep)
+			}
+		case filepath.Ext(entry.Name()) == ".wit":
+			dep, err := readFile(depPath)
+			if err != nil {
+				return nil, err
+			}
+			deps = append(deps, []*astFile{dep})
+		}
+	}
+	return resolveFiles(filepath.Base(filepath.Clean(path)), deps, files)
+}
+
+// resolveFiles resolves the package made of files together with the packages it depends on.
+// Packages nested in files are resolved as dependencies, or as part of the root package if they
+// have its name.
+func resolveFiles(name string, deps [][]*astFile, files []*astFile) (WitDefinition, error) {
+	var rootName string
+	for _, f := range files {
+		if f.pkg != nil {
+			rootName = f.pkg.String()
+		}
+	}
+	var nested [][]*astFile
+	nestedIndex := map[string]int{}
+	for _, group := range append(deps, files) {
+		for _, f := range group {
+			for _, pkg := range f.nested {
+				switch index, ok := nestedIndex[pkg.pkg.String()]; {
+				case pkg.pkg.String() == rootName:
+					files = append(files, pkg)
+				case ok:
+					nested[index] = append(nested[index], pkg)
+				default:
+					nestedIndex[pkg.pkg.String()] = len(nested)
+					nested = append(nested, []*astFile{pkg})
+				}
+			}
+		}
+	}
+
+	var packages []*witPackage
+	for _, dep := range append(append(deps, nested...), files) {
+		pkg, err := newPackage(dep)
+		if err != nil {
+			return nil, err
+		}
+		packages = append(packages, pkg)
+	}
+	raw, err := resolve(packages)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromJson(raw, name)
+}
+
+func readFile(path string) (*astFile, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading WIT file: %w", err)
+	}
+	return parseFile(path, string(src))
+}
+
+// readDir parses the `.wit` files of a directory in lexical order.
+func readDir(path string) ([]*astFile, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading WIT directory: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".wit" {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	var files []*astFile
+	for _, name := range names {
+		f, err := readFile(filepath.Join(path, name))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+func nameOf(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
@@ -0,0 +1,428 @@
+package wit_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/rioam2/witigo/pkg/wit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// describe renders a definition in a normalized text form that does not depend on the order of
+// types and interfaces, so that definitions can be compared regardless of how they were indexed.
+func describe(t *testing.T, raw json.RawMessage) []string {
+	t.Helper()
+	var def struct {
+		Worlds []struct {
+			Name      string                                `json:"name"`
+			Imports   map[string]map[string]json.RawMessage `json:"imports"`
+			Exports   map[string]map[string]json.RawMessage `json:"exports"`
+			Package   int                                   `json:"package"`
+			Docs      *struct{ Contents string }            `json:"docs"`
+			Stability any                                   `json:"stability"`
+		} `json:"worlds"`
+		Interfaces []struct {
+			Name      *string                    `json:"name"`
+			Types     map[string]int             `json:"types"`
+			Functions map[string]json.RawMessage `json:"functions"`
+			Package   int                        `json:"package"`
+			Docs      *struct{ Contents string } `json:"docs"`
+			Stability any                        `json:"stability"`
+		} `json:"interfaces"`
+		Types []struct {
+			Name      *string                    `json:"name"`
+			Kind      any                        `json:"kind"`
+			Owner     map[string]int             `json:"owner"`
+			Docs      *struct{ Contents string } `json:"docs"`
+			Stability any                        `json:"stability"`
+		} `json:"types"`
+		Packages []struct {
+			Name string `json:"name"`
+		} `json:"packages"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &def))
+
+	docs := func(d *struct{ Contents string }) string {
+		if d == nil {
+			return ""
+		}
+		return " /// " + d.Contents
+	}
+	stability := func(s any) string {
+		if s == nil {
+			return ""
+		}
+		encoded, err := json.Marshal(s)
+		require.NoError(t, err)
+		return " @" + string(encoded)
+	}
+	worldName := func(idx int) string {
+		return def.Packages[def.Worlds[idx].Package].Name + "/" + def.Worlds[idx].Name
+	}
+	interfaceNames := map[int]string{}
+	for idx, iface := range def.Interfaces {
+		if iface.Name != nil {
+			interfaceNames[idx] = def.Packages[iface.Package].Name + "/" + *iface.Name
+		}
+	}
+	for idx, world := range def.Worlds {
+		for direction, items := range map[string]map[string]map[string]json.RawMessage{"import": world.Imports, "export": world.Exports} {
+			for key, item := range items {
+				if ref, ok := item["interface"]; ok {
+					var id struct{ Id int }
+					require.NoError(t, json.Unmarshal(ref, &id))
+					if _, named := interfaceNames[id.Id]; !named {
+						interfaceNames[id.Id] = worldName(idx) + " " + direction + " " + key
+					}
+				}
+			}
+		}
+	}
+
+	var renderRef func(ref any) string
+	var renderKind func(kind any) string
+	renderRef = func(ref any) string {
+		switch ref := ref.(type) {
+		case nil:
+			return "_"
+		case string:
+			return ref
+		case float64:
+			typ := def.Types[int(ref)]
+			if typ.Name == nil {
+				return renderKind(typ.Kind)
+			}
+			if owner, ok := typ.Owner["interface"]; ok {
+				return interfaceNames[owner] + "." + *typ.Name
+			}
+			return worldName(typ.Owner["world"]) + "." + *typ.Name
+		}
+		t.Fatalf("unexpected type reference %v", ref)
+		return ""
+	}
+	renderCases := func(cases []any, key string) string {
+		var rendered []string
+		for _, c := range cases {
+			c := c.(map[string]any)
+			s := c["name"].(string)
+			if ref, ok := c[key]; ok && ref != nil {
+				s += "(" + renderRef(ref) + ")"
+			}
+			if d, ok := c["docs"]; ok {
+				s += " /// " + d.(map[string]any)["contents"].(string)
+			}
+			rendered = append(rendered, s)
+		}
+		return strings.Join(rendered, ", ")
+	}
+	renderKind = func(kind any) string {
+		if kind, ok := kind.(string); ok {
+			return kind
+		}
+		for name, value := range kind.(map[string]any) {
+			switch name {
+			case "record":
+				return "record {" + renderCases(value.(map[string]any)["fields"].([]any), "type") + "}"
+			case "variant":
+				return "variant {" + renderCases(value.(map[string]any)["cases"].([]any), "type") + "}"
+			case "enum":
+				return "enum {" + renderCases(value.(map[string]any)["cases"].([]any), "") + "}"
+			case "flags":
+				return "flags {" + renderCases(value.(map[string]any)["flags"].([]any), "") + "}"
+			case "tuple":
+				var types []string
+				for _, ref := range value.(map[string]any)["types"].([]any) {
+					types = append(types, renderRef(ref))
+				}
+				return "tuple<" + strings.Join(types, ", ") + ">"
+			case "result":
+				value := value.(map[string]any)
+				return "result<" + renderRef(value["ok"]) + ", " + renderRef(value["err"]) + ">"
+			case "handle":
+				for handle, ref := range value.(map[string]any) {
+					return handle + "<" + renderRef(ref) + ">"
+				}
+			case "type":
+				return "= " + renderRef(value)
+			default:
+				return name + "<" + renderRef(value) + ">"
+			}
+		}
+		t.Fatalf("unexpected type kind %v", kind)
+		return ""
+	}
+	renderFunc := func(raw json.RawMessage) string {
+		var fn struct {
+			Name   string `json:"name"`
+			Kind   any    `json:"kind"`
+			Params []struct {
+				Name string `json:"name"`
+				Type any    `json:"type"`
+			} `json:"params"`
+			Result    any                        `json:"result"`
+			Docs      *struct{ Contents string } `json:"docs"`
+			Stability any                        `json:"stability"`
+		}
+		require.NoError(t, json.Unmarshal(raw, &fn))
+		var params []string
+		for _, param := range fn.Params {
+			params = append(params, param.Name+": "+renderRef(param.Type))
+		}
+		kind := "func"
+		if k, ok := fn.Kind.(map[string]any); ok {
+			for name := range k {
+				kind = name + " func"
+			}
+		}
+		return fmt.Sprintf("%s: %s(%s) -> %s%s%s", fn.Name, kind, strings.Join(params, ", "), renderRef(fn.Result), docs(fn.Docs), stability(fn.Stability))
+	}
+
+	var lines []string
+	for _, pkg := range def.Packages {
+		lines = append(lines, "package "+pkg.Name)
+	}
+	for idx, typ := range def.Types {
+		switch {
+		case typ.Name != nil:
+			lines = append(lines, "type "+renderRef(float64(idx))+" "+renderKind(typ.Kind)+docs(typ.Docs)+stability(typ.Stability))
+		case typ.Stability != nil:
+			lines = append(lines, "anonymous type "+renderKind(typ.Kind)+stability(typ.Stability))
+		}
+	}
+	for idx, iface := range def.Interfaces {
+		name := interfaceNames[idx]
+		lines = append(lines, "interface "+name+docs(iface.Docs)+stability(iface.Stability))
+		for typeName, typ := range iface.Types {
+			lines = append(lines, "interface "+name+" type "+typeName+" "+renderRef(float64(typ)))
+		}
+		for key, fn := range iface.Functions {
+			lines = append(lines, "interface "+name+" "+key+" "+renderFunc(fn))
+		}
+	}
+	for idx, world := range def.Worlds {
+		lines = append(lines, "world "+worldName(idx)+docs(world.Docs)+stability(world.Stability))
+		for direction, items := range map[string]map[string]map[string]json.RawMessage{"import": world.Imports, "export": world.Exports} {
+			for key, item := range items {
+				prefix := "world " + worldName(idx) + " " + direction + " "
+				switch {
+				case item["function"] != nil:
+					lines = append(lines, prefix+key+" "+renderFunc(item["function"]))
+				case item["type"] != nil:
+					var typ int
+					require.NoError(t, json.Unmarshal(item["type"], &typ))
+					lines = append(lines, prefix+key+" type "+renderRef(float64(typ)))
+				case item["interface"] != nil:
+					var ref struct {
+						Id        int
+						Stability any
+					}
+					require.NoError(t, json.Unmarshal(item["interface"], &ref))
+					if strings.HasPrefix(key, "interface-") {
+						key = "interface"
+					}
+					lines = append(lines, prefix+key+" "+interfaceNames[ref.Id]+stability(ref.Stability))
+				}
+			}
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func rawJson(t *testing.T, def wit.WitDefinition) json.RawMessage {
+	t.Helper()
+	impl, ok := def.(*wit.WitDefinitionImpl)
+	require.True(t, ok)
+	return impl.Raw
+}
+
+// The expected JSON files in testdata are generated from the WIT sources with
+// `wasm-tools component wit -j --all-features`.
+func TestParseMatchesWasmTools(t *testing.T) {
+	tests := []struct {
+		name     string
+		parse    func() (wit.WitDefinition, error)
+		expected string
+		defName  string
+	}{
+		{
+			name:     "file",
+			parse:    func() (wit.WitDefinition, error) { return wit.ParseFile("testdata/all-types.wit") },
+			expected: "testdata/all-types.json",
+			defName:  "all-types",
+		},
+		{
+			name:     "directory with dependencies",
+			parse:    func() (wit.WitDefinition, error) { return wit.ParseDir("testdata/resolve") },
+			expected: "testdata/resolve.json",
+			defName:  "resolve",
+		},
+		{
+			name:     "versioned dependencies and feature gates",
+			parse:    func() (wit.WitDefinition, error) { return wit.ParseDir("testdata/wasi") },
+			expected: "testdata/wasi.json",
+			defName:  "wasi",
+		},
+		{
+			name:     "stability",
+			parse:    func() (wit.WitDefinition, error) { return wit.ParseFile("testdata/stability.wit") },
+			expected: "testdata/stability.json",
+			defName:  "stability",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def, err := tt.parse()
+			require.NoError(t, err)
+			assert.Equal(t, tt.defName, def.Name())
+
+			expected, err := os.ReadFile(tt.expected)
+			require.NoError(t, err)
+			assert.Equal(t, describe(t, expected), describe(t, rawJson(t, def)))
+		})
+	}
+}
+
+func TestParseModel(t *testing.T) {
+	def, err := wit.ParseFile("testdata/all-types.wit")
+	require.NoError(t, err)
+	expected, err := os.ReadFile("testdata/all-types.json")
+	require.NoError(t, err)
+	oracle, err := wit.NewFromJson(expected, "all-types")
+	require.NoError(t, err)
+
+	functions := func(def wit.WitDefinition) []string {
+		var rendered []string
+		for _, f := range def.Worlds()[0].ExportedFunctions() {
+			s := f.Name() + "("
+			for _, param := range f.Params() {
+				s += param.Name() + ": " + param.Type().Kind().String() + ","
+			}
+			s += ")"
+			if f.Returns() != nil {
+				s += " -> " + f.Returns().Kind().String()
+			}
+			rendered = append(rendered, s)
+		}
+		sort.Strings(rendered)
+		return rendered
+	}
+	assert.Equal(t, functions(oracle), functions(def))
+	assert.Equal(t, oracle.Worlds()[0].Name(), def.Worlds()[0].Name())
+	assert.Len(t, def.Worlds()[0].Types(), len(oracle.Worlds()[0].Types()))
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "unexpected token",
+			source:   "package a:b;\n\nworld w {\n  export f: func() -> ;\n}\n",
+			expected: "test.wit:4:23: expected a type, found `;`",
+		},
+		{
+			name:     "invalid identifier",
+			source:   "package a:b;\ninterface Bad-name {}\n",
+			expected: "test.wit:2:11: invalid identifier `Bad-name`: word `Bad` mixes upper and lower case",
+		},
+		{
+			name:     "unterminated comment",
+			source:   "package a:b;\n /* comment\n",
+			expected: "test.wit:2:2: unterminated block comment",
+		},
+		{
+			name:     "missing package",
+			source:   "interface i {}\n",
+			expected: "test.wit:1:1: no package declaration found",
+		},
+		{
+			name:     "unknown attribute",
+			source:   "package a:b;\n@foo\ninterface i {}\n",
+			expected: "test.wit:2:2: unknown attribute `foo`",
+		},
+		{
+			name:     "deprecated without since",
+			source:   "package a:b;\ninterface i {\n  @deprecated(version = 1.0.0)\n  f: func();\n}\n",
+			expected: "test.wit:3:3: must pair @deprecated with either @since or @unstable",
+		},
+		{
+			name:     "since and unstable",
+			source:   "package a:b;\n@since(version = 1.0.0)\n@unstable(feature = x)\ninterface i {}\n",
+			expected: "test.wit:3:1: unsupported combination of attributes",
+		},
+		{
+			name:     "since without version",
+			source:   "package a:b;\n@since(feature = x)\ninterface i {}\n",
+			expected: "test.wit:2:8: expected `version`, found `feature`",
+		},
+		{
+			name:     "use in nested package",
+			source:   "package a:b;\npackage c:d {\n  use e:f/g;\n}\n",
+			expected: "test.wit:3:3: expected `interface`, `world` or `}`, found `use`",
+		},
+		{
+			name:     "unknown type",
+			source:   "package a:b;\ninterface i {\n  f: func(x: missing);\n}\n",
+			expected: "test.wit:3:14: type `missing` does not exist",
+		},
+		{
+			name:     "unknown interface",
+			source:   "package a:b;\nworld w {\n  import missing;\n}\n",
+			expected: "test.wit:3:10: interface `missing` does not exist in package `a:b`",
+		},
+		{
+			name:     "unknown package",
+			source:   "package a:b;\nworld w {\n  import c:d/e;\n}\n",
+			expected: "test.wit:3:10: package `c:d` not found",
+		},
+		{
+			name:     "unknown used type",
+			source:   "package a:b;\ninterface i {}\ninterface j {\n  use i.{t};\n}\n",
+			expected: "test.wit:4:10: type `t` does not exist in interface `i`",
+		},
+		{
+			name:     "duplicate type",
+			source:   "package a:b;\ninterface i {\n  type t = u8;\n  type t = u16;\n}\n",
+			expected: "test.wit:4:3: `t` is already defined at test.wit:3:3",
+		},
+		{
+			name:     "recursive type",
+			source:   "package a:b;\ninterface i {\n  record r { next: option<r> }\n}\n",
+			expected: "test.wit:3:3: type `r` refers to itself",
+		},
+		{
+			name:     "interface cycle",
+			source:   "package a:b;\ninterface i {\n  use j.{t};\n}\ninterface j {\n  use i.{u};\n}\n",
+			expected: "test.wit:6:7: interface `i` depends on itself",
+		},
+		{
+			name:     "handle to non-resource",
+			source:   "package a:b;\ninterface i {\n  f: func(x: borrow<u8>);\n}\n",
+			expected: "test.wit:3:21: `borrow` requires a resource type",
+		},
+		{
+			name:     "include conflict",
+			source:   "package a:b;\nworld v {\n  export f: func();\n}\nworld w {\n  export f: func() -> u8;\n  include v;\n}\n",
+			expected: "test.wit:7:3: export `f` of world `v` conflicts with an existing export",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := wit.Parse("test.wit", tt.source)
+			var parseErr *wit.ParseError
+			require.True(t, errors.As(err, &parseErr), "expected a ParseError, got %v", err)
+			assert.Equal(t, tt.expected, err.Error())
+		})
+	}
+}
@@ -0,0 +1,951 @@
+package wit
+
+import (
+	"fmt"
+)
+
+// The AST of a WIT file, as produced by the parser and consumed by the resolver.
+
+type astFile struct {
+	path string
+	pkg  *astPackageName
+	// docs holds the doc comments of the package declaration.
+	docs       string
+	uses       []*astTopUse
+	interfaces []*astInterface
+	worlds     []*astWorld
+	// nested holds the packages declared with `package name { ... }` blocks, which contain
+	// interfaces and worlds only.
+	nested []*astFile
+}
+
+type astPackageName struct {
+	pos       Position
+	namespace string
+	name      string
+	version   string
+}
+
+func (p *astPackageName) String() string {
+	name := p.namespace + ":" + p.name
+	if p.version != "" {
+		name += "@" + p.version
+	}
+	return name
+}
+
+// astUsePath refers to an interface or world, either by its name in the current package or
+// qualified with a package name.
+type astUsePath struct {
+	pos  Position
+	pkg  *astPackageName
+	name string
+}
+
+func (p *astUsePath) String() string {
+	if p.pkg == nil {
+		return p.name
+	}
+	name := p.pkg.namespace + ":" + p.pkg.name + "/" + p.name
+	if p.pkg.version != "" {
+		name += "@" + p.pkg.version
+	}
+	return name
+}
+
+// astTopUse is a `use path as name;` statement at the top level of a file.
+type astTopUse struct {
+	pos  Position
+	path *astUsePath
+	as   string
+}
+
+// astUse is a `use path.{a, b as c};` statement in an interface or world.
+type astUse struct {
+	pos       Position
+	path      *astUsePath
+	names     []*astUseName
+	stability *astStability
+}
+
+type astUseName struct {
+	pos  Position
+	name string
+	as   string
+}
+
+// astType is a type expression. kind is "prim" or "named" with name set, or the name of a
+// generic type ("list", "option", "result", "tuple", "own", "borrow", "future" or "stream")
+// with args set. Absent arguments of results are nil.
+type astType struct {
+	pos  Position
+	kind string
+	name string
+	args []*astType
+}
+
+// astTypeDef defines a named type. kind is "record", "variant", "enum", "flags", "resource" or
+// "type" for aliases.
+type astTypeDef struct {
+	pos       Position
+	docs      string
+	kind      string
+	name      string
+	fields    []*astField
+	alias     *astType
+	funcs     []*astFunc
+	stability *astStability
+}
+
+// astField is a record field, a function parameter, or a variant, enum or flags case. typ is nil
+// for cases without a payload.
+type astField struct {
+	pos  Position
+	docs string
+	name string
+	typ  *astType
+}
+
+// astFunc is a function. kind is "freestanding", or "method", "static" or "constructor" for
+// functions of a resource.
+type astFunc struct {
+	pos       Position
+	docs      string
+	name      string
+	kind      string
+	params    []*astField
+	result    *astType
+	stability *astStability
+}
+
+type astInterface struct {
+	pos  Position
+	docs string
+	// name is empty for interfaces declared inline in a world.
+	name string
+	// items holds the *astUse, *astTypeDef and *astFunc of the interface in order.
+	items     []any
+	stability *astStability
+}
+
+type astWorld struct {
+	pos  Position
+	docs string
+	name string
+	// items holds the *astUse, *astTypeDef, *astExtern and *astInclude of the world in order.
+	items     []any
+	stability *astStability
+}
+
+// astExtern is an import or export of a world. Exactly one of fn, iface and path is set.
+type astExtern struct {
+	pos       Position
+	docs      string
+	export    bool
+	name      string
+	fn        *astFunc
+	iface     *astInterface
+	path      *astUsePath
+	stability *astStability
+}
+
+type astInclude struct {
+	pos  Position
+	path *astUsePath
+	with []*astUseName
+}
+
+// astStability is the stability of an item, given by its feature gates. Exactly one of since and
+// feature is set.
+type astStability struct {
+	since      string
+	feature    string
+	deprecated string
+}
+
+var primitiveTypes = map[string]string{
+	"bool":          "bool",
+	"s8":            "s8",
+	"s16":           "s16",
+	"s32":           "s32",
+	"s64":           "s64",
+	"u8":            "u8",
+	"u16":           "u16",
+	"u32":           "u32",
+	"u64":           "u64",
+	"f32":           "f32",
+	"f64":           "f64",
+	"float32":       "f32",
+	"float64":       "f64",
+	"char":          "char",
+	"string":        "string",
+	"error-context": "error-context",
+}
+
+type parser struct {
+	tokens []token
+	idx    int
+}
+
+// parseFile parses the WIT source of a single file.
+func parseFile(path string, src string) (*astFile, error) {
+	tokens, err := tokenize(path, src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	return p.file(path)
+}
+
+func (p *parser) tok() token {
+	return p.tokens[p.idx]
+}
+
+func (p *parser) peek(n int) token {
+	if p.idx+n >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.idx+n]
+}
+
+func (p *parser) advance() token {
+	tok := p.tokens[p.idx]
+	if tok.kind != tokenEOF {
+		p.idx++
+	}
+	return tok
+}
+
+func (p *parser) errorf(pos Position, format string, args ...any) error {
+	return &ParseError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) unexpected(expected string) error {
+	return p.errorf(p.tok().pos, "expected %s, found %s", expected, p.tok())
+}
+
+func (p *parser) expect(kind tokenKind) (token, error) {
+	if p.tok().kind != kind {
+		return token{}, p.unexpected(kind.String())
+	}
+	return p.advance(), nil
+}
+
+func (p *parser) expectKeyword(kw string) error {
+	if !p.tok().isKeyword(kw) {
+		return p.unexpected("`" + kw + "`")
+	}
+	p.advance()
+	return nil
+}
+
+// accept consumes the next token if it is of the given kind.
+func (p *parser) accept(kind tokenKind) bool {
+	if p.tok().kind == kind {
+		p.advance()
+		return true
+	}
+	return false
+}
+
+func (p *parser) ident() (string, error) {
+	tok, err := p.expect(tokenIdent)
+	return tok.text, err
+}
+
+// gates parses the feature gates of an item, such as `@since(version = 1.0.0)` and
+// `@unstable(feature = x)`, and returns its stability, or nil if it has no gates. Gated items are
+// always included.
+func (p *parser) gates() (*astStability, error) {
+	docs := p.tok().docs
+	defer func() {
+		// Doc comments precede the gates of an item.
+		if p.tokens[p.idx].docs == "" {
+			p.tokens[p.idx].docs = docs
+		}
+	}()
+	var stability astStability
+	var first Position
+	gated, deprecated := false, false
+	for p.tok().kind == tokenAt {
+		at := p.advance()
+		if !gated && !deprecated {
+			first = at.pos
+		}
+		name, err := p.expect(tokenIdent)
+		if err != nil {
+			return nil, err
+		}
+		var key, value string
+		switch name.text {
+		case "since", "deprecated":
+			key = "version"
+		case "unstable":
+			key = "feature"
+		default:
+			return nil, p.errorf(name.pos, "unknown attribute `%s`", name.text)
+		}
+		if value, err = p.gateArgument(key); err != nil {
+			return nil, err
+		}
+		switch {
+		case name.text == "deprecated" && !deprecated:
+			stability.deprecated = value
+			deprecated = true
+		case name.text != "deprecated" && !gated:
+			if name.text == "since" {
+				stability.since = value
+			} else {
+				stability.feature = value
+			}
+			gated = true
+		default:
+			return nil, p.errorf(at.pos, "unsupported combination of attributes")
+		}
+	}
+	switch {
+	case deprecated && !gated:
+		return nil, p.errorf(first, "must pair @deprecated with either @since or @unstable")
+	case !gated:
+		return nil, nil
+	}
+	return &stability, nil
+}
+
+// gateArgument parses `(key = value)`, where value is a version or an identifier.
+func (p *parser) gateArgument(key string) (string, error) {
+	if _, err := p.expect(tokenLParen); err != nil {
+		return "", err
+	}
+	if err := p.expectKeyword(key); err != nil {
+		return "", err
+	}
+	if _, err := p.expect(tokenEquals); err != nil {
+		return "", err
+	}
+	kind := tokenVersion
+	if key == "feature" {
+		kind = tokenIdent
+	}
+	value, err := p.expect(kind)
+	if err != nil {
+		return "", err
+	}
+	if _, err := p.expect(tokenRParen); err != nil {
+		return "", err
+	}
+	return value.text, nil
+}
+
+func (p *parser) file(path string) (*astFile, error) {
+	f := &astFile{path: path}
+	if _, err := p.gates(); err != nil {
+		return nil, err
+	}
+	if p.tok().isKeyword("package") && p.peekPackageBody() != tokenLBrace {
+		tok := p.advance()
+		f.docs = tok.docs
+		name, err := p.packageName(tok.pos)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenSemicolon); err != nil {
+			return nil, err
+		}
+		f.pkg = name
+	}
+	for p.tok().kind != tokenEOF {
+		stability, err := p.gates()
+		if err != nil {
+			return nil, err
+		}
+		tok := p.tok()
+		switch {
+		case tok.isKeyword("interface"):
+			iface, err := p.interfaceDecl()
+			if err != nil {
+				return nil, err
+			}
+			iface.stability = stability
+			f.interfaces = append(f.interfaces, iface)
+		case tok.isKeyword("world"):
+			world, err := p.worldDecl()
+			if err != nil {
+				return nil, err
+			}
+			world.stability = stability
+			f.worlds = append(f.worlds, world)
+		case tok.isKeyword("use"):
+			p.advance()
+			path, err := p.usePath()
+			if err != nil {
+				return nil, err
+			}
+			use := &astTopUse{pos: tok.pos, path: path, as: path.name}
+			if p.tok().isKeyword("as") {
+				p.advance()
+				if use.as, err = p.ident(); err != nil {
+					return nil, err
+				}
+			}
+			if _, err := p.expect(tokenSemicolon); err != nil {
+				return nil, err
+			}
+			f.uses = append(f.uses, use)
+		case tok.isKeyword("package") && p.peekPackageBody() == tokenLBrace:
+			nested, err := p.nestedPackage(path)
+			if err != nil {
+				return nil, err
+			}
+			f.nested = append(f.nested, nested)
+		case tok.isKeyword("package"):
+			return nil, p.errorf(tok.pos, "package declaration must come first in the file")
+		default:
+			return nil, p.unexpected("`interface`, `world` or `use`")
+		}
+	}
+	return f, nil
+}
+
+// peekPackageBody returns the kind of the token following the package name of a `package`
+// declaration, which is `{` for nested packages.
+func (p *parser) peekPackageBody() tokenKind {
+	n := 4
+	if p.peek(n).kind == tokenAt {
+		n += 2
+	}
+	return p.peek(n).kind
+}
+
+// nestedPackage parses `package name { ... }`, which declares the interfaces and worlds of
+// another package than the one of the file.
+func (p *parser) nestedPackage(path string) (*astFile, error) {
+	tok := p.advance()
+	f := &astFile{path: path, docs: tok.docs}
+	name, err := p.packageName(tok.pos)
+	if err != nil {
+		return nil, err
+	}
+	f.pkg = name
+	if _, err := p.expect(tokenLBrace); err != nil {
+		return nil, err
+	}
+	for !p.accept(tokenRBrace) {
+		stability, err := p.gates()
+		if err != nil {
+			return nil, err
+		}
+		switch tok := p.tok(); {
+		case tok.isKeyword("interface"):
+			iface, err := p.interfaceDecl()
+			if err != nil {
+				return nil, err
+			}
+			iface.stability = stability
+			f.interfaces = append(f.interfaces, iface)
+		case tok.isKeyword("world"):
+			world, err := p.worldDecl()
+			if err != nil {
+				return nil, err
+			}
+			world.stability = stability
+			f.worlds = append(f.worlds, world)
+		default:
+			return nil, p.unexpected("`interface`, `world` or `}`")
+		}
+	}
+	return f, nil
+}
+
+// packageName parses `namespace:name@version`, where the version is optional.
+func (p *parser) packageName(pos Position) (*astPackageName, error) {
+	var err error
+	name := &astPackageName{pos: pos}
+	if name.namespace, err = p.ident(); err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokenColon); err != nil {
+		return nil, err
+	}
+	if name.name, err = p.ident(); err != nil {
+		return nil, err
+	}
+	if name.version, err = p.version(); err != nil {
+		return nil, err
+	}
+	return name, nil
+}
+
+func (p *parser) version() (string, error) {
+	if !p.accept(tokenAt) {
+		return "", nil
+	}
+	tok, err := p.expect(tokenVersion)
+	return tok.text, err
+}
+
+// usePath parses `name` or `namespace:package/name@version`.
+func (p *parser) usePath() (*astUsePath, error) {
+	pos := p.tok().pos
+	first, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept(tokenColon) {
+		return &astUsePath{pos: pos, name: first}, nil
+	}
+	path := &astUsePath{pos: pos, pkg: &astPackageName{pos: pos, namespace: first}}
+	if path.pkg.name, err = p.ident(); err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokenSlash); err != nil {
+		return nil, err
+	}
+	if path.name, err = p.ident(); err != nil {
+		return nil, err
+	}
+	if path.pkg.version, err = p.version(); err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+func (p *parser) interfaceDecl() (*astInterface, error) {
+	tok := p.advance()
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	iface := &astInterface{pos: tok.pos, docs: tok.docs, name: name}
+	return iface, p.interfaceBody(iface)
+}
+
+func (p *parser) interfaceBody(iface *astInterface) error {
+	if _, err := p.expect(tokenLBrace); err != nil {
+		return err
+	}
+	for !p.accept(tokenRBrace) {
+		stability, err := p.gates()
+		if err != nil {
+			return err
+		}
+		tok := p.tok()
+		var item any
+		switch {
+		case tok.isKeyword("use"):
+			item, err = p.use()
+		case p.isTypeDef():
+			item, err = p.typeDef()
+		case tok.kind == tokenIdent:
+			item, err = p.namedFunc()
+		default:
+			err = p.unexpected("`use`, a type definition or a function")
+		}
+		if err != nil {
+			return err
+		}
+		setStability(item, stability)
+		iface.items = append(iface.items, item)
+	}
+	return nil
+}
+
+func (p *parser) worldDecl() (*astWorld, error) {
+	tok := p.advance()
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	world := &astWorld{pos: tok.pos, docs: tok.docs, name: name}
+	if _, err := p.expect(tokenLBrace); err != nil {
+		return nil, err
+	}
+	for !p.accept(tokenRBrace) {
+		stability, err := p.gates()
+		if err != nil {
+			return nil, err
+		}
+		tok := p.tok()
+		var item any
+		switch {
+		case tok.isKeyword("use"):
+			item, err = p.use()
+		case tok.isKeyword("import"), tok.isKeyword("export"):
+			item, err = p.extern()
+		case tok.isKeyword("include"):
+			item, err = p.include()
+		case p.isTypeDef():
+			item, err = p.typeDef()
+		default:
+			err = p.unexpected("`use`, `import`, `export`, `include` or a type definition")
+		}
+		if err != nil {
+			return nil, err
+		}
+		setStability(item, stability)
+		world.items = append(world.items, item)
+	}
+	return world, nil
+}
+
+// setStability sets the stability of an item of an interface or world. The stability of imported
+// and exported functions and inline interfaces is the stability of the import or export.
+func setStability(item any, stability *astStability) {
+	switch item := item.(type) {
+	case *astUse:
+		item.stability = stability
+	case *astTypeDef:
+		item.stability = stability
+	case *astFunc:
+		item.stability = stability
+	case *astExtern:
+		item.stability = stability
+		if item.fn != nil {
+			item.fn.stability = stability
+		}
+		if item.iface != nil {
+			item.iface.stability = stability
+		}
+	}
+}
+
+func (p *parser) use() (*astUse, error) {
+	tok := p.advance()
+	path, err := p.usePath()
+	if err != nil {
+		return nil, err
+	}
+	use := &astUse{pos: tok.pos, path: path}
+	if _, err := p.expect(tokenPeriod); err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokenLBrace); err != nil {
+		return nil, err
+	}
+	for !p.accept(tokenRBrace) {
+		name := &astUseName{pos: p.tok().pos}
+		if name.name, err = p.ident(); err != nil {
+			return nil, err
+		}
+		name.as = name.name
+		if p.tok().isKeyword("as") {
+			p.advance()
+			if name.as, err = p.ident(); err != nil {
+				return nil, err
+			}
+		}
+		use.names = append(use.names, name)
+		if !p.accept(tokenComma) && p.tok().kind != tokenRBrace {
+			return nil, p.unexpected("`,` or `}`")
+		}
+	}
+	_, err = p.expect(tokenSemicolon)
+	return use, err
+}
+
+func (p *parser) extern() (*astExtern, error) {
+	tok := p.advance()
+	extern := &astExtern{pos: tok.pos, docs: tok.docs, export: tok.isKeyword("export")}
+	next := p.peek(2)
+	if p.tok().kind == tokenIdent && p.peek(1).kind == tokenColon && (next.isKeyword("func") || next.isKeyword("async") || next.isKeyword("interface")) {
+		extern.name = p.advance().text
+		p.advance()
+		if p.tok().isKeyword("interface") {
+			extern.iface = &astInterface{pos: p.advance().pos, docs: tok.docs}
+			return extern, p.interfaceBody(extern.iface)
+		}
+		fn, err := p.funcType(tok.pos, tok.docs, extern.name)
+		if err != nil {
+			return nil, err
+		}
+		extern.fn = fn
+		_, err = p.expect(tokenSemicolon)
+		return extern, err
+	}
+	path, err := p.usePath()
+	if err != nil {
+		return nil, err
+	}
+	extern.path = path
+	_, err = p.expect(tokenSemicolon)
+	return extern, err
+}
+
+func (p *parser) include() (*astInclude, error) {
+	tok := p.advance()
+	path, err := p.usePath()
+	if err != nil {
+		return nil, err
+	}
+	include := &astInclude{pos: tok.pos, path: path}
+	if p.tok().isKeyword("with") {
+		p.advance()
+		if _, err := p.expect(tokenLBrace); err != nil {
+			return nil, err
+		}
+		for !p.accept(tokenRBrace) {
+			name := &astUseName{pos: p.tok().pos}
+			if name.name, err = p.ident(); err != nil {
+				return nil, err
+			}
+			if err := p.expectKeyword("as"); err != nil {
+				return nil, err
+			}
+			if name.as, err = p.ident(); err != nil {
+				return nil, err
+			}
+			include.with = append(include.with, name)
+			if !p.accept(tokenComma) && p.tok().kind != tokenRBrace {
+				return nil, p.unexpected("`,` or `}`")
+			}
+		}
+		return include, nil
+	}
+	_, err = p.expect(tokenSemicolon)
+	return include, err
+}
+
+func (p *parser) isTypeDef() bool {
+	tok := p.tok()
+	for _, kw := range []string{"type", "record", "variant", "enum", "flags", "resource"} {
+		if tok.isKeyword(kw) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) typeDef() (*astTypeDef, error) {
+	tok := p.advance()
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	def := &astTypeDef{pos: tok.pos, docs: tok.docs, kind: tok.text, name: name}
+	switch def.kind {
+	case "type":
+		if _, err := p.expect(tokenEquals); err != nil {
+			return nil, err
+		}
+		if def.alias, err = p.typ(); err != nil {
+			return nil, err
+		}
+		_, err = p.expect(tokenSemicolon)
+		return def, err
+	case "resource":
+		if p.accept(tokenSemicolon) {
+			return def, nil
+		}
+		if _, err := p.expect(tokenLBrace); err != nil {
+			return nil, err
+		}
+		for !p.accept(tokenRBrace) {
+			stability, err := p.gates()
+			if err != nil {
+				return nil, err
+			}
+			fn, err := p.resourceFunc()
+			if err != nil {
+				return nil, err
+			}
+			fn.stability = stability
+			def.funcs = append(def.funcs, fn)
+		}
+		return def, nil
+	}
+
+	if _, err := p.expect(tokenLBrace); err != nil {
+		return nil, err
+	}
+	for !p.accept(tokenRBrace) {
+		tok := p.tok()
+		field := &astField{pos: tok.pos, docs: tok.docs}
+		if field.name, err = p.ident(); err != nil {
+			return nil, err
+		}
+		switch def.kind {
+		case "record":
+			if _, err := p.expect(tokenColon); err != nil {
+				return nil, err
+			}
+			if field.typ, err = p.typ(); err != nil {
+				return nil, err
+			}
+		case "variant":
+			if p.accept(tokenLParen) {
+				if field.typ, err = p.typ(); err != nil {
+					return nil, err
+				}
+				if _, err := p.expect(tokenRParen); err != nil {
+					return nil, err
+				}
+			}
+		}
+		def.fields = append(def.fields, field)
+		if !p.accept(tokenComma) && p.tok().kind != tokenRBrace {
+			return nil, p.unexpected("`,` or `}`")
+		}
+	}
+	return def, nil
+}
+
+// namedFunc parses `name: func(...) -> result;`.
+func (p *parser) namedFunc() (*astFunc, error) {
+	tok := p.advance()
+	if _, err := p.expect(tokenColon); err != nil {
+		return nil, err
+	}
+	fn, err := p.funcType(tok.pos, tok.docs, tok.text)
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.expect(tokenSemicolon)
+	return fn, err
+}
+
+func (p *parser) resourceFunc() (*astFunc, error) {
+	tok := p.tok()
+	if tok.isKeyword("constructor") {
+		p.advance()
+		fn := &astFunc{pos: tok.pos, docs: tok.docs, name: "constructor", kind: "constructor"}
+		if err := p.funcSignature(fn); err != nil {
+			return nil, err
+		}
+		_, err := p.expect(tokenSemicolon)
+		return fn, err
+	}
+	p.advance()
+	if _, err := p.expect(tokenColon); err != nil {
+		return nil, err
+	}
+	kind := "method"
+	if p.tok().isKeyword("static") {
+		p.advance()
+		kind = "static"
+	}
+	fn, err := p.funcType(tok.pos, tok.docs, tok.text)
+	if err != nil {
+		return nil, err
+	}
+	fn.kind = kind
+	_, err = p.expect(tokenSemicolon)
+	return fn, err
+}
+
+// funcType parses `func(params) -> result` with the result being optional.
+func (p *parser) funcType(pos Position, docs string, name string) (*astFunc, error) {
+	if p.tok().isKeyword("async") {
+		return nil, p.errorf(p.tok().pos, "async functions are not supported")
+	}
+	if err := p.expectKeyword("func"); err != nil {
+		return nil, err
+	}
+	fn := &astFunc{pos: pos, docs: docs, name: name, kind: "freestanding"}
+	return fn, p.funcSignature(fn)
+}
+
+func (p *parser) funcSignature(fn *astFunc) error {
+	if _, err := p.expect(tokenLParen); err != nil {
+		return err
+	}
+	for !p.accept(tokenRParen) {
+		param := &astField{pos: p.tok().pos}
+		var err error
+		if param.name, err = p.ident(); err != nil {
+			return err
+		}
+		if _, err := p.expect(tokenColon); err != nil {
+			return err
+		}
+		if param.typ, err = p.typ(); err != nil {
+			return err
+		}
+		fn.params = append(fn.params, param)
+		if !p.accept(tokenComma) && p.tok().kind != tokenRParen {
+			return p.unexpected("`,` or `)`")
+		}
+	}
+	if p.accept(tokenArrow) {
+		if p.tok().kind == tokenLParen {
+			return p.errorf(p.tok().pos, "named function results are not supported")
+		}
+		var err error
+		if fn.result, err = p.typ(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *parser) typ() (*astType, error) {
+	tok := p.tok()
+	if tok.kind != tokenIdent {
+		return nil, p.unexpected("a type")
+	}
+	p.advance()
+	if tok.escaped {
+		return &astType{pos: tok.pos, kind: "named", name: tok.text}, nil
+	}
+	if prim, ok := primitiveTypes[tok.text]; ok {
+		return &astType{pos: tok.pos, kind: "prim", name: prim}, nil
+	}
+
+	t := &astType{pos: tok.pos, kind: tok.text}
+	var err error
+	switch tok.text {
+	case "list", "option", "own", "borrow":
+		t.args, err = p.typeArgs(1, 1, false)
+	case "tuple":
+		t.args, err = p.typeArgs(0, -1, false)
+	case "result":
+		if p.tok().kind == tokenLAngle {
+			t.args, err = p.typeArgs(1, 2, true)
+		}
+		switch len(t.args) {
+		case 0:
+			t.args = []*astType{nil, nil}
+		case 1:
+			t.args = append(t.args, nil)
+		}
+	case "future", "stream":
+		t.args = []*astType{nil}
+		if p.tok().kind == tokenLAngle {
+			t.args, err = p.typeArgs(1, 1, false)
+		}
+	default:
+		t.kind = "named"
+		t.name = tok.text
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// typeArgs parses `<T, ...>` with between min and max arguments, or any number if max is
+// negative. If underscore is set, the first argument may be `_`, which is returned as nil.
+func (p *parser) typeArgs(min int, max int, underscore bool) ([]*astType, error) {
+	open, err := p.expect(tokenLAngle)
+	if err != nil {
+		return nil, err
+	}
+	var args []*astType
+	for !p.accept(tokenRAngle) {
+		if underscore && len(args) == 0 && p.accept(tokenUnderscore) {
+			args = append(args, nil)
+		} else {
+			arg, err := p.typ()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		if !p.accept(tokenComma) && p.tok().kind != tokenRAngle {
+			return nil, p.unexpected("`,` or `>`")
+		}
+	}
+	if len(args) < min || max >= 0 && len(args) > max {
+		return nil, p.errorf(open.pos, "wrong number of type arguments: %d", len(args))
+	}
+	return args, nil
+}
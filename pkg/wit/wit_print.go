@@ -0,0 +1,631 @@
+package wit
+
+import (
+	"reflect"
+	"strings"
+
+	witigo "github.com/rioam2/witigo/pkg"
+)
+
+// keywords are the words of the WIT grammar, which identifiers spelled the same way must escape
+// with `%`.
+var keywords = map[string]bool{
+	"as": true, "async": true, "bool": true, "borrow": true, "char": true, "constructor": true,
+	"enum": true, "error-context": true, "export": true, "f32": true, "f64": true, "flags": true,
+	"float32": true, "float64": true, "from": true, "func": true, "future": true, "import": true,
+	"include": true, "interface": true, "list": true, "option": true, "own": true, "package": true,
+	"record": true, "resource": true, "result": true, "s16": true, "s32": true, "s64": true,
+	"s8": true, "static": true, "stream": true, "string": true, "tuple": true, "type": true,
+	"u16": true, "u32": true, "u64": true, "u8": true, "use": true, "variant": true, "with": true,
+	"world": true,
+}
+
+// Wit returns the definition as formatted WIT source. The root package comes first, followed by
+// the packages it depends on as nested `package name { ... }` blocks. Parsing the source again
+// gives the same definition, except that imports of interfaces a world depends on are explicit.
+func (w *WitDefinitionImpl) Wit() string {
+	p := &printer{m: w.model}
+	p.definition()
+	return p.b.String()
+}
+
+// printer writes the WIT source of a model.
+type printer struct {
+	m     *model
+	b     strings.Builder
+	depth int
+	// group is the group of the last item of the current block, or "" at the start of a block.
+	// Items of the same group, like consecutive imports, are not separated by blank lines.
+	group string
+	// pkg is the index of the package being printed, or -1.
+	pkg int
+}
+
+func (p *printer) line(format string) {
+	p.b.WriteString(strings.Repeat("  ", p.depth))
+	p.b.WriteString(format)
+	p.b.WriteString("\n")
+}
+
+// item starts an item of a block. Items are separated by blank lines unless they are of the same
+// non-empty group.
+func (p *printer) item(group string) {
+	if p.group != "" && (group == "" || group != p.group) {
+		p.b.WriteString("\n")
+	}
+	if group == "" {
+		group = "-"
+	}
+	p.group = group
+}
+
+func (p *printer) open(format string) {
+	p.line(format + " {")
+	p.depth++
+	p.group = ""
+}
+
+func (p *printer) close() {
+	p.depth--
+	p.line("}")
+	p.group = "-"
+}
+
+func (p *printer) docs(docs string) {
+	if docs == "" {
+		return
+	}
+	for _, line := range strings.Split(docs, "\n") {
+		if line == "" {
+			p.line("///")
+		} else {
+			p.line("/// " + line)
+		}
+	}
+}
+
+func (p *printer) gates(s *Stability) {
+	if s == nil {
+		return
+	}
+	if s.Unstable() {
+		p.line("@unstable(feature = " + s.Feature + ")")
+	} else {
+		p.line("@since(version = " + s.Since + ")")
+	}
+	if s.Deprecated != "" {
+		p.line("@deprecated(version = " + s.Deprecated + ")")
+	}
+}
+
+func escape(name string) string {
+	if keywords[name] {
+		return "%" + name
+	}
+	return name
+}
+
+func (p *printer) definition() {
+	p.pkg = -1
+	if len(p.m.packages) == 0 {
+		for i := range p.m.interfaces {
+			if p.m.interfaces[i].name != nil {
+				p.iface(i)
+			}
+		}
+		for i := range p.m.worlds {
+			p.world(i)
+		}
+		return
+	}
+
+	root := len(p.m.packages) - 1
+	p.pkg = root
+	p.docs(p.m.packages[root].docs)
+	p.line("package " + p.m.packages[root].name + ";")
+	p.group = "-"
+	p.packageItems(root)
+	for i := 0; i < root; i++ {
+		p.pkg = i
+		p.item("")
+		p.docs(p.m.packages[i].docs)
+		p.open("package " + p.m.packages[i].name)
+		p.packageItems(i)
+		p.close()
+	}
+}
+
+func (p *printer) packageItems(pkg int) {
+	for _, iface := range p.m.packages[pkg].interfaces {
+		p.iface(iface.index)
+	}
+	for _, world := range p.m.packages[pkg].worlds {
+		p.world(world.index)
+	}
+}
+
+// interfacePath returns the name of interface i as referred to from the package being printed.
+func (p *printer) interfacePath(i int) string {
+	data := &p.m.interfaces[i]
+	name := ""
+	if data.name != nil {
+		name = escape(*data.name)
+	}
+	if data.pkg == nil || *data.pkg == p.pkg {
+		return name
+	}
+	return qualify(p.m.packages[*data.pkg].name, name)
+}
+
+// worldPath returns the name of world i as referred to from the package being printed.
+func (p *printer) worldPath(i int) string {
+	data := &p.m.worlds[i]
+	if data.pkg == nil || *data.pkg == p.pkg {
+		return escape(data.name)
+	}
+	return qualify(p.m.packages[*data.pkg].name, escape(data.name))
+}
+
+// qualify returns the path of the interface or world name of package pkg, like
+// `wasi:io/streams@0.2.0`.
+func qualify(pkg string, name string) string {
+	unversioned, version, versioned := strings.Cut(pkg, "@")
+	if versioned {
+		return unversioned + "/" + name + "@" + version
+	}
+	return unversioned + "/" + name
+}
+
+func (p *printer) iface(i int) {
+	data := &p.m.interfaces[i]
+	p.item("")
+	p.docs(data.docs)
+	p.gates(data.stability)
+	p.open("interface " + escape(*data.name))
+	p.interfaceBody(i)
+	p.close()
+}
+
+// interfaceBody prints the types and functions of interface i in the order they are declared.
+// The functions of resources are printed with the resource, so freestanding functions declared
+// before a resource are printed before it.
+func (p *printer) interfaceBody(i int) {
+	data := &p.m.interfaces[i]
+	position := map[int]int{}
+	for pos, t := range data.types {
+		position[t.index] = pos
+	}
+	methods := map[int][]*functionData{}
+	// first is the position of the first function of each resource.
+	first := map[int]int{}
+	for j := range data.functions {
+		if f := &data.functions[j]; f.resource != nil {
+			if _, ok := first[*f.resource]; !ok {
+				first[*f.resource] = j
+			}
+			methods[*f.resource] = append(methods[*f.resource], f)
+		}
+	}
+
+	next := 0
+	flush := func(end int) {
+		if end > next {
+			p.typeDefs(data.types[next:end], methods, i, nil)
+			next = end
+		}
+	}
+	for j := range data.functions {
+		f := &data.functions[j]
+		if f.resource == nil {
+			// Types come first, except resources with functions declared after this one.
+			end := len(data.types)
+			for resource, function := range first {
+				if pos, ok := position[resource]; ok && function > j && pos >= next && pos < end {
+					end = pos
+				}
+			}
+			flush(end)
+			p.item("")
+			p.docs(f.docs)
+			p.gates(f.stability)
+			p.line(escape(f.name) + ": " + p.signature(f) + ";")
+			continue
+		}
+		if pos, ok := position[*f.resource]; ok && pos >= next {
+			flush(pos + 1)
+		}
+	}
+	flush(len(data.types))
+}
+
+// typeDefs prints the named types of an interface or world. Types brought in scope by `use` are
+// printed as `use` statements, grouping consecutive types used from the same interface.
+func (p *printer) typeDefs(types []namedIndex, methods map[int][]*functionData, iface int, world *int) {
+	for j := 0; j < len(types); j++ {
+		from, ok := p.usedFrom(types[j].index, iface, world)
+		if !ok {
+			p.item("")
+			p.typeDef(types[j].index, types[j].name, methods[types[j].index])
+			continue
+		}
+		stability := p.m.types[types[j].index].stability
+		var names []string
+		for ; j < len(types); j++ {
+			from2, ok := p.usedFrom(types[j].index, iface, world)
+			if !ok || from2 != from || !reflect.DeepEqual(p.m.types[types[j].index].stability, stability) {
+				break
+			}
+			target := p.m.types[p.m.types[types[j].index].alias.index].name
+			name := escape(*target)
+			if *target != types[j].name {
+				name += " as " + escape(types[j].name)
+			}
+			names = append(names, name)
+		}
+		j--
+		p.item("use")
+		p.gates(stability)
+		p.line("use " + p.interfacePath(from) + ".{" + strings.Join(names, ", ") + "};")
+	}
+}
+
+// usedFrom returns the interface type t is used from, if t is an alias, owned by interface iface
+// or world world, of a type owned by another interface.
+func (p *printer) usedFrom(t int, iface int, world *int) (int, bool) {
+	alias := p.m.types[t].alias
+	if alias == nil || alias.index < 0 {
+		return 0, false
+	}
+	owner := p.m.types[alias.index].owner
+	if owner.iface == nil || p.m.types[alias.index].name == nil || world == nil && *owner.iface == iface {
+		return 0, false
+	}
+	return *owner.iface, true
+}
+
+func (p *printer) typeDef(t int, name string, methods []*functionData) {
+	data := &p.m.types[t]
+	p.docs(data.docs)
+	p.gates(data.stability)
+	name = escape(name)
+	if data.alias != nil {
+		p.line("type " + name + " = " + p.ref(data.alias) + ";")
+		return
+	}
+	switch data.kind {
+	case witigo.AbiTypeRecord:
+		p.cases("record "+name, data.fields, func(field namedRef) string {
+			return escape(field.name) + ": " + p.ref(field.ref)
+		})
+	case witigo.AbiTypeVariant:
+		p.cases("variant "+name, data.fields, func(c namedRef) string {
+			if c.ref == nil {
+				return escape(c.name)
+			}
+			return escape(c.name) + "(" + p.ref(c.ref) + ")"
+		})
+	case witigo.AbiTypeEnum, witigo.AbiTypeFlags:
+		keyword := "enum "
+		if data.kind == witigo.AbiTypeFlags {
+			keyword = "flags "
+		}
+		p.cases(keyword+name, data.fields, func(c namedRef) string {
+			return escape(c.name)
+		})
+	case witigo.AbiTypeResource:
+		if len(methods) == 0 {
+			p.line("resource " + name + ";")
+			return
+		}
+		p.open("resource " + name)
+		for _, f := range methods {
+			p.docs(f.docs)
+			p.gates(f.stability)
+			p.line(p.method(f) + ";")
+		}
+		p.close()
+	default:
+		p.line("type " + name + " = " + p.anonymous(t) + ";")
+	}
+}
+
+func (p *printer) cases(header string, cases []namedRef, format func(namedRef) string) {
+	if len(cases) == 0 {
+		p.line(header + " {}")
+		return
+	}
+	p.open(header)
+	for _, c := range cases {
+		p.docs(c.docs)
+		p.line(format(c) + ",")
+	}
+	p.close()
+}
+
+// method returns the declaration of a method, static function or constructor of a resource, as
+// written in the resource block.
+func (p *printer) method(f *functionData) string {
+	switch f.kind {
+	case "constructor":
+		params := p.params(f.params)
+		if f.result == nil || f.result.index >= 0 && p.m.types[f.result.index].kind == witigo.AbiTypeHandle && p.m.types[f.result.index].name == nil {
+			return "constructor(" + params + ")"
+		}
+		return "constructor(" + params + ") -> " + p.ref(f.result)
+	case "static":
+		return escape(methodName(f.name)) + ": static " + p.signature(f)
+	default:
+		return escape(methodName(f.name)) + ": " + p.signature(f)
+	}
+}
+
+// methodName returns the name of a method or static function without the `[method]r.` prefix.
+func methodName(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// signature returns the type of f, like `func(a: u32) -> string`. The self parameter of methods is
+// implicit.
+func (p *printer) signature(f *functionData) string {
+	params := f.params
+	if f.kind == "method" && len(params) > 0 {
+		params = params[1:]
+	}
+	s := "func(" + p.params(params) + ")"
+	if f.result != nil {
+		s += " -> " + p.ref(f.result)
+	}
+	return s
+}
+
+func (p *printer) params(params []namedRef) string {
+	rendered := make([]string, len(params))
+	for i, param := range params {
+		rendered[i] = escape(param.name) + ": " + p.ref(param.ref)
+	}
+	return strings.Join(rendered, ", ")
+}
+
+// ref returns the type expression of a reference, which is the name of named types.
+func (p *printer) ref(ref *typeRef) string {
+	if ref == nil {
+		return "_"
+	}
+	if ref.index < 0 {
+		return ref.prim
+	}
+	if name := p.m.types[ref.index].name; name != nil {
+		return escape(*name)
+	}
+	return p.anonymous(ref.index)
+}
+
+// anonymous returns the type expression of type t, like `list<u8>`, regardless of its name.
+func (p *printer) anonymous(t int) string {
+	data := &p.m.types[t]
+	switch data.kind {
+	case witigo.AbiTypeList:
+		return "list<" + p.ref(data.elem) + ">"
+	case witigo.AbiTypeOption:
+		return "option<" + p.ref(data.elem) + ">"
+	case witigo.AbiTypeFuture, witigo.AbiTypeStream:
+		keyword := "future"
+		if data.kind == witigo.AbiTypeStream {
+			keyword = "stream"
+		}
+		if data.elem == nil {
+			return keyword
+		}
+		return keyword + "<" + p.ref(data.elem) + ">"
+	case witigo.AbiTypeHandle:
+		if data.borrow {
+			return "borrow<" + p.ref(data.elem) + ">"
+		}
+		return p.ref(data.elem)
+	case witigo.AbiTypeTuple:
+		elems := make([]string, len(data.tuple))
+		for i := range data.tuple {
+			elems[i] = p.ref(&data.tuple[i])
+		}
+		return "tuple<" + strings.Join(elems, ", ") + ">"
+	case witigo.AbiTypeResult:
+		switch {
+		case data.ok == nil && data.err == nil:
+			return "result"
+		case data.err == nil:
+			return "result<" + p.ref(data.ok) + ">"
+		default:
+			return "result<" + p.ref(data.ok) + ", " + p.ref(data.err) + ">"
+		}
+	default:
+		return p.ref(data.alias)
+	}
+}
+
+// include is an `include` statement reconstructed from the items a world shares with another one.
+type include struct {
+	world   int
+	renames []string
+	// imports and exports are the keys of the items the include stands for.
+	imports, exports map[string]bool
+	printed          bool
+}
+
+func (p *printer) world(i int) {
+	data := &p.m.worlds[i]
+	p.item("")
+	p.docs(data.docs)
+	p.gates(data.stability)
+	p.open("world " + escape(data.name))
+
+	includes := p.includes(i)
+	methods := map[int][]*functionData{}
+	for _, items := range [][]worldItem{data.imports, data.exports} {
+		for _, item := range items {
+			if item.function != nil && item.function.resource != nil {
+				methods[*item.function.resource] = append(methods[*item.function.resource], item.function)
+			}
+		}
+	}
+	for _, direction := range []string{"import", "export"} {
+		items := data.imports
+		if direction == "export" {
+			items = data.exports
+		}
+		// types holds consecutive types, printed together so that their uses are grouped.
+		var types []namedIndex
+		flush := func() {
+			p.typeDefs(types, methods, -1, &i)
+			types = nil
+		}
+	items:
+		for _, item := range items {
+			for _, inc := range includes {
+				if direction == "import" && inc.imports[item.key] || direction == "export" && inc.exports[item.key] {
+					if !inc.printed {
+						flush()
+						p.include(inc)
+					}
+					continue items
+				}
+			}
+			if item.typ != nil {
+				types = append(types, namedIndex{name: item.key, index: *item.typ})
+				continue
+			}
+			flush()
+			p.worldItem(direction, item)
+		}
+		flush()
+	}
+	p.close()
+}
+
+func (p *printer) include(inc *include) {
+	inc.printed = true
+	p.item("include")
+	if len(inc.renames) == 0 {
+		p.line("include " + p.worldPath(inc.world) + ";")
+		return
+	}
+	p.line("include " + p.worldPath(inc.world) + " with { " + strings.Join(inc.renames, ", ") + " }")
+}
+
+func (p *printer) worldItem(direction string, item worldItem) {
+	switch {
+	case item.function != nil:
+		if item.function.resource != nil {
+			return
+		}
+		p.item(direction)
+		p.docs(item.function.docs)
+		p.gates(item.function.stability)
+		p.line(direction + " " + escape(item.key) + ": " + p.signature(item.function) + ";")
+	case p.m.interfaces[*item.iface].name == nil:
+		p.item("")
+		p.docs(p.m.interfaces[*item.iface].docs)
+		p.gates(item.stability)
+		p.open(direction + " " + escape(item.key) + ": interface")
+		p.interfaceBody(*item.iface)
+		p.close()
+	default:
+		p.item(direction)
+		p.gates(item.stability)
+		p.line(direction + " " + p.interfacePath(*item.iface) + ";")
+	}
+}
+
+// includes returns the worlds included by world i. Includes cannot be told from the items they
+// add, except when the items refer to types owned by the included world or are renamed, so only
+// these worlds are considered.
+func (p *printer) includes(i int) []*include {
+	data := &p.m.worlds[i]
+	var candidates []int
+	addCandidate := func(world int) {
+		for _, c := range candidates {
+			if c == world {
+				return
+			}
+		}
+		candidates = append(candidates, world)
+	}
+	for _, items := range [][]worldItem{data.imports, data.exports} {
+		for _, item := range items {
+			switch {
+			case item.typ != nil:
+				if owner := p.m.types[*item.typ].owner.world; owner != nil && *owner != i {
+					addCandidate(*owner)
+				}
+			case item.function != nil && item.function.name != item.key:
+				for j := range p.m.worlds {
+					if j != i && (p.findItem(p.m.worlds[j].imports, item, item.function.name) || p.findItem(p.m.worlds[j].exports, item, item.function.name)) {
+						addCandidate(j)
+					}
+				}
+			}
+		}
+	}
+
+	var includes []*include
+candidates:
+	for _, c := range candidates {
+		inc := &include{world: c, imports: map[string]bool{}, exports: map[string]bool{}}
+		included := &p.m.worlds[c]
+		for _, pair := range []struct {
+			from, to []worldItem
+			keys     map[string]bool
+		}{{included.imports, data.imports, inc.imports}, {included.exports, data.exports, inc.exports}} {
+			for _, item := range pair.from {
+				key, ok := p.matchItem(pair.to, item)
+				if !ok {
+					continue candidates
+				}
+				if key != item.key {
+					inc.renames = append(inc.renames, escape(item.key)+" as "+escape(key))
+				}
+				pair.keys[key] = true
+			}
+		}
+		includes = append(includes, inc)
+	}
+	return includes
+}
+
+// findItem reports whether items has an item named key equal to item.
+func (p *printer) findItem(items []worldItem, item worldItem, key string) bool {
+	for _, other := range items {
+		if other.key == key && sameItem(other, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchItem returns the key of the item of items equal to item, preferring the same key.
+func (p *printer) matchItem(items []worldItem, item worldItem) (string, bool) {
+	if p.findItem(items, item, item.key) {
+		return item.key, true
+	}
+	for _, other := range items {
+		if sameItem(other, item) && (item.function != nil || item.typ != nil) {
+			return other.key, true
+		}
+	}
+	return "", false
+}
+
+func sameItem(a worldItem, b worldItem) bool {
+	switch {
+	case a.function != nil && b.function != nil:
+		return reflect.DeepEqual(*a.function, *b.function)
+	case a.typ != nil && b.typ != nil:
+		return *a.typ == *b.typ
+	case a.iface != nil && b.iface != nil:
+		return *a.iface == *b.iface && reflect.DeepEqual(a.stability, b.stability)
+	default:
+		return false
+	}
+}
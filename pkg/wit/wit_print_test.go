@@ -0,0 +1,138 @@
+package wit_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/rioam2/witigo/pkg/wit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWitRoundTrip(t *testing.T) {
+	fromJson := func(path string) func() (wit.WitDefinition, error) {
+		return func() (wit.WitDefinition, error) {
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			return wit.NewFromJson(raw, "oracle")
+		}
+	}
+	tests := []struct {
+		name  string
+		parse func() (wit.WitDefinition, error)
+		// source is set for definitions parsed from WIT, which print the same once reparsed.
+		source bool
+	}{
+		{name: "file", parse: func() (wit.WitDefinition, error) { return wit.ParseFile("testdata/all-types.wit") }, source: true},
+		{name: "directory with dependencies", parse: func() (wit.WitDefinition, error) { return wit.ParseDir("testdata/resolve") }, source: true},
+		{name: "versioned dependencies", parse: func() (wit.WitDefinition, error) { return wit.ParseDir("testdata/wasi") }, source: true},
+		{name: "stability", parse: func() (wit.WitDefinition, error) { return wit.ParseFile("testdata/stability.wit") }, source: true},
+		{name: "oracle file", parse: fromJson("testdata/all-types.json")},
+		{name: "oracle directory with dependencies", parse: fromJson("testdata/resolve.json")},
+		{name: "oracle versioned dependencies", parse: fromJson("testdata/wasi.json")},
+		{name: "oracle stability", parse: fromJson("testdata/stability.json")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def, err := tt.parse()
+			require.NoError(t, err)
+			printed := def.Wit()
+			reparsed, err := wit.Parse("printed.wit", printed)
+			require.NoError(t, err, printed)
+			assert.Equal(t, describe(t, rawJson(t, def)), describe(t, rawJson(t, reparsed)))
+
+			// Definitions decoded from JSON may order items differently than the parser, so they
+			// print the same from the second generation on.
+			if !tt.source {
+				printed = reparsed.Wit()
+				reparsed, err = wit.Parse("printed.wit", printed)
+				require.NoError(t, err, printed)
+			}
+			assert.Equal(t, printed, reparsed.Wit())
+		})
+	}
+}
+
+func TestWitFormat(t *testing.T) {
+	def, err := wit.Parse("format.wit", `/// The package.
+package test:format@0.1.0;
+
+interface types {
+  use test:dep/base.{id as base-id};
+  /// A handle.
+  resource file { constructor(path: string); read: func(n: u32) -> result<list<u8>>; open: static func() -> file; }
+  record point { /// Horizontal.
+    x: s32, y: s32 }
+  variant shape { none, circle(u32) }
+  type pair = tuple<point, option<borrow<file>>>;
+  flags %flags { %list, b }
+  get: func(%type: base-id) -> result<_, string>;
+}
+
+world app {
+  import types;
+  @since(version = 0.1.0)
+  export run: func(p: borrow<file>) -> result;
+  use types.{file};
+}
+
+package test:dep {
+  interface base {
+    type id = u64;
+  }
+}
+`)
+	require.NoError(t, err)
+	assert.Equal(t, `/// The package.
+package test:format@0.1.0;
+
+interface types {
+  use test:dep/base.{id as base-id};
+
+  /// A handle.
+  resource file {
+    constructor(path: string);
+    read: func(n: u32) -> result<list<u8>>;
+    open: static func() -> file;
+  }
+
+  record point {
+    /// Horizontal.
+    x: s32,
+    y: s32,
+  }
+
+  variant shape {
+    none,
+    circle(u32),
+  }
+
+  type pair = tuple<point, option<borrow<file>>>;
+
+  flags %flags {
+    %list,
+    b,
+  }
+
+  get: func(%type: base-id) -> result<_, string>;
+}
+
+world app {
+  import test:dep/base;
+  import types;
+
+  use types.{file};
+
+  @since(version = 0.1.0)
+  export run: func(p: borrow<file>) -> result;
+}
+
+package test:dep {
+  interface base {
+    type id = u64;
+  }
+}
+`, def.Wit())
+}
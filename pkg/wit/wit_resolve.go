@@ -0,0 +1,1025 @@
+package wit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// The resolver turns the AST of a set of packages into the JSON representation emitted by
+// `wasm-tools component wit -j`, which backs WitDefinitionImpl.
+
+type jsonDefinition struct {
+	Worlds     []*jsonWorld     `json:"worlds"`
+	Interfaces []*jsonInterface `json:"interfaces"`
+	Types      []*jsonType      `json:"types"`
+	Packages   []*jsonPackage   `json:"packages"`
+}
+
+type jsonDocs struct {
+	Contents string `json:"contents"`
+}
+
+func newJsonDocs(docs string) *jsonDocs {
+	if docs == "" {
+		return nil
+	}
+	return &jsonDocs{Contents: docs}
+}
+
+type jsonStability struct {
+	Stable   *jsonStable   `json:"stable,omitempty"`
+	Unstable *jsonUnstable `json:"unstable,omitempty"`
+}
+
+type jsonStable struct {
+	Since      string `json:"since"`
+	Deprecated string `json:"deprecated,omitempty"`
+}
+
+type jsonUnstable struct {
+	Feature    string `json:"feature"`
+	Deprecated string `json:"deprecated,omitempty"`
+}
+
+func newJsonStability(stability *astStability) *jsonStability {
+	switch {
+	case stability == nil:
+		return nil
+	case stability.since != "":
+		return &jsonStability{Stable: &jsonStable{Since: stability.since, Deprecated: stability.deprecated}}
+	default:
+		return &jsonStability{Unstable: &jsonUnstable{Feature: stability.feature, Deprecated: stability.deprecated}}
+	}
+}
+
+type jsonWorld struct {
+	Name      string         `json:"name"`
+	Imports   *orderedMap    `json:"imports"`
+	Exports   *orderedMap    `json:"exports"`
+	Package   int            `json:"package"`
+	Docs      *jsonDocs      `json:"docs,omitempty"`
+	Stability *jsonStability `json:"stability,omitempty"`
+}
+
+type jsonInterface struct {
+	Name      *string        `json:"name"`
+	Types     *orderedMap    `json:"types"`
+	Functions *orderedMap    `json:"functions"`
+	Docs      *jsonDocs      `json:"docs,omitempty"`
+	Stability *jsonStability `json:"stability,omitempty"`
+	Package   int            `json:"package"`
+}
+
+// jsonType is a type definition. Type references elsewhere are either the name of a primitive
+// type or the index of a jsonType.
+type jsonType struct {
+	Name      *string        `json:"name"`
+	Kind      any            `json:"kind"`
+	Owner     any            `json:"owner"`
+	Docs      *jsonDocs      `json:"docs,omitempty"`
+	Stability *jsonStability `json:"stability,omitempty"`
+}
+
+type jsonFunction struct {
+	Name      string         `json:"name"`
+	Kind      any            `json:"kind"`
+	Params    []*jsonParam   `json:"params"`
+	Result    any            `json:"result,omitempty"`
+	Docs      *jsonDocs      `json:"docs,omitempty"`
+	Stability *jsonStability `json:"stability,omitempty"`
+}
+
+// jsonInterfaceItem is an interface imported or exported by a world.
+type jsonInterfaceItem struct {
+	Interface struct {
+		Id        int            `json:"id"`
+		Stability *jsonStability `json:"stability,omitempty"`
+	} `json:"interface"`
+}
+
+func newJsonInterfaceItem(id int, stability *astStability) *jsonInterfaceItem {
+	item := &jsonInterfaceItem{}
+	item.Interface.Id = id
+	item.Interface.Stability = newJsonStability(stability)
+	return item
+}
+
+type jsonParam struct {
+	Name string `json:"name"`
+	Type any    `json:"type"`
+}
+
+type jsonField struct {
+	Name string    `json:"name"`
+	Type any       `json:"type"`
+	Docs *jsonDocs `json:"docs,omitempty"`
+}
+
+type jsonCase struct {
+	Name string    `json:"name"`
+	Docs *jsonDocs `json:"docs,omitempty"`
+}
+
+type jsonResult struct {
+	Ok  any `json:"ok"`
+	Err any `json:"err"`
+}
+
+type jsonPackage struct {
+	Name       string      `json:"name"`
+	Interfaces *orderedMap `json:"interfaces"`
+	Worlds     *orderedMap `json:"worlds"`
+	Docs       *jsonDocs   `json:"docs,omitempty"`
+}
+
+// orderedMap is a JSON object that preserves the insertion order of its keys, like the maps
+// emitted by wasm-tools.
+type orderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func newOrderedMap() *orderedMap {
+	return &orderedMap{values: map[string]any{}}
+}
+
+func (m *orderedMap) get(key string) (any, bool) {
+	value, ok := m.values[key]
+	return value, ok
+}
+
+func (m *orderedMap) set(key string, value any) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// witPackage groups the files declaring the same package.
+type witPackage struct {
+	name       *astPackageName
+	docs       string
+	files      []*astFile
+	interfaces map[string]*astInterface
+	worlds     map[string]*astWorld
+	// fileOf maps interfaces and worlds to the file declaring them.
+	fileOf map[any]*astFile
+	id     int
+}
+
+// newPackage groups files into a package. All files must declare the same package name,
+// except for files without a package declaration.
+func newPackage(files []*astFile) (*witPackage, error) {
+	pkg := &witPackage{
+		files:      files,
+		interfaces: map[string]*astInterface{},
+		worlds:     map[string]*astWorld{},
+		fileOf:     map[any]*astFile{},
+		id:         -1,
+	}
+	for _, f := range files {
+		if f.pkg == nil {
+			continue
+		}
+		if pkg.name != nil && pkg.name.String() != f.pkg.String() {
+			return nil, &ParseError{Pos: f.pkg.pos, Msg: fmt.Sprintf("package `%s` conflicts with package `%s` declared at %s", f.pkg, pkg.name, pkg.name.pos)}
+		}
+		pkg.name = f.pkg
+		if pkg.docs == "" {
+			pkg.docs = f.docs
+		}
+	}
+	if pkg.name == nil {
+		return nil, &ParseError{Pos: Position{File: files[0].path, Line: 1, Column: 1}, Msg: "no package declaration found"}
+	}
+
+	defined := map[string]Position{}
+	define := func(name string, pos Position) error {
+		if prev, ok := defined[name]; ok {
+			return &ParseError{Pos: pos, Msg: fmt.Sprintf("`%s` is already defined at %s", name, prev)}
+		}
+		defined[name] = pos
+		return nil
+	}
+	for _, f := range files {
+		for _, iface := range f.interfaces {
+			if err := define(iface.name, iface.pos); err != nil {
+				return nil, err
+			}
+			pkg.interfaces[iface.name] = iface
+			pkg.fileOf[iface] = f
+		}
+		for _, world := range f.worlds {
+			if err := define(world.name, world.pos); err != nil {
+				return nil, err
+			}
+			pkg.worlds[world.name] = world
+			pkg.fileOf[world] = f
+		}
+	}
+	return pkg, nil
+}
+
+// paths returns every reference to an interface or world made by the package.
+func (pkg *witPackage) paths() []*astUsePath {
+	var paths []*astUsePath
+	addItems := func(items []any) {
+		for _, item := range items {
+			switch item := item.(type) {
+			case *astUse:
+				paths = append(paths, item.path)
+			case *astExtern:
+				if item.path != nil {
+					paths = append(paths, item.path)
+				}
+				if item.iface != nil {
+					for _, item := range item.iface.items {
+						if use, ok := item.(*astUse); ok {
+							paths = append(paths, use.path)
+						}
+					}
+				}
+			case *astInclude:
+				paths = append(paths, item.path)
+			}
+		}
+	}
+	for _, f := range pkg.files {
+		for _, use := range f.uses {
+			paths = append(paths, use.path)
+		}
+		for _, iface := range f.interfaces {
+			addItems(iface.items)
+		}
+		for _, world := range f.worlds {
+			addItems(world.items)
+		}
+	}
+	return paths
+}
+
+type resolver struct {
+	def      jsonDefinition
+	packages []*witPackage
+	// anonymous interns anonymous types by their JSON encoding.
+	anonymous    map[string]int
+	interfaceIDs map[*astInterface]int
+	worldIDs     map[*astWorld]int
+	// interfaceTypes maps interfaces to the indices of their types by name.
+	interfaceTypes map[int]map[string]int
+	// interfaceDeps lists the interfaces each interface uses types from.
+	interfaceDeps map[int][]int
+	resolving     map[any]bool
+}
+
+// resolve resolves packages, where the last package is the root package and the others are its
+// dependencies.
+func resolve(packages []*witPackage) (json.RawMessage, error) {
+	r := &resolver{
+		def: jsonDefinition{
+			Worlds:     []*jsonWorld{},
+			Interfaces: []*jsonInterface{},
+			Types:      []*jsonType{},
+			Packages:   []*jsonPackage{},
+		},
+		packages:       packages,
+		anonymous:      map[string]int{},
+		interfaceIDs:   map[*astInterface]int{},
+		worldIDs:       map[*astWorld]int{},
+		interfaceTypes: map[int]map[string]int{},
+		interfaceDeps:  map[int][]int{},
+		resolving:      map[any]bool{},
+	}
+	for _, pkg := range packages {
+		if err := r.resolvePackage(pkg, pkg.name.pos); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(r.def)
+}
+
+func (r *resolver) errorf(pos Position, format string, args ...any) error {
+	return &ParseError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (r *resolver) resolvePackage(pkg *witPackage, pos Position) error {
+	if pkg.id >= 0 {
+		return nil
+	}
+	if r.resolving[pkg] {
+		return r.errorf(pos, "package `%s` depends on itself", pkg.name)
+	}
+	r.resolving[pkg] = true
+	defer delete(r.resolving, pkg)
+
+	// Dependencies are resolved first so that they precede the package in the output.
+	for _, path := range pkg.paths() {
+		if path.pkg == nil {
+			continue
+		}
+		dep, err := r.lookupPackage(path)
+		if err != nil {
+			return err
+		}
+		if dep != pkg {
+			if err := r.resolvePackage(dep, path.pos); err != nil {
+				return err
+			}
+		}
+	}
+
+	pkg.id = len(r.def.Packages)
+	out := &jsonPackage{
+		Name:       pkg.name.String(),
+		Interfaces: newOrderedMap(),
+		Worlds:     newOrderedMap(),
+		Docs:       newJsonDocs(pkg.docs),
+	}
+	r.def.Packages = append(r.def.Packages, out)
+	for _, f := range pkg.files {
+		for _, iface := range f.interfaces {
+			id, err := r.resolveInterface(pkg, iface, iface.pos)
+			if err != nil {
+				return err
+			}
+			out.Interfaces.set(iface.name, id)
+		}
+	}
+	for _, f := range pkg.files {
+		for _, world := range f.worlds {
+			id, err := r.resolveWorld(pkg, world, world.pos)
+			if err != nil {
+				return err
+			}
+			out.Worlds.set(world.name, id)
+		}
+	}
+	return nil
+}
+
+// lookupPackage finds the package referred to by a qualified path. The version may be omitted
+// when only one version of the package is present.
+func (r *resolver) lookupPackage(path *astUsePath) (*witPackage, error) {
+	var candidates []*witPackage
+	for _, pkg := range r.packages {
+		if pkg.name.namespace != path.pkg.namespace || pkg.name.name != path.pkg.name {
+			continue
+		}
+		if path.pkg.version != "" && pkg.name.version != path.pkg.version {
+			continue
+		}
+		candidates = append(candidates, pkg)
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, r.errorf(path.pos, "package `%s` not found", path.pkg)
+	case 1:
+		return candidates[0], nil
+	default:
+		return nil, r.errorf(path.pos, "package `%s` is ambiguous, specify its version", path.pkg)
+	}
+}
+
+// lookupInterface resolves the interface referred to by path from file.
+func (r *resolver) lookupInterface(pkg *witPackage, file *astFile, path *astUsePath) (int, error) {
+	if path.pkg == nil {
+		for _, use := range file.uses {
+			if use.as == path.name {
+				if use.path.pkg == nil {
+					return r.packageInterface(pkg, use.path)
+				}
+				path = use.path
+				break
+			}
+		}
+	}
+	if path.pkg != nil {
+		var err error
+		if pkg, err = r.lookupPackage(path); err != nil {
+			return 0, err
+		}
+	}
+	return r.packageInterface(pkg, path)
+}
+
+func (r *resolver) packageInterface(pkg *witPackage, path *astUsePath) (int, error) {
+	iface, ok := pkg.interfaces[path.name]
+	if !ok {
+		return 0, r.errorf(path.pos, "interface `%s` does not exist in package `%s`", path.name, pkg.name)
+	}
+	return r.resolveInterface(pkg, iface, path.pos)
+}
+
+func (r *resolver) lookupWorld(pkg *witPackage, path *astUsePath) (int, error) {
+	if path.pkg != nil {
+		var err error
+		if pkg, err = r.lookupPackage(path); err != nil {
+			return 0, err
+		}
+	}
+	world, ok := pkg.worlds[path.name]
+	if !ok {
+		return 0, r.errorf(path.pos, "world `%s` does not exist in package `%s`", path.name, pkg.name)
+	}
+	return r.resolveWorld(pkg, world, path.pos)
+}
+
+// scope holds the named types visible in an interface or world.
+type scope struct {
+	owner map[string]int
+	types map[string]int
+	// defined records where names were defined, to report duplicates.
+	defined map[string]Position
+}
+
+func (s *scope) define(name string, pos Position) error {
+	if prev, ok := s.defined[name]; ok {
+		return &ParseError{Pos: pos, Msg: fmt.Sprintf("`%s` is already defined at %s", name, prev)}
+	}
+	s.defined[name] = pos
+	return nil
+}
+
+// resolveInterface resolves iface and returns its index, resolving interfaces it depends on
+// first. Interfaces declared inline in worlds are resolved with pkg set to the package of the
+// world.
+func (r *resolver) resolveInterface(pkg *witPackage, iface *astInterface, pos Position) (int, error) {
+	if id, ok := r.interfaceIDs[iface]; ok {
+		return id, nil
+	}
+	if r.resolving[iface] {
+		return 0, r.errorf(pos, "interface `%s` depends on itself", iface.name)
+	}
+	r.resolving[iface] = true
+	defer delete(r.resolving, iface)
+
+	file := pkg.fileOf[iface]
+	var deps []int
+	for _, item := range iface.items {
+		if use, ok := item.(*astUse); ok {
+			dep, err := r.lookupInterface(pkg, file, use.path)
+			if err != nil {
+				return 0, err
+			}
+			deps = appendUnique(deps, dep)
+		}
+	}
+
+	id := len(r.def.Interfaces)
+	r.interfaceIDs[iface] = id
+	r.interfaceDeps[id] = deps
+	out := &jsonInterface{
+		Types:     newOrderedMap(),
+		Functions: newOrderedMap(),
+		Docs:      newJsonDocs(iface.docs),
+		Stability: newJsonStability(iface.stability),
+		Package:   pkg.id,
+	}
+	if iface.name != "" {
+		out.Name = &iface.name
+	}
+	r.def.Interfaces = append(r.def.Interfaces, out)
+
+	s := &scope{owner: map[string]int{"interface": id}, types: map[string]int{}, defined: map[string]Position{}}
+	if err := r.resolveTypes(pkg, file, s, iface.items); err != nil {
+		return 0, err
+	}
+	for _, item := range iface.items {
+		switch item := item.(type) {
+		case *astUse:
+			for _, name := range item.names {
+				out.Types.set(name.as, s.types[name.as])
+			}
+		case *astTypeDef:
+			out.Types.set(item.name, s.types[item.name])
+			for _, fn := range item.funcs {
+				f, err := r.function(s, fn, item.name)
+				if err != nil {
+					return 0, err
+				}
+				out.Functions.set(f.Name, f)
+			}
+		case *astFunc:
+			if err := s.define(item.name, item.pos); err != nil {
+				return 0, err
+			}
+			f, err := r.function(s, item, "")
+			if err != nil {
+				return 0, err
+			}
+			out.Functions.set(f.Name, f)
+		}
+	}
+	r.interfaceTypes[id] = s.types
+	return id, nil
+}
+
+// resolveWorld resolves world and returns its index, resolving worlds it includes first.
+func (r *resolver) resolveWorld(pkg *witPackage, world *astWorld, pos Position) (int, error) {
+	if id, ok := r.worldIDs[world]; ok {
+		return id, nil
+	}
+	if r.resolving[world] {
+		return 0, r.errorf(pos, "world `%s` depends on itself", world.name)
+	}
+	r.resolving[world] = true
+	defer delete(r.resolving, world)
+
+	file := pkg.fileOf[world]
+	includes := map[*astInclude]int{}
+	for _, item := range world.items {
+		if include, ok := item.(*astInclude); ok {
+			id, err := r.lookupWorld(pkg, include.path)
+			if err != nil {
+				return 0, err
+			}
+			includes[include] = id
+		}
+	}
+
+	id := len(r.def.Worlds)
+	r.worldIDs[world] = id
+	out := &jsonWorld{
+		Name:      world.name,
+		Imports:   newOrderedMap(),
+		Exports:   newOrderedMap(),
+		Package:   pkg.id,
+		Docs:      newJsonDocs(world.docs),
+		Stability: newJsonStability(world.stability),
+	}
+	r.def.Worlds = append(r.def.Worlds, out)
+
+	for _, item := range world.items {
+		if use, ok := item.(*astUse); ok {
+			dep, err := r.lookupInterface(pkg, file, use.path)
+			if err != nil {
+				return 0, err
+			}
+			r.importInterface(out, dep, use.stability)
+		}
+	}
+	s := &scope{owner: map[string]int{"world": id}, types: map[string]int{}, defined: map[string]Position{}}
+	if err := r.resolveTypes(pkg, file, s, world.items); err != nil {
+		return 0, err
+	}
+
+	type exportedInterface struct {
+		id        int
+		stability *astStability
+	}
+	var exported []exportedInterface
+	for _, item := range world.items {
+		switch item := item.(type) {
+		case *astUse:
+			for _, name := range item.names {
+				out.Imports.set(name.as, map[string]any{"type": s.types[name.as]})
+			}
+		case *astTypeDef:
+			out.Imports.set(item.name, map[string]any{"type": s.types[item.name]})
+			for _, fn := range item.funcs {
+				return 0, r.errorf(fn.pos, "resources defined in worlds cannot have functions")
+			}
+		case *astExtern:
+			items, direction := out.Imports, "import"
+			if item.export {
+				items, direction = out.Exports, "export"
+			}
+			switch {
+			case item.fn != nil:
+				if _, ok := items.get(item.name); ok {
+					return 0, r.errorf(item.pos, "%s `%s` is already defined", direction, item.name)
+				}
+				f, err := r.function(s, item.fn, "")
+				if err != nil {
+					return 0, err
+				}
+				items.set(item.name, map[string]any{"function": f})
+			case item.iface != nil:
+				if _, ok := items.get(item.name); ok {
+					return 0, r.errorf(item.pos, "%s `%s` is already defined", direction, item.name)
+				}
+				pkg.fileOf[item.iface] = file
+				iface, err := r.resolveInterface(pkg, item.iface, item.pos)
+				if err != nil {
+					return 0, err
+				}
+				if !item.export {
+					for _, dep := range r.interfaceDeps[iface] {
+						r.importInterface(out, dep, item.stability)
+					}
+				}
+				items.set(item.name, newJsonInterfaceItem(iface, item.stability))
+				if item.export {
+					exported = append(exported, exportedInterface{iface, item.stability})
+				}
+			default:
+				iface, err := r.lookupInterface(pkg, file, item.path)
+				if err != nil {
+					return 0, err
+				}
+				if item.export {
+					out.Exports.set(interfaceKey(iface), newJsonInterfaceItem(iface, item.stability))
+					exported = append(exported, exportedInterface{iface, item.stability})
+				} else {
+					r.importInterface(out, iface, item.stability)
+				}
+			}
+		case *astInclude:
+			if err := r.include(out, r.def.Worlds[includes[item]], item); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	// Interfaces used by exported interfaces are imported unless they are exported as well.
+	for _, iface := range exported {
+		for _, dep := range r.interfaceDeps[iface.id] {
+			if _, ok := out.Exports.get(interfaceKey(dep)); !ok {
+				r.importInterface(out, dep, iface.stability)
+			}
+		}
+	}
+	return id, nil
+}
+
+// interfaceKey is the key of an imported or exported interface in the JSON of a world.
+func interfaceKey(id int) string {
+	return fmt.Sprintf("interface-%d", id)
+}
+
+// importInterface adds the interface id and the interfaces it depends on to the imports of world.
+// The interfaces share the stability of the item importing them.
+func (r *resolver) importInterface(world *jsonWorld, id int, stability *astStability) {
+	if _, ok := world.Imports.get(interfaceKey(id)); ok {
+		return
+	}
+	for _, dep := range r.interfaceDeps[id] {
+		r.importInterface(world, dep, stability)
+	}
+	world.Imports.set(interfaceKey(id), newJsonInterfaceItem(id, stability))
+}
+
+// include merges the imports and exports of the included world into world. Items renamed by the
+// include statement are added under their new name.
+func (r *resolver) include(world *jsonWorld, included *jsonWorld, include *astInclude) error {
+	renames := map[string]string{}
+	for _, name := range include.with {
+		_, isImport := included.Imports.get(name.name)
+		_, isExport := included.Exports.get(name.name)
+		if !isImport && !isExport {
+			return r.errorf(name.pos, "world `%s` has no import or export named `%s`", included.Name, name.name)
+		}
+		renames[name.name] = name.as
+	}
+	merge := func(dst *orderedMap, src *orderedMap, direction string) error {
+		for _, key := range src.keys {
+			value := src.values[key]
+			if as, ok := renames[key]; ok {
+				key = as
+			}
+			if existing, ok := dst.get(key); ok {
+				a, _ := json.Marshal(existing)
+				b, _ := json.Marshal(value)
+				if !bytes.Equal(a, b) {
+					return r.errorf(include.pos, "%s `%s` of world `%s` conflicts with an existing %s", direction, key, included.Name, direction)
+				}
+				continue
+			}
+			dst.set(key, value)
+		}
+		return nil
+	}
+	if err := merge(world.Imports, included.Imports, "import"); err != nil {
+		return err
+	}
+	return merge(world.Exports, included.Exports, "export")
+}
+
+// resolveTypes defines the types used and declared by the items of an interface or world in s.
+// Names are defined before type expressions are resolved, so types may refer to types declared
+// after them.
+func (r *resolver) resolveTypes(pkg *witPackage, file *astFile, s *scope, items []any) error {
+	for _, item := range items {
+		switch item := item.(type) {
+		case *astUse:
+			dep, err := r.lookupInterface(pkg, file, item.path)
+			if err != nil {
+				return err
+			}
+			for _, name := range item.names {
+				target, ok := r.interfaceTypes[dep][name.name]
+				if !ok {
+					return r.errorf(name.pos, "type `%s` does not exist in interface `%s`", name.name, item.path)
+				}
+				if err := s.define(name.as, name.pos); err != nil {
+					return err
+				}
+				s.types[name.as] = r.addType(&jsonType{Name: &name.as, Kind: map[string]any{"type": target}, Owner: s.owner, Stability: newJsonStability(item.stability)})
+			}
+		case *astTypeDef:
+			if err := s.define(item.name, item.pos); err != nil {
+				return err
+			}
+			t := &jsonType{Name: &item.name, Owner: s.owner, Docs: newJsonDocs(item.docs), Stability: newJsonStability(item.stability)}
+			if item.kind == "resource" {
+				t.Kind = "resource"
+			}
+			s.types[item.name] = r.addType(t)
+		}
+	}
+
+	for _, item := range items {
+		def, ok := item.(*astTypeDef)
+		if !ok || def.kind == "resource" {
+			continue
+		}
+		kind, err := r.typeDefKind(s, def)
+		if err != nil {
+			return err
+		}
+		r.def.Types[s.types[def.name]].Kind = kind
+	}
+
+	for _, item := range items {
+		if def, ok := item.(*astTypeDef); ok && r.refersTo(s.types[def.name], s.types[def.name], map[int]bool{}) {
+			return r.errorf(def.pos, "type `%s` refers to itself", def.name)
+		}
+	}
+	return nil
+}
+
+func (r *resolver) addType(t *jsonType) int {
+	r.def.Types = append(r.def.Types, t)
+	return len(r.def.Types) - 1
+}
+
+func (r *resolver) typeDefKind(s *scope, def *astTypeDef) (any, error) {
+	names := map[string]bool{}
+	for _, field := range def.fields {
+		if names[field.name] {
+			return nil, r.errorf(field.pos, "`%s` is defined more than once in %s `%s`", field.name, def.kind, def.name)
+		}
+		names[field.name] = true
+	}
+
+	switch def.kind {
+	case "type":
+		switch def.alias.kind {
+		case "prim":
+			return map[string]any{"type": def.alias.name}, nil
+		case "named":
+			idx, ok := s.types[def.alias.name]
+			if !ok {
+				return nil, r.errorf(def.alias.pos, "type `%s` does not exist", def.alias.name)
+			}
+			return map[string]any{"type": idx}, nil
+		default:
+			// Aliases of anonymous types define the type under their name.
+			return r.typeKind(s, def.alias)
+		}
+	case "record":
+		if len(def.fields) == 0 {
+			return nil, r.errorf(def.pos, "record `%s` must have at least one field", def.name)
+		}
+		fields := []*jsonField{}
+		for _, field := range def.fields {
+			ref, err := r.typeRef(s, field.typ)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, &jsonField{Name: field.name, Type: ref, Docs: newJsonDocs(field.docs)})
+		}
+		return map[string]any{"record": map[string]any{"fields": fields}}, nil
+	case "variant":
+		if len(def.fields) == 0 {
+			return nil, r.errorf(def.pos, "variant `%s` must have at least one case", def.name)
+		}
+		cases := []*jsonField{}
+		for _, c := range def.fields {
+			ref, err := r.typeRef(s, c.typ)
+			if err != nil {
+				return nil, err
+			}
+			cases = append(cases, &jsonField{Name: c.name, Type: ref, Docs: newJsonDocs(c.docs)})
+		}
+		return map[string]any{"variant": map[string]any{"cases": cases}}, nil
+	default:
+		if def.kind == "enum" && len(def.fields) == 0 {
+			return nil, r.errorf(def.pos, "enum `%s` must have at least one case", def.name)
+		}
+		cases := []*jsonCase{}
+		for _, c := range def.fields {
+			cases = append(cases, &jsonCase{Name: c.name, Docs: newJsonDocs(c.docs)})
+		}
+		if def.kind == "flags" {
+			return map[string]any{"flags": map[string]any{"flags": cases}}, nil
+		}
+		return map[string]any{"enum": map[string]any{"cases": cases}}, nil
+	}
+}
+
+// typeRef resolves a type expression to the name of a primitive type or the index of a type.
+// Anonymous types are interned, so equal type expressions share an index.
+func (r *resolver) typeRef(s *scope, t *astType) (any, error) {
+	if t == nil {
+		return nil, nil
+	}
+	switch t.kind {
+	case "prim":
+		return t.name, nil
+	case "named":
+		idx, ok := s.types[t.name]
+		if !ok {
+			return nil, r.errorf(t.pos, "type `%s` does not exist", t.name)
+		}
+		if !r.isResource(idx) {
+			return idx, nil
+		}
+	}
+
+	kind, err := r.typeKind(s, t)
+	if err != nil {
+		return nil, err
+	}
+	key, err := json.Marshal(kind)
+	if err != nil {
+		return nil, err
+	}
+	if idx, ok := r.anonymous[string(key)]; ok {
+		return idx, nil
+	}
+	idx := r.addType(&jsonType{Kind: kind})
+	r.anonymous[string(key)] = idx
+	return idx, nil
+}
+
+// typeKind resolves the kind of an anonymous type expression. A resource used as a type is an
+// owned handle.
+func (r *resolver) typeKind(s *scope, t *astType) (map[string]any, error) {
+	switch t.kind {
+	case "named":
+		return map[string]any{"handle": map[string]any{"own": s.types[t.name]}}, nil
+	case "own", "borrow":
+		resource := t.args[0]
+		idx, ok := s.types[resource.name]
+		if resource.kind != "named" || !ok || !r.isResource(idx) {
+			return nil, r.errorf(resource.pos, "`%s` requires a resource type", t.kind)
+		}
+		return map[string]any{"handle": map[string]any{t.kind: idx}}, nil
+	}
+
+	args := make([]any, len(t.args))
+	for i, arg := range t.args {
+		ref, err := r.typeRef(s, arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = ref
+	}
+	switch t.kind {
+	case "result":
+		return map[string]any{"result": jsonResult{Ok: args[0], Err: args[1]}}, nil
+	case "tuple":
+		return map[string]any{"tuple": map[string]any{"types": args}}, nil
+	default:
+		return map[string]any{t.kind: args[0]}, nil
+	}
+}
+
+// isResource reports whether the type idx is a resource or an alias of one.
+func (r *resolver) isResource(idx int) bool {
+	switch kind := r.def.Types[idx].Kind.(type) {
+	case string:
+		return kind == "resource"
+	case map[string]any:
+		if target, ok := kind["type"].(int); ok {
+			return r.isResource(target)
+		}
+	}
+	return false
+}
+
+// refersTo reports whether the type idx contains the type target, without going through handles.
+func (r *resolver) refersTo(idx int, target int, visited map[int]bool) bool {
+	var refs []any
+	kind, _ := r.def.Types[idx].Kind.(map[string]any)
+	for name, value := range kind {
+		switch name {
+		case "record":
+			for _, field := range value.(map[string]any)["fields"].([]*jsonField) {
+				refs = append(refs, field.Type)
+			}
+		case "variant":
+			for _, c := range value.(map[string]any)["cases"].([]*jsonField) {
+				refs = append(refs, c.Type)
+			}
+		case "tuple":
+			refs = append(refs, value.(map[string]any)["types"].([]any)...)
+		case "result":
+			refs = append(refs, value.(jsonResult).Ok, value.(jsonResult).Err)
+		case "type", "list", "option":
+			refs = append(refs, value)
+		}
+	}
+	for _, ref := range refs {
+		next, ok := ref.(int)
+		if !ok {
+			continue
+		}
+		if next == target {
+			return true
+		}
+		if !visited[next] {
+			visited[next] = true
+			if r.refersTo(next, target, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// function resolves a function. Functions of resources are named and typed like the canonical
+// ABI expects, for example `[method]blob.write` with an implicit `self` parameter.
+func (r *resolver) function(s *scope, fn *astFunc, resource string) (*jsonFunction, error) {
+	out := &jsonFunction{Name: fn.name, Kind: "freestanding", Params: []*jsonParam{}, Docs: newJsonDocs(fn.docs), Stability: newJsonStability(fn.stability)}
+	if resource != "" {
+		idx := s.types[resource]
+		out.Kind = map[string]any{fn.kind: idx}
+		switch fn.kind {
+		case "constructor":
+			out.Name = "[constructor]" + resource
+		case "method":
+			out.Name = "[method]" + resource + "." + fn.name
+			count := len(r.def.Types)
+			self, err := r.typeRef(s, &astType{pos: fn.pos, kind: "borrow", args: []*astType{{pos: fn.pos, kind: "named", name: resource}}})
+			if err != nil {
+				return nil, err
+			}
+			// Like wasm-tools, the handle of `self` has the stability of the resource.
+			if len(r.def.Types) > count {
+				r.def.Types[self.(int)].Stability = r.def.Types[idx].Stability
+			}
+			out.Params = append(out.Params, &jsonParam{Name: "self", Type: self})
+		case "static":
+			out.Name = "[static]" + resource + "." + fn.name
+		}
+	}
+
+	names := map[string]bool{}
+	for _, param := range fn.params {
+		if names[param.name] {
+			return nil, r.errorf(param.pos, "parameter `%s` is defined more than once", param.name)
+		}
+		names[param.name] = true
+		ref, err := r.typeRef(s, param.typ)
+		if err != nil {
+			return nil, err
+		}
+		out.Params = append(out.Params, &jsonParam{Name: param.name, Type: ref})
+	}
+
+	result := fn.result
+	if fn.kind == "constructor" && result == nil {
+		result = &astType{pos: fn.pos, kind: "named", name: resource}
+	}
+	ref, err := r.typeRef(s, result)
+	if err != nil {
+		return nil, err
+	}
+	out.Result = ref
+	return out, nil
+}
+
+func appendUnique(ids []int, id int) []int {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
@@ -0,0 +1,32 @@
+package wit
+
+// Stability is the stability of an item, given by its feature gates in WIT sources, like
+// `@since(version = 1.0.0)` or `@unstable(feature = x)`. Items without gates have a nil Stability.
+type Stability struct {
+	// Since is the version that stabilized the item. It is empty for unstable items.
+	Since string
+	// Feature is the feature gating an unstable item.
+	Feature string
+	// Deprecated is the version that deprecated the item, if any.
+	Deprecated string
+}
+
+// Unstable reports whether the item is gated by a feature.
+func (s *Stability) Unstable() bool {
+	return s != nil && s.Since == ""
+}
+
+// String returns the feature gates of the item, as written in WIT sources.
+func (s *Stability) String() string {
+	if s == nil {
+		return ""
+	}
+	gates := "@since(version = " + s.Since + ")"
+	if s.Unstable() {
+		gates = "@unstable(feature = " + s.Feature + ")"
+	}
+	if s.Deprecated != "" {
+		gates += " @deprecated(version = " + s.Deprecated + ")"
+	}
+	return gates
+}
@@ -0,0 +1,226 @@
+package wit_test
+
+import (
+	"testing"
+
+	"github.com/rioam2/witigo/pkg/wit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorldSelection(t *testing.T) {
+	resolve, err := wit.ParseDir("testdata/resolve")
+	require.NoError(t, err)
+	wasi, err := wit.ParseDir("testdata/wasi")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		def     wit.WitDefinition
+		world   string
+		pkg     string
+		wantErr string
+	}{
+		{name: "default world", def: wasi, world: "", pkg: "my:app"},
+		{name: "multiple worlds", def: resolve, world: "", wantErr: "package test:main@1.0.0 defines multiple worlds, select one of: base, app"},
+		{name: "plain name", def: resolve, world: "base", pkg: "test:main@1.0.0"},
+		{name: "plain name prefers root package", def: wasi, world: "app", pkg: "my:app"},
+		{name: "ambiguous name", def: wasi, world: "imports", wantErr: "world imports is ambiguous, select one of: wasi:io@0.2.0/imports, wasi:cli@0.2.0/imports"},
+		{name: "qualified name", def: wasi, world: "wasi:cli/imports", pkg: "wasi:cli@0.2.0"},
+		{name: "qualified name with version", def: wasi, world: "wasi:cli/imports@0.2.0", pkg: "wasi:cli@0.2.0"},
+		{name: "missing world", def: wasi, world: "missing", wantErr: "world missing not found"},
+		{name: "missing package", def: wasi, world: "wasi:http/imports", wantErr: "world wasi:http/imports not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			world, err := tt.def.World(tt.world)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.pkg, world.Package())
+		})
+	}
+}
+
+func TestNewFromJsonErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+	}{
+		{
+			name:    "malformed document",
+			json:    `{"types": 1}`,
+			wantErr: "error decoding WIT definition: json: cannot unmarshal number",
+		},
+		{
+			name:    "unknown kind",
+			json:    `{"types": [{"name": "a", "kind": {"map": ["u8", "u8"]}, "owner": null}]}`,
+			wantErr: `error decoding type 0: unknown type kind "map"`,
+		},
+		{
+			name:    "unknown primitive",
+			json:    `{"types": [{"name": "a", "kind": {"list": "u128"}, "owner": null}]}`,
+			wantErr: `error decoding type 0: unknown primitive type "u128"`,
+		},
+		{
+			name:    "type index out of range",
+			json:    `{"types": [{"name": "a", "kind": {"option": 3}, "owner": null}]}`,
+			wantErr: "error decoding type 0: type index 3 out of range",
+		},
+		{
+			name:    "alias cycle",
+			json:    `{"types": [{"name": "a", "kind": {"type": 1}, "owner": null}, {"name": "b", "kind": {"type": 0}, "owner": null}]}`,
+			wantErr: "type 0 is an alias of itself",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := wit.NewFromJson([]byte(tt.json), "test")
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestExportedFunctionsOrder(t *testing.T) {
+	def, err := wit.Parse("order.wit", `package test:order;
+
+world order {
+  export zeta: func();
+  export alpha: func(a: u32) -> u32;
+  export mid: func(s: string);
+}
+`)
+	require.NoError(t, err)
+	world, err := def.World("")
+	require.NoError(t, err)
+	var names []string
+	for _, f := range world.ExportedFunctions() {
+		names = append(names, f.Name())
+	}
+	assert.Equal(t, []string{"zeta", "alpha", "mid"}, names)
+}
+
+func TestReachableTypes(t *testing.T) {
+	def, err := wit.Parse("types.wit", `package test:types;
+
+interface shapes {
+  record id { id: u32 }
+  record point { x: u32, y: u32 }
+  record unused { p: point }
+  enum level { low, high }
+  get: func(l: level) -> list<tuple<u32, u32>>;
+}
+
+world app {
+  use shapes.{id, point};
+  import shapes;
+  import log: func(i: id);
+  export area: func(p: point) -> tuple<u32, u32>;
+  export count: func() -> u64;
+}
+`)
+	require.NoError(t, err)
+	world, err := def.World("")
+	require.NoError(t, err)
+
+	var types []string
+	for _, typ := range world.Types() {
+		types = append(types, typ.Name()+" "+typ.String())
+	}
+	assert.Equal(t, []string{
+		"id record{ id: u32 }",
+		"point record{ x: u32, y: u32 }",
+		"level enum{ low: u8, high: u8 }",
+		"(none) tuple<u32, u32>",
+		"(none) list<tuple<u32, u32>>",
+	}, types)
+
+	typeOf := func(name string) wit.WitType {
+		for _, typ := range def.Types() {
+			if typ.Name() == name && typ.OwnerInterface() != nil {
+				return typ
+			}
+		}
+		t.Fatalf("type %s not found", name)
+		return nil
+	}
+	area, count := world.ExportedFunctions()[0], world.ExportedFunctions()[1]
+	assert.True(t, area.ReferencesType(area.Params()[0].Type()))
+	assert.True(t, area.ReferencesType(typeOf("point")))
+	assert.False(t, area.ReferencesType(typeOf("id")), "types containing the same primitive are unrelated")
+	assert.True(t, area.ReferencesType(area.Returns().SubTypes()[0].Type()))
+	assert.False(t, count.ReferencesType(area.Returns().SubTypes()[0].Type()))
+	assert.True(t, world.ReferencesType(typeOf("id")), "types of imported functions are reachable")
+	assert.True(t, world.ReferencesType(typeOf("level")), "types of imported interfaces are reachable")
+	assert.False(t, world.ReferencesType(typeOf("unused")))
+}
+
+func TestNavigation(t *testing.T) {
+	def, err := wit.ParseFile("testdata/stability.wit")
+	require.NoError(t, err)
+
+	require.Len(t, def.Packages(), 1)
+	pkg := def.Packages()[0]
+	assert.Equal(t, "test:stability@1.1.0", pkg.Name())
+
+	var interfaces []string
+	for _, iface := range def.Interfaces() {
+		interfaces = append(interfaces, iface.QualifiedName())
+	}
+	assert.Equal(t, []string{"test:stability/legacy@1.1.0", "test:stability/current@1.1.0", "app.inline"}, interfaces)
+
+	legacy := def.Interfaces()[0]
+	assert.Equal(t, "legacy", legacy.Name())
+	assert.Equal(t, "A deprecated interface.", legacy.Docs())
+	assert.Equal(t, "@unstable(feature = experimental) @deprecated(version = 1.1.0)", legacy.Stability().String())
+	assert.Equal(t, pkg.Name(), legacy.Package().Name())
+
+	var methods []string
+	for _, function := range legacy.Functions() {
+		require.NotNil(t, function.Resource())
+		assert.Equal(t, "handle", function.Resource().Name())
+		methods = append(methods, function.Kind()+" "+function.Name())
+	}
+	assert.Equal(t, []string{"constructor [constructor]handle", "method [method]handle.poke", "static [static]handle.open"}, methods)
+
+	current := def.Interfaces()[1]
+	require.Len(t, current.Functions(), 1)
+	setMode := current.Functions()[0]
+	assert.Equal(t, "freestanding", setMode.Kind())
+	assert.Nil(t, setMode.Resource())
+	assert.Equal(t, "Sets the mode.", setMode.Docs())
+	assert.Equal(t, "@since(version = 1.1.0)", setMode.Stability().String())
+
+	mode := setMode.Params()[0].Type()
+	assert.Equal(t, "mode", mode.Name())
+	assert.Equal(t, "test:stability/current@1.1.0", *mode.Owner())
+	assert.Equal(t, "@since(version = 1.0.0)", mode.Stability().String())
+	require.NotNil(t, mode.AliasOf())
+	assert.Equal(t, "test:stability/legacy@1.1.0", mode.AliasOf().OwnerInterface().QualifiedName())
+	assert.Equal(t, "@since(version = 1.0.0) @deprecated(version = 1.1.0)", mode.AliasOf().Stability().String())
+
+	app, err := def.World("app")
+	require.NoError(t, err)
+	var imports []string
+	for _, item := range app.Imports() {
+		imports = append(imports, item.Key()+" "+item.Stability().String())
+	}
+	assert.Equal(t, []string{
+		"test:stability/legacy@1.1.0 @since(version = 1.0.0)",
+		"mode @since(version = 1.0.0)",
+		"inline @unstable(feature = inline)",
+		"test:stability/current@1.1.0 @since(version = 1.0.0)",
+		"level @since(version = 1.1.0)",
+	}, imports)
+
+	run := app.Exports()[len(app.Exports())-1].Function()
+	require.NotNil(t, run)
+	level := run.Returns()
+	assert.Equal(t, "level", level.Name())
+	assert.Equal(t, "app", *level.Owner())
+	assert.Equal(t, "app", level.OwnerWorld().Name())
+	assert.Nil(t, level.OwnerInterface())
+}
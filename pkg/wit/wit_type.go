@@ -1,7 +1,6 @@
 package wit
 
 import (
-	"encoding/json"
 	"fmt"
 	"math"
 
@@ -11,315 +10,221 @@ import (
 type WitType interface {
 	Name() string
 	Kind() witigo.AbiType
+	Docs() string
+	Stability() *Stability
 	Owner() *string
+	OwnerInterface() WitInterface
+	OwnerWorld() WitWorldDefinition
+	AliasOf() WitType
+	IsBorrow() bool
 	String() string
 	SubType() WitTypeReference
 	SubTypes() []WitTypeReference
 	IsPrimitive() bool
 }
 
+// WitTypeImpl is a view of the type at index in a definition, or of a primitive type when index is
+// negative.
 type WitTypeImpl struct {
-	Raw  json.RawMessage
-	Root WitDefinition
+	def   *WitDefinitionImpl
+	index int
+	prim  string
+	// name is the name of the reference a primitive type was reached through.
+	name string
 }
 
 var _ WitType = &WitTypeImpl{}
 
-func (w *WitTypeImpl) Name() string {
-	var data struct {
-		Name *string `json:"name"`
-	}
-	json.Unmarshal(w.Raw, &data)
-	if data.Name == nil {
-		return "(none)"
+// typeOf returns the type referenced by ref, or nil if ref is nil. name is the name of the
+// reference, which primitive types are named after.
+func (w *WitDefinitionImpl) typeOf(ref *typeRef, name string) WitType {
+	switch {
+	case ref == nil:
+		return nil
+	case ref.index < 0:
+		return &WitTypeImpl{def: w, index: -1, prim: ref.prim, name: name}
+	default:
+		return w.types[ref.index]
 	}
-	return *data.Name
 }
 
-func (w *WitTypeImpl) Kind() witigo.AbiType {
-	var dataVer1 struct {
-		Kind *string `json:"kind"`
+// references reports whether t is reachable by walk. Primitive types are reachable if a reference
+// to a primitive of the same kind is.
+func (w *WitDefinitionImpl) references(t WitType, walk func(reachable map[int]bool, visit func(ref *typeRef))) bool {
+	target, ok := t.(*WitTypeImpl)
+	if !ok || target.def != w {
+		return false
 	}
-	err := json.Unmarshal(w.Raw, &dataVer1)
-	if err == nil && dataVer1.Kind != nil {
-		switch *dataVer1.Kind {
-		case "resource":
-			return witigo.AbiTypeResource
+	reachable := map[int]bool{}
+	found := false
+	walk(reachable, func(ref *typeRef) {
+		if ref.index < 0 && target.index < 0 && ref.prim == target.prim {
+			found = true
 		}
-	}
+	})
+	return found || target.index >= 0 && reachable[target.index]
+}
 
-	var dataVer2 struct {
-		Kind *struct {
-			List    *json.RawMessage `json:"list"`
-			Option  *json.RawMessage `json:"option"`
-			Record  *json.RawMessage `json:"record"`
-			Tuple   *json.RawMessage `json:"tuple"`
-			Result  *json.RawMessage `json:"result"`
-			Variant *json.RawMessage `json:"variant"`
-			Enum    *json.RawMessage `json:"enum"`
-			Type    *json.RawMessage `json:"type"`
-			Handle  *json.RawMessage `json:"handle"`
-			Flags   *json.RawMessage `json:"flags"`
-		} `json:"kind"`
-		Type *string `json:"type"`
+func (w *WitTypeImpl) data() *typeData {
+	if w.index < 0 {
+		return nil
 	}
-	json.Unmarshal(w.Raw, &dataVer2)
-	if dataVer2.Type != nil {
-		switch *dataVer2.Type {
-		case "string":
-			return witigo.AbiTypeString
-		case "bool":
-			return witigo.AbiTypeBool
-		case "s8":
-			return witigo.AbiTypeS8
-		case "s16":
-			return witigo.AbiTypeS16
-		case "s32":
-			return witigo.AbiTypeS32
-		case "s64":
-			return witigo.AbiTypeS64
-		case "u8":
-			return witigo.AbiTypeU8
-		case "u16":
-			return witigo.AbiTypeU16
-		case "u32":
-			return witigo.AbiTypeU32
-		case "u64":
-			return witigo.AbiTypeU64
-		case "f32":
-			return witigo.AbiTypeF32
-		case "f64":
-			return witigo.AbiTypeF64
-		case "char":
-			return witigo.AbiTypeChar
+	return &w.def.model.types[w.index]
+}
+
+func (w *WitTypeImpl) Name() string {
+	name := w.name
+	if data := w.data(); data != nil {
+		name = ""
+		if data.name != nil {
+			name = *data.name
 		}
 	}
-	if dataVer2.Kind.List != nil {
-		return witigo.AbiTypeList
-	}
-	if dataVer2.Kind.Option != nil {
-		return witigo.AbiTypeOption
-	}
-	if dataVer2.Kind.Record != nil {
-		return witigo.AbiTypeRecord
-	}
-	if dataVer2.Kind.Tuple != nil {
-		return witigo.AbiTypeTuple
-	}
-	if dataVer2.Kind.Result != nil {
-		return witigo.AbiTypeResult
-	}
-	if dataVer2.Kind.Variant != nil {
-		return witigo.AbiTypeVariant
-	}
-	if dataVer2.Kind.Enum != nil {
-		return witigo.AbiTypeEnum
+	if name == "" {
+		return "(none)"
 	}
-	if dataVer2.Kind.Type != nil {
-		rawTypeRef, err := json.Marshal(map[string]any{
-			"type": dataVer2.Kind.Type,
-			"name": w.Name(),
-		})
-		if err != nil {
-			panic(fmt.Sprintf("Failed to marshal type reference: %v", err))
-		}
-		ref := &WitTypeReferenceImpl{Raw: rawTypeRef, Root: w.Root}
-		return ref.Type().Kind()
+	return name
+}
+
+func (w *WitTypeImpl) Kind() witigo.AbiType {
+	if data := w.data(); data != nil {
+		return data.kind
 	}
-	if dataVer2.Kind.Handle != nil {
-		return witigo.AbiTypeHandle
+	return primitiveKinds[w.prim]
+}
+
+func (w *WitTypeImpl) Docs() string {
+	if data := w.data(); data != nil {
+		return data.docs
 	}
-	if dataVer2.Kind.Flags != nil {
-		return witigo.AbiTypeFlags
+	return ""
+}
+
+func (w *WitTypeImpl) Stability() *Stability {
+	if data := w.data(); data != nil {
+		return data.stability
 	}
-	panic(fmt.Sprintf("Unknown WIT type kind: %v\n%v", dataVer2.Kind, string(w.Raw)))
+	return nil
 }
 
+// Owner returns the name of the world or the qualified name of the interface defining the type,
+// or nil for anonymous and primitive types.
 func (w *WitTypeImpl) Owner() *string {
-	var data struct {
-		Owner *struct {
-			Interface *float64 `json:"interface"`
-			World     *float64 `json:"world"`
-		} `json:"owner"`
-	}
-	err := json.Unmarshal(w.Raw, &data)
-	if err != nil || data.Owner == nil {
+	var name string
+	if world := w.OwnerWorld(); world != nil {
+		name = world.Name()
+	} else if iface := w.OwnerInterface(); iface != nil {
+		name = iface.QualifiedName()
+	} else {
 		return nil
 	}
-	if data.Owner.World != nil {
-		name := w.Root.Worlds()[int(*data.Owner.World)].Name()
-		return &name
-	}
-	if data.Owner.Interface != nil {
-		name := fmt.Sprintf("interface %d", int(*data.Owner.Interface))
-		return &name
-	}
-	return nil
+	return &name
 }
 
-func (w *WitTypeImpl) SubType() WitTypeReference {
-	var data struct {
-		Kind struct {
-			List   *any `json:"list"`
-			Option *any `json:"option"`
-			Handle *struct {
-				Own    *json.RawMessage `json:"own"`
-				Borrow *json.RawMessage `json:"borrow"`
-			}
-		} `json:"kind"`
-	}
-	err := json.Unmarshal(w.Raw, &data)
-	if err != nil {
+// OwnerInterface returns the interface defining the type, or nil if it is not defined by an
+// interface.
+func (w *WitTypeImpl) OwnerInterface() WitInterface {
+	data := w.data()
+	if data == nil || data.owner.iface == nil {
 		return nil
 	}
-	var subTypeRef any = nil
-	if data.Kind.List != nil {
-		subTypeRef = data.Kind.List
-	} else if data.Kind.Option != nil {
-		subTypeRef = data.Kind.Option
-	} else if data.Kind.Handle != nil {
-		if data.Kind.Handle.Own != nil {
-			subTypeRef = data.Kind.Handle.Own
-		} else if data.Kind.Handle.Borrow != nil {
-			subTypeRef = data.Kind.Handle.Borrow
-		}
-	}
-	rawTypeRef, err := json.Marshal(map[string]any{
-		"type": subTypeRef,
-		"name": w.Name(),
-	})
-	if err != nil {
-		panic(fmt.Sprintf("Failed to marshal list type reference: %v", err))
-	}
-	return &WitTypeReferenceImpl{Raw: rawTypeRef, Root: w.Root}
+	return w.def.interfaces[*data.owner.iface]
 }
 
-func (w *WitTypeImpl) SubTypes() []WitTypeReference {
-	var data struct {
-		Kind struct {
-			Record  *json.RawMessage `json:"record"`
-			Variant *json.RawMessage `json:"variant"`
-			Enum    *json.RawMessage `json:"enum"`
-			Tuple   *json.RawMessage `json:"tuple"`
-			Result  *json.RawMessage `json:"result"`
-		} `json:"kind"`
-	}
-	err := json.Unmarshal(w.Raw, &data)
-	if err != nil {
+// OwnerWorld returns the world defining the type, or nil if it is not defined by a world.
+func (w *WitTypeImpl) OwnerWorld() WitWorldDefinition {
+	data := w.data()
+	if data == nil || data.owner.world == nil {
 		return nil
 	}
-	if data.Kind.Record != nil {
-		return w.handleRecordType(data.Kind.Record)
-	}
-	if data.Kind.Variant != nil {
-		return w.handleVariantType(data.Kind.Variant)
-	}
-	if data.Kind.Enum != nil {
-		return w.handleEnumType(data.Kind.Enum)
-	}
-	if data.Kind.Tuple != nil {
-		return w.handleTupleType(data.Kind.Tuple)
-	}
-	if data.Kind.Result != nil {
-		return w.handleResultType(data.Kind.Result)
-	}
-	return nil
+	return w.def.worlds[*data.owner.world]
 }
 
-func discriminantSize(n int) int {
-	bits := math.Ceil(math.Log2(float64(n))/8) * 8
-	return int(bits)
+// AliasOf returns the type this type is an alias of, for `type a = b` definitions and types
+// brought in scope by `use`, or nil otherwise.
+func (w *WitTypeImpl) AliasOf() WitType {
+	return w.aliasTarget()
 }
 
-func (w *WitTypeImpl) handleRecordType(rawRecord *json.RawMessage) []WitTypeReference {
-	var subTypes []WitTypeReference
-	var record struct {
-		Fields []json.RawMessage `json:"fields"`
-	}
-	json.Unmarshal(*rawRecord, &record)
-	for _, field := range record.Fields {
-		subTypes = append(subTypes, &WitTypeReferenceImpl{Raw: field, Root: w.Root})
+// IsBorrow reports whether the type is a borrowed handle, or an alias of one.
+func (w *WitTypeImpl) IsBorrow() bool {
+	if target := w.aliasTarget(); target != nil {
+		return target.IsBorrow()
 	}
-	return subTypes
+	data := w.data()
+	return data != nil && data.kind == witigo.AbiTypeHandle && data.borrow
 }
 
-func (w *WitTypeImpl) handleVariantType(rawVariant *json.RawMessage) []WitTypeReference {
-	var subTypes []WitTypeReference
-	var variant struct {
-		Cases []json.RawMessage `json:"cases"`
+func (w *WitTypeImpl) SubType() WitTypeReference {
+	if target := w.aliasTarget(); target != nil {
+		return target.SubType()
 	}
-	json.Unmarshal(*rawVariant, &variant)
-	for _, c := range variant.Cases {
-		subTypes = append(subTypes, &WitTypeReferenceImpl{Raw: c, Root: w.Root})
+	var elem *typeRef
+	if data := w.data(); data != nil {
+		elem = data.elem
 	}
-	return subTypes
+	return &WitTypeReferenceImpl{def: w.def, name: w.Name(), ref: elem}
 }
 
-func (w *WitTypeImpl) handleEnumType(rawEnum *json.RawMessage) []WitTypeReference {
-	var subTypes []WitTypeReference
-	var enum struct {
-		Cases []struct {
-			Name string `json:"name"`
-		} `json:"cases"`
+func (w *WitTypeImpl) SubTypes() []WitTypeReference {
+	if target := w.aliasTarget(); target != nil {
+		return target.SubTypes()
 	}
-	json.Unmarshal(*rawEnum, &enum)
-	discriminantType := fmt.Sprintf("u%d", discriminantSize(len(enum.Cases)))
-	for _, c := range enum.Cases {
-		remappedType, err := json.Marshal(map[string]any{
-			"type": discriminantType,
-			"name": c.Name,
-		})
-		if err != nil {
-			panic(fmt.Sprintf("Failed to marshal enum type reference: %v", err))
+	data := w.data()
+	if data == nil {
+		return nil
+	}
+	switch data.kind {
+	case witigo.AbiTypeRecord, witigo.AbiTypeVariant, witigo.AbiTypeFlags:
+		return w.namedSubTypes(data.fields, nil)
+	case witigo.AbiTypeEnum:
+		discriminantType := &typeRef{prim: fmt.Sprintf("u%d", discriminantSize(len(data.fields))), index: -1}
+		return w.namedSubTypes(data.fields, discriminantType)
+	case witigo.AbiTypeTuple:
+		subTypes := make([]WitTypeReference, len(data.tuple))
+		for i := range data.tuple {
+			subTypes[i] = &WitTypeReferenceImpl{def: w.def, ref: &data.tuple[i]}
+		}
+		return subTypes
+	case witigo.AbiTypeResult:
+		return []WitTypeReference{
+			&WitTypeReferenceImpl{def: w.def, name: "ok", ref: data.ok},
+			&WitTypeReferenceImpl{def: w.def, name: "error", ref: data.err},
 		}
-		subTypes = append(subTypes, &WitTypeReferenceImpl{Raw: remappedType, Root: w.Root})
+	default:
+		return nil
 	}
-	return subTypes
 }
 
-func (w *WitTypeImpl) handleTupleType(rawTuple *json.RawMessage) []WitTypeReference {
-	var subTypes []WitTypeReference
-	var tuple struct {
-		Types []any `json:"types"`
-	}
-	json.Unmarshal(*rawTuple, &tuple)
-	for _, t := range tuple.Types {
-		remappedType, err := json.Marshal(map[string]any{
-			"type": t,
-			"name": nil,
-		})
-		if err != nil {
-			panic(fmt.Sprintf("Failed to marshal tuple type reference: %v", err))
+// namedSubTypes returns references to fields. Fields without type refer to typ instead.
+func (w *WitTypeImpl) namedSubTypes(fields []namedRef, typ *typeRef) []WitTypeReference {
+	subTypes := make([]WitTypeReference, len(fields))
+	for i, field := range fields {
+		ref := field.ref
+		if ref == nil {
+			ref = typ
 		}
-		subTypes = append(subTypes, &WitTypeReferenceImpl{Raw: remappedType, Root: w.Root})
+		subTypes[i] = &WitTypeReferenceImpl{def: w.def, name: field.name, ref: ref, docs: field.docs}
 	}
 	return subTypes
 }
 
-func (w *WitTypeImpl) handleResultType(rawResult *json.RawMessage) []WitTypeReference {
-	var result struct {
-		Ok  *any `json:"ok"`
-		Err *any `json:"err"`
-	}
-	json.Unmarshal(*rawResult, &result)
-	okType, err := json.Marshal(map[string]any{
-		"type": result.Ok,
-		"name": "ok",
-	})
-	if err != nil {
-		panic(fmt.Sprintf("Failed to marshal result ok type reference: %v", err))
-	}
-	errType, err := json.Marshal(map[string]any{
-		"type": result.Err,
-		"name": "error",
-	})
-	if err != nil {
-		panic(fmt.Sprintf("Failed to marshal result err type reference: %v", err))
+// aliasTarget returns the referenced type if this type is an alias (`type a = b`), or nil otherwise.
+func (w *WitTypeImpl) aliasTarget() WitType {
+	data := w.data()
+	if data == nil || data.alias == nil {
+		return nil
 	}
-	return []WitTypeReference{
-		&WitTypeReferenceImpl{Raw: okType, Root: w.Root},
-		&WitTypeReferenceImpl{Raw: errType, Root: w.Root},
+	return w.def.typeOf(data.alias, w.Name())
+}
+
+// discriminantSize returns the number of bits of the discriminant of an enum with n cases.
+func discriminantSize(n int) int {
+	bits := int(math.Ceil(math.Log2(float64(n))/8) * 8)
+	if bits < 8 {
+		return 8
 	}
+	return bits
 }
 
 func (w *WitTypeImpl) IsPrimitive() bool {
@@ -1,49 +1,38 @@
 package wit
 
-import (
-	"encoding/json"
-)
-
 type WitTypeReference interface {
 	Name() string
 	Type() WitType
+	Docs() string
 	String() string
 }
 
+// WitTypeReferenceImpl is a named use of a type, like a parameter or a record field.
 type WitTypeReferenceImpl struct {
-	Raw  json.RawMessage
-	Root WitDefinition
+	def  *WitDefinitionImpl
+	name string
+	ref  *typeRef
+	docs string
 }
 
 var _ WitTypeReference = &WitTypeReferenceImpl{}
 
 func (w *WitTypeReferenceImpl) Name() string {
-	var data struct {
-		Name *string `json:"name"`
-	}
-	json.Unmarshal(w.Raw, &data)
-	if data.Name == nil {
+	if w.name == "" {
 		return "(none)"
 	}
-	return *data.Name
+	return w.name
 }
 
+// Type returns the referenced type, or nil when the reference has no type, like enum cases and
+// variant cases without payload.
 func (w *WitTypeReferenceImpl) Type() WitType {
-	var data struct {
-		Type *any `json:"type"`
-	}
-	json.Unmarshal(w.Raw, &data)
-	if data.Type == nil {
-		return nil
-	}
-	switch t := (*data.Type).(type) {
-	case string:
-		return &WitTypeImpl{w.Raw, w.Root}
-	case float64:
-		return w.Root.Types()[int(t)]
-	default:
-		return nil
-	}
+	return w.def.typeOf(w.ref, w.name)
+}
+
+// Docs returns the documentation of record fields and of variant, enum and flags cases.
+func (w *WitTypeReferenceImpl) Docs() string {
+	return w.docs
 }
 
 func (w *WitTypeReferenceImpl) String() string {
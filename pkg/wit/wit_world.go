@@ -1,58 +1,121 @@
 package wit
 
 import (
-	"encoding/json"
+	"sort"
+	"strings"
 )
 
 type WitWorldDefinition interface {
 	Name() string
+	Package() string
+	Docs() string
+	Stability() *Stability
+	Imports() []WitWorldItem
+	Exports() []WitWorldItem
+	ImportedFunctions() []WitFunction
 	ExportedFunctions() []WitFunction
 	Types() []WitType
 	String() string
 	ReferencesType(w WitType) bool
 }
 
+// WitWorldDefinitionImpl is a view of the world at index in a definition.
 type WitWorldDefinitionImpl struct {
-	Raw  json.RawMessage
-	Root WitDefinition
+	def   *WitDefinitionImpl
+	index int
 }
 
 var _ WitWorldDefinition = &WitWorldDefinitionImpl{}
 
+func (w *WitWorldDefinitionImpl) data() *worldData {
+	return &w.def.model.worlds[w.index]
+}
+
 func (w *WitWorldDefinitionImpl) Name() string {
-	var data struct {
-		Name string `json:"name"`
+	return w.data().name
+}
+
+// Package returns the name of the package defining the world, for example `wasi:cli@0.2.0`.
+func (w *WitWorldDefinitionImpl) Package() string {
+	pkg := w.data().pkg
+	if pkg == nil {
+		return ""
 	}
-	json.Unmarshal(w.Raw, &data)
-	return data.Name
+	return w.def.model.packages[*pkg].name
 }
 
-func (w *WitWorldDefinitionImpl) ExportedFunctions() []WitFunction {
-	var data struct {
-		Exports map[string]struct {
-			Function *json.RawMessage `json:"function"`
-		} `json:"exports"`
+func (w *WitWorldDefinitionImpl) Docs() string {
+	return w.data().docs
+}
+
+func (w *WitWorldDefinitionImpl) Stability() *Stability {
+	return w.data().stability
+}
+
+// Imports returns the functions, interfaces and types imported by the world, including the
+// interfaces they depend on, in the order they are declared.
+func (w *WitWorldDefinitionImpl) Imports() []WitWorldItem {
+	return w.items(w.data().imports)
+}
+
+// Exports returns the functions and interfaces exported by the world, in the order they are
+// declared.
+func (w *WitWorldDefinitionImpl) Exports() []WitWorldItem {
+	return w.items(w.data().exports)
+}
+
+func (w *WitWorldDefinitionImpl) items(data []worldItem) []WitWorldItem {
+	items := make([]WitWorldItem, len(data))
+	for i := range data {
+		items[i] = &WitWorldItemImpl{def: w.def, data: &data[i]}
 	}
-	json.Unmarshal(w.Raw, &data)
+	return items
+}
+
+// ImportedFunctions returns the functions imported by the world, in the order they are declared.
+func (w *WitWorldDefinitionImpl) ImportedFunctions() []WitFunction {
+	return w.functions(w.data().imports)
+}
+
+// ExportedFunctions returns the functions exported by the world, in the order they are declared.
+func (w *WitWorldDefinitionImpl) ExportedFunctions() []WitFunction {
+	return w.functions(w.data().exports)
+}
+
+func (w *WitWorldDefinitionImpl) functions(items []worldItem) []WitFunction {
 	var functions []WitFunction
-	for _, export := range data.Exports {
-		if export.Function == nil {
-			continue
+	for _, item := range items {
+		if item.function != nil {
+			functions = append(functions, &WitFunctionImpl{def: w.def, data: item.function})
 		}
-		functions = append(functions, &WitFunctionImpl{*export.Function, w.Root})
 	}
 	return functions
 }
 
+// Types returns the named and anonymous types reachable from the functions imported and exported
+// by the world, in the order they are defined. Aliases brought in scope by `use` that keep the name
+// of the type they refer to are returned once, as are identical anonymous types.
 func (w *WitWorldDefinitionImpl) Types() []WitType {
-	types := make([]WitType, 0)
-	for _, t := range w.Root.Types() {
-		for _, function := range w.ExportedFunctions() {
-			if function.ReferencesType(t) {
-				types = append(types, t)
-				break
-			}
+	reachable := map[int]bool{}
+	w.def.model.walkWorld(w.data(), reachable, nil)
+	indices := make([]int, 0, len(reachable))
+	for index := range reachable {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+
+	types := make([]WitType, 0, len(indices))
+	seen := map[string]bool{}
+	for _, index := range indices {
+		key := w.def.model.shape(&typeRef{index: index})
+		if name := w.def.model.types[index].name; name != nil {
+			key = *name + " " + key
 		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		types = append(types, w.def.types[index])
 	}
 	return types
 }
@@ -66,11 +129,73 @@ func (w *WitWorldDefinitionImpl) String() string {
 	return base
 }
 
+// ReferencesType reports whether t is reachable from the functions imported and exported by the
+// world. t must be a type of the same definition.
 func (w *WitWorldDefinitionImpl) ReferencesType(t WitType) bool {
-	for _, function := range w.ExportedFunctions() {
-		if function.ReferencesType(t) {
-			return true
+	return w.def.references(t, func(reachable map[int]bool, visit func(ref *typeRef)) {
+		w.def.model.walkWorld(w.data(), reachable, visit)
+	})
+}
+
+// WitWorldItem is an import or export of a world. Exactly one of Function, Interface and Type is
+// non-nil.
+type WitWorldItem interface {
+	// Key is the name of the item. Interfaces imported or exported by path have keys like
+	// `wasi:io/streams@0.2.0`.
+	Key() string
+	Function() WitFunction
+	Interface() WitInterface
+	Type() WitType
+	Stability() *Stability
+}
+
+// WitWorldItemImpl is a view of an import or export of a world.
+type WitWorldItemImpl struct {
+	def  *WitDefinitionImpl
+	data *worldItem
+}
+
+var _ WitWorldItem = &WitWorldItemImpl{}
+
+func (w *WitWorldItemImpl) Key() string {
+	if w.data.iface != nil && strings.HasPrefix(w.data.key, "interface-") {
+		if name := w.Interface().QualifiedName(); name != "" {
+			return name
 		}
 	}
-	return false
+	return w.data.key
+}
+
+func (w *WitWorldItemImpl) Function() WitFunction {
+	if w.data.function == nil {
+		return nil
+	}
+	return &WitFunctionImpl{def: w.def, data: w.data.function}
+}
+
+func (w *WitWorldItemImpl) Interface() WitInterface {
+	if w.data.iface == nil {
+		return nil
+	}
+	return w.def.interfaces[*w.data.iface]
+}
+
+func (w *WitWorldItemImpl) Type() WitType {
+	if w.data.typ == nil {
+		return nil
+	}
+	return w.def.types[*w.data.typ]
+}
+
+// Stability returns the stability of the item, which is the stability of the function or type for
+// function and type items.
+func (w *WitWorldItemImpl) Stability() *Stability {
+	switch {
+	case w.data.function != nil:
+		return w.data.function.stability
+	case w.data.typ != nil:
+		return w.def.model.types[*w.data.typ].stability
+	default:
+		return w.data.stability
+	}
 }